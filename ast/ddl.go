@@ -194,10 +194,20 @@ type IndexColName struct {
 
 	Column *ColumnName
 	Length int
+	// Expr is used for functional key parts, e.g. `INDEX idx((a+1))`.
+	Expr ExprNode
 }
 
 // Restore implements Node interface.
 func (n *IndexColName) Restore(ctx *RestoreCtx) error {
+	if n.Expr != nil {
+		ctx.WritePlain("(")
+		if err := n.Expr.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while splicing IndexColName Expr")
+		}
+		ctx.WritePlain(")")
+		return nil
+	}
 	if err := n.Column.Restore(ctx); err != nil {
 		return errors.Annotate(err, "An error occurred while splicing IndexColName")
 	}
@@ -214,11 +224,20 @@ func (n *IndexColName) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*IndexColName)
-	node, ok := n.Column.Accept(v)
-	if !ok {
-		return n, false
+	if n.Column != nil {
+		node, ok := n.Column.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Column = node.(*ColumnName)
+	}
+	if n.Expr != nil {
+		node, ok := n.Expr.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Expr = node.(ExprNode)
 	}
-	n.Column = node.(*ColumnName)
 	return v.Leave(n)
 }
 
@@ -398,6 +417,8 @@ const (
 	ColumnOptionCollate
 	ColumnOptionCheck
 	ColumnOptionColumnFormat
+	ColumnOptionVisible
+	ColumnOptionInvisible
 )
 
 var (
@@ -422,6 +443,10 @@ type ColumnOption struct {
 	// Refer is used for foreign key.
 	Refer    *ReferenceDef
 	StrValue string
+	// Enforced is only for ColumnOptionCheck, default is true.
+	Enforced bool
+	// ConstraintName is only for ColumnOptionCheck.
+	ConstraintName string
 }
 
 // Restore implements Node interface.
@@ -478,6 +503,25 @@ func (n *ColumnOption) Restore(ctx *RestoreCtx) error {
 		}
 		ctx.WriteKeyWord("COLLATE ")
 		ctx.WritePlain(n.StrValue)
+	case ColumnOptionCheck:
+		if n.ConstraintName != "" {
+			ctx.WriteKeyWord("CONSTRAINT ")
+			ctx.WriteName(n.ConstraintName)
+			ctx.WritePlain(" ")
+		}
+		ctx.WriteKeyWord("CHECK")
+		ctx.WritePlain("(")
+		if err := n.Expr.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while splicing ColumnOption CHECK Expr")
+		}
+		ctx.WritePlain(")")
+		if !n.Enforced {
+			ctx.WriteKeyWord(" NOT ENFORCED")
+		}
+	case ColumnOptionVisible:
+		ctx.WriteKeyWord("VISIBLE")
+	case ColumnOptionInvisible:
+		ctx.WriteKeyWord("INVISIBLE")
 	default:
 		return errors.New("An error occurred while splicing ColumnOption")
 	}
@@ -511,6 +555,16 @@ const (
 	IndexVisibilityInvisible
 )
 
+func (v IndexVisibility) String() string {
+	switch v {
+	case IndexVisibilityVisible:
+		return "VISIBLE"
+	case IndexVisibilityInvisible:
+		return "INVISIBLE"
+	}
+	return ""
+}
+
 // IndexOption is the index options.
 //    KEY_BLOCK_SIZE [=] value
 //  | index_type
@@ -551,6 +605,14 @@ func (n *IndexOption) Restore(ctx *RestoreCtx) error {
 		}
 		ctx.WriteKeyWord("COMMENT ")
 		ctx.WriteString(n.Comment)
+		hasPrevOption = true
+	}
+
+	if n.Visibility != IndexVisibilityDefault {
+		if hasPrevOption {
+			ctx.WritePlain(" ")
+		}
+		ctx.WriteKeyWord(n.Visibility.String())
 	}
 	return nil
 }
@@ -609,6 +671,22 @@ func (n *Constraint) Restore(ctx *RestoreCtx) error {
 	switch n.Tp {
 	case ConstraintNoConstraint:
 		return nil
+	case ConstraintCheck:
+		if n.Name != "" {
+			ctx.WriteKeyWord("CONSTRAINT ")
+			ctx.WriteName(n.Name)
+			ctx.WritePlain(" ")
+		}
+		ctx.WriteKeyWord("CHECK")
+		ctx.WritePlain("(")
+		if err := n.Expr.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while splicing Constraint Expr")
+		}
+		ctx.WritePlain(")")
+		if !n.Enforced {
+			ctx.WriteKeyWord(" NOT ENFORCED")
+		}
+		return nil
 	case ConstraintPrimaryKey:
 		ctx.WriteKeyWord("PRIMARY KEY")
 	case ConstraintKey:
@@ -693,6 +771,13 @@ func (n *Constraint) Accept(v Visitor) (Node, bool) {
 		}
 		n.Option = node.(*IndexOption)
 	}
+	if n.Expr != nil {
+		node, ok := n.Expr.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Expr = node.(ExprNode)
+	}
 	return v.Leave(n)
 }
 
@@ -1392,6 +1477,13 @@ const (
 	TokuDBRowFormatUncompressed
 )
 
+// TableOptionCharset UintValue types, which distinguish `DEFAULT CHARACTER SET`
+// from `CONVERT TO CHARACTER SET` in ALTER TABLE.
+const (
+	TableOptionCharsetWithoutConvertTo uint64 = iota
+	TableOptionCharsetWithConvertTo
+)
+
 // OnDuplicateKeyHandlingType is the option that handle unique key values in 'CREATE TABLE ... SELECT' or `LOAD DATA`.
 // See https://dev.mysql.com/doc/refman/5.7/en/create-table-select.html
 // See https://dev.mysql.com/doc/refman/5.7/en/load-data.html
@@ -1628,6 +1720,12 @@ const (
 	AlterTablePartition
 	AlterTableEnableKeys
 	AlterTableDisableKeys
+	AlterTableRenameColumn
+	AlterTableIndexInvisible
+	AlterTableDropCheck
+	AlterTableAlterCheck
+	AlterTableReorganizePartition
+	AlterTableExchangePartition
 
 // TODO: Add more actions
 )
@@ -1735,6 +1833,7 @@ type AlterTableSpec struct {
 	NewTable        *TableName
 	NewColumns      []*ColumnDef
 	OldColumnName   *ColumnName
+	NewColumnName   *ColumnName
 	Position        *ColumnPosition
 	LockType        LockType
 	Algorithm       AlgorithmType
@@ -1746,6 +1845,8 @@ type AlterTableSpec struct {
 	PartDefinitions []*PartitionDefinition
 	Num             uint64
 	Visibility      IndexVisibility
+	// WithValidation is used by EXCHANGE PARTITION, false means WITHOUT VALIDATION
+	WithValidation bool
 }
 
 // Restore implements Node interface.
@@ -1753,13 +1854,15 @@ func (n *AlterTableSpec) Restore(ctx *RestoreCtx) error {
 	switch n.Tp {
 	case AlterTableOption:
 		switch {
-		case len(n.Options) == 2 &&
+		case len(n.Options) > 0 &&
 			n.Options[0].Tp == TableOptionCharset &&
-			n.Options[1].Tp == TableOptionCollate:
+			n.Options[0].UintValue == TableOptionCharsetWithConvertTo:
 			ctx.WriteKeyWord("CONVERT TO CHARACTER SET ")
 			ctx.WriteKeyWord(n.Options[0].StrValue)
-			ctx.WriteKeyWord(" COLLATE ")
-			ctx.WriteKeyWord(n.Options[1].StrValue)
+			if len(n.Options) == 2 && n.Options[1].Tp == TableOptionCollate {
+				ctx.WriteKeyWord(" COLLATE ")
+				ctx.WriteKeyWord(n.Options[1].StrValue)
+			}
 		default:
 			for i, opt := range n.Options {
 				if i != 0 {
@@ -1846,6 +1949,18 @@ func (n *AlterTableSpec) Restore(ctx *RestoreCtx) error {
 		}
 	case AlterTableAlterColumn:
 		ctx.WriteKeyWord("ALTER COLUMN ")
+		if len(n.NewColumns[0].Options) == 1 &&
+			(n.NewColumns[0].Options[0].Tp == ColumnOptionVisible ||
+				n.NewColumns[0].Options[0].Tp == ColumnOptionInvisible) {
+			if err := n.NewColumns[0].Name.Restore(ctx); err != nil {
+				return errors.Annotate(err, "An error occurred while restore AlterTableSpec.NewColumns[0].Name")
+			}
+			ctx.WriteKeyWord(" SET ")
+			if err := n.NewColumns[0].Options[0].Restore(ctx); err != nil {
+				return errors.Annotate(err, "An error occurred while restore AlterTableSpec.NewColumns[0].Options[0]")
+			}
+			break
+		}
 		if err := n.NewColumns[0].Restore(ctx); err != nil {
 			return errors.Annotate(err, "An error occurred while restore AlterTableSpec.NewColumns[0]")
 		}
@@ -1914,10 +2029,63 @@ func (n *AlterTableSpec) Restore(ctx *RestoreCtx) error {
 		if err := n.Partition.Restore(ctx); err != nil {
 			return errors.Annotate(err, "An error occurred while restore AlterTableSpec.Partition")
 		}
+	case AlterTableReorganizePartition:
+		ctx.WriteKeyWord("REORGANIZE PARTITION ")
+		for i, name := range n.PartitionNames {
+			if i != 0 {
+				ctx.WritePlain(",")
+			}
+			ctx.WriteName(name.O)
+		}
+		ctx.WriteKeyWord(" INTO ")
+		ctx.WritePlain("(")
+		for i, def := range n.PartDefinitions {
+			if i != 0 {
+				ctx.WritePlain(", ")
+			}
+			if err := def.Restore(ctx); err != nil {
+				return errors.Annotatef(err, "An error occurred while restore AlterTableSpec.PartDefinitions[%d]", i)
+			}
+		}
+		ctx.WritePlain(")")
+	case AlterTableExchangePartition:
+		ctx.WriteKeyWord("EXCHANGE PARTITION ")
+		ctx.WriteName(n.Name)
+		ctx.WriteKeyWord(" WITH TABLE ")
+		if err := n.NewTable.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore AlterTableSpec.NewTable")
+		}
+		if !n.WithValidation {
+			ctx.WriteKeyWord(" WITHOUT VALIDATION")
+		}
 	case AlterTableEnableKeys:
 		ctx.WriteKeyWord("ENABLE KEYS")
 	case AlterTableDisableKeys:
 		ctx.WriteKeyWord("DISABLE KEYS")
+	case AlterTableRenameColumn:
+		ctx.WriteKeyWord("RENAME COLUMN ")
+		if err := n.OldColumnName.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore AlterTableSpec.OldColumnName")
+		}
+		ctx.WriteKeyWord(" TO ")
+		if err := n.NewColumnName.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore AlterTableSpec.NewColumnName")
+		}
+	case AlterTableIndexInvisible:
+		ctx.WriteKeyWord("ALTER INDEX ")
+		ctx.WriteName(n.Name)
+		ctx.WritePlain(" ")
+		ctx.WriteKeyWord(n.Visibility.String())
+	case AlterTableDropCheck:
+		ctx.WriteKeyWord("DROP CHECK ")
+		ctx.WriteName(n.Name)
+	case AlterTableAlterCheck:
+		ctx.WriteKeyWord("ALTER CHECK ")
+		ctx.WriteName(n.Name)
+		if !n.Constraint.Enforced {
+			ctx.WriteKeyWord(" NOT")
+		}
+		ctx.WriteKeyWord(" ENFORCED")
 	default:
 		// TODO: not support
 		ctx.WritePlainf(" /* AlterTableType(%d) is not supported */ ", n.Tp)
@@ -1960,6 +2128,13 @@ func (n *AlterTableSpec) Accept(v Visitor) (Node, bool) {
 		}
 		n.OldColumnName = node.(*ColumnName)
 	}
+	if n.NewColumnName != nil {
+		node, ok := n.NewColumnName.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.NewColumnName = node.(*ColumnName)
+	}
 	if n.Position != nil {
 		node, ok := n.Position.Accept(v)
 		if !ok {
@@ -67,6 +67,7 @@ func (ts *testDDLSuite) TestDDLIndexColNameRestore(c *C) {
 	testCases := []NodeRestoreTestCase{
 		{"world", "`world`"},
 		{"world(2)", "`world`(2)"},
+		{"(lower(world))", "(LOWER(`world`))"},
 	}
 	extractNodeFunc := func(node Node) Node {
 		return node.(*CreateIndexStmt).IndexColNames[0]
@@ -160,6 +161,9 @@ func (ts *testDDLSuite) TestDDLConstraintRestore(c *C) {
 		{"CONSTRAINT fk_123 FOREIGN KEY (parent_id) REFERENCES parent(id) ON DELETE CASCADE ON UPDATE RESTRICT", "CONSTRAINT `fk_123` FOREIGN KEY (`parent_id`) REFERENCES `parent`(`id`) ON DELETE CASCADE ON UPDATE RESTRICT"},
 		{"FOREIGN KEY (parent_id(2),hello(4)) REFERENCES parent(id) ON DELETE CASCADE", "CONSTRAINT FOREIGN KEY (`parent_id`(2), `hello`(4)) REFERENCES `parent`(`id`) ON DELETE CASCADE"},
 		{"FOREIGN KEY (parent_id) REFERENCES parent(id) ON DELETE CASCADE ON UPDATE RESTRICT", "CONSTRAINT FOREIGN KEY (`parent_id`) REFERENCES `parent`(`id`) ON DELETE CASCADE ON UPDATE RESTRICT"},
+		{"CHECK (id > 0)", "CHECK(`id`>0)"},
+		{"CONSTRAINT c1 CHECK (id > 0) NOT ENFORCED", "CONSTRAINT `c1` CHECK(`id`>0) NOT ENFORCED"},
+		{"INDEX idx ((id + 1), parent_id) INVISIBLE", "INDEX `idx`((`id`+1), `parent_id`) INVISIBLE"},
 	}
 	extractNodeFunc := func(node Node) Node {
 		return node.(*CreateTableStmt).Constraints[0]
@@ -189,6 +193,9 @@ func (ts *testDDLSuite) TestDDLColumnOptionRestore(c *C) {
 		{"generated always as(id + 1) stored", "GENERATED ALWAYS AS(`id`+1) STORED"},
 		{"REFERENCES parent(id)", "REFERENCES `parent`(`id`)"},
 		{"COLLATE utf8_bin", "COLLATE utf8_bin"},
+		{"check (id > 0)", "CHECK(`id`>0)"},
+		{"constraint c1 check (id > 0) not enforced", "CONSTRAINT `c1` CHECK(`id`>0) NOT ENFORCED"},
+		{"invisible", "INVISIBLE"},
 	}
 	extractNodeFunc := func(node Node) Node {
 		return node.(*CreateTableStmt).Cols[0].Options[0]
@@ -380,8 +387,8 @@ func (ts *testDDLSuite) TestAlterTableSpecRestore(c *C) {
 
 		{"shard_row_id_bits 1", "SHARD_ROW_ID_BITS = 1"},
 		{"shard_row_id_bits = 1", "SHARD_ROW_ID_BITS = 1"},
-		{"CONVERT TO CHARACTER SET utf8", "DEFAULT CHARACTER SET = UTF8"},
-		{"CONVERT TO CHARSET utf8", "DEFAULT CHARACTER SET = UTF8"},
+		{"CONVERT TO CHARACTER SET utf8", "CONVERT TO CHARACTER SET UTF8"},
+		{"CONVERT TO CHARSET utf8", "CONVERT TO CHARACTER SET UTF8"},
 		{"CONVERT TO CHARACTER SET utf8 COLLATE utf8_bin", "CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN"},
 		{"CONVERT TO CHARSET utf8 COLLATE utf8_bin", "CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN"},
 		{"ADD COLUMN (a SMALLINT UNSIGNED)", "ADD COLUMN (`a` SMALLINT UNSIGNED)"},
@@ -429,6 +436,12 @@ func (ts *testDDLSuite) TestAlterTableSpecRestore(c *C) {
 		{"LOCK=EXCLUSIVE", "LOCK = EXCLUSIVE"},
 		{"RENAME KEY a TO b", "RENAME INDEX `a` TO `b`"},
 		{"RENAME INDEX a TO b", "RENAME INDEX `a` TO `b`"},
+		{"RENAME COLUMN a TO b", "RENAME COLUMN `a` TO `b`"},
+		{"ALTER INDEX a INVISIBLE", "ALTER INDEX `a` INVISIBLE"},
+		{"ALTER COLUMN a SET VISIBLE", "ALTER COLUMN `a` SET VISIBLE"},
+		{"DROP CHECK c1", "DROP CHECK `c1`"},
+		{"ALTER CHECK c1 NOT ENFORCED", "ALTER CHECK `c1` NOT ENFORCED"},
+		{"ALGORITHM = INSTANT", "ALGORITHM = INSTANT"},
 		{"ADD PARTITION", "ADD PARTITION"},
 		{"ADD PARTITION ( PARTITION P1 VALUES LESS THAN (2010))", "ADD PARTITION (PARTITION `P1` VALUES LESS THAN (2010))"},
 		{"ADD PARTITION ( PARTITION P2 VALUES LESS THAN MAXVALUE)", "ADD PARTITION (PARTITION `P2` VALUES LESS THAN (MAXVALUE))"},
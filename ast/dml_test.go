@@ -310,3 +310,36 @@ func (ts *testDMLSuite) TestHavingClauseRestore(c *C) {
 	}
 	RunNodeRestoreTest(c, testCases, "select 1 from t1 group by 1 %s", extractNodeFunc)
 }
+
+func (ts *testDMLSuite) TestWithClauseRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"with cte as (select 1) select * from cte", "WITH `cte` AS (SELECT 1) SELECT * FROM `cte`"},
+		{"with recursive cte(n) as (select 1 union all select n+1 from cte where n < 5) select n from cte",
+			"WITH RECURSIVE `cte`(`n`) AS (SELECT 1 UNION ALL SELECT `n`+1 FROM `cte` WHERE `n`<5) SELECT `n` FROM `cte`"},
+		{"with a as (select 1), b as (select 2) select * from a union select * from b",
+			"WITH `a` AS (SELECT 1), `b` AS (SELECT 2) SELECT * FROM `a` UNION SELECT * FROM `b`"},
+		{"with cte as (select 1) update t set a = 1", "WITH `cte` AS (SELECT 1) UPDATE `t` SET `a`=1"},
+		{"with cte as (select a from t1) delete from t where a in (select a from cte)",
+			"WITH `cte` AS (SELECT `a` FROM `t1`) DELETE FROM `t` WHERE `a` IN (SELECT `a` FROM `cte`)"},
+		{"insert into t with cte as (select 1) select * from cte",
+			"INSERT INTO `t` WITH `cte` AS (SELECT 1) SELECT * FROM `cte`"},
+		{"select * from t where a in (with cte as (select 1) select * from cte)",
+			"SELECT * FROM `t` WHERE `a` IN (WITH `cte` AS (SELECT 1) SELECT * FROM `cte`)"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return node
+	}
+	RunNodeRestoreTest(c, testCases, "%s", extractNodeFunc)
+}
+
+func (ts *testDMLSuite) TestWindowSpecRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"w as (partition by a)", "`w` AS (PARTITION BY `a`)"},
+		{"w as (w1 order by b desc rows 1 preceding)", "`w` AS (`w1` ORDER BY `b` DESC ROWS BETWEEN 1 PRECEDING AND CURRENT ROW)"},
+		{"w as (range between unbounded preceding and unbounded following)", "`w` AS (RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return &node.(*SelectStmt).WindowSpecs[0]
+	}
+	RunNodeRestoreTest(c, testCases, "select sum(a) over w from t window %s", extractNodeFunc)
+}
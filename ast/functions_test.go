@@ -165,3 +165,17 @@ func (ts *testFunctionsSuite) TestChar(c *C) {
 		c.Assert(charsetArg.GetString(), Equals, testCase.CharsetName)
 	}
 }
+
+func (ts *testFunctionsSuite) TestWindowFuncExprRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"ROW_NUMBER() OVER ()", "ROW_NUMBER() OVER ()"},
+		{"RANK() OVER (PARTITION BY a ORDER BY b)", "RANK() OVER (PARTITION BY `a` ORDER BY `b`)"},
+		{"SUM(DISTINCT a) OVER w", "SUM(DISTINCT `a`) OVER `w`"},
+		{"LAG(a, 1) OVER (w ORDER BY b ROWS BETWEEN 2 PRECEDING AND 1 FOLLOWING)", "LAG(`a`, 1) OVER (`w` ORDER BY `b` ROWS BETWEEN 2 PRECEDING AND 1 FOLLOWING)"},
+		{"AVG(a) OVER (ORDER BY b RANGE INTERVAL 1 DAY PRECEDING)", "AVG(`a`) OVER (ORDER BY `b` RANGE BETWEEN INTERVAL 1 DAY PRECEDING AND CURRENT ROW)"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return node.(*SelectStmt).Fields.Fields[0].Expr
+	}
+	RunNodeRestoreTest(c, testCases, "select %s from t", extractNodeFunc)
+}
@@ -494,6 +494,8 @@ const (
 // VariableAssignment is a variable assignment struct.
 type VariableAssignment struct {
 	node
+	// Table is NEW or OLD when assigning to a column of the subject table in triggers.
+	Table    string
 	Name     string
 	Value    ExprNode
 	IsGlobal bool
@@ -509,7 +511,10 @@ type VariableAssignment struct {
 
 // Restore implements Node interface.
 func (n *VariableAssignment) Restore(ctx *RestoreCtx) error {
-	if n.IsSystem {
+	if n.Table != "" {
+		ctx.WriteKeyWord(n.Table)
+		ctx.WritePlain(".")
+	} else if n.IsSystem {
 		ctx.WritePlain("@@")
 		if n.IsGlobal {
 			ctx.WriteKeyWord("GLOBAL")
@@ -991,7 +996,7 @@ func (t *TslOption) Restore(ctx *RestoreCtx) error {
 		ctx.WriteKeyWord("ISSUER ")
 		ctx.WriteString(t.Value)
 	case Subject:
-		ctx.WriteKeyWord("CIPHER")
+		ctx.WriteKeyWord("SUBJECT ")
 		ctx.WriteString(t.Value)
 	default:
 		return errors.Errorf("Unsupported TslOption.Type %d", t.Type)
@@ -187,3 +187,15 @@ func (ts *testMiscSuite) TestUserSpec(c *C) {
 	c.Assert(ok, IsTrue)
 	c.Assert(pwd, Equals, "")
 }
+
+func (ts *testMiscSuite) TestCreateUserRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"create user 'u1'@'localhost' identified by 'pwd' require ssl", "CREATE USER `u1`@`localhost` IDENTIFIED BY 'pwd' REQUIRE SSL"},
+		{"create user u1 require none", "CREATE USER `u1`@`%` REQUIRE NONE"},
+		{"create user u1 require subject '/CN=u1' and issuer '/CN=ca' cipher 'AES'", "CREATE USER `u1`@`%` REQUIRE SUBJECT '/CN=u1' AND ISSUER '/CN=ca' AND CIPHER 'AES'"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return node
+	}
+	RunNodeRestoreTest(c, testCases, "%s", extractNodeFunc)
+}
@@ -0,0 +1,1369 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	. "github.com/hanchuanchuan/inception-core/format"
+	"github.com/hanchuanchuan/inception-core/model"
+	"github.com/hanchuanchuan/inception-core/types"
+	"github.com/hanchuanchuan/inception-core/util/auth"
+	"github.com/pingcap/errors"
+)
+
+var (
+	_ DDLNode = &CreateRoutineStmt{}
+	_ DDLNode = &CreateTriggerStmt{}
+	_ DDLNode = &CreateEventStmt{}
+	_ DDLNode = &DropRoutineStmt{}
+
+	_ StmtNode = &BlockStmt{}
+	_ StmtNode = &DeclareVarStmt{}
+	_ StmtNode = &DeclareCursorStmt{}
+	_ StmtNode = &DeclareHandlerStmt{}
+	_ StmtNode = &IfStmt{}
+	_ StmtNode = &WhileStmt{}
+	_ StmtNode = &LoopStmt{}
+	_ StmtNode = &RepeatStmt{}
+	_ StmtNode = &LeaveStmt{}
+	_ StmtNode = &IterateStmt{}
+	_ StmtNode = &ReturnStmt{}
+	_ StmtNode = &OpenCursorStmt{}
+	_ StmtNode = &FetchCursorStmt{}
+	_ StmtNode = &CloseCursorStmt{}
+	_ StmtNode = &CaseStmt{}
+	_ StmtNode = &CallStmt{}
+	_ StmtNode = &SignalStmt{}
+
+	_ Node = &RoutineParam{}
+	_ Node = &RoutineOption{}
+	_ Node = &HandlerCondition{}
+	_ Node = &EventSchedule{}
+	_ Node = &CaseWhenClause{}
+	_ Node = &SignalInfo{}
+)
+
+// RoutineType is the type of a stored program.
+type RoutineType int
+
+// Stored program types.
+const (
+	RoutineProcedure RoutineType = iota + 1
+	RoutineFunction
+	RoutineTrigger
+	RoutineEvent
+)
+
+// String implements fmt.Stringer interface.
+func (t RoutineType) String() string {
+	switch t {
+	case RoutineProcedure:
+		return "PROCEDURE"
+	case RoutineFunction:
+		return "FUNCTION"
+	case RoutineTrigger:
+		return "TRIGGER"
+	case RoutineEvent:
+		return "EVENT"
+	}
+	return ""
+}
+
+// ParamMode is the mode of a procedure parameter.
+type ParamMode int
+
+// Procedure parameter modes, functions parameters have no mode.
+const (
+	ParamModeNone ParamMode = iota
+	ParamModeIn
+	ParamModeOut
+	ParamModeInOut
+)
+
+// RoutineParam is a parameter of a stored procedure or function.
+type RoutineParam struct {
+	node
+
+	Mode ParamMode
+	Name string
+	Tp   *types.FieldType
+}
+
+// Restore implements Node interface.
+func (n *RoutineParam) Restore(ctx *RestoreCtx) error {
+	switch n.Mode {
+	case ParamModeIn:
+		ctx.WriteKeyWord("IN ")
+	case ParamModeOut:
+		ctx.WriteKeyWord("OUT ")
+	case ParamModeInOut:
+		ctx.WriteKeyWord("INOUT ")
+	}
+	ctx.WriteName(n.Name)
+	ctx.WritePlain(" ")
+	return errors.Annotate(n.Tp.Restore(ctx), "An error occurred while restore RoutineParam.Tp")
+}
+
+// Accept implements Node Accept interface.
+func (n *RoutineParam) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*RoutineParam))
+}
+
+// RoutineOptionType is the type of a stored routine characteristic.
+type RoutineOptionType int
+
+// Stored routine characteristics.
+const (
+	RoutineOptionComment RoutineOptionType = iota + 1
+	RoutineOptionLanguage
+	RoutineOptionDeterministic
+	RoutineOptionDataAccess
+	RoutineOptionSecurity
+)
+
+// RoutineOption is a characteristic of a stored procedure or function.
+type RoutineOption struct {
+	node
+
+	Tp RoutineOptionType
+	// StrValue is the comment, or the data access like "READS SQL DATA".
+	StrValue  string
+	BoolValue bool
+	Security  model.ViewSecurity
+}
+
+// Restore implements Node interface.
+func (n *RoutineOption) Restore(ctx *RestoreCtx) error {
+	switch n.Tp {
+	case RoutineOptionComment:
+		ctx.WriteKeyWord("COMMENT ")
+		ctx.WriteString(n.StrValue)
+	case RoutineOptionLanguage:
+		ctx.WriteKeyWord("LANGUAGE SQL")
+	case RoutineOptionDeterministic:
+		if !n.BoolValue {
+			ctx.WriteKeyWord("NOT ")
+		}
+		ctx.WriteKeyWord("DETERMINISTIC")
+	case RoutineOptionDataAccess:
+		ctx.WriteKeyWord(n.StrValue)
+	case RoutineOptionSecurity:
+		ctx.WriteKeyWord("SQL SECURITY ")
+		ctx.WriteKeyWord(n.Security.String())
+	default:
+		return errors.Errorf("invalid RoutineOption: %d", n.Tp)
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *RoutineOption) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*RoutineOption))
+}
+
+// restoreDefiner writes the DEFINER clause, the default CURRENT_USER is omitted.
+func restoreDefiner(ctx *RestoreCtx, definer *auth.UserIdentity) error {
+	if definer == nil || definer.CurrentUser {
+		return nil
+	}
+	ctx.WriteKeyWord("DEFINER")
+	ctx.WritePlain(" = ")
+	if err := definer.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore Definer")
+	}
+	ctx.WritePlain(" ")
+	return nil
+}
+
+// restoreStmts writes the statements, each followed by a semicolon.
+func restoreStmts(ctx *RestoreCtx, stmts []StmtNode) error {
+	for i, stmt := range stmts {
+		if err := stmt.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore Stmts[%d]", i)
+		}
+		ctx.WritePlain("; ")
+	}
+	return nil
+}
+
+// acceptStmts visits the statements.
+func acceptStmts(v Visitor, stmts []StmtNode) bool {
+	for i, stmt := range stmts {
+		node, ok := stmt.Accept(v)
+		if !ok {
+			return false
+		}
+		stmts[i] = node.(StmtNode)
+	}
+	return true
+}
+
+// restoreLabel writes the begin label of a compound statement.
+func restoreLabel(ctx *RestoreCtx, label string) {
+	if label != "" {
+		ctx.WriteName(label)
+		ctx.WritePlain(": ")
+	}
+}
+
+// restoreEndLabel writes the end label of a compound statement.
+func restoreEndLabel(ctx *RestoreCtx, label string) {
+	if label != "" {
+		ctx.WritePlain(" ")
+		ctx.WriteName(label)
+	}
+}
+
+// CreateRoutineStmt is a statement to create a stored procedure or function.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-procedure.html
+type CreateRoutineStmt struct {
+	ddlNode
+
+	Tp          RoutineType
+	Definer     *auth.UserIdentity
+	IfNotExists bool
+	Name        *TableName
+	Params      []*RoutineParam
+	// Returns is the return type of a function.
+	Returns *types.FieldType
+	Options []*RoutineOption
+	Body    StmtNode
+}
+
+// Restore implements Node interface.
+func (n *CreateRoutineStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CREATE ")
+	if err := restoreDefiner(ctx, n.Definer); err != nil {
+		return err
+	}
+	ctx.WriteKeyWord(n.Tp.String())
+	ctx.WritePlain(" ")
+	if n.IfNotExists {
+		ctx.WriteKeyWord("IF NOT EXISTS ")
+	}
+	if err := n.Name.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CreateRoutineStmt.Name")
+	}
+	ctx.WritePlain("(")
+	for i, param := range n.Params {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := param.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore CreateRoutineStmt.Params[%d]", i)
+		}
+	}
+	ctx.WritePlain(")")
+	if n.Returns != nil {
+		ctx.WriteKeyWord(" RETURNS ")
+		if err := n.Returns.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore CreateRoutineStmt.Returns")
+		}
+	}
+	for i, opt := range n.Options {
+		ctx.WritePlain(" ")
+		if err := opt.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore CreateRoutineStmt.Options[%d]", i)
+		}
+	}
+	ctx.WritePlain(" ")
+	return errors.Annotate(n.Body.Restore(ctx), "An error occurred while restore CreateRoutineStmt.Body")
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateRoutineStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateRoutineStmt)
+	node, ok := n.Name.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Name = node.(*TableName)
+	node, ok = n.Body.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Body = node.(StmtNode)
+	return v.Leave(n)
+}
+
+// TriggerTime is the action time of a trigger.
+type TriggerTime int
+
+// Trigger action times.
+const (
+	TriggerBefore TriggerTime = iota + 1
+	TriggerAfter
+)
+
+// String implements fmt.Stringer interface.
+func (t TriggerTime) String() string {
+	if t == TriggerBefore {
+		return "BEFORE"
+	}
+	return "AFTER"
+}
+
+// TriggerEvent is the kind of operation that activates a trigger.
+type TriggerEvent int
+
+// Trigger events.
+const (
+	TriggerInsert TriggerEvent = iota + 1
+	TriggerUpdate
+	TriggerDelete
+)
+
+// String implements fmt.Stringer interface.
+func (e TriggerEvent) String() string {
+	switch e {
+	case TriggerInsert:
+		return "INSERT"
+	case TriggerUpdate:
+		return "UPDATE"
+	}
+	return "DELETE"
+}
+
+// CreateTriggerStmt is a statement to create a trigger.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-trigger.html
+type CreateTriggerStmt struct {
+	ddlNode
+
+	Definer     *auth.UserIdentity
+	IfNotExists bool
+	Name        *TableName
+	Time        TriggerTime
+	Event       TriggerEvent
+	Table       *TableName
+	// Follows is true for FOLLOWS and false for PRECEDES.
+	Follows      bool
+	OtherTrigger string
+	Body         StmtNode
+}
+
+// Restore implements Node interface.
+func (n *CreateTriggerStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CREATE ")
+	if err := restoreDefiner(ctx, n.Definer); err != nil {
+		return err
+	}
+	ctx.WriteKeyWord("TRIGGER ")
+	if n.IfNotExists {
+		ctx.WriteKeyWord("IF NOT EXISTS ")
+	}
+	if err := n.Name.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CreateTriggerStmt.Name")
+	}
+	ctx.WritePlain(" ")
+	ctx.WriteKeyWord(n.Time.String())
+	ctx.WritePlain(" ")
+	ctx.WriteKeyWord(n.Event.String())
+	ctx.WriteKeyWord(" ON ")
+	if err := n.Table.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CreateTriggerStmt.Table")
+	}
+	ctx.WriteKeyWord(" FOR EACH ROW ")
+	if n.OtherTrigger != "" {
+		if n.Follows {
+			ctx.WriteKeyWord("FOLLOWS ")
+		} else {
+			ctx.WriteKeyWord("PRECEDES ")
+		}
+		ctx.WriteName(n.OtherTrigger)
+		ctx.WritePlain(" ")
+	}
+	return errors.Annotate(n.Body.Restore(ctx), "An error occurred while restore CreateTriggerStmt.Body")
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateTriggerStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateTriggerStmt)
+	node, ok := n.Name.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Name = node.(*TableName)
+	node, ok = n.Table.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Table = node.(*TableName)
+	node, ok = n.Body.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Body = node.(StmtNode)
+	return v.Leave(n)
+}
+
+// EventSchedule is the schedule of an event, either AT or EVERY.
+type EventSchedule struct {
+	node
+
+	At     ExprNode
+	Every  ExprNode
+	Unit   string
+	Starts ExprNode
+	Ends   ExprNode
+}
+
+// Restore implements Node interface.
+func (n *EventSchedule) Restore(ctx *RestoreCtx) error {
+	if n.At != nil {
+		ctx.WriteKeyWord("AT ")
+		return errors.Annotate(n.At.Restore(ctx), "An error occurred while restore EventSchedule.At")
+	}
+	ctx.WriteKeyWord("EVERY ")
+	if err := n.Every.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore EventSchedule.Every")
+	}
+	ctx.WritePlain(" ")
+	ctx.WriteKeyWord(n.Unit)
+	if n.Starts != nil {
+		ctx.WriteKeyWord(" STARTS ")
+		if err := n.Starts.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore EventSchedule.Starts")
+		}
+	}
+	if n.Ends != nil {
+		ctx.WriteKeyWord(" ENDS ")
+		if err := n.Ends.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore EventSchedule.Ends")
+		}
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *EventSchedule) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*EventSchedule)
+	for _, expr := range []*ExprNode{&n.At, &n.Every, &n.Starts, &n.Ends} {
+		if *expr == nil {
+			continue
+		}
+		node, ok := (*expr).Accept(v)
+		if !ok {
+			return n, false
+		}
+		*expr = node.(ExprNode)
+	}
+	return v.Leave(n)
+}
+
+// EventStatus is the status of an event.
+type EventStatus int
+
+// Event status.
+const (
+	EventEnable EventStatus = iota
+	EventDisable
+	EventDisableOnSlave
+)
+
+// String implements fmt.Stringer interface.
+func (s EventStatus) String() string {
+	switch s {
+	case EventDisable:
+		return "DISABLE"
+	case EventDisableOnSlave:
+		return "DISABLE ON SLAVE"
+	}
+	return "ENABLE"
+}
+
+// CreateEventStmt is a statement to create an event.
+// See https://dev.mysql.com/doc/refman/5.7/en/create-event.html
+type CreateEventStmt struct {
+	ddlNode
+
+	Definer     *auth.UserIdentity
+	IfNotExists bool
+	Name        *TableName
+	Schedule    *EventSchedule
+	// Preserve is true for ON COMPLETION PRESERVE.
+	Preserve bool
+	Status   EventStatus
+	Comment  string
+	Body     StmtNode
+}
+
+// Restore implements Node interface.
+func (n *CreateEventStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CREATE ")
+	if err := restoreDefiner(ctx, n.Definer); err != nil {
+		return err
+	}
+	ctx.WriteKeyWord("EVENT ")
+	if n.IfNotExists {
+		ctx.WriteKeyWord("IF NOT EXISTS ")
+	}
+	if err := n.Name.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CreateEventStmt.Name")
+	}
+	ctx.WriteKeyWord(" ON SCHEDULE ")
+	if err := n.Schedule.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CreateEventStmt.Schedule")
+	}
+	if n.Preserve {
+		ctx.WriteKeyWord(" ON COMPLETION PRESERVE")
+	}
+	ctx.WritePlain(" ")
+	ctx.WriteKeyWord(n.Status.String())
+	if n.Comment != "" {
+		ctx.WriteKeyWord(" COMMENT ")
+		ctx.WriteString(n.Comment)
+	}
+	ctx.WriteKeyWord(" DO ")
+	return errors.Annotate(n.Body.Restore(ctx), "An error occurred while restore CreateEventStmt.Body")
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateEventStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateEventStmt)
+	node, ok := n.Name.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Name = node.(*TableName)
+	node, ok = n.Schedule.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Schedule = node.(*EventSchedule)
+	node, ok = n.Body.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Body = node.(StmtNode)
+	return v.Leave(n)
+}
+
+// DropRoutineStmt is a statement to drop a procedure, function, trigger or event.
+type DropRoutineStmt struct {
+	ddlNode
+
+	Tp       RoutineType
+	IfExists bool
+	Name     *TableName
+}
+
+// Restore implements Node interface.
+func (n *DropRoutineStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("DROP ")
+	ctx.WriteKeyWord(n.Tp.String())
+	ctx.WritePlain(" ")
+	if n.IfExists {
+		ctx.WriteKeyWord("IF EXISTS ")
+	}
+	return errors.Annotate(n.Name.Restore(ctx), "An error occurred while restore DropRoutineStmt.Name")
+}
+
+// Accept implements Node Accept interface.
+func (n *DropRoutineStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DropRoutineStmt)
+	node, ok := n.Name.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Name = node.(*TableName)
+	return v.Leave(n)
+}
+
+// BlockStmt is a BEGIN ... END compound statement.
+type BlockStmt struct {
+	stmtNode
+
+	Label string
+	Stmts []StmtNode
+}
+
+// Restore implements Node interface.
+func (n *BlockStmt) Restore(ctx *RestoreCtx) error {
+	restoreLabel(ctx, n.Label)
+	ctx.WriteKeyWord("BEGIN ")
+	if err := restoreStmts(ctx, n.Stmts); err != nil {
+		return errors.Annotate(err, "An error occurred while restore BlockStmt")
+	}
+	ctx.WriteKeyWord("END")
+	restoreEndLabel(ctx, n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *BlockStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*BlockStmt)
+	if !acceptStmts(v, n.Stmts) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// DeclareVarStmt is a statement to declare local variables.
+type DeclareVarStmt struct {
+	stmtNode
+
+	Names   []string
+	Tp      *types.FieldType
+	Default ExprNode
+}
+
+// Restore implements Node interface.
+func (n *DeclareVarStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("DECLARE ")
+	for i, name := range n.Names {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		ctx.WriteName(name)
+	}
+	ctx.WritePlain(" ")
+	if err := n.Tp.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore DeclareVarStmt.Tp")
+	}
+	if n.Default != nil {
+		ctx.WriteKeyWord(" DEFAULT ")
+		if err := n.Default.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore DeclareVarStmt.Default")
+		}
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *DeclareVarStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DeclareVarStmt)
+	if n.Default != nil {
+		node, ok := n.Default.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Default = node.(ExprNode)
+	}
+	return v.Leave(n)
+}
+
+// DeclareCursorStmt is a statement to declare a cursor.
+type DeclareCursorStmt struct {
+	stmtNode
+
+	Name   string
+	Select StmtNode
+}
+
+// Restore implements Node interface.
+func (n *DeclareCursorStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("DECLARE ")
+	ctx.WriteName(n.Name)
+	ctx.WriteKeyWord(" CURSOR FOR ")
+	return errors.Annotate(n.Select.Restore(ctx), "An error occurred while restore DeclareCursorStmt.Select")
+}
+
+// Accept implements Node Accept interface.
+func (n *DeclareCursorStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DeclareCursorStmt)
+	node, ok := n.Select.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Select = node.(StmtNode)
+	return v.Leave(n)
+}
+
+// HandlerConditionType is the type of a handler condition.
+type HandlerConditionType int
+
+// Handler condition types.
+const (
+	HandlerErrorCode HandlerConditionType = iota + 1
+	HandlerSQLState
+	HandlerConditionName
+	HandlerSQLWarning
+	HandlerNotFound
+	HandlerSQLException
+)
+
+// HandlerCondition is a condition of a handler.
+type HandlerCondition struct {
+	node
+
+	Tp HandlerConditionType
+	// Value is the error code, the sqlstate value or the condition name.
+	Value string
+}
+
+// Restore implements Node interface.
+func (n *HandlerCondition) Restore(ctx *RestoreCtx) error {
+	switch n.Tp {
+	case HandlerErrorCode:
+		ctx.WritePlain(n.Value)
+	case HandlerSQLState:
+		ctx.WriteKeyWord("SQLSTATE ")
+		ctx.WriteString(n.Value)
+	case HandlerConditionName:
+		ctx.WriteName(n.Value)
+	case HandlerSQLWarning:
+		ctx.WriteKeyWord("SQLWARNING")
+	case HandlerNotFound:
+		ctx.WriteKeyWord("NOT FOUND")
+	case HandlerSQLException:
+		ctx.WriteKeyWord("SQLEXCEPTION")
+	default:
+		return errors.Errorf("invalid HandlerCondition: %d", n.Tp)
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *HandlerCondition) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*HandlerCondition))
+}
+
+// DeclareHandlerStmt is a statement to declare a condition handler.
+type DeclareHandlerStmt struct {
+	stmtNode
+
+	// Exit is true for EXIT handlers and false for CONTINUE handlers.
+	Exit       bool
+	Conditions []*HandlerCondition
+	Stmt       StmtNode
+}
+
+// Restore implements Node interface.
+func (n *DeclareHandlerStmt) Restore(ctx *RestoreCtx) error {
+	if n.Exit {
+		ctx.WriteKeyWord("DECLARE EXIT HANDLER FOR ")
+	} else {
+		ctx.WriteKeyWord("DECLARE CONTINUE HANDLER FOR ")
+	}
+	for i, cond := range n.Conditions {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := cond.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore DeclareHandlerStmt.Conditions[%d]", i)
+		}
+	}
+	ctx.WritePlain(" ")
+	return errors.Annotate(n.Stmt.Restore(ctx), "An error occurred while restore DeclareHandlerStmt.Stmt")
+}
+
+// Accept implements Node Accept interface.
+func (n *DeclareHandlerStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DeclareHandlerStmt)
+	node, ok := n.Stmt.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Stmt = node.(StmtNode)
+	return v.Leave(n)
+}
+
+// IfStmt is an IF statement, an ELSEIF clause is an IfStmt in the Else list.
+type IfStmt struct {
+	stmtNode
+
+	Cond ExprNode
+	Then []StmtNode
+	Else []StmtNode
+	// ElseIf is true when the statement is restored as an ELSEIF clause.
+	ElseIf bool
+}
+
+// Restore implements Node interface.
+func (n *IfStmt) Restore(ctx *RestoreCtx) error {
+	if n.ElseIf {
+		ctx.WriteKeyWord("ELSEIF ")
+	} else {
+		ctx.WriteKeyWord("IF ")
+	}
+	if err := n.Cond.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore IfStmt.Cond")
+	}
+	ctx.WriteKeyWord(" THEN ")
+	if err := restoreStmts(ctx, n.Then); err != nil {
+		return errors.Annotate(err, "An error occurred while restore IfStmt.Then")
+	}
+	if len(n.Else) == 1 {
+		if elseIf, ok := n.Else[0].(*IfStmt); ok && elseIf.ElseIf {
+			return elseIf.Restore(ctx)
+		}
+	}
+	if len(n.Else) > 0 {
+		ctx.WriteKeyWord("ELSE ")
+		if err := restoreStmts(ctx, n.Else); err != nil {
+			return errors.Annotate(err, "An error occurred while restore IfStmt.Else")
+		}
+	}
+	ctx.WriteKeyWord("END IF")
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *IfStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*IfStmt)
+	node, ok := n.Cond.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Cond = node.(ExprNode)
+	if !acceptStmts(v, n.Then) || !acceptStmts(v, n.Else) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// WhileStmt is a WHILE ... DO ... END WHILE statement.
+type WhileStmt struct {
+	stmtNode
+
+	Label string
+	Cond  ExprNode
+	Stmts []StmtNode
+}
+
+// Restore implements Node interface.
+func (n *WhileStmt) Restore(ctx *RestoreCtx) error {
+	restoreLabel(ctx, n.Label)
+	ctx.WriteKeyWord("WHILE ")
+	if err := n.Cond.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore WhileStmt.Cond")
+	}
+	ctx.WriteKeyWord(" DO ")
+	if err := restoreStmts(ctx, n.Stmts); err != nil {
+		return errors.Annotate(err, "An error occurred while restore WhileStmt.Stmts")
+	}
+	ctx.WriteKeyWord("END WHILE")
+	restoreEndLabel(ctx, n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *WhileStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*WhileStmt)
+	node, ok := n.Cond.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Cond = node.(ExprNode)
+	if !acceptStmts(v, n.Stmts) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// LoopStmt is a LOOP ... END LOOP statement.
+type LoopStmt struct {
+	stmtNode
+
+	Label string
+	Stmts []StmtNode
+}
+
+// Restore implements Node interface.
+func (n *LoopStmt) Restore(ctx *RestoreCtx) error {
+	restoreLabel(ctx, n.Label)
+	ctx.WriteKeyWord("LOOP ")
+	if err := restoreStmts(ctx, n.Stmts); err != nil {
+		return errors.Annotate(err, "An error occurred while restore LoopStmt.Stmts")
+	}
+	ctx.WriteKeyWord("END LOOP")
+	restoreEndLabel(ctx, n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *LoopStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*LoopStmt)
+	if !acceptStmts(v, n.Stmts) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// RepeatStmt is a REPEAT ... UNTIL ... END REPEAT statement.
+type RepeatStmt struct {
+	stmtNode
+
+	Label string
+	Stmts []StmtNode
+	Until ExprNode
+}
+
+// Restore implements Node interface.
+func (n *RepeatStmt) Restore(ctx *RestoreCtx) error {
+	restoreLabel(ctx, n.Label)
+	ctx.WriteKeyWord("REPEAT ")
+	if err := restoreStmts(ctx, n.Stmts); err != nil {
+		return errors.Annotate(err, "An error occurred while restore RepeatStmt.Stmts")
+	}
+	ctx.WriteKeyWord("UNTIL ")
+	if err := n.Until.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore RepeatStmt.Until")
+	}
+	ctx.WriteKeyWord(" END REPEAT")
+	restoreEndLabel(ctx, n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *RepeatStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*RepeatStmt)
+	if !acceptStmts(v, n.Stmts) {
+		return n, false
+	}
+	node, ok := n.Until.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Until = node.(ExprNode)
+	return v.Leave(n)
+}
+
+// LeaveStmt is a LEAVE statement.
+type LeaveStmt struct {
+	stmtNode
+
+	Label string
+}
+
+// Restore implements Node interface.
+func (n *LeaveStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("LEAVE ")
+	ctx.WriteName(n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *LeaveStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*LeaveStmt))
+}
+
+// IterateStmt is an ITERATE statement.
+type IterateStmt struct {
+	stmtNode
+
+	Label string
+}
+
+// Restore implements Node interface.
+func (n *IterateStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("ITERATE ")
+	ctx.WriteName(n.Label)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *IterateStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*IterateStmt))
+}
+
+// ReturnStmt is a RETURN statement of a stored function.
+type ReturnStmt struct {
+	stmtNode
+
+	Expr ExprNode
+}
+
+// Restore implements Node interface.
+func (n *ReturnStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("RETURN ")
+	return errors.Annotate(n.Expr.Restore(ctx), "An error occurred while restore ReturnStmt.Expr")
+}
+
+// Accept implements Node Accept interface.
+func (n *ReturnStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*ReturnStmt)
+	node, ok := n.Expr.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Expr = node.(ExprNode)
+	return v.Leave(n)
+}
+
+// OpenCursorStmt is an OPEN cursor statement.
+type OpenCursorStmt struct {
+	stmtNode
+
+	Name string
+}
+
+// Restore implements Node interface.
+func (n *OpenCursorStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("OPEN ")
+	ctx.WriteName(n.Name)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *OpenCursorStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*OpenCursorStmt))
+}
+
+// FetchCursorStmt is a FETCH cursor statement.
+type FetchCursorStmt struct {
+	stmtNode
+
+	Name string
+	Vars []string
+}
+
+// Restore implements Node interface.
+func (n *FetchCursorStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("FETCH ")
+	ctx.WriteName(n.Name)
+	ctx.WriteKeyWord(" INTO ")
+	for i, name := range n.Vars {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		ctx.WriteName(name)
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *FetchCursorStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*FetchCursorStmt))
+}
+
+// CloseCursorStmt is a CLOSE cursor statement.
+type CloseCursorStmt struct {
+	stmtNode
+
+	Name string
+}
+
+// Restore implements Node interface.
+func (n *CloseCursorStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CLOSE ")
+	ctx.WriteName(n.Name)
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *CloseCursorStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	return v.Leave(newNode.(*CloseCursorStmt))
+}
+
+// CaseWhenClause is a WHEN clause of a CASE statement.
+type CaseWhenClause struct {
+	node
+
+	Expr  ExprNode
+	Stmts []StmtNode
+}
+
+// Restore implements Node interface.
+func (n *CaseWhenClause) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("WHEN ")
+	if err := n.Expr.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CaseWhenClause.Expr")
+	}
+	ctx.WriteKeyWord(" THEN ")
+	return errors.Annotate(restoreStmts(ctx, n.Stmts), "An error occurred while restore CaseWhenClause.Stmts")
+}
+
+// Accept implements Node Accept interface.
+func (n *CaseWhenClause) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CaseWhenClause)
+	node, ok := n.Expr.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Expr = node.(ExprNode)
+	if !acceptStmts(v, n.Stmts) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// CaseStmt is a CASE ... END CASE statement.
+// See https://dev.mysql.com/doc/refman/5.7/en/case.html
+type CaseStmt struct {
+	stmtNode
+
+	// Value is nil for the searched CASE statement.
+	Value       ExprNode
+	WhenClauses []*CaseWhenClause
+	Else        []StmtNode
+}
+
+// Restore implements Node interface.
+func (n *CaseStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CASE ")
+	if n.Value != nil {
+		if err := n.Value.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore CaseStmt.Value")
+		}
+		ctx.WritePlain(" ")
+	}
+	for i, clause := range n.WhenClauses {
+		if err := clause.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore CaseStmt.WhenClauses[%d]", i)
+		}
+	}
+	if n.Else != nil {
+		ctx.WriteKeyWord("ELSE ")
+		if err := restoreStmts(ctx, n.Else); err != nil {
+			return errors.Annotate(err, "An error occurred while restore CaseStmt.Else")
+		}
+	}
+	ctx.WriteKeyWord("END CASE")
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *CaseStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CaseStmt)
+	if n.Value != nil {
+		node, ok := n.Value.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Value = node.(ExprNode)
+	}
+	for i, clause := range n.WhenClauses {
+		node, ok := clause.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.WhenClauses[i] = node.(*CaseWhenClause)
+	}
+	if !acceptStmts(v, n.Else) {
+		return n, false
+	}
+	return v.Leave(n)
+}
+
+// CallStmt is a statement to invoke a stored procedure.
+// See https://dev.mysql.com/doc/refman/5.7/en/call.html
+type CallStmt struct {
+	stmtNode
+
+	Procedure *TableName
+	Args      []ExprNode
+}
+
+// Restore implements Node interface.
+func (n *CallStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("CALL ")
+	if err := n.Procedure.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore CallStmt.Procedure")
+	}
+	ctx.WritePlain("(")
+	for i, arg := range n.Args {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := arg.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore CallStmt.Args[%d]", i)
+		}
+	}
+	ctx.WritePlain(")")
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *CallStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CallStmt)
+	node, ok := n.Procedure.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Procedure = node.(*TableName)
+	for i, arg := range n.Args {
+		node, ok = arg.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Args[i] = node.(ExprNode)
+	}
+	return v.Leave(n)
+}
+
+// SignalInfo is a condition information item of a SIGNAL statement, e.g. MESSAGE_TEXT = 'error'.
+type SignalInfo struct {
+	node
+
+	Name  string
+	Value ExprNode
+}
+
+// Restore implements Node interface.
+func (n *SignalInfo) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord(n.Name)
+	ctx.WritePlain(" = ")
+	return errors.Annotate(n.Value.Restore(ctx), "An error occurred while restore SignalInfo.Value")
+}
+
+// Accept implements Node Accept interface.
+func (n *SignalInfo) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SignalInfo)
+	node, ok := n.Value.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.Value = node.(ExprNode)
+	return v.Leave(n)
+}
+
+// SignalStmt is a SIGNAL statement.
+// See https://dev.mysql.com/doc/refman/5.7/en/signal.html
+type SignalStmt struct {
+	stmtNode
+
+	// Condition is a SQLSTATE value or a condition name.
+	Condition *HandlerCondition
+	Items     []*SignalInfo
+}
+
+// Restore implements Node interface.
+func (n *SignalStmt) Restore(ctx *RestoreCtx) error {
+	ctx.WriteKeyWord("SIGNAL ")
+	if err := n.Condition.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while restore SignalStmt.Condition")
+	}
+	for i, item := range n.Items {
+		if i == 0 {
+			ctx.WriteKeyWord(" SET ")
+		} else {
+			ctx.WritePlain(", ")
+		}
+		if err := item.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while restore SignalStmt.Items[%d]", i)
+		}
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *SignalStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*SignalStmt)
+	for i, item := range n.Items {
+		node, ok := item.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Items[i] = node.(*SignalInfo)
+	}
+	return v.Leave(n)
+}
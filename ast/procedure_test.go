@@ -0,0 +1,112 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	. "github.com/hanchuanchuan/inception-core/ast"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testProcedureSuite{})
+
+type testProcedureSuite struct {
+}
+
+func (ts *testProcedureSuite) TestProcedureVisitorCover(c *C) {
+	ce := &checkExpr{}
+	stmts := []struct {
+		node             Node
+		expectedEnterCnt int
+		expectedLeaveCnt int
+	}{
+		{&CreateRoutineStmt{Name: &TableName{}, Body: &ReturnStmt{Expr: ce}}, 1, 1},
+		{&CreateTriggerStmt{Name: &TableName{}, Table: &TableName{}, Body: &BlockStmt{}}, 0, 0},
+		{&CreateEventStmt{Name: &TableName{}, Schedule: &EventSchedule{Every: ce, Starts: ce}, Body: &DoStmt{}}, 2, 2},
+		{&DropRoutineStmt{Name: &TableName{}}, 0, 0},
+		{&BlockStmt{Stmts: []StmtNode{&ReturnStmt{Expr: ce}}}, 1, 1},
+		{&DeclareVarStmt{Default: ce}, 1, 1},
+		{&DeclareHandlerStmt{Stmt: &LeaveStmt{}}, 0, 0},
+		{&IfStmt{Cond: ce, Then: []StmtNode{&IterateStmt{}}, Else: []StmtNode{&IfStmt{Cond: ce}}}, 2, 2},
+		{&WhileStmt{Cond: ce, Stmts: []StmtNode{&OpenCursorStmt{}}}, 1, 1},
+		{&LoopStmt{Stmts: []StmtNode{&FetchCursorStmt{}}}, 0, 0},
+		{&RepeatStmt{Stmts: []StmtNode{&CloseCursorStmt{}}, Until: ce}, 1, 1},
+		{&CaseStmt{Value: ce, WhenClauses: []*CaseWhenClause{{Expr: ce, Stmts: []StmtNode{&ReturnStmt{Expr: ce}}}}, Else: []StmtNode{&LeaveStmt{}}}, 3, 3},
+		{&CallStmt{Procedure: &TableName{}, Args: []ExprNode{ce, ce}}, 2, 2},
+		{&SignalStmt{Condition: &HandlerCondition{}, Items: []*SignalInfo{{Value: ce}}}, 1, 1},
+	}
+
+	for _, v := range stmts {
+		ce.reset()
+		v.node.Accept(checkVisitor{})
+		c.Check(ce.enterCnt, Equals, v.expectedEnterCnt)
+		c.Check(ce.leaveCnt, Equals, v.expectedLeaveCnt)
+		v.node.Accept(visitor1{})
+	}
+}
+
+func (ts *testProcedureSuite) TestProcedureRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"create procedure p1() select 1",
+			"CREATE PROCEDURE `p1`() SELECT 1"},
+		{"create definer = 'root'@'%' procedure if not exists test.p1(in a int, out b varchar(10), inout c int) comment 'p1' reads sql data sql security invoker begin set b = a; end",
+			"CREATE DEFINER = `root`@`%` PROCEDURE IF NOT EXISTS `test`.`p1`(IN `a` INT, OUT `b` VARCHAR(10), INOUT `c` INT) COMMENT 'p1' READS SQL DATA SQL SECURITY INVOKER BEGIN SET @@SESSION.`b`=`a`; END"},
+		{"create function f1(a int) returns int deterministic no sql return a + 1",
+			"CREATE FUNCTION `f1`(`a` INT) RETURNS INT DETERMINISTIC NO SQL RETURN `a`+1"},
+		{"create trigger tr1 before insert on t1 for each row follows tr0 insert into t2 values (1)",
+			"CREATE TRIGGER `tr1` BEFORE INSERT ON `t1` FOR EACH ROW FOLLOWS `tr0` INSERT INTO `t2` VALUES (1)"},
+		{"create trigger tr before update on t for each row begin if new.a < 0 then set new.a = 0; end if; end",
+			"CREATE TRIGGER `tr` BEFORE UPDATE ON `t` FOR EACH ROW BEGIN IF `new`.`a`<0 THEN SET NEW.`a`=0; END IF; END"},
+		{"create event e1 on schedule every 1 day starts now() on completion preserve disable on slave comment 'e1' do delete from t1",
+			"CREATE EVENT `e1` ON SCHEDULE EVERY 1 DAY STARTS NOW() ON COMPLETION PRESERVE DISABLE ON SLAVE COMMENT 'e1' DO DELETE FROM `t1`"},
+		{"create event e1 on schedule at '2019-01-01' do truncate table t1",
+			"CREATE EVENT `e1` ON SCHEDULE AT '2019-01-01' ENABLE DO TRUNCATE TABLE `t1`"},
+		{"drop procedure if exists p1", "DROP PROCEDURE IF EXISTS `p1`"},
+		{"drop function test.f1", "DROP FUNCTION `test`.`f1`"},
+		{"drop trigger tr1", "DROP TRIGGER `tr1`"},
+		{"drop event e1", "DROP EVENT `e1`"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return node
+	}
+	RunNodeRestoreTest(c, testCases, "%s", extractNodeFunc)
+}
+
+func (ts *testProcedureSuite) TestCompoundStmtRestore(c *C) {
+	testCases := []NodeRestoreTestCase{
+		{"begin end", "BEGIN END"},
+		{"lbl: begin leave lbl; end lbl", "`lbl`: BEGIN LEAVE `lbl`; END `lbl`"},
+		{"begin declare a, b int default 0; declare cur cursor for select id from t; declare continue handler for not found, sqlstate '02000', 1062 set a = 1; end",
+			"BEGIN DECLARE `a`, `b` INT DEFAULT 0; DECLARE `cur` CURSOR FOR SELECT `id` FROM `t`; DECLARE CONTINUE HANDLER FOR NOT FOUND, SQLSTATE '02000', 1062 SET @@SESSION.`a`=1; END"},
+		{"begin declare exit handler for sqlexception, sqlwarning begin rollback; end; end",
+			"BEGIN DECLARE EXIT HANDLER FOR SQLEXCEPTION, SQLWARNING BEGIN ROLLBACK; END; END"},
+		{"begin if a > 1 then set b = 1; elseif a > 0 then set b = 2; else set b = 3; end if; end",
+			"BEGIN IF `a`>1 THEN SET @@SESSION.`b`=1; ELSEIF `a`>0 THEN SET @@SESSION.`b`=2; ELSE SET @@SESSION.`b`=3; END IF; END"},
+		{"begin l1: while a < 10 do iterate l1; end while l1; loop open cur; fetch cur into a, b; close cur; end loop; repeat set a = a + 1; until a > 10 end repeat; end",
+			"BEGIN `l1`: WHILE `a`<10 DO ITERATE `l1`; END WHILE `l1`; LOOP OPEN `cur`; FETCH `cur` INTO `a`, `b`; CLOSE `cur`; END LOOP; REPEAT SET @@SESSION.`a`=`a`+1; UNTIL `a`>10 END REPEAT; END"},
+		{"begin call p2(); call test.p3(1, 'a'); call p4; end",
+			"BEGIN CALL `p2`(); CALL `test`.`p3`(1, 'a'); CALL `p4`(); END"},
+		{"begin signal sqlstate '45000' set message_text = 'invalid', mysql_errno = 1644; signal sqlstate value '45000'; signal not_found; end",
+			"BEGIN SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'invalid', MYSQL_ERRNO = 1644; SIGNAL SQLSTATE '45000'; SIGNAL `not_found`; END"},
+		{"begin select count(*) into n from t1 where id > 1; select id, c1 from t1 order by id limit 1 into a, b; select 1 into a; end",
+			"BEGIN SELECT COUNT(1) INTO `n` FROM `t1` WHERE `id`>1; SELECT `id`,`c1` INTO `a`, `b` FROM `t1` ORDER BY `id` LIMIT 1; SELECT 1 INTO `a`; END"},
+		{"begin case a when 1 then select 1; when 2 then select 2; select 3; else begin end; end case; end",
+			"BEGIN CASE `a` WHEN 1 THEN SELECT 1; WHEN 2 THEN SELECT 2; SELECT 3; ELSE BEGIN END; END CASE; END"},
+		{"begin case when a > 1 then set @b = case a when 2 then 1 else 0 end; end case; end",
+			"BEGIN CASE WHEN `a`>1 THEN SET @`b`=CASE `a` WHEN 2 THEN 1 ELSE 0 END; END CASE; END"},
+	}
+	extractNodeFunc := func(node Node) Node {
+		return node.(*CreateRoutineStmt).Body
+	}
+	RunNodeRestoreTest(c, testCases, "CREATE PROCEDURE `p`() %s", extractNodeFunc)
+}
@@ -1,89 +1,205 @@
+// inception-core 命令行工具.
+//
+// 用法:
+//
+//	inception-core <audit|execute|split|print|rollback|redundant-index|serve> [flags] [file ...]
+//
+// 未指定文件时从标准输入读取SQL. 退出码为审核结果的最高级别:
+// 0为成功,1为警告,2为错误,3为参数错误或运行失败.
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 
-	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/server"
 	"github.com/hanchuanchuan/inception-core/session"
 )
 
-func main() {
-	core := session.NewInception()
-	// core.LoadOptions(session.SourceOptions{
-	// 	Host:           "127.0.0.1",
-	// 	Port:           3306,
-	// 	User:           "test",
-	// 	Password:       "test",
-	// 	Backup:         true,
-	// 	IgnoreWarnings: true,
-	// })
-	inc := &config.GetGlobalConfig().Inc
+const (
+	exitOK = 0
+	// exitRedundantIndex 存在冗余索引
+	exitRedundantIndex = 1
+	exitFailure        = 3
+)
+
+const usage = `Usage: inception-core <command> [flags] [file ...]
 
-	inc.BackupHost = "127.0.0.1"
-	inc.BackupPort = 3306
-	inc.BackupUser = "test"
-	inc.BackupPassword = "test"
-	opt := session.SourceOptions{Host: "db1", Port: 3306, User: "test", Password: "test",
-		Check: false, Execute: true, Backup: true, IgnoreWarnings: true,
-		Sleep: 0, SleepRows: 0, MiddlewareExtend: "", MiddlewareDB: "",
-		ParseHost: "", ParsePort: 0, Fingerprint: false, Print: false,
-		Split: false, RealRowCount: false, DB: "",
-		Ssl: "", SslCA: "", SslCert: "", SslKey: "",
-		TranBatch: 0}
-	core.LoadOptions(opt)
+Commands:
+  audit     审核SQL
+  execute   审核并执行SQL
+  split     拆分SQL,连续的DDL或DML为一组
+  print     打印语法树
+  rollback  根据opid获取回滚语句
+  redundant-index
+            分析已有表的冗余索引
+  serve     启动HTTP审核服务
 
-	sql := `
-	;
-use test_inc;
+Run 'inception-core <command> -h' for the flags of each command.
+`
 
-inception set ghost_on=1;
-inception set osc_on=0;
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-alter table t1 add column c31 int;
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitFailure
+	}
 
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "audit", "execute", "split", "print":
+	case "rollback":
+		return runRollback(args, stdout, stderr)
+	case "redundant-index":
+		return runRedundantIndex(args, stdout, stderr)
+	case "serve":
+		return runServe(args, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", cmd, usage)
+		return exitFailure
+	}
 
-# drop table if exists t1,t2;
+	opts := newOptions(cmd)
+	if err := opts.parse(args, stderr); err != nil {
+		return exitFailure
+	}
+	out, err := newOutput(opts.format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
 
-# create table t1(id int primary key,c1 char(1));
+	sources, err := readSources(opts.flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
 
-# insert into t1 values(1,1),(2,2);
+	for _, src := range sources {
+		// 解析失败等审核中止的错误作为错误级别的结果输出,
+		// 没有任何结果时(如数据源配置错误)为运行失败
+		results, err := opts.runSource(cmd, src.sql)
+		if err != nil && len(results) == 0 {
+			fmt.Fprintf(stderr, "%s: %v\n", src.name, err)
+			return exitFailure
+		}
+		out.add(src, withError(results, err))
+	}
 
-# alter table t1 add column c2 int;
+	if err := out.flush(stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	return int(out.maxLevel)
+}
 
-# insert into t1 values(3,3,3);
+// source SQL来源,文件或标准输入
+type source struct {
+	name string
+	sql  string
+}
 
-# drop table if exists t1,t2;
+// readSources 读取SQL文件,未指定文件或文件名为'-'时读取标准输入
+func readSources(files []string, stdin io.Reader) ([]source, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
 
-# create table t2(id int primary key,c1 int);
+	sources := make([]source, 0, len(files))
+	for _, name := range files {
+		var (
+			data []byte
+			err  error
+		)
+		if name == "-" {
+			data, err = ioutil.ReadAll(stdin)
+			name = "stdin"
+		} else {
+			data, err = ioutil.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source{name: name, sql: string(data)})
+	}
+	return sources, nil
+}
 
-# insert into t2 values(3,3);
+// runSource 使用新的会话审核/执行单个来源的SQL
+func (o *options) runSource(cmd string, sql string) ([]result, error) {
+	core := session.NewInception()
+	if err := core.LoadOptions(o.source); err != nil {
+		return nil, err
+	}
 
-# CREATE TABLE share_data_template (
-# id bigint(32) unsigned NOT NULL AUTO_INCREMENT COMMENT '主键id，自增',
-# share_template json DEFAULT NULL COMMENT '模板内容',
-# PRIMARY KEY (id)
-# ) ENGINE=InnoDB AUTO_INCREMENT=6 DEFAULT CHARSET=utf8mb4 COMMENT='通用分享数据模板表';
+	ctx := context.Background()
+	switch cmd {
+	case "audit":
+		records, err := core.Audit(ctx, sql)
+		return fromRecords(records), err
+	case "execute":
+		records, err := core.RunExecute(ctx, sql)
+		return fromRecords(records), err
+	case "split":
+		records, err := core.Split(ctx, sql)
+		return fromSplitRecords(records), err
+	case "print":
+		records, err := core.Print(ctx, sql)
+		return fromPrintRecords(records), err
+	}
+	return nil, fmt.Errorf("unknown command: %s", cmd)
+}
 
-inception_magic_commit;
-`
+// withError 中止审核的错误未记录在最后一条结果中时,追加为错误级别的结果
+func withError(results []result, err error) []result {
+	if err == nil {
+		return results
+	}
+	if n := len(results); n > 0 && results[n-1].ErrLevel == 2 {
+		return results
+	}
+	return append(results, result{
+		ID:           len(results) + 1,
+		ErrLevel:     2,
+		ErrorMessage: err.Error(),
+		Findings:     []session.Finding{{Level: 2, Message: err.Error()}},
+	})
+}
 
-	sql = `
-use test_inc;
-insert into t1 values(1,1),(2,2);
-`
-	result, err := core.Split(context.Background(), sql)
-	if err != nil {
-		fmt.Println("error:")
-		fmt.Println(err)
+// runServe 启动HTTP审核服务
+func runServe(args []string, stderr io.Writer) int {
+	var (
+		confFile string
+		addr     string
+		sets     setFlags
+	)
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&confFile, "config", "", "配置文件(toml),格式同config.toml")
+	fs.StringVar(&addr, "addr", "127.0.0.1:4080", "监听地址")
+	fs.Var(&sets, "set", "覆盖配置项,格式为name=value,可指定多次")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	if err := loadConfig(confFile, sets); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
 	}
 
-	for _, row := range result {
-		fmt.Println(fmt.Sprintf("%#v", row))
-		// if row.ErrLevel == 2 {
-		// 	fmt.Println(fmt.Sprintf("sql: %v, err: %v", row.Sql, row.ErrorMessage))
-		// } else {
-		// 	fmt.Println(fmt.Sprintf("[%v] sql: %v", session.StatusList[row.StageStatus], row.Sql))
-		// }
+	if err := server.New().ListenAndServe(addr); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
 	}
+	return exitOK
 }
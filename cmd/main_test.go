@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testMainSuite{})
+
+type testMainSuite struct {
+	dir string
+	inc config.Inc
+}
+
+func TestCmd(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *testMainSuite) SetUpSuite(c *C) {
+	s.inc = config.GetGlobalConfig().Inc
+	config.GetGlobalConfig().Inc.Lang = "en-US"
+
+	s.dir = c.MkDir()
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id') comment 't1';`
+	err := ioutil.WriteFile(filepath.Join(s.dir, "snapshot.sql"), []byte(snapshot), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *testMainSuite) TearDownSuite(c *C) {
+	config.GetGlobalConfig().Inc = s.inc
+}
+
+func (s *testMainSuite) run(c *C, stdin string, args ...string) (int, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String()
+}
+
+func (s *testMainSuite) TestAudit(c *C) {
+	snapshot := filepath.Join(s.dir, "snapshot.sql")
+	sql := "use test_inc;\n\ninsert into t1 values(1);\nalter table t1 add column c1 int;"
+
+	code, out := s.run(c, sql, "audit", "-snapshot", snapshot,
+		"-set", "check_insert_field=true", "-set", "check_column_comment=false")
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Matches, "(?s).*Set the field list for insert statements.*")
+
+	code, out = s.run(c, sql, "audit", "-snapshot", snapshot, "-format", "json",
+		"-set", "check_insert_field=false", "-set", "inc.check_column_comment=true")
+	c.Assert(code, Equals, 1)
+	var results []result
+	c.Assert(json.Unmarshal([]byte(out), &results), IsNil)
+	c.Assert(results, HasLen, 3)
+	c.Assert(results[1].ErrLevel, Equals, uint8(0))
+	c.Assert(results[2].Findings, HasLen, 1)
+	c.Assert(results[2].Findings[0].Name, Equals, "er_column_have_no_comment")
+
+	code, out = s.run(c, sql, "audit", "-snapshot", snapshot, "-format", "sarif")
+	c.Assert(code, Equals, 1)
+	var log sarifLog
+	c.Assert(json.Unmarshal([]byte(out), &log), IsNil)
+	c.Assert(log.Runs[0].Results, HasLen, 1)
+	r := log.Runs[0].Results[0]
+	c.Assert(r.RuleID, Equals, "er_column_have_no_comment")
+	c.Assert(r.Level, Equals, "warning")
+	c.Assert(r.Locations[0].PhysicalLocation.ArtifactLocation.URI, Equals, "stdin")
+	c.Assert(r.Locations[0].PhysicalLocation.Region.StartLine, Equals, 4)
+
+	code, _ = s.run(c, "use test_inc;\ninsert into t2(id) values(1);", "audit", "-snapshot", snapshot)
+	c.Assert(code, Equals, 2)
+}
+
+func (s *testMainSuite) TestPosition(c *C) {
+	snapshot := filepath.Join(s.dir, "snapshot.sql")
+	sql := "use test_inc; alter table t1\n  add column c1 int;"
+
+	code, out := s.run(c, sql, "audit", "-snapshot", snapshot, "-format", "json",
+		"-set", "check_column_comment=true")
+	c.Assert(code, Equals, 1)
+	var results []result
+	c.Assert(json.Unmarshal([]byte(out), &results), IsNil)
+	c.Assert(results, HasLen, 2)
+	c.Assert(results[1].Position, NotNil)
+	c.Assert(*results[1].Position, Equals, session.Position{
+		Offset: 14, EndOffset: 48, Line: 1, Column: 15, EndLine: 2, EndColumn: 20})
+
+	// SARIF的位置直接取自审核结果
+	code, out = s.run(c, sql, "audit", "-snapshot", snapshot, "-format", "sarif",
+		"-set", "check_column_comment=true")
+	c.Assert(code, Equals, 1)
+	var log sarifLog
+	c.Assert(json.Unmarshal([]byte(out), &log), IsNil)
+	c.Assert(log.Runs[0].Results, HasLen, 1)
+	c.Assert(log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region, Equals,
+		sarifRegion{StartLine: 1, StartColumn: 15, EndLine: 2, EndColumn: 20})
+}
+
+func (s *testMainSuite) TestFiles(c *C) {
+	file := filepath.Join(s.dir, "a.sql")
+	c.Assert(ioutil.WriteFile(file, []byte("use test_inc;\nselect 1;"), 0644), IsNil)
+
+	code, out := s.run(c, "", "split", "-offline", "-format", "json", file, file)
+	c.Assert(code, Equals, 0)
+	var files []fileResults
+	c.Assert(json.Unmarshal([]byte(out), &files), IsNil)
+	c.Assert(files, HasLen, 2)
+	c.Assert(files[0].File, Equals, file)
+
+	code, _ = s.run(c, "", "audit", "-offline", filepath.Join(s.dir, "not_exists.sql"))
+	c.Assert(code, Equals, exitFailure)
+}
+
+func (s *testMainSuite) TestParseError(c *C) {
+	snapshot := filepath.Join(s.dir, "snapshot.sql")
+	bad := filepath.Join(s.dir, "bad.sql")
+	c.Assert(ioutil.WriteFile(bad, []byte("use test_inc;\nselect * fro t1;"), 0644), IsNil)
+	good := filepath.Join(s.dir, "good.sql")
+	c.Assert(ioutil.WriteFile(good, []byte("use test_inc;\nselect 1;"), 0644), IsNil)
+
+	// 解析失败作为错误级别的结果输出,其他文件仍正常审核
+	code, out := s.run(c, "", "audit", "-snapshot", snapshot, "-format", "json", bad, good)
+	c.Assert(code, Equals, 2)
+	var files []fileResults
+	c.Assert(json.Unmarshal([]byte(out), &files), IsNil)
+	c.Assert(files, HasLen, 2)
+	results := files[0].Results
+	c.Assert(results[len(results)-1].ErrLevel, Equals, uint8(2))
+	c.Assert(results[len(results)-1].SQL, Equals, "select * fro t1")
+	c.Assert(files[1].Results, HasLen, 2)
+
+	code, out = s.run(c, "", "audit", "-snapshot", snapshot, "-format", "sarif", bad)
+	c.Assert(code, Equals, 2)
+	var log sarifLog
+	c.Assert(json.Unmarshal([]byte(out), &log), IsNil)
+	c.Assert(log.Runs[0].Results, HasLen, 1)
+	c.Assert(log.Runs[0].Results[0].Level, Equals, "error")
+	c.Assert(log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine, Equals, 2)
+
+	code, out = s.run(c, "", "split", "-offline", bad)
+	c.Assert(code, Equals, 2)
+	c.Assert(out, Matches, "(?s).*select \\* fro t1.*")
+}
+
+func (s *testMainSuite) TestRedundantIndex(c *C) {
+	file := filepath.Join(s.dir, "index.sql")
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key, a int, b int, key idx_a(a), key idx_ab(a,b));
+	create table t2(id int primary key, a int, key idx_a(a));`
+	c.Assert(ioutil.WriteFile(file, []byte(snapshot), 0644), IsNil)
+
+	code, out := s.run(c, "", "redundant-index", "-db", "test_inc", "-snapshot", file)
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Matches, "(?s).*t1 +idx_a\\(a\\) +idx_ab\\(a,b\\) +DROP INDEX `idx_a` ON `test_inc`.`t1`;.*")
+
+	code, out = s.run(c, "", "redundant-index", "-db", "test_inc", "-snapshot", file,
+		"-format", "json", "t2")
+	c.Assert(code, Equals, 0)
+	c.Assert(strings.TrimSpace(out), Equals, "[]")
+
+	code, _ = s.run(c, "", "redundant-index", "-snapshot", file)
+	c.Assert(code, Equals, exitFailure)
+}
+
+func (s *testMainSuite) TestUsage(c *C) {
+	code, _ := s.run(c, "")
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "unknown")
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "audit", "-format", "xml")
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "audit", "-set", "not_exists=1")
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "rollback")
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "rollback", "-binlog", s.dir)
+	c.Assert(code, Equals, exitFailure)
+	code, _ = s.run(c, "", "rollback", "-binlog", s.dir, "-snapshot", filepath.Join(s.dir, "snapshot.sql"))
+	c.Assert(code, Equals, exitFailure)
+}
+
+func (s *testMainSuite) TestSetConfigValue(c *C) {
+	cfg := config.NewConfig()
+	c.Assert(setConfigValue(cfg, "max_char_length", "32"), IsNil)
+	c.Assert(cfg.Inc.MaxCharLength, Equals, uint(32))
+	c.Assert(setConfigValue(cfg, "osc.osc_on", "true"), IsNil)
+	c.Assert(cfg.Osc.OscOn, IsTrue)
+	c.Assert(setConfigValue(cfg, "er_with_insert_field", "2"), IsNil)
+	c.Assert(cfg.IncLevel.ER_WITH_INSERT_FIELD, Equals, int8(2))
+	c.Assert(setConfigValue(cfg, "osc_on", "abc"), NotNil)
+	c.Assert(setConfigValue(cfg, "inc.osc_on", "true"), NotNil)
+}
+
+func (s *testMainSuite) TestBackupDBName(c *C) {
+	c.Assert(backupDBName("127.0.0.1", 3306, "test-db"), Equals, "127_0_0_1_3306_test_db")
+}
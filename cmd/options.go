@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+)
+
+// options 命令行参数
+type options struct {
+	flags *flag.FlagSet
+
+	source   session.SourceOptions
+	confFile string
+	snapshot string
+	format   string
+	sets     setFlags
+}
+
+// setFlags 可重复指定的 -set name=value 参数
+type setFlags []string
+
+func (f *setFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *setFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func newOptions(cmd string) *options {
+	o := &options{}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	o.flags = fs
+
+	fs.StringVar(&o.confFile, "config", "", "配置文件(toml),格式同config.toml")
+	fs.StringVar(&o.format, "format", "table", "输出格式: table, json, sarif")
+	fs.Var(&o.sets, "set", "覆盖配置项,格式为name=value,可指定多次. 如 -set check_column_comment=true")
+
+	fs.StringVar(&o.source.Host, "host", "127.0.0.1", "数据库地址")
+	fs.IntVar(&o.source.Port, "port", 3306, "数据库端口")
+	fs.StringVar(&o.source.User, "user", "", "数据库用户")
+	fs.StringVar(&o.source.Password, "password", "", "数据库密码")
+	fs.StringVar(&o.source.DB, "db", "", "默认数据库")
+
+	fs.StringVar(&o.source.Ssl, "ssl", "", "连接加密: disabled, preferred, required, verify_ca, verify_identity")
+	fs.StringVar(&o.source.SslCA, "ssl-ca", "", "CA证书")
+	fs.StringVar(&o.source.SslCert, "ssl-cert", "", "客户端公共密钥证书")
+	fs.StringVar(&o.source.SslKey, "ssl-key", "", "客户端私钥文件")
+
+	fs.BoolVar(&o.source.Offline, "offline", false, "离线审核,不连接数据库")
+	fs.StringVar(&o.snapshot, "snapshot", "", "离线审核的表结构快照文件(建库建表语句或JSON)")
+	fs.BoolVar(&o.source.RealRowCount, "real-row-count", false, "使用count(*)计算受影响行数")
+
+	if cmd == "execute" {
+		fs.BoolVar(&o.source.Backup, "backup", false, "执行时备份并生成回滚语句")
+		fs.BoolVar(&o.source.IgnoreWarnings, "ignore-warnings", false, "忽略警告,仅有警告时继续执行")
+		fs.IntVar(&o.source.Sleep, "sleep", 0, "每次执行后休眠的毫秒数")
+		fs.IntVar(&o.source.SleepRows, "sleep-rows", 1, "执行多少条后休眠")
+		fs.IntVar(&o.source.TranBatch, "tran-batch", 0, "事务支持,一次执行多少条")
+	}
+
+	switch cmd {
+	case "audit":
+		o.source.Check = true
+	case "execute":
+		o.source.Execute = true
+	case "split":
+		o.source.Split = true
+	case "print":
+		o.source.Print = true
+	}
+	return o
+}
+
+// parse 解析命令行参数,并加载配置文件和配置覆盖项
+func (o *options) parse(args []string, stderr io.Writer) error {
+	o.flags.SetOutput(stderr)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := loadConfig(o.confFile, o.sets); err != nil {
+		fmt.Fprintln(stderr, err)
+		return err
+	}
+
+	if o.snapshot != "" {
+		data, err := ioutil.ReadFile(o.snapshot)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return err
+		}
+		o.source.Snapshot = string(data)
+		o.source.Offline = true
+	}
+	return nil
+}
+
+// loadConfig 加载配置文件,并应用 -set 指定的配置项
+func loadConfig(confFile string, sets []string) error {
+	cfg := config.GetGlobalConfig()
+	if confFile != "" {
+		if err := cfg.Load(confFile); err != nil {
+			return fmt.Errorf("load config %s: %v", confFile, err)
+		}
+	}
+
+	for _, item := range sets {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid -set value '%s', expect name=value", item)
+		}
+		if err := setConfigValue(cfg, strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setConfigValue 按toml名称设置配置项.
+// 支持inc,osc,ghost,inc_level各节,可使用节名前缀,如 osc.osc_on=true
+func setConfigValue(cfg *config.Config, name string, value string) error {
+	sections := []struct {
+		name  string
+		value reflect.Value
+	}{
+		{"inc", reflect.ValueOf(&cfg.Inc).Elem()},
+		{"osc", reflect.ValueOf(&cfg.Osc).Elem()},
+		{"ghost", reflect.ValueOf(&cfg.Ghost).Elem()},
+		{"inc_level", reflect.ValueOf(&cfg.IncLevel).Elem()},
+	}
+
+	section := ""
+	if i := strings.Index(name, "."); i > 0 {
+		section, name = strings.ToLower(name[:i]), name[i+1:]
+	}
+
+	for _, sec := range sections {
+		if section != "" && section != sec.name {
+			continue
+		}
+		t := sec.value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+			if strings.EqualFold(tag, name) || strings.EqualFold(t.Field(i).Name, name) {
+				if err := setFieldValue(sec.value.Field(i), value); err != nil {
+					return fmt.Errorf("invalid value '%s' for %s: %v", value, name, err)
+				}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown config item: %s", name)
+}
+
+func setFieldValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
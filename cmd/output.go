@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+)
+
+// result 审核/执行/拆分/打印的统一输出格式
+type result struct {
+	ID           int
+	Stage        string `json:",omitempty"`
+	StageStatus  string `json:",omitempty"`
+	ErrLevel     uint8
+	ErrorMessage string
+	SQL          string
+	AffectedRows int    `json:",omitempty"`
+	OPID         string `json:",omitempty"`
+	BackupDBName string `json:",omitempty"`
+	ExecTime     string `json:",omitempty"`
+	Sqlsha1      string `json:",omitempty"`
+	IsDDL        bool   `json:",omitempty"`
+	QueryTree    string `json:",omitempty"`
+	Algorithm    string `json:",omitempty"`
+	Findings     []session.Finding
+	// 语句在源文件中的位置,仅审核/执行结果提供
+	Position *session.Position `json:",omitempty"`
+}
+
+func fromRecords(records []session.Record) []result {
+	results := make([]result, 0, len(records))
+	for i, r := range records {
+		results = append(results, result{
+			ID:           i + 1,
+			Stage:        session.StageList[r.Stage],
+			StageStatus:  session.StatusList[r.StageStatus],
+			ErrLevel:     r.ErrLevel,
+			ErrorMessage: r.ErrorMessage,
+			SQL:          r.Sql,
+			AffectedRows: r.AffectedRows,
+			OPID:         r.OPID,
+			BackupDBName: r.BackupDBName,
+			ExecTime:     r.ExecTime,
+			Sqlsha1:      r.Sqlsha1,
+			Findings:     r.Findings,
+		})
+		if r.DDLAlgorithm != nil {
+			results[i].Algorithm = r.DDLAlgorithm.String()
+		}
+		if r.Position.Line > 0 {
+			pos := r.Position
+			results[i].Position = &pos
+		}
+	}
+	return results
+}
+
+func fromSplitRecords(records []session.SplitRecord) []result {
+	results := make([]result, 0, len(records))
+	for _, r := range records {
+		results = append(results, result{
+			ID:           r.ID,
+			ErrLevel:     r.ErrLevel,
+			ErrorMessage: r.ErrorMessage,
+			SQL:          r.SQL,
+			IsDDL:        r.IsDDL,
+			Findings:     r.Findings,
+		})
+	}
+	return results
+}
+
+func fromPrintRecords(records []session.PrintRecord) []result {
+	results := make([]result, 0, len(records))
+	for _, r := range records {
+		results = append(results, result{
+			ID:           r.ID,
+			ErrLevel:     r.ErrLevel,
+			ErrorMessage: r.ErrorMessage,
+			SQL:          r.SQL,
+			QueryTree:    r.QueryTree,
+			Findings:     r.Findings,
+		})
+	}
+	return results
+}
+
+// fileResults 单个SQL来源的结果
+type fileResults struct {
+	File    string
+	Results []result
+	source  source
+}
+
+// output 输出格式化
+type output struct {
+	format   string
+	files    []fileResults
+	maxLevel uint8
+}
+
+func newOutput(format string) (*output, error) {
+	switch format {
+	case "table", "json", "sarif":
+		return &output{format: format}, nil
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+func (o *output) add(src source, results []result) {
+	for _, r := range results {
+		if r.ErrLevel > o.maxLevel {
+			o.maxLevel = r.ErrLevel
+		}
+	}
+	o.files = append(o.files, fileResults{File: src.name, Results: results, source: src})
+}
+
+func (o *output) flush(w io.Writer) error {
+	switch o.format {
+	case "json":
+		return o.writeJSON(w)
+	case "sarif":
+		return o.writeSARIF(w)
+	default:
+		return o.writeTable(w)
+	}
+}
+
+func (o *output) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, f := range o.files {
+		if len(o.files) > 1 {
+			fmt.Fprintf(tw, "-- %s\n", f.File)
+		}
+		fmt.Fprintln(tw, "ID\tLEVEL\tSTATUS\tROWS\tSQL\tMESSAGE")
+		for _, r := range f.Results {
+			status := r.StageStatus
+			if r.IsDDL {
+				status = "DDL"
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%d\t%s\t%s\n", r.ID, r.ErrLevel, status,
+				r.AffectedRows, oneLine(r.SQL, 60), oneLine(r.ErrorMessage, 0))
+			if r.QueryTree != "" {
+				fmt.Fprintf(tw, "\t\t\t\t%s\t\n", r.QueryTree)
+			}
+			if r.Algorithm != "" {
+				fmt.Fprintf(tw, "\t\t\t\t%s\t\n", r.Algorithm)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// oneLine 合并为单行显示,maxLen大于0时截断
+func oneLine(s string, maxLen int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if maxLen > 0 && len([]rune(s)) > maxLen {
+		s = string([]rune(s)[:maxLen-3]) + "..."
+	}
+	return s
+}
+
+func (o *output) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if len(o.files) == 1 {
+		return enc.Encode(o.files[0].Results)
+	}
+	return enc.Encode(o.files)
+}
+
+// SARIF 2.1.0 格式,用以在代码评审工具中展示审核结果
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// 没有错误码的审核信息使用的规则名
+const sarifDefaultRule = "inception"
+
+func (o *output) writeSARIF(w io.Writer) error {
+	rules := make(map[string]string)
+	results := []sarifResult{}
+
+	for _, f := range o.files {
+		offset := 0
+		for _, r := range f.Results {
+			var region sarifRegion
+			if p := r.Position; p != nil {
+				region = sarifRegion{
+					StartLine:   p.Line,
+					StartColumn: p.Column,
+					EndLine:     p.EndLine,
+					EndColumn:   p.EndColumn,
+				}
+				offset = p.EndOffset
+			} else {
+				region.StartLine, offset = locate(f.source.sql, r.SQL, offset)
+			}
+			for _, finding := range r.Findings {
+				ruleID := finding.Name
+				if ruleID == "" {
+					ruleID = sarifDefaultRule
+				}
+				if _, ok := rules[ruleID]; !ok {
+					rules[ruleID] = ruleDescription(finding)
+				}
+
+				level := "warning"
+				if finding.Level > 1 {
+					level = "error"
+				}
+				results = append(results, sarifResult{
+					RuleID:  ruleID,
+					Level:   level,
+					Message: sarifMessage{Text: finding.Message},
+					Locations: []sarifLocation{{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: f.File},
+							Region:           region,
+						},
+					}},
+				})
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	driver := sarifDriver{
+		Name:           "inception-core",
+		InformationURI: "https://github.com/hanchuanchuan/inception-core",
+		Rules:          make([]sarifRule, 0, len(ids)),
+	}
+	for _, id := range ids {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: rules[id]},
+		})
+	}
+
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// ruleDescription 规则说明,内置错误码使用错误信息模板
+func ruleDescription(f session.Finding) string {
+	if f.Code > 0 && f.Code < session.ER_ERROR_LAST {
+		lang := strings.Replace(strings.ToLower(config.GetGlobalConfig().Inc.Lang), "-", "_", 1)
+		return session.GetErrorMessage(f.Code, lang)
+	}
+	return f.Name
+}
+
+// locate 从offset开始查找语句在源文件中的位置,返回行号(从1开始)和语句结束的偏移量.
+// 未找到时返回offset所在的行
+func locate(text string, sql string, offset int) (int, int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	sql = strings.TrimSpace(sql)
+	if sql != "" {
+		if i := strings.Index(text[offset:], sql); i >= 0 {
+			start := offset + i
+			return strings.Count(text[:start], "\n") + 1, start + len(sql)
+		}
+	}
+	return strings.Count(text[:offset], "\n") + 1, offset
+}
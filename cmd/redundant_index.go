@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hanchuanchuan/inception-core/session"
+)
+
+// runRedundantIndex 分析已有表的冗余索引,并输出建议的删除语句.
+// 未指定表时分析-db指定库的所有表. 存在冗余索引时退出码为exitRedundantIndex
+func runRedundantIndex(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts := newOptions("redundant-index")
+	opts.flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: inception-core redundant-index -db name [flags] [table ...]")
+		opts.flags.PrintDefaults()
+	}
+	if err := opts.parse(args, stderr); err != nil {
+		return exitFailure
+	}
+	if opts.format != "table" && opts.format != "json" {
+		fmt.Fprintf(stderr, "unsupported format: %s\n", opts.format)
+		return exitFailure
+	}
+	if opts.source.DB == "" {
+		opts.flags.Usage()
+		return exitFailure
+	}
+
+	core := session.NewInception()
+	if err := core.LoadOptions(opts.source); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	indexes, err := core.RedundantIndexes(context.Background(), opts.flags.Args()...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	if opts.format == "json" {
+		if indexes == nil {
+			indexes = []session.RedundantIndex{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(indexes); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+	} else {
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TABLE\tINDEX\tCOVERED BY\tSUGGESTION")
+		for _, r := range indexes {
+			fmt.Fprintf(tw, "%s\t%s(%s)\t%s(%s)\t%s\n", r.Table,
+				r.IndexName, strings.Join(r.Columns, ","),
+				r.CoveredBy, strings.Join(r.CoveredColumns, ","), r.Suggestion)
+		}
+		if err := tw.Flush(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+	}
+
+	if len(indexes) > 0 {
+		return exitRedundantIndex
+	}
+	return exitOK
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/session"
+)
+
+// rollbackResult 单个opid的回滚语句
+type rollbackResult struct {
+	OPID       string
+	DBName     string
+	TableName  string
+	Statements []string
+}
+
+// 时间参数格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// runRollback 从备份存储获取回滚语句.
+// 备份存储使用配置中的backup_storage,默认为backup_host等参数指定的备份库.
+// 指定-binlog时解析本地binlog文件生成回滚语句
+func runRollback(args []string, stdout io.Writer, stderr io.Writer) int {
+	var (
+		confFile string
+		format   string
+		backupDB string
+		host     string
+		port     int
+		db       string
+		sets     setFlags
+
+		binlog    session.BinlogOptions
+		snapshot  string
+		startTime string
+		stopTime  string
+		threadID  uint
+	)
+
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&confFile, "config", "", "配置文件(toml),格式同config.toml")
+	fs.StringVar(&format, "format", "table", "输出格式: table, json")
+	fs.Var(&sets, "set", "覆盖配置项,格式为name=value,可指定多次. 如 -set backup_host=127.0.0.1")
+	fs.StringVar(&backupDB, "backup-db", "", "备份库名,即执行结果中的BackupDBName")
+	fs.StringVar(&host, "host", "127.0.0.1", "未指定-backup-db时,根据执行的数据库地址,端口和库名确定备份库名")
+	fs.IntVar(&port, "port", 3306, "执行的数据库端口")
+	fs.StringVar(&db, "db", "", "执行的数据库库名")
+	fs.StringVar(&binlog.Path, "binlog", "", "本地binlog文件或目录,指定后解析binlog生成回滚语句")
+	fs.StringVar(&snapshot, "snapshot", "", "解析binlog使用的表结构快照文件(建库建表语句或JSON)")
+	fs.StringVar(&binlog.StartFile, "start-file", "", "起始binlog文件")
+	fs.Int64Var(&binlog.StartPosition, "start-pos", 0, "起始位置,应为事务的起始位置")
+	fs.StringVar(&binlog.StopFile, "stop-file", "", "结束binlog文件")
+	fs.Int64Var(&binlog.StopPosition, "stop-pos", 0, "结束位置(不含)")
+	fs.StringVar(&startTime, "start-time", "", "起始时间,格式为"+timeLayout)
+	fs.StringVar(&stopTime, "stop-time", "", "结束时间(不含),格式为"+timeLayout)
+	fs.UintVar(&threadID, "thread-id", 0, "线程号")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: inception-core rollback [flags] opid ...")
+		fmt.Fprintln(stderr, "       inception-core rollback -binlog path -snapshot file [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	if format != "table" && format != "json" {
+		fmt.Fprintf(stderr, "unsupported format: %s\n", format)
+		return exitFailure
+	}
+	if fs.NArg() == 0 && binlog.Path == "" {
+		fs.Usage()
+		return exitFailure
+	}
+	if err := loadConfig(confFile, sets); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	if binlog.Path != "" {
+		binlog.ThreadID = uint32(threadID)
+		if err := parseBinlogOptions(&binlog, snapshot, startTime, stopTime); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		return runBinlogRollback(binlog, format, stdout, stderr)
+	}
+
+	if backupDB == "" {
+		if db == "" {
+			fmt.Fprintln(stderr, "either -backup-db or -db must be specified")
+			return exitFailure
+		}
+		backupDB = backupDBName(host, port, db)
+	}
+
+	results, err := queryRollback(backupDB, fs.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(stdout, "-- opid: %s, table: %s.%s\n", r.OPID, r.DBName, r.TableName)
+		for _, stmt := range r.Statements {
+			fmt.Fprintln(stdout, stmt)
+		}
+	}
+	return exitOK
+}
+
+// parseBinlogOptions 读取表结构快照并解析时间范围
+func parseBinlogOptions(opt *session.BinlogOptions, snapshot string,
+	startTime string, stopTime string) error {
+	if snapshot == "" {
+		return fmt.Errorf("-snapshot must be specified with -binlog")
+	}
+	data, err := ioutil.ReadFile(snapshot)
+	if err != nil {
+		return err
+	}
+	opt.Snapshot = string(data)
+
+	if startTime != "" {
+		if opt.StartTime, err = time.ParseInLocation(timeLayout, startTime, time.Local); err != nil {
+			return err
+		}
+	}
+	if stopTime != "" {
+		if opt.StopTime, err = time.ParseInLocation(timeLayout, stopTime, time.Local); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBinlogRollback 解析本地binlog文件并输出回滚语句
+func runBinlogRollback(opt session.BinlogOptions, format string, stdout io.Writer, stderr io.Writer) int {
+	records, err := session.NewInception().RollbackFromBinlog(context.Background(), opt)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	var file string
+	var pos uint32
+	for _, r := range records {
+		if r.File != file || r.Position != pos {
+			file, pos = r.File, r.Position
+			fmt.Fprintf(stdout, "-- %s:%d, table: %s.%s, thread: %d, time: %s\n",
+				r.File, r.Position, r.DBName, r.TableName, r.ThreadID, r.Time.Format(timeLayout))
+		}
+		fmt.Fprintln(stdout, r.Sql)
+	}
+	return exitOK
+}
+
+// backupDBName 备份库名,规则同session.getRemoteBackupDBName
+func backupDBName(host string, port int, db string) string {
+	v := fmt.Sprintf("%s_%d_%s", host, port, db)
+	if len(v) > mysql.MaxDatabaseNameLength {
+		v = v[len(v)-mysql.MaxDatabaseNameLength:]
+	}
+	v = strings.Replace(v, "-", "_", -1)
+	v = strings.Replace(v, ".", "_", -1)
+	return v
+}
+
+// queryRollback 查询opid对应的回滚语句.
+// 回滚需要按执行的逆序进行,因此opid和同一opid内的语句均按逆序返回
+func queryRollback(backupDB string, opids []string) ([]rollbackResult, error) {
+	sink, err := session.NewBackupSink(config.GetGlobalConfig().Inc)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]rollbackResult, 0, len(opids))
+	for i := len(opids) - 1; i >= 0; i-- {
+		info, statements, err := sink.Rollback(backupDB, opids[i])
+		if err != nil {
+			return nil, fmt.Errorf("opid %s: %v", opids[i], err)
+		}
+		results = append(results, rollbackResult{
+			OPID:       opids[i],
+			DBName:     info.DBName,
+			TableName:  info.TableName,
+			Statements: statements,
+		})
+	}
+	return results, nil
+}
@@ -71,6 +71,9 @@ type Config struct {
 	IncLevel            IncLevel   `toml:"inc_level" json:"inc_level"`
 	CompatibleKillQuery bool       `toml:"compatible-kill-query" json:"compatible-kill-query"`
 
+	// 审核规则配置集,key为配置集名称
+	Profiles map[string]Profile `toml:"profiles" json:"profiles"`
+
 	// 是否跳过用户权限校验
 	SkipGrantTable bool `toml:"skip_grant_table" json:"skip_grant_table"`
 
@@ -192,6 +195,17 @@ type Inc struct {
 	BackupPort     uint   `toml:"backup_port" json:"backup_port"`
 	BackupUser     string `toml:"backup_user" json:"backup_user"`
 
+	// 备份存储方式: mysql(默认,即备份库), file(本地文件), s3(S3兼容的对象存储)
+	BackupStorage string `toml:"backup_storage" json:"backup_storage"`
+	// 本地备份目录,backup_storage为file时使用
+	BackupDir string `toml:"backup_dir" json:"backup_dir"`
+	// S3兼容的对象存储,backup_storage为s3时使用. endpoint如 http://127.0.0.1:9000
+	BackupS3Endpoint  string `toml:"backup_s3_endpoint" json:"backup_s3_endpoint"`
+	BackupS3Region    string `toml:"backup_s3_region" json:"backup_s3_region"`
+	BackupS3Bucket    string `toml:"backup_s3_bucket" json:"backup_s3_bucket"`
+	BackupS3AccessKey string `toml:"backup_s3_access_key" json:"backup_s3_access_key"`
+	BackupS3SecretKey string `toml:"backup_s3_secret_key" json:"backup_s3_secret_key"`
+
 	CheckAutoIncrementDataType  bool `toml:"check_autoincrement_datatype" json:"check_autoincrement_datatype"`
 	CheckAutoIncrementInitValue bool `toml:"check_autoincrement_init_value" json:"check_autoincrement_init_value"`
 	CheckAutoIncrementName      bool `toml:"check_autoincrement_name" json:"check_autoincrement_name"`
@@ -223,6 +237,9 @@ type Inc struct {
 
 	CheckIdentifierUpper bool `toml:"check_identifier_upper" json:"check_identifier_upper"`
 
+	// 检查冗余索引,即与其他索引完全重复或为其他索引最左前缀的索引
+	CheckRedundantIndex bool `toml:"check_redundant_index" json:"check_redundant_index"`
+
 	// 连接服务器的默认字符集,默认值为utf8mb4
 	DefaultCharset              string `toml:"default_charset" json:"default_charset"`
 	EnableAutoIncrementUnsigned bool   `toml:"enable_autoincrement_unsigned" json:"enable_autoincrement_unsigned"`
@@ -231,10 +248,17 @@ type Inc struct {
 	EnableBlobType      bool `toml:"enable_blob_type" json:"enable_blob_type"`
 	EnableChangeColumn  bool `toml:"enable_change_column" json:"enable_change_column"` // 允许change column操作
 	EnableColumnCharset bool `toml:"enable_column_charset" json:"enable_column_charset"`
+	EnableCreateView    bool `toml:"enable_create_view" json:"enable_create_view"` // 允许创建视图
+	// 允许创建存储过程,函数和事件
+	EnableCreateRoutine bool `toml:"enable_create_routine" json:"enable_create_routine"`
+	EnableCreateTrigger bool `toml:"enable_create_trigger" json:"enable_create_trigger"` // 允许创建触发器
 	EnableDropDatabase  bool `toml:"enable_drop_database" json:"enable_drop_database"`
 	EnableDropTable     bool `toml:"enable_drop_table" json:"enable_drop_table"` // 允许删除表
 	EnableEnumSetBit    bool `toml:"enable_enum_set_bit" json:"enable_enum_set_bit"`
 
+	// 允许账号管理,包括创建/修改/删除用户,授权和回收权限
+	EnableAccountManagement bool `toml:"enable_account_management" json:"enable_account_management"`
+
 	// DML指纹功能,开启后,在审核时,类似DML将直接复用审核结果,可大幅优化审核效率
 	EnableFingerprint      bool `toml:"enable_fingerprint" json:"enable_fingerprint"`
 	EnableForeignKey       bool `toml:"enable_foreign_key" json:"enable_foreign_key"`
@@ -244,6 +268,9 @@ type Inc struct {
 	// EnableLevel bool `toml:"enable_level" json:"enable_level"`
 	// 是否启用最小化回滚SQL设置,当开启时,update语句中未变更的值不再记录到回滚语句中
 	EnableMinimalRollback bool `toml:"enable_minimal_rollback" json:"enable_minimal_rollback"`
+	// 是否在update回滚语句的where条件中加入更新后的列值,
+	// 数据在执行后再次被修改时回滚语句不再生效,以免覆盖新数据
+	EnableRollbackGuard bool `toml:"enable_rollback_guard" json:"enable_rollback_guard"`
 	// 是否允许指定存储引擎
 	EnableSetEngine        bool `toml:"enable_set_engine" json:"enable_set_engine"`
 	EnableNullable         bool `toml:"enable_nullable" json:"enable_nullable"`               // 允许空列
@@ -276,6 +303,22 @@ type Inc struct {
 	UniqIndexPrefix string `toml:"uniq_index_prefix" json:"uniq_index_prefix"`
 	TablePrefix     string `toml:"table_prefix" json:"table_prefix"`
 
+	// 存储过程/函数/触发器/事件名前缀，为空时不作限制
+	ProcedurePrefix string `toml:"procedure_prefix" json:"procedure_prefix"`
+	FunctionPrefix  string `toml:"function_prefix" json:"function_prefix"`
+	TriggerPrefix   string `toml:"trigger_prefix" json:"trigger_prefix"`
+	EventPrefix     string `toml:"event_prefix" json:"event_prefix"`
+
+	// 命名规范(正则表达式)，为空时不作限制，规则须匹配完整的名称
+	// 索引和外键的规则中可使用{table}和{column}占位符,分别替换为表名和首列列名
+	DatabaseNamePattern   string `toml:"database_name_pattern" json:"database_name_pattern"`
+	TableNamePattern      string `toml:"table_name_pattern" json:"table_name_pattern"`
+	ColumnNamePattern     string `toml:"column_name_pattern" json:"column_name_pattern"`
+	IndexNamePattern      string `toml:"index_name_pattern" json:"index_name_pattern"`
+	UniqIndexNamePattern  string `toml:"uniq_index_name_pattern" json:"uniq_index_name_pattern"`
+	ForeignKeyNamePattern string `toml:"foreign_key_name_pattern" json:"foreign_key_name_pattern"`
+	ViewNamePattern       string `toml:"view_name_pattern" json:"view_name_pattern"`
+
 	Lang string `toml:"lang" json:"lang"`
 	// 连接服务器允许的最大包大小,以字节为单位 默认值为4194304(即4MB)
 	MaxAllowedPacket uint `toml:"max_allowed_packet" json:"max_allowed_packet"`
@@ -294,11 +337,54 @@ type Inc struct {
 	MaxPrimaryKeyParts uint `toml:"max_primary_key_parts" json:"max_primary_key_parts"` // 主键最多允许有几列组合
 	MergeAlterTable    bool `toml:"merge_alter_table" json:"merge_alter_table"`
 
+	// 自动合并同一张表的多条ALTER TABLE语句,合并后的语句用以审核和执行
+	AutoMergeAlterTable bool `toml:"auto_merge_alter_table" json:"auto_merge_alter_table"`
+
+	// 分区表最多允许的分区数. 默认值0,即按MySQL的上限8192
+	MaxPartitions uint `toml:"max_partitions" json:"max_partitions"`
+
+	// 执行DDL前检查持有表元数据锁的事务和长事务. 默认为空,即不检查
+	// [refuse | wait | retry]
+	// refuse: 存在阻塞的事务时拒绝执行
+	// wait: 等待阻塞的事务结束,超过ddl_blocker_wait_time后拒绝执行
+	// 使用gh-ost时切换表前同样检查,pt-osc时限制切换表的重试次数
+	// retry: 以较短的lock_wait_timeout执行,锁等待超时后按指数退避重试
+	DDLBlockerAction string `toml:"ddl_blocker_action" json:"ddl_blocker_action"`
+	// 无法查询元数据锁时(MySQL 5.6,或5.7未开启wait/lock/metadata/sql/mdl),运行时间超过该值(秒)的事务视为阻塞DDL的事务.
+	// MySQL 8.0仅检查在该表上加锁的事务. 默认值60,0表示不检查长事务
+	DDLLongTrxTime uint `toml:"ddl_long_trx_time" json:"ddl_long_trx_time"`
+	// wait时最长等待时间(秒). 默认值60
+	DDLBlockerWaitTime uint `toml:"ddl_blocker_wait_time" json:"ddl_blocker_wait_time"`
+	// retry时执行DDL的lock_wait_timeout(秒). 默认值5
+	DDLLockWaitTimeout uint `toml:"ddl_lock_wait_timeout" json:"ddl_lock_wait_timeout"`
+	// retry时锁等待超时后的最大重试次数. 默认值5
+	DDLLockRetryCount uint `toml:"ddl_lock_retry_count" json:"ddl_lock_retry_count"`
+
+	// 执行DML时的限流. 从库地址,多个以逗号分隔,格式: host:port. 使用和主库相同的用户名和密码
+	ThrottleReplicas string `toml:"throttle_replicas" json:"throttle_replicas"`
+	// 查询主从延迟(秒)的语句,如pt-heartbeat. 配置从库时在从库执行,否则在主库执行. 为空时使用SHOW SLAVE STATUS
+	ThrottleLagQuery string `toml:"throttle_lag_query" json:"throttle_lag_query"`
+	// 主从延迟(秒)超过该值时暂停执行,超过一半时减慢执行. 默认值0,即不检查
+	ThrottleMaxLag float64 `toml:"throttle_max_lag" json:"throttle_max_lag"`
+	// Threads_running超过该值时暂停执行,超过一半时减慢执行. 默认值0,即不检查
+	ThrottleMaxThreadsRunning uint `toml:"throttle_max_threads_running" json:"throttle_max_threads_running"`
+	// Threads_running超过该值时终止执行. 默认值0,即不检查
+	ThrottleCriticalThreadsRunning uint `toml:"throttle_critical_threads_running" json:"throttle_critical_threads_running"`
+	// PXC集群因流控暂停的时间比例(0-1)超过该值时暂停执行. 默认值0,即不检查
+	ThrottleMaxFlowCtl float64 `toml:"throttle_max_flow_ctl" json:"throttle_max_flow_ctl"`
+	// 限流检查间隔(毫秒). 默认值1000
+	ThrottleCheckInterval uint `toml:"throttle_check_interval" json:"throttle_check_interval"`
+	// 暂停超过该时间(秒)后终止执行. 默认值0,即一直等待
+	ThrottleMaxPauseTime uint `toml:"throttle_max_pause_time" json:"throttle_max_pause_time"`
+
 	// 建表必须创建的列. 可指定多个列,以逗号分隔.列类型可选. 格式: 列名 [列类型,可选],...
 	MustHaveColumns string `toml:"must_have_columns" json:"must_have_columns"`
 	// 如果表包含以下列，列必须有索引。可指定多个列,以逗号分隔.列类型可选.   格式: 列名 [列类型,可选],...
 	ColumnsMustHaveIndex string `toml:"columns_must_have_index" json:"columns_must_have_index"`
 
+	// 用户密码最小长度,且须同时包含大写字母,小写字母,数字和特殊字符
+	PasswordMinLength uint `toml:"password_min_length" json:"password_min_length"`
+
 	// 是否跳过用户权限校验
 	SkipGrantTable bool `toml:"skip_grant_table" json:"skip_grant_table"`
 	// 要跳过的sql语句, 多个时以分号分隔
@@ -347,6 +433,9 @@ type Osc struct {
 	// [0-1048576]
 	OscMinTableSize uint `toml:"osc_min_table_size" json:"osc_min_table_size"`
 
+	// ALTER可以INSTANT执行,或INPLACE执行且无需重建表,不阻塞DML时,直接执行而不使用pt-osc/gh-ost。默认值：OFF
+	OscPreferNativeDDL bool `toml:"osc_prefer_native_ddl" json:"osc_prefer_native_ddl"`
+
 	// 对应参数pt-online-schema-change中的参数alter-foreign-keys-method，具体意义可以参考OSC官方手册。默认值：none
 	// [auto | none | rebuild_constraints | drop_swap]
 	OscAlterForeignKeysMethod string `toml:"osc_alter_foreign_keys_method" json:"osc_alter_foreign_keys_method"`
@@ -540,6 +629,19 @@ type Ghost struct {
 	GhostReplicationLagQuery string `toml:"ghost_replication_lag_query"`
 }
 
+// Profile 审核规则配置集,按主机或库名匹配会话,覆盖全局的inc和inc_level设置
+type Profile struct {
+	// 匹配的主机,格式为host或host:port,支持通配符*和?
+	Hosts []string `toml:"hosts" json:"hosts"`
+	// 匹配的库名,支持通配符*和?
+	Schemas []string `toml:"schemas" json:"schemas"`
+
+	// 覆盖的审核参数,key为[inc]中的参数名
+	Inc map[string]interface{} `toml:"inc" json:"inc"`
+	// 覆盖的审核级别,key为[inc_level]中的参数名
+	IncLevel map[string]int8 `toml:"inc_level" json:"inc_level"`
+}
+
 type IncLevel struct {
 	ER_ALTER_TABLE_ONCE             int8 `toml:"er_alter_table_once"`
 	ER_AUTO_INCR_ID_WARNING         int8 `toml:"er_auto_incr_id_warning"`
@@ -598,6 +700,38 @@ type IncLevel struct {
 	ErrJoinNoOnCondition            int8 `toml:"er_join_no_on_condition"`
 	ErrUseValueExpr                 int8 `toml:"er_use_value_expr"`
 	ErrWrongAndExpr                 int8 `toml:"er_wrong_and_expr"`
+	ErrViewSelectStar               int8 `toml:"er_view_select_star"`
+	ErrViewSecurityInvoker          int8 `toml:"er_view_security_invoker"`
+	ErrViewAlgorithmTemptable       int8 `toml:"er_view_algorithm_temptable"`
+	ErrRoutineDefiner               int8 `toml:"er_routine_definer"`
+	ErrRoutinePrefix                int8 `toml:"er_routine_prefix"`
+	ErrTriggerOnOscTable            int8 `toml:"er_trigger_on_osc_table"`
+	ErrGrantAllOnGlobal             int8 `toml:"er_grant_all_on_global"`
+	ErrUserHostWildcard             int8 `toml:"er_user_host_wildcard"`
+	ErrPasswordWeak                 int8 `toml:"er_password_weak"`
+	ErrUserRequireSsl               int8 `toml:"er_user_require_ssl"`
+	ErrFeatureRequireVersion        int8 `toml:"er_feature_require_version"`
+	ErrDatabaseNamePattern          int8 `toml:"er_database_name_pattern"`
+	ErrTableNamePattern             int8 `toml:"er_table_name_pattern"`
+	ErrColumnNamePattern            int8 `toml:"er_column_name_pattern"`
+	ErrIndexNamePattern             int8 `toml:"er_index_name_pattern"`
+	ErrUniqIndexNamePattern         int8 `toml:"er_uniq_index_name_pattern"`
+	ErrForeignKeyNamePattern        int8 `toml:"er_foreign_key_name_pattern"`
+	ErrViewNamePattern              int8 `toml:"er_view_name_pattern"`
+	ErrRedundantIndex               int8 `toml:"er_redundant_index"`
+	ErrRowSizeTooLarge              int8 `toml:"er_row_size_too_large"`
+	ErrInnodbRowSizeTooLarge        int8 `toml:"er_innodb_row_size_too_large"`
+	ErrIndexKeyTooLong              int8 `toml:"er_index_key_too_long"`
+	ErrPartitionKeyNotInUniqueKey   int8 `toml:"er_partition_key_not_in_unique_key"`
+	ErrTooManyPartitions            int8 `toml:"er_too_many_partitions"`
+	ErrPartitionRangeNotIncreasing  int8 `toml:"er_partition_range_not_increasing"`
+	ErrPartitionNotExisted          int8 `toml:"er_partition_not_existed"`
+	ErrDupPartitionName             int8 `toml:"er_dup_partition_name"`
+	ErrNotPartitionedTable          int8 `toml:"er_not_partitioned_table"`
+	ErrPartitionMgmtNotSupported    int8 `toml:"er_partition_mgmt_not_supported"`
+	ErrPartitionDataLoss            int8 `toml:"er_partition_data_loss"`
+	ErrPartitionExchange            int8 `toml:"er_partition_exchange"`
+	ErrAlterAlgorithmNotSupported   int8 `toml:"er_alter_algorithm_not_supported"`
 }
 
 var defaultConf = Config{
@@ -662,6 +796,7 @@ var defaultConf = Config{
 		EnableTimeStampType:   true,
 		CheckFloatDouble:      false,
 		CheckIdentifierUpper:  false,
+		CheckRedundantIndex:   false,
 		SqlSafeUpdates:        -1,
 		SupportCharset:        "utf8,utf8mb4",
 		SupportEngine:         "innodb",
@@ -682,12 +817,22 @@ var defaultConf = Config{
 		IndexPrefix:     "idx_",  // 默认不检查,由CheckIndexPrefix控制
 		UniqIndexPrefix: "uniq_", // 默认不检查,由CheckIndexPrefix控制
 		TablePrefix:     "",      // 默认不检查表前缀
+
+		PasswordMinLength: 8,
+
+		DDLLongTrxTime:     60,
+		DDLBlockerWaitTime: 60,
+		DDLLockWaitTimeout: 5,
+		DDLLockRetryCount:  5,
+
+		ThrottleCheckInterval: 1000,
 	},
 	Osc: Osc{
 		OscPrintNone:               false,
 		OscPrintSql:                false,
 		OscOn:                      false,
 		OscMinTableSize:            16,
+		OscPreferNativeDDL:         false,
 		OscAlterForeignKeysMethod:  "none",
 		OscRecursionMethod:         "processlist",
 		OscMaxLag:                  3,
@@ -783,6 +928,38 @@ var defaultConf = Config{
 		ErrJoinNoOnCondition:            1,
 		ErrUseValueExpr:                 1,
 		ErrWrongAndExpr:                 1,
+		ErrViewSelectStar:               1,
+		ErrViewSecurityInvoker:          0,
+		ErrViewAlgorithmTemptable:       1,
+		ErrRoutineDefiner:               1,
+		ErrRoutinePrefix:                1,
+		ErrTriggerOnOscTable:            2,
+		ErrGrantAllOnGlobal:             2,
+		ErrUserHostWildcard:             1,
+		ErrPasswordWeak:                 2,
+		ErrUserRequireSsl:               1,
+		ErrFeatureRequireVersion:        2,
+		ErrDatabaseNamePattern:          1,
+		ErrTableNamePattern:             1,
+		ErrColumnNamePattern:            1,
+		ErrIndexNamePattern:             1,
+		ErrUniqIndexNamePattern:         1,
+		ErrForeignKeyNamePattern:        1,
+		ErrViewNamePattern:              1,
+		ErrRedundantIndex:               1,
+		ErrRowSizeTooLarge:              2,
+		ErrInnodbRowSizeTooLarge:        1,
+		ErrIndexKeyTooLong:              2,
+		ErrPartitionKeyNotInUniqueKey:   2,
+		ErrTooManyPartitions:            2,
+		ErrPartitionRangeNotIncreasing:  2,
+		ErrPartitionNotExisted:          2,
+		ErrDupPartitionName:             2,
+		ErrNotPartitionedTable:          2,
+		ErrPartitionMgmtNotSupported:    2,
+		ErrPartitionDataLoss:            1,
+		ErrPartitionExchange:            2,
+		ErrAlterAlgorithmNotSupported:   1,
 	},
 }
 
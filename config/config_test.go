@@ -70,3 +70,37 @@ commit-timeout="41s"`)
 	// Make sure the example config is the same as default config.
 	c.Assert(conf, DeepEquals, GetGlobalConfig())
 }
+
+func (s *testConfigSuite) TestProfiles(c *C) {
+	conf := new(Config)
+
+	configFile := "config.profiles.toml"
+	_, localFile, _, _ := runtime.Caller(0)
+	configFile = path.Join(path.Dir(localFile), configFile)
+
+	f, err := os.Create(configFile)
+	c.Assert(err, IsNil)
+	_, err = f.WriteString(`[profiles.strict]
+schemas = ["prod_*"]
+[profiles.strict.inc]
+check_table_comment = true
+max_keys = 8
+[profiles.strict.inc_level]
+er_table_must_have_comment = 2
+[profiles.analytics]
+hosts = ["olap-*"]`)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sync(), IsNil)
+
+	c.Assert(conf.Load(configFile), IsNil)
+	c.Assert(f.Close(), IsNil)
+	c.Assert(os.Remove(configFile), IsNil)
+
+	c.Assert(len(conf.Profiles), Equals, 2)
+	strict := conf.Profiles["strict"]
+	c.Assert(strict.Schemas, DeepEquals, []string{"prod_*"})
+	c.Assert(strict.Inc["check_table_comment"], Equals, true)
+	c.Assert(strict.Inc["max_keys"], Equals, int64(8))
+	c.Assert(strict.IncLevel["er_table_must_have_comment"], Equals, int8(2))
+	c.Assert(conf.Profiles["analytics"].Hosts, DeepEquals, []string{"olap-*"})
+}
@@ -56,7 +56,7 @@ type testCaseItem struct {
 func (s *testLexerSuite) TestSingleCharOther(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCaseItem{
-		{"AT", identifier},
+		{"AT", at},
 		{"?", paramMarker},
 		{"PLACEHOLDER", identifier},
 		{"=", eq},
@@ -361,3 +361,55 @@ func (s *testLexerSuite) TestIllegal(c *C) {
 	}
 	runTest(c, table)
 }
+
+func (s *testLexerSuite) TestSplit(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []struct {
+		sql   string
+		texts []string
+	}{
+		{"select 1;select 2", []string{"select 1", "select 2"}},
+		{"select 1;\n\nselect 2;\n", []string{"select 1", "select 2"}},
+		{"select 'a;b';select \"c';d\"", []string{"select 'a;b'", "select \"c';d\""}},
+		{"select `a';b` from t;select 1", []string{"select `a';b` from t", "select 1"}},
+		{"select 1 -- it's;\n;select 2 # don't;\n", []string{"select 1 -- it's;", "select 2 # don't;"}},
+		{"/* it's; */ select 1; /*!40101 set a=1; */;", []string{"/* it's; */ select 1", "/*!40101 set a=1; */"}},
+		{"-- comment\n;;  ", nil},
+		{"select 'unclosed;", []string{"select 'unclosed;"}},
+		{"DELIMITER $$\ncreate procedure p() begin select 1; select 2; end$$\ndelimiter ;\nselect 3;",
+			[]string{"create procedure p() begin select 1; select 2; end", "select 3"}},
+		{"delimiter //\nselect 1//select 2 //", []string{"select 1", "select 2"}},
+		{"delimiter ;;\nselect 1;;select '1;;'", []string{"select 1", "select '1;;'"}},
+		{"select 1 $ 2; select $", []string{"select 1 $ 2", "select $"}},
+		{"create procedure p() begin if a then select case when 1 then 2 end; end if; end; select 1",
+			[]string{"create procedure p() begin if a then select case when 1 then 2 end; end if; end", "select 1"}},
+		{"create trigger tr before insert on t for each row begin\nlbl: loop leave lbl; end loop lbl;\nend;create table t1(`begin` int);",
+			[]string{"create trigger tr before insert on t for each row begin\nlbl: loop leave lbl; end loop lbl;\nend", "create table t1(`begin` int)"}},
+		{"create procedure p(a int) begin case a when 1 then select 1; else select 2; end case; end; select 3;",
+			[]string{"create procedure p(a int) begin case a when 1 then select 1; else select 2; end case; end", "select 3"}},
+		{"create table t(id int); begin; select 1", []string{"create table t(id int)", "begin", "select 1"}},
+	}
+	for _, t := range table {
+		segs, err := Split(t.sql)
+		c.Assert(err, IsNil)
+		var texts []string
+		for _, seg := range segs {
+			c.Assert(t.sql[seg.Start.Offset:seg.End.Offset], Equals, seg.Text)
+			texts = append(texts, seg.Text)
+		}
+		c.Assert(texts, DeepEquals, t.texts, Commentf("%s", t.sql))
+	}
+
+	sql := "use test;\n  insert into t values('中文');\r\nupdate t\n set c1 = 1;"
+	segs, err := Split(sql)
+	c.Assert(err, IsNil)
+	c.Assert(segs, HasLen, 3)
+	c.Assert(segs[1].Start, Equals, Pos{Line: 2, Col: 3, Offset: 12})
+	c.Assert(segs[1].End, Equals, Pos{Line: 2, Col: 29, Offset: 42})
+	c.Assert(segs[2].Start, Equals, Pos{Line: 3, Col: 1, Offset: 45})
+	c.Assert(segs[2].End, Equals, Pos{Line: 4, Col: 12, Offset: 65})
+	c.Assert(segs[2].PosAt(9), Equals, Pos{Line: 4, Col: 1, Offset: 54})
+
+	_, err = Split("select 1;\ndelimiter \nselect 2")
+	c.Assert(err, ErrorMatches, ".*at line 2")
+}
@@ -148,9 +148,11 @@ var tokenMap = map[string]int{
 	"AS":             as,
 	"ASC":            asc,
 	"ASCII":          ascii,
+	"AT":             at,
 	"AUTO_INCREMENT": autoIncrement,
 	"AVG":            avg,
 	"AVG_ROW_LENGTH": avgRowLength,
+	"BEFORE":         before,
 	"BEGIN":          begin,
 	"BETWEEN":        between,
 	"BIGINT":         bigIntType,
@@ -167,6 +169,7 @@ var tokenMap = map[string]int{
 	"BTREE":          btree,
 	"BUCKETS":        buckets,
 	"BY":             by,
+	"CALL":           call,
 	"BYTE":           byteType,
 	"CANCEL":         cancel,
 	"CASCADE":        cascade,
@@ -179,8 +182,10 @@ var tokenMap = map[string]int{
 	"CHARSET":        charsetKwd,
 	"CHECK":          check,
 	"CHECKSUM":       checksum,
+	"CIPHER":         cipher,
 	"CLEANUP":        cleanup,
 	"CLIENT":         client,
+	"CLOSE":          closeKwd,
 	"COALESCE":       coalesce,
 	"COLLATE":        collate,
 	"COLLATION":      collation,
@@ -191,11 +196,14 @@ var tokenMap = map[string]int{
 
 	"COMMITTED":                committed,
 	"COMPACT":                  compact,
+	"COMPLETION":               completion,
 	"COMPRESSED":               compressed,
 	"COMPRESSION":              compression,
 	"CONNECTION":               connection,
 	"CONSISTENT":               consistent,
 	"CONSTRAINT":               constraint,
+	"CONTAINS":                 contains,
+	"CONTINUE":                 continueKwd,
 	"CONVERT":                  convert,
 	"COPY":                     copyKwd,
 	"COUNT":                    count,
@@ -206,6 +214,7 @@ var tokenMap = map[string]int{
 	"CURRENT_TIME":             currentTime,
 	"CURRENT_TIMESTAMP":        currentTs,
 	"CURRENT_USER":             currentUser,
+	"CURSOR":                   cursor,
 	"CURTIME":                  curTime,
 	"DATA":                     data,
 	"DATABASE":                 database,
@@ -223,6 +232,7 @@ var tokenMap = map[string]int{
 	"DEALLOCATE":               deallocate,
 	"DEC":                      decimalType,
 	"DECIMAL":                  decimalType,
+	"DECLARE":                  declare,
 	"DEFAULT":                  defaultKwd,
 	"DEFINER":                  definer,
 	"DELAY_KEY_WRITE":          delayKeyWrite,
@@ -230,6 +240,7 @@ var tokenMap = map[string]int{
 	"DELETE":                   deleteKwd,
 	"DESC":                     desc,
 	"DESCRIBE":                 describe,
+	"DETERMINISTIC":            deterministic,
 	"DIRECTORY":                directory,
 	"DISABLE":                  disable,
 	"DISTINCT":                 distinct,
@@ -241,10 +252,14 @@ var tokenMap = map[string]int{
 	"DUAL":                     dual,
 	"DUPLICATE":                duplicate,
 	"DYNAMIC":                  dynamic,
+	"EACH":                     each,
 	"ELSE":                     elseKwd,
+	"ELSEIF":                   elseIfKwd,
 	"ENABLE":                   enable,
 	"ENCLOSED":                 enclosed,
 	"END":                      end,
+	"ENDS":                     ends,
+	"ENFORCED":                 enforced,
 	"ENGINE":                   engine,
 	"ENGINES":                  engines,
 	"ENUM":                     enum,
@@ -252,27 +267,44 @@ var tokenMap = map[string]int{
 	"ESCAPED":                  escaped,
 	"EVENT":                    event,
 	"EVENTS":                   events,
+	"EVERY":                    every,
+	"EXCHANGE":                 exchange,
 	"EXCLUSIVE":                exclusive,
 	"EXECUTE":                  execute,
 	"EXISTS":                   exists,
+	"EXIT":                     exit,
 	"EXPLAIN":                  explain,
 	"EXTRACT":                  extract,
 	"FALSE":                    falseKwd,
+	"FETCH":                    fetch,
 	"FIELDS":                   fields,
 	"FIRST":                    first,
 	"FIXED":                    fixed,
 	"FLOAT":                    floatType,
 	"FLUSH":                    flush,
+	"FOLLOWING":                following,
+	"FOLLOWS":                  follows,
 	"FOR":                      forKwd,
 	"FORCE":                    force,
 	"FOREIGN":                  foreign,
 	"FORMAT":                   format,
+	"FOUND":                    found,
 	"FROM":                     from,
 	"FULL":                     full,
 	"FULLTEXT":                 fulltext,
 	"FUNCTION":                 function,
 	"GENERATED":                generated,
 	"GEOMETRY":                 geometryType,
+	"HANDLER":                  handler,
+	"INOUT":                    inout,
+	"ISSUER":                   issuer,
+	"ITERATE":                  iterate,
+	"LANGUAGE":                 language,
+	"LEAVE":                    leave,
+	"LOOP":                     loop,
+	"MODIFIES":                 modifies,
+	"OPEN":                     open,
+	"OUT":                      out,
 	"POINT":                    geometryType,
 	"LINESTRING":               geometryType,
 	"POLYGON":                  geometryType,
@@ -385,14 +417,18 @@ var tokenMap = map[string]int{
 	"OR":                       or,
 	"ORDER":                    order,
 	"OUTER":                    outer,
+	"OVER":                     over,
 	"PACK_KEYS":                packKeys,
 	"PARTITION":                partition,
 	"PARTITIONS":               partitions,
 	"PASSWORD":                 password,
 	"PLUGINS":                  plugins,
 	"POSITION":                 position,
+	"PRECEDES":                 precedes,
+	"PRECEDING":                preceding,
 	"PRECISION":                precisionType,
 	"PREPARE":                  prepare,
+	"PRESERVE":                 preserve,
 	"PRIMARY":                  primary,
 	"PRIVILEGES":               privileges,
 	"PROCEDURE":                procedure,
@@ -403,9 +439,15 @@ var tokenMap = map[string]int{
 	"QUERY":                    query,
 	"QUERIES":                  queries,
 	"QUICK":                    quick,
+	"READS":                    reads,
+	"REQUIRE":                  require,
+	"RETURN":                   returnKwd,
+	"RETURNS":                  returns,
+	"SCHEDULE":                 schedule,
 	"SHARD_ROW_ID_BITS":        shardRowIDBits,
 	"RANGE":                    rangeKwd,
 	"RECOVER":                  recover,
+	"RECURSIVE":                recursive,
 	"READ":                     read,
 	"REAL":                     realType,
 	"RECENT":                   recent,
@@ -414,6 +456,7 @@ var tokenMap = map[string]int{
 	"REGEXP":                   regexpKwd,
 	"RELOAD":                   reload,
 	"RENAME":                   rename,
+	"REORGANIZE":               reorganize,
 	"REPEAT":                   repeat,
 	"REPEATABLE":               repeatable,
 	"REPLACE":                  replace,
@@ -426,6 +469,7 @@ var tokenMap = map[string]int{
 	"ROLLBACK":                 rollback,
 	"ROUTINE":                  routine,
 	"ROW":                      row,
+	"ROWS":                     rows,
 	"ROW_COUNT":                rowCount,
 	"ROW_FORMAT":               rowFormat,
 	"RTREE":                    rtree,
@@ -442,6 +486,7 @@ var tokenMap = map[string]int{
 	"SHARE":                    share,
 	"SHARED":                   shared,
 	"SHOW":                     show,
+	"SIGNAL":                   signal,
 	"SIGNED":                   signed,
 	"SLAVE":                    slave,
 	"SLOW":                     slow,
@@ -450,11 +495,16 @@ var tokenMap = map[string]int{
 	"SOME":                     some,
 	"SPATIAL":                  spatial,
 	"SQL":                      sql,
+	"SQLEXCEPTION":             sqlexception,
+	"SQLSTATE":                 sqlstate,
+	"SQLWARNING":               sqlwarning,
 	"SQL_CACHE":                sqlCache,
 	"SQL_CALC_FOUND_ROWS":      sqlCalcFoundRows,
 	"SQL_NO_CACHE":             sqlNoCache,
+	"SSL":                      ssl,
 	"START":                    start,
 	"STARTING":                 starting,
+	"STARTS":                   starts,
 	"STATS":                    stats,
 	"STATS_BUCKETS":            statsBuckets,
 	"STATS_HISTOGRAMS":         statsHistograms,
@@ -465,6 +515,7 @@ var tokenMap = map[string]int{
 	"STORED":                   stored,
 	"STRAIGHT_JOIN":            straightJoin,
 	"SUBDATE":                  subDate,
+	"SUBJECT":                  subject,
 	"SUBPARTITION":             subpartition,
 	"SUBPARTITIONS":            subpartitions,
 	"SUBSTR":                   substring,
@@ -503,6 +554,7 @@ var tokenMap = map[string]int{
 	"TRUE":                     trueKwd,
 	"TRUNCATE":                 truncate,
 	"TYPE":                     tp,
+	"UNBOUNDED":                unbounded,
 	"UNCOMMITTED":              uncommitted,
 	"UNDEFINED":                undefined,
 	"UNION":                    union,
@@ -510,6 +562,7 @@ var tokenMap = map[string]int{
 	"UNKNOWN":                  unknown,
 	"UNLOCK":                   unlock,
 	"UNSIGNED":                 unsigned,
+	"UNTIL":                    until,
 	"UPDATE":                   update,
 	"USAGE":                    usage,
 	"USE":                      use,
@@ -518,6 +571,7 @@ var tokenMap = map[string]int{
 	"UTC_DATE":                 utcDate,
 	"UTC_TIME":                 utcTime,
 	"UTC_TIMESTAMP":            utcTimestamp,
+	"VALIDATION":               validation,
 	"VALUE":                    value,
 	"VALUES":                   values,
 	"VARBINARY":                varbinaryType,
@@ -531,8 +585,12 @@ var tokenMap = map[string]int{
 	"WEEK":                     week,
 	"WHEN":                     when,
 	"WHERE":                    where,
+	"WHILE":                    while,
+	"WINDOW":                   window,
 	"WITH":                     with,
+	"WITHOUT":                  without,
 	"WRITE":                    write,
+	"X509":                     x509,
 	"XOR":                      xor,
 	"YEAR":                     yearType,
 	"YEAR_MONTH":               yearMonth,
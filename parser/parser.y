@@ -73,12 +73,14 @@ import (
 	and			"AND"
 	as			"AS"
 	asc			"ASC"
+	before			"BEFORE"
 	between			"BETWEEN"
 	bigIntType		"BIGINT"
 	binaryType		"BINARY"
 	blobType		"BLOB"
 	both			"BOTH"
 	by			"BY"
+	call			"CALL"
 	cascade			"CASCADE"
 	caseKwd			"CASE"
 	change        		"CHANGE"
@@ -89,12 +91,14 @@ import (
 	column			"COLUMN"
 	constraint		"CONSTRAINT"
 	convert			"CONVERT"
+	continueKwd		"CONTINUE"
 	create			"CREATE"
 	cross 			"CROSS"
 	currentDate 		"CURRENT_DATE"
 	currentTime 		"CURRENT_TIME"
 	currentTs		"CURRENT_TIMESTAMP"
 	currentUser		"CURRENT_USER"
+	cursor			"CURSOR"
 	database		"DATABASE"
 	databases		"DATABASES"
 	dayHour			"DAY_HOUR"
@@ -102,23 +106,29 @@ import (
 	dayMinute		"DAY_MINUTE"
 	daySecond 		"DAY_SECOND"
 	decimalType		"DECIMAL"
+	declare			"DECLARE"
 	defaultKwd		"DEFAULT"
 	delayed			"DELAYED"
 	deleteKwd		"DELETE"
 	desc			"DESC"
 	describe		"DESCRIBE"
+	deterministic		"DETERMINISTIC"
 	distinct		"DISTINCT"
 	distinctRow		"DISTINCTROW"
 	div 			"DIV"
 	doubleType		"DOUBLE"
 	drop			"DROP"
 	dual 			"DUAL"
+	each			"EACH"
 	elseKwd			"ELSE"
+	elseIfKwd		"ELSEIF"
 	enclosed		"ENCLOSED"
 	escaped 		"ESCAPED"
 	exists			"EXISTS"
+	exit			"EXIT"
 	explain			"EXPLAIN"
 	falseKwd		"FALSE"
+	fetch			"FETCH"
 	floatType		"FLOAT"
 	forKwd			"FOR"
 	force			"FORCE"
@@ -140,10 +150,12 @@ import (
 	index			"INDEX"
 	infile			"INFILE"
 	inner 			"INNER"
+	inout			"INOUT"
 	integerType		"INTEGER"
 	interval		"INTERVAL"
 	into			"INTO"
 	is			"IS"
+	iterate			"ITERATE"
 	insert			"INSERT"
 	intType			"INT"
 	int1Type		"INT1"
@@ -156,6 +168,7 @@ import (
 	keys			"KEYS"
 	kill			"KILL"
 	leading			"LEADING"
+	leave			"LEAVE"
 	left			"LEFT"
 	like			"LIKE"
 	limit			"LIMIT"
@@ -167,6 +180,7 @@ import (
 	lock			"LOCK"
 	longblobType		"LONGBLOB"
 	longtextType		"LONGTEXT"
+	loop			"LOOP"
 	lowPriority		"LOW_PRIORITY"
 	maxValue		"MAXVALUE"
 	mediumblobType		"MEDIUMBLOB"
@@ -175,6 +189,7 @@ import (
 	minuteMicrosecond	"MINUTE_MICROSECOND"
 	minuteSecond 		"MINUTE_SECOND"
 	mod 			"MOD"
+	modifies		"MODIFIES"
 	not			"NOT"
 	noWriteToBinLog 	"NO_WRITE_TO_BINLOG"
 	null			"NULL"
@@ -184,7 +199,9 @@ import (
 	option			"OPTION"
 	or			"OR"
 	order			"ORDER"
+	out			"OUT"
 	outer			"OUTER"
+	over			"OVER"
 	packKeys		"PACK_KEYS"
 	partition		"PARTITION"
 	precisionType		"PRECISION"
@@ -193,25 +210,33 @@ import (
 	shardRowIDBits		"SHARD_ROW_ID_BITS"
 	rangeKwd		"RANGE"
 	read			"READ"
+	reads			"READS"
 	realType		"REAL"
+	recursive		"RECURSIVE"
 	references		"REFERENCES"
 	regexpKwd		"REGEXP"
 	rename         		"RENAME"
 	repeat			"REPEAT"
 	replace			"REPLACE"
 	restrict		"RESTRICT"
+	returnKwd		"RETURN"
 	revoke			"REVOKE"
 	right			"RIGHT"
 	rlike			"RLIKE"
+	rows			"ROWS"
 	secondMicrosecond	"SECOND_MICROSECOND"
 	selectKwd		"SELECT"
 	set			"SET"
 	show			"SHOW"
 	get				"GET"
+	signal			"SIGNAL"
 	smallIntType		"SMALLINT"
 	spatial			"SPATIAL"
 	sql			"SQL"
 	sqlCalcFoundRows	"SQL_CALC_FOUND_ROWS"
+	sqlexception		"SQLEXCEPTION"
+	sqlstate		"SQLSTATE"
+	sqlwarning		"SQLWARNING"
 	starting		"STARTING"
 	straightJoin		"STRAIGHT_JOIN"
 	tableKwd		"TABLE"
@@ -229,6 +254,7 @@ import (
 	union			"UNION"
 	unlock			"UNLOCK"
 	unsigned		"UNSIGNED"
+	until			"UNTIL"
 	update			"UPDATE"
 	usage			"USAGE"
 	use			"USE"
@@ -243,6 +269,8 @@ import (
 	virtual			"VIRTUAL"
 	when			"WHEN"
 	where			"WHERE"
+	while			"WHILE"
+	window			"WINDOW"
 	write			"WRITE"
 	with			"WITH"
 	xor 			"XOR"
@@ -257,6 +285,7 @@ import (
 	algorithm	"ALGORITHM"
 	any 		"ANY"
 	ascii		"ASCII"
+	at		"AT"
 	autoIncrement	"AUTO_INCREMENT"
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
@@ -270,8 +299,10 @@ import (
 	cascaded	"CASCADED"
 	charsetKwd	"CHARSET"
 	checksum	"CHECKSUM"
+	cipher		"CIPHER"
 	cleanup		"CLEANUP"
 	client		"CLIENT"
+	closeKwd	"CLOSE"
 	coalesce	"COALESCE"
 	collation	"COLLATION"
 	columns		"COLUMNS"
@@ -290,10 +321,12 @@ import (
 
 	committed	"COMMITTED"
 	compact		"COMPACT"
+	completion	"COMPLETION"
 	compressed	"COMPRESSED"
 	compression	"COMPRESSION"
 	connection 	"CONNECTION"
 	consistent	"CONSISTENT"
+	contains	"CONTAINS"
 	current		"CURRENT"
 	day		"DAY"
 	data 		"DATA"
@@ -309,22 +342,30 @@ import (
 	dynamic		"DYNAMIC"
 	enable		"ENABLE"
 	end		"END"
+	ends		"ENDS"
+	enforced	"ENFORCED"
 	engine		"ENGINE"
 	engines		"ENGINES"
 	enum 		"ENUM"
 	event		"EVENT"
 	events		"EVENTS"
+	every		"EVERY"
 	escape 		"ESCAPE"
+	exchange	"EXCHANGE"
 	exclusive       "EXCLUSIVE"
 	execute		"EXECUTE"
 	fields		"FIELDS"
 	first		"FIRST"
 	fixed		"FIXED"
 	flush		"FLUSH"
+	following	"FOLLOWING"
+	follows		"FOLLOWS"
 	format		"FORMAT"
+	found		"FOUND"
 	full		"FULL"
 	function	"FUNCTION"
 	grants		"GRANTS"
+	handler		"HANDLER"
 	hash		"HASH"
 	history		"HISTORY"
 	hour		"HOUR"
@@ -333,8 +374,10 @@ import (
 	indexes		"INDEXES"
 	invisible	"INVISIBLE"
 	invoker		"INVOKER"
+	issuer		"ISSUER"
 	jsonType	"JSON"
 	keyBlockSize	"KEY_BLOCK_SIZE"
+	language	"LANGUAGE"
 	local		"LOCAL"
 	less		"LESS"
 	level		"LEVEL"
@@ -359,11 +402,15 @@ import (
 	none		"NONE"
 	offset		"OFFSET"
 	only		"ONLY"
+	open		"OPEN"
 	password	"PASSWORD"
 	partitions	"PARTITIONS"
 	pipesAsOr
 	plugins		"PLUGINS"
+	precedes	"PRECEDES"
+	preceding	"PRECEDING"
 	prepare		"PREPARE"
+	preserve	"PRESERVE"
 	privileges	"PRIVILEGES"
 	process		"PROCESS"
 	processlist	"PROCESSLIST"
@@ -375,8 +422,11 @@ import (
 	recover 	"RECOVER"
 	redundant	"REDUNDANT"
 	reload		"RELOAD"
+	reorganize	"REORGANIZE"
 	repeatable	"REPEATABLE"
 	replication	"REPLICATION"
+	require		"REQUIRE"
+	returns		"RETURNS"
 	reverse		"REVERSE"
 	rollback	"ROLLBACK"
 	routine		"ROUTINE"
@@ -384,6 +434,7 @@ import (
 	rowCount	"ROW_COUNT"
 	rowFormat	"ROW_FORMAT"
 	rtree		"RTREE"
+	schedule	"SCHEDULE"
 	second		"SECOND"
 	security	"SECURITY"
 	separator 	"SEPARATOR"
@@ -397,10 +448,13 @@ import (
 	snapshot	"SNAPSHOT"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
+	ssl		"SSL"
 	start		"START"
+	starts		"STARTS"
 	statsPersistent	"STATS_PERSISTENT"
 	status		"STATUS"
 	systemTime	"SYSTEM_TIME"
+	subject		"SUBJECT"
 	subpartition	"SUBPARTITION"
 	subpartitions	"SUBPARTITIONS"
 	super		"SUPER"
@@ -419,10 +473,12 @@ import (
 	triggers	"TRIGGERS"
 	truncate	"TRUNCATE"
 	tp             	"TYPE"
+	unbounded	"UNBOUNDED"
 	uncommitted	"UNCOMMITTED"
 	unknown 	"UNKNOWN"
 	user		"USER"
 	undefined	"UNDEFINED"
+	validation	"VALIDATION"
 	value		"VALUE"
 	variables	"VARIABLES"
 	levels 		"LEVELS"
@@ -431,6 +487,8 @@ import (
 	warnings	"WARNINGS"
 	identSQLErrors	"ERRORS"
 	week		"WEEK"
+	without		"WITHOUT"
+	x509		"X509"
 	yearType	"YEAR"
 
 	/* The following tokens belong to NotKeywordToken. */
@@ -545,6 +603,7 @@ import (
 	FunctionCallGeneric		"Function call with Identifier"
 	FunctionCallKeyword		"Function call with keyword as function name"
 	FunctionCallNonKeyword		"Function call with nonkeyword as function name"
+	WindowFuncCall			"Window function call"
 	Literal				"literal value"
 	Variable			"User or system variable"
 	SystemVariable			"System defined variable name"
@@ -571,6 +630,9 @@ import (
 
 	CreateTableStmt			"CREATE TABLE statement"
 	CreateViewStmt			"CREATE VIEW  stetement"
+	CreateRoutineStmt		"CREATE PROCEDURE/FUNCTION statement"
+	CreateTriggerStmt		"CREATE TRIGGER statement"
+	CreateEventStmt			"CREATE EVENT statement"
 	CreateUserStmt			"CREATE User statement"
 	CreateDatabaseStmt		"Create Database Statement"
 	CreateIndexStmt			"CREATE INDEX statement"
@@ -581,8 +643,12 @@ import (
 	DropTableStmt			"DROP TABLE statement"
 	DropUserStmt			"DROP USER"
 	DropViewStmt			"DROP VIEW statement"
+	DropRoutineStmt			"DROP PROCEDURE/FUNCTION/TRIGGER/EVENT statement"
+	ProcedureStatement		"statement in stored programs"
+	ProcedureLabeledStmt		"compound statement which may have a label"
 	DeallocateStmt			"Deallocate prepared statement"
 	DeleteFromStmt			"DELETE FROM statement"
+	DeleteFromStmtNoWith		"DELETE FROM statement without CTEs"
 	EmptyStmt			"empty statement"
 	ExecuteStmt			"Execute statement"
 	ExplainStmt			"EXPLAIN statement"
@@ -596,6 +662,7 @@ import (
 	LockTablesStmt			"Lock tables statement"
 	PreparedStmt			"PreparedStmt"
 	SelectStmt			"SELECT statement"
+	SelectStmtWithClause		"SELECT statement with CTEs"
 	RenameTableStmt         	"rename table statement"
 	ReplaceIntoStmt			"REPLACE INTO statement"
 	RevokeStmt			"Revoke statement"
@@ -608,6 +675,7 @@ import (
 	TruncateTableStmt		"TRUNCATE TABLE statement"
 	UnlockTablesStmt		"Unlock tables statement"
 	UpdateStmt			"UPDATE statement"
+	UpdateStmtNoWith		"UPDATE statement without CTEs"
 	UnionStmt			"Union select state ment"
 	UseStmt				"USE statement"
 
@@ -615,6 +683,35 @@ import (
 
 %type   <item>
 	AdminShowSlow			"Admin Show Slow statement"
+	EventCommentOpt			"event comment"
+	EventCompletionOpt		"event ON COMPLETION clause"
+	EventEndsOpt			"event ENDS clause"
+	EventSchedule			"event schedule"
+	EventStartsOpt			"event STARTS clause"
+	EventStatusOpt			"event status"
+	HandlerCondition		"handler condition"
+	HandlerConditionList		"handler condition list"
+	ProcedureCallArgsOpt		"optional CALL argument list"
+	ProcedureCaseElseOpt		"ELSE clause of CASE statement"
+	ProcedureCaseWhen		"WHEN clause of CASE statement"
+	ProcedureCaseWhenList		"WHEN clause list of CASE statement"
+	ProcedureElseOpt		"ELSE or ELSEIF clause"
+	ProcedureLabelOpt		"optional end label"
+	ProcedureStmtList		"statement list in stored programs"
+	ProcedureVarList		"local variable list"
+	RoutineOption			"stored routine characteristic"
+	RoutineOptionList		"stored routine characteristic list"
+	RoutineParam			"stored routine parameter"
+	RoutineParamList		"stored routine parameter list"
+	RoutineParamListOpt		"optional stored routine parameter list"
+	RoutineParamMode		"stored procedure parameter mode"
+	SignalCondition			"SIGNAL condition value"
+	SignalInfo			"SIGNAL condition information item"
+	SignalInfoList			"SIGNAL condition information item list"
+	SignalInfoListOpt		"optional SIGNAL condition information items"
+	TriggerEvent			"trigger event"
+	TriggerOrderOpt			"trigger order"
+	TriggerTime			"trigger action time"
 	AlgorithmClause			"Alter table algorithm"
 	AlterTableOptionListOpt		"alter table option list opt"
 	AlterTableSpec			"Alter table specification"
@@ -639,6 +736,7 @@ import (
 	ColumnSetValue			"insert statement set value by column name"
 	ColumnSetValueList		"insert statement set value by column name list"
 	CompareOp			"Compare opcode"
+	CommonTableExpr			"Common table expression"
 	ColumnOption			"column definition option"
 	ColumnOptionList		"column definition option list"
 	VirtualOrStored			"indicate generated column is stored or not"
@@ -657,6 +755,9 @@ import (
 	DefaultTrueDistinctOpt		"Distinct option which defaults to true"
 	BuggyDefaultFalseDistinctOpt	"Distinct option which accepts DISTINCT ALL and defaults to false"
 	Enclosed			"Enclosed by"
+	EnforcedOrNot			"{ENFORCED|NOT ENFORCED}"
+	EnforcedOrNotOpt		"Optional {ENFORCED|NOT ENFORCED}"
+	EnforcedOrNotOrNotNullOpt	"{[ENFORCED|NOT ENFORCED|NOT NULL]}"
 	EqOpt				"= or empty"
 	EscapedTableRef 		"escaped table reference"
 	Escaped				"Escaped by"
@@ -734,6 +835,7 @@ import (
 	PartitionMethod			"Partition method"
 	PartitionOpt			"Partition option"
 	PartitionNameList		"Partition name list"
+	WithValidationOpt		"optional WITH VALIDATION or WITHOUT VALIDATION"
 	PartitionNameListOpt    "table partition names list optional"
 	PartitionNumOpt			"PARTITION NUM option"
 	PartDefValuesOpt		"VALUES {LESS THAN {(expr | value_list) | MAXVALUE} | IN {value_list}"
@@ -752,6 +854,9 @@ import (
 	OnUpdateOpt			"optional ON UPDATE clause"
 	OptGConcatSeparator		"optional GROUP_CONCAT SEPARATOR"
 	ReferOpt			"reference option"
+	RequireClauseOpt		"optional REQUIRE clause"
+	RequireList			"REQUIRE tls option list"
+	RequireListElement		"REQUIRE tls option"
 	RowFormat			"Row format option"
 	RowValue			"Row value"
 	SelectLockOpt			"FOR UPDATE or LOCK IN SHARE MODE,"
@@ -825,6 +930,23 @@ import (
 	WhereClauseOptional	"Optional WHERE clause"
 	WhenClause		"When clause"
 	WhenClauseList		"When clause list"
+	WindowClauseOptional	"Window clause optional"
+	WindowDefinition	"Window definition"
+	WindowDefinitionList	"Window definition list"
+	WindowFrameBetween	"Window frame between"
+	WindowFrameBound	"Window frame bound"
+	WindowFrameClauseOpt	"Window frame clause optional"
+	WindowFrameExtent	"Window frame extent"
+	WindowFrameStart	"Window frame start"
+	WindowFrameUnits	"Window frame units"
+	WindowNameOrSpec	"Window name or spec"
+	WindowOrderByClauseOpt	"Window order by clause optional"
+	WindowPartitionClauseOpt	"Window partition clause optional"
+	WindowSpec		"Window specification"
+	WindowSpecDetails	"Window specification details"
+	WindowingClause		"Windowing clause"
+	WithClause		"With clause"
+	WithList		"With list"
 	WithReadLockOpt		"With Read Lock opt"
 	WithGrantOptionOpt	"With Grant Option opt"
 	ElseOpt			"Optional else clause"
@@ -901,6 +1023,8 @@ import (
 	LinearOpt		"linear or empty"
 	FieldsOrColumns 	"Fields or columns"
 	GetFormatSelector	"{DATE|DATETIME|TIME|TIMESTAMP}"
+	WindowExistingNameOpt	"Existing window name optional"
+	WindowName		"Window name"
 
 %type	<ident>
 	ODBCDateTimeType		"ODBC type keywords for date and time literals"
@@ -949,6 +1073,7 @@ import (
 %left 	'*' '/' '%' div mod
 %left 	'^'
 %left 	'~' neg
+%precedence lowerThanNot
 %right 	not not2
 %right	collate
 
@@ -1004,7 +1129,8 @@ AlterTableSpec:
 	{
 		op := &ast.AlterTableSpec{
 			Tp: ast.AlterTableOption,
-			Options:[]*ast.TableOption{{Tp: ast.TableOptionCharset, StrValue: $4.(string)}},
+			Options:[]*ast.TableOption{{Tp: ast.TableOptionCharset, StrValue: $4.(string),
+				UintValue: ast.TableOptionCharsetWithConvertTo}},
 		}
 		if $5 != "" {
 			op.Options = append(op.Options, &ast.TableOption{Tp: ast.TableOptionCollate, StrValue: $5.(string)})
@@ -1056,11 +1182,42 @@ AlterTableSpec:
 	{
 		$$ = &ast.AlterTableSpec{Tp: ast.AlterTableDropPrimaryKey}
 	}
-|	"DROP" "PARTITION" Identifier
+|	"COALESCE" "PARTITION" NUM
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableCoalescePartitions,
+			Num: getUint64FromNUM($3),
+		}
+	}
+|	"DROP" "PARTITION" PartitionNameList %prec lowerThanComma
 	{
 		$$ = &ast.AlterTableSpec{
 			Tp: ast.AlterTableDropPartition,
+			PartitionNames: $3.([]model.CIStr),
+		}
+	}
+|	"TRUNCATE" "PARTITION" PartitionNameList %prec lowerThanComma
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableTruncatePartition,
+			PartitionNames: $3.([]model.CIStr),
+		}
+	}
+|	"REORGANIZE" "PARTITION" PartitionNameList "INTO" '(' PartitionDefinitionList ')'
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableReorganizePartition,
+			PartitionNames: $3.([]model.CIStr),
+			PartDefinitions: $6.([]*ast.PartitionDefinition),
+		}
+	}
+|	"EXCHANGE" "PARTITION" Identifier "WITH" "TABLE" TableName WithValidationOpt
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableExchangePartition,
 			Name: $3,
+			NewTable: $6.(*ast.TableName),
+			WithValidation: $7.(bool),
 		}
 	}
 |	"DROP" KeyOrIndex Identifier
@@ -1077,6 +1234,32 @@ AlterTableSpec:
 			Name: $4.(string),
 		}
 	}
+|	"DROP" "CHECK" Identifier
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableDropCheck,
+			Name: $3,
+		}
+	}
+|	"ALTER" "CHECK" Identifier EnforcedOrNot
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp: ast.AlterTableAlterCheck,
+			Name: $3,
+			Constraint: &ast.Constraint{
+				Tp:		ast.ConstraintCheck,
+				Enforced:	$4.(bool),
+			},
+		}
+	}
+|	"ALTER" KeyOrIndex Identifier IndexInvisible
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:		ast.AlterTableIndexInvisible,
+			Name:		$3,
+			Visibility:	$4.(ast.IndexVisibility),
+		}
+	}
 |	"DISABLE" "KEYS"
 	{
 		$$ = &ast.AlterTableSpec{}
@@ -1114,6 +1297,21 @@ AlterTableSpec:
 			NewColumns:	[]*ast.ColumnDef{colDef},
 		}
 	}
+|	"ALTER" ColumnKeywordOpt ColumnName "SET" IndexInvisible
+	{
+		option := &ast.ColumnOption{Tp: ast.ColumnOptionVisible}
+		if $5.(ast.IndexVisibility) == ast.IndexVisibilityInvisible {
+			option.Tp = ast.ColumnOptionInvisible
+		}
+		colDef := &ast.ColumnDef{
+			Name: 	 $3.(*ast.ColumnName),
+			Options: []*ast.ColumnOption{option},
+		}
+		$$ = &ast.AlterTableSpec{
+			Tp:		ast.AlterTableAlterColumn,
+			NewColumns:	[]*ast.ColumnDef{colDef},
+		}
+	}
 |	"ALTER" ColumnKeywordOpt ColumnName "DROP" "DEFAULT"
 	{
 		colDef := &ast.ColumnDef{
@@ -1145,6 +1343,14 @@ AlterTableSpec:
 			NewTable:      $3.(*ast.TableName),
 		}
 	}
+|	"RENAME" "COLUMN" ColumnName "TO" ColumnName
+	{
+		$$ = &ast.AlterTableSpec{
+			Tp:		ast.AlterTableRenameColumn,
+			OldColumnName:	$3.(*ast.ColumnName),
+			NewColumnName:	$5.(*ast.ColumnName),
+		}
+	}
 |	"RENAME" KeyOrIndex Identifier "TO" Identifier
 	{
 		$$ = &ast.AlterTableSpec{
@@ -1160,11 +1366,11 @@ AlterTableSpec:
 			LockType:   $1.(ast.LockType),
 		}
 	}
-| "ALGORITHM" EqOpt AlterAlgorithm
+|	AlgorithmClause
 	{
-		// Parse it and ignore it. Just for compatibility.
 		$$ = &ast.AlterTableSpec{
 			Tp:    		ast.AlterTableAlgorithm,
+			Algorithm:	$1.(ast.AlgorithmType),
 		}
 	}
 | "FORCE"
@@ -1176,10 +1382,6 @@ AlterTableSpec:
 	}
 
 
-AlterAlgorithm:
-	"DEFAULT" | "INPLACE" | "COPY"
-
-
 AlgorithmClause:
 	"ALGORITHM" EqOpt "DEFAULT"
 	{
@@ -1261,6 +1463,19 @@ AlterTableSpecList:
 		$$ = append($1.([]*ast.AlterTableSpec), $3.(*ast.AlterTableSpec))
 	}
 
+WithValidationOpt:
+	{
+		$$ = true
+	}
+|	"WITH" "VALIDATION"
+	{
+		$$ = true
+	}
+|	"WITHOUT" "VALIDATION"
+	{
+		$$ = false
+	}
+
 PartitionNameList:
 	Identifier
 	{
@@ -1736,18 +1951,41 @@ ColumnOption:
 	}
 |	"ON" "UPDATE" NowSymOptionFraction
 	{
-		nowFunc := &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
-		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionOnUpdate, Expr: nowFunc}
+		$$ = &ast.ColumnOption{Tp: ast.ColumnOptionOnUpdate, Expr: $3}
 	}
 |	"COMMENT" stringLit
 	{
 		$$ =  &ast.ColumnOption{Tp: ast.ColumnOptionComment, Expr: ast.NewValueExpr($2)}
 	}
-|	"CHECK" '(' Expression ')'
+|	ConstraintKeywordOpt "CHECK" '(' Expression ')' EnforcedOrNotOrNotNullOpt
 	{
-		// See https://dev.mysql.com/doc/refman/5.7/en/create-table.html
-		// The CHECK clause is parsed but ignored by all storage engines.
-		$$ = &ast.ColumnOption{}
+		// See https://dev.mysql.com/doc/refman/8.0/en/create-table-check-constraints.html
+		// The CHECK clause is parsed but ignored by all storage engines before MySQL 8.0.16.
+		optionCheck := &ast.ColumnOption{
+			Tp:		ast.ColumnOptionCheck,
+			Expr:		$4,
+			Enforced:	true,
+		}
+		if $1 != nil {
+			optionCheck.ConstraintName = $1.(string)
+		}
+		switch $6.(int) {
+		case 0:
+			$$ = []*ast.ColumnOption{optionCheck, {Tp: ast.ColumnOptionNotNull}}
+		case 1:
+			$$ = optionCheck
+		case 2:
+			optionCheck.Enforced = false
+			$$ = optionCheck
+		}
+	}
+|	IndexInvisible
+	{
+		if $1.(ast.IndexVisibility) == ast.IndexVisibilityInvisible {
+			$$ = &ast.ColumnOption{Tp: ast.ColumnOptionInvisible}
+		} else {
+			$$ = &ast.ColumnOption{Tp: ast.ColumnOptionVisible}
+		}
 	}
 |	GeneratedAlways "AS" '(' Expression ')' VirtualOrStored
 	{
@@ -1792,11 +2030,56 @@ VirtualOrStored:
 ColumnOptionList:
 	ColumnOption
 	{
-		$$ = []*ast.ColumnOption{$1.(*ast.ColumnOption)}
+		if columnOption, ok := $1.(*ast.ColumnOption); ok {
+			$$ = []*ast.ColumnOption{columnOption}
+		} else {
+			$$ = $1
+		}
 	}
 |	ColumnOptionList ColumnOption
 	{
-		$$ = append($1.([]*ast.ColumnOption), $2.(*ast.ColumnOption))
+		if columnOption, ok := $2.(*ast.ColumnOption); ok {
+			$$ = append($1.([]*ast.ColumnOption), columnOption)
+		} else {
+			$$ = append($1.([]*ast.ColumnOption), $2.([]*ast.ColumnOption)...)
+		}
+	}
+
+EnforcedOrNot:
+	"ENFORCED"
+	{
+		$$ = true
+	}
+|	"NOT" "ENFORCED"
+	{
+		$$ = false
+	}
+
+EnforcedOrNotOpt:
+	%prec lowerThanNot
+	{
+		$$ = true
+	}
+|	EnforcedOrNot
+	{
+		$$ = $1
+	}
+
+EnforcedOrNotOrNotNullOpt:
+	// This branch is needed to workaround the need of a lookahead of 2 for the grammar:
+	//
+	//  { [NOT] NULL | CHECK(...) [NOT] ENFORCED } ...
+	"NOT" "NULL"
+	{
+		$$ = 0
+	}
+|	EnforcedOrNotOpt
+	{
+		if $1.(bool) {
+			$$ = 1
+		} else {
+			$$ = 2
+		}
 	}
 
 ColumnOptionListOpt:
@@ -1897,6 +2180,14 @@ ConstraintElem:
 			Refer:		$8.(*ast.ReferenceDef),
 		}
 	}
+|	"CHECK" '(' Expression ')' EnforcedOrNotOpt
+	{
+		$$ = &ast.Constraint{
+			Tp:		ast.ConstraintCheck,
+			Expr:		$3.(ast.ExprNode),
+			Enforced:	$5.(bool),
+		}
+	}
 
 ReferDef:
 	"REFERENCES" TableName '(' IndexColNameList ')' OnDeleteOpt OnUpdateOpt
@@ -1978,7 +2269,8 @@ NowSymOptionFraction:
 	}
 |	NowSymFunc '(' NUM ')'
 	{
-		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP")}
+		// Keep the fsp, e.g. CURRENT_TIMESTAMP(3).
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr("CURRENT_TIMESTAMP"), Args: []ast.ExprNode{ast.NewValueExpr($3)}}
 	}
 
 /*
@@ -2081,6 +2373,10 @@ IndexColName:
 		//Order is parsed but just ignored as MySQL did
 		$$ = &ast.IndexColName{Column: $1.(*ast.ColumnName), Length: $2.(int)}
 	}
+|	'(' Expression ')' Order
+	{
+		$$ = &ast.IndexColName{Expr: $2}
+	}
 
 IndexColNameList:
 	IndexColName
@@ -2603,102 +2899,737 @@ CreateViewStmt:
  			OrReplace:     $2.(bool),
 			ViewName:      $7.(*ast.TableName),
 			Select:        selStmt,
+			Algorithm:     $3.(model.ViewAlgorithm),
+			Definer:       $4.(*auth.UserIdentity),
+			Security:      $5.(model.ViewSecurity),
+			CheckOption:   model.CheckOptionCascaded,
 		}
 		if $8 != nil{
 			x.Cols = $8.([]model.CIStr)
 		}
+		if $11 != nil {
+			x.CheckOption = $11.(model.ViewCheckOption)
+			endOffset := parser.startOffset(&yyS[yypt])
+			selStmt.SetText(strings.TrimSpace(parser.src[startOffset:endOffset]))
+		}
 		$$ = x
 	}
 
 OrReplace:
 	{
-		$$ = false
+		$$ = false
+	}
+|	"OR" "REPLACE"
+	{
+		$$ = true
+	}
+
+ViewAlgorithm:
+	/* EMPTY */
+	{
+		$$ = model.AlgorithmUndefined
+	}
+|	"ALGORITHM" "=" "UNDEFINED"
+	{
+		$$ = model.AlgorithmUndefined
+	}
+|	"ALGORITHM" "=" "MERGE"
+	{
+		$$ = model.AlgorithmMerge
+	}
+|	"ALGORITHM" "=" "TEMPTABLE"
+	{
+		$$ = model.AlgorithmTemptable
+	}
+
+ViewDefiner:
+	/* EMPTY */
+	{
+		$$ = &auth.UserIdentity{CurrentUser: true}
+	}
+|   "DEFINER" "=" Username
+	{
+		$$ = $3
+	}
+
+ViewSQLSecurity:
+	/* EMPTY */
+	{
+		$$ = model.SecurityDefiner
+	}
+|   "SQL" "SECURITY" "DEFINER"
+	 {
+		 $$ = model.SecurityDefiner
+	 }
+|   "SQL" "SECURITY" "INVOKER"
+	 {
+		 $$ = model.SecurityInvoker
+	 }
+
+ViewName:
+	TableName
+	{
+		$$ = $1.(*ast.TableName)
+	}
+
+ViewFieldList:
+	/* Empty */
+	{
+		$$ = nil
+	}
+|   '(' ColumnList ')'
+	{
+		$$ = $2.([]model.CIStr)
+	}
+
+ColumnList:
+	Identifier
+	{
+		$$ = []model.CIStr{model.NewCIStr($1)}
+	}
+|   ColumnList ',' Identifier
+	{
+	$$ = append($1.([]model.CIStr), model.NewCIStr($3))
+	}
+
+ViewCheckOption:
+	/* EMPTY */
+	{
+		$$ = nil
+	}
+|   "WITH" "CASCADED" "CHECK" "OPTION"
+	{
+		$$ = model.CheckOptionCascaded
+	}
+|   "WITH" "LOCAL" "CHECK" "OPTION"
+	{
+		$$ = model.CheckOptionLocal
+	}
+
+/*******************************************************************
+ *
+ *  Create Procedure/Function Statement
+ *
+ *  Example:
+ *      CREATE DEFINER = CURRENT_USER PROCEDURE p1(IN a INT, OUT b INT)
+ *          COMMENT 'p1' BEGIN SET b = a + 1; END
+ *      CREATE FUNCTION f1(a INT) RETURNS INT DETERMINISTIC RETURN a + 1
+ *
+ *  The OR REPLACE and ALGORITHM clauses are shared with CREATE VIEW
+ *  to avoid conflicts, and are not allowed here.
+ *******************************************************************/
+CreateRoutineStmt:
+	"CREATE" OrReplace ViewAlgorithm ViewDefiner "PROCEDURE" IfNotExists TableName '(' RoutineParamListOpt ')' RoutineOptionList ProcedureStatement
+	{
+		if $2.(bool) || $3.(model.ViewAlgorithm) != model.AlgorithmUndefined {
+			yylex.AppendError(yylex.Errorf(""))
+			return 1
+		}
+		$$ = &ast.CreateRoutineStmt{
+			Tp:          ast.RoutineProcedure,
+			Definer:     $4.(*auth.UserIdentity),
+			IfNotExists: $6.(bool),
+			Name:        $7.(*ast.TableName),
+			Params:      $9.([]*ast.RoutineParam),
+			Options:     $11.([]*ast.RoutineOption),
+			Body:        $12,
+		}
+	}
+|	"CREATE" OrReplace ViewAlgorithm ViewDefiner "FUNCTION" IfNotExists TableName '(' RoutineParamListOpt ')' "RETURNS" Type RoutineOptionList ProcedureStatement
+	{
+		if $2.(bool) || $3.(model.ViewAlgorithm) != model.AlgorithmUndefined {
+			yylex.AppendError(yylex.Errorf(""))
+			return 1
+		}
+		for _, param := range $9.([]*ast.RoutineParam) {
+			if param.Mode != ast.ParamModeNone {
+				yylex.AppendError(yylex.Errorf(""))
+				return 1
+			}
+		}
+		$$ = &ast.CreateRoutineStmt{
+			Tp:          ast.RoutineFunction,
+			Definer:     $4.(*auth.UserIdentity),
+			IfNotExists: $6.(bool),
+			Name:        $7.(*ast.TableName),
+			Params:      $9.([]*ast.RoutineParam),
+			Returns:     $12.(*types.FieldType),
+			Options:     $13.([]*ast.RoutineOption),
+			Body:        $14,
+		}
+	}
+
+RoutineParamListOpt:
+	{
+		$$ = []*ast.RoutineParam{}
+	}
+|	RoutineParamList
+	{
+		$$ = $1
+	}
+
+RoutineParamList:
+	RoutineParam
+	{
+		$$ = []*ast.RoutineParam{$1.(*ast.RoutineParam)}
+	}
+|	RoutineParamList ',' RoutineParam
+	{
+		$$ = append($1.([]*ast.RoutineParam), $3.(*ast.RoutineParam))
+	}
+
+RoutineParam:
+	RoutineParamMode Identifier Type
+	{
+		$$ = &ast.RoutineParam{Mode: $1.(ast.ParamMode), Name: $2, Tp: $3.(*types.FieldType)}
+	}
+
+RoutineParamMode:
+	{
+		$$ = ast.ParamModeNone
+	}
+|	"IN"
+	{
+		$$ = ast.ParamModeIn
+	}
+|	"OUT"
+	{
+		$$ = ast.ParamModeOut
+	}
+|	"INOUT"
+	{
+		$$ = ast.ParamModeInOut
+	}
+
+RoutineOptionList:
+	{
+		$$ = []*ast.RoutineOption{}
+	}
+|	RoutineOptionList RoutineOption
+	{
+		$$ = append($1.([]*ast.RoutineOption), $2.(*ast.RoutineOption))
+	}
+
+RoutineOption:
+	"COMMENT" stringLit
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionComment, StrValue: $2}
+	}
+|	"LANGUAGE" "SQL"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionLanguage}
+	}
+|	"DETERMINISTIC"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDeterministic, BoolValue: true}
+	}
+|	"NOT" "DETERMINISTIC"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDeterministic}
+	}
+|	"CONTAINS" "SQL"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDataAccess, StrValue: "CONTAINS SQL"}
+	}
+|	"NO" "SQL"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDataAccess, StrValue: "NO SQL"}
+	}
+|	"READS" "SQL" "DATA"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDataAccess, StrValue: "READS SQL DATA"}
+	}
+|	"MODIFIES" "SQL" "DATA"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionDataAccess, StrValue: "MODIFIES SQL DATA"}
+	}
+|	"SQL" "SECURITY" "DEFINER"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionSecurity, Security: model.SecurityDefiner}
+	}
+|	"SQL" "SECURITY" "INVOKER"
+	{
+		$$ = &ast.RoutineOption{Tp: ast.RoutineOptionSecurity, Security: model.SecurityInvoker}
+	}
+
+/*******************************************************************
+ *
+ *  Create Trigger Statement
+ *
+ *  Example:
+ *      CREATE TRIGGER tr1 BEFORE INSERT ON t1 FOR EACH ROW
+ *          SET NEW.c1 = UPPER(NEW.c1)
+ *******************************************************************/
+CreateTriggerStmt:
+	"CREATE" OrReplace ViewAlgorithm ViewDefiner "TRIGGER" IfNotExists TableName TriggerTime TriggerEvent "ON" TableName "FOR" "EACH" "ROW" TriggerOrderOpt ProcedureStatement
+	{
+		if $2.(bool) || $3.(model.ViewAlgorithm) != model.AlgorithmUndefined {
+			yylex.AppendError(yylex.Errorf(""))
+			return 1
+		}
+		x := &ast.CreateTriggerStmt{
+			Definer:     $4.(*auth.UserIdentity),
+			IfNotExists: $6.(bool),
+			Name:        $7.(*ast.TableName),
+			Time:        $8.(ast.TriggerTime),
+			Event:       $9.(ast.TriggerEvent),
+			Table:       $11.(*ast.TableName),
+			Body:        $16,
+		}
+		if $15 != nil {
+			order := $15.([]interface{})
+			x.Follows = order[0].(bool)
+			x.OtherTrigger = order[1].(string)
+		}
+		$$ = x
+	}
+
+TriggerTime:
+	"BEFORE"
+	{
+		$$ = ast.TriggerBefore
+	}
+|	"AFTER"
+	{
+		$$ = ast.TriggerAfter
+	}
+
+TriggerEvent:
+	"INSERT"
+	{
+		$$ = ast.TriggerInsert
+	}
+|	"UPDATE"
+	{
+		$$ = ast.TriggerUpdate
+	}
+|	"DELETE"
+	{
+		$$ = ast.TriggerDelete
+	}
+
+TriggerOrderOpt:
+	{
+		$$ = nil
+	}
+|	"FOLLOWS" Identifier
+	{
+		$$ = []interface{}{true, $2}
+	}
+|	"PRECEDES" Identifier
+	{
+		$$ = []interface{}{false, $2}
+	}
+
+/*******************************************************************
+ *
+ *  Create Event Statement
+ *
+ *  Example:
+ *      CREATE EVENT e1 ON SCHEDULE EVERY 1 DAY STARTS '2019-01-01 00:00:00'
+ *          ON COMPLETION PRESERVE DISABLE COMMENT 'e1'
+ *          DO DELETE FROM t1 WHERE c1 < NOW() - INTERVAL 7 DAY
+ *******************************************************************/
+CreateEventStmt:
+	"CREATE" OrReplace ViewAlgorithm ViewDefiner "EVENT" IfNotExists TableName "ON" "SCHEDULE" EventSchedule EventCompletionOpt EventStatusOpt EventCommentOpt "DO" ProcedureStatement
+	{
+		if $2.(bool) || $3.(model.ViewAlgorithm) != model.AlgorithmUndefined {
+			yylex.AppendError(yylex.Errorf(""))
+			return 1
+		}
+		$$ = &ast.CreateEventStmt{
+			Definer:     $4.(*auth.UserIdentity),
+			IfNotExists: $6.(bool),
+			Name:        $7.(*ast.TableName),
+			Schedule:    $10.(*ast.EventSchedule),
+			Preserve:    $11.(bool),
+			Status:      $12.(ast.EventStatus),
+			Comment:     $13.(string),
+			Body:        $15,
+		}
+	}
+
+EventSchedule:
+	"AT" Expression
+	{
+		$$ = &ast.EventSchedule{At: $2}
+	}
+|	"EVERY" Expression TimeUnit EventStartsOpt EventEndsOpt
+	{
+		x := &ast.EventSchedule{Every: $2, Unit: $3}
+		if $4 != nil {
+			x.Starts = $4.(ast.ExprNode)
+		}
+		if $5 != nil {
+			x.Ends = $5.(ast.ExprNode)
+		}
+		$$ = x
+	}
+
+EventStartsOpt:
+	{
+		$$ = nil
+	}
+|	"STARTS" Expression
+	{
+		$$ = $2
+	}
+
+EventEndsOpt:
+	{
+		$$ = nil
+	}
+|	"ENDS" Expression
+	{
+		$$ = $2
+	}
+
+EventCompletionOpt:
+	{
+		$$ = false
+	}
+|	"ON" "COMPLETION" "PRESERVE"
+	{
+		$$ = true
+	}
+|	"ON" "COMPLETION" "NOT" "PRESERVE"
+	{
+		$$ = false
+	}
+
+EventStatusOpt:
+	{
+		$$ = ast.EventEnable
+	}
+|	"ENABLE"
+	{
+		$$ = ast.EventEnable
+	}
+|	"DISABLE"
+	{
+		$$ = ast.EventDisable
+	}
+|	"DISABLE" "ON" "SLAVE"
+	{
+		$$ = ast.EventDisableOnSlave
+	}
+
+EventCommentOpt:
+	{
+		$$ = ""
+	}
+|	"COMMENT" stringLit
+	{
+		$$ = $2
+	}
+
+/*******************************************************************
+ *
+ *  Compound statements of stored programs
+ *  See https://dev.mysql.com/doc/refman/5.7/en/sql-syntax-compound-statements.html
+ *
+ *******************************************************************/
+ProcedureStatement:
+	AlterTableStmt
+|	CommitStmt
+|	CreateIndexStmt
+|	CreateTableStmt
+|	DeleteFromStmt
+|	DoStmt
+|	DropIndexStmt
+|	DropTableStmt
+|	InsertIntoStmt
+|	ReplaceIntoStmt
+|	RollbackStmt
+|	SelectStmt
+|	SelectStmtFromTable OrderByOptional SelectStmtLimit SelectLockOpt "INTO" ProcedureVarList
+	{
+		// SELECT ... FROM ... INTO var_list
+		st := $1.(*ast.SelectStmt)
+		st.LockTp = $4.(ast.SelectLockType)
+		if $2 != nil {
+			st.OrderBy = $2.(*ast.OrderByClause)
+		}
+		if $3 != nil {
+			st.Limit = $3.(*ast.Limit)
+		}
+		st.IntoVars = $6.([]string)
+		$$ = st
+	}
+|	SetStmt
+|	"SET" Identifier '.' Identifier eq Expression
+	{
+		// Assign to a column of the subject table in triggers, e.g. SET NEW.col = expr.
+		table := strings.ToUpper($2)
+		if table != "NEW" && table != "OLD" {
+			yylex.AppendError(yylex.Errorf(""))
+			return 1
+		}
+		$$ = &ast.SetStmt{Variables: []*ast.VariableAssignment{{Table: table, Name: $4, Value: $6}}}
+	}
+|	TruncateTableStmt
+|	UpdateStmt
+|	ProcedureLabeledStmt
+|	identifier ':' ProcedureLabeledStmt
+	{
+		switch x := $3.(type) {
+		case *ast.BlockStmt:
+			x.Label = $1
+		case *ast.WhileStmt:
+			x.Label = $1
+		case *ast.LoopStmt:
+			x.Label = $1
+		case *ast.RepeatStmt:
+			x.Label = $1
+		}
+		$$ = $3
+	}
+|	"DECLARE" ProcedureVarList Type
+	{
+		$$ = &ast.DeclareVarStmt{Names: $2.([]string), Tp: $3.(*types.FieldType)}
+	}
+|	"DECLARE" ProcedureVarList Type "DEFAULT" Expression
+	{
+		$$ = &ast.DeclareVarStmt{Names: $2.([]string), Tp: $3.(*types.FieldType), Default: $5}
+	}
+|	"DECLARE" Identifier "CURSOR" "FOR" SelectStmt
+	{
+		$$ = &ast.DeclareCursorStmt{Name: $2, Select: $5}
+	}
+|	"DECLARE" "CONTINUE" "HANDLER" "FOR" HandlerConditionList ProcedureStatement
+	{
+		$$ = &ast.DeclareHandlerStmt{Conditions: $5.([]*ast.HandlerCondition), Stmt: $6}
+	}
+|	"DECLARE" "EXIT" "HANDLER" "FOR" HandlerConditionList ProcedureStatement
+	{
+		$$ = &ast.DeclareHandlerStmt{Exit: true, Conditions: $5.([]*ast.HandlerCondition), Stmt: $6}
+	}
+|	"IF" Expression "THEN" ProcedureStmtList ProcedureElseOpt "END" "IF"
+	{
+		x := &ast.IfStmt{Cond: $2, Then: $4.([]ast.StmtNode)}
+		if $5 != nil {
+			x.Else = $5.([]ast.StmtNode)
+		}
+		$$ = x
+	}
+|	"LEAVE" Identifier
+	{
+		$$ = &ast.LeaveStmt{Label: $2}
+	}
+|	"ITERATE" Identifier
+	{
+		$$ = &ast.IterateStmt{Label: $2}
+	}
+|	"RETURN" Expression
+	{
+		$$ = &ast.ReturnStmt{Expr: $2}
+	}
+|	"OPEN" Identifier
+	{
+		$$ = &ast.OpenCursorStmt{Name: $2}
+	}
+|	"FETCH" Identifier "INTO" ProcedureVarList
+	{
+		$$ = &ast.FetchCursorStmt{Name: $2, Vars: $4.([]string)}
+	}
+|	"CLOSE" Identifier
+	{
+		$$ = &ast.CloseCursorStmt{Name: $2}
+	}
+|	"CASE" ExpressionOpt ProcedureCaseWhenList ProcedureCaseElseOpt "END" "CASE"
+	{
+		x := &ast.CaseStmt{WhenClauses: $3.([]*ast.CaseWhenClause)}
+		if $2 != nil {
+			x.Value = $2.(ast.ExprNode)
+		}
+		if $4 != nil {
+			x.Else = $4.([]ast.StmtNode)
+		}
+		$$ = x
+	}
+|	"CALL" TableName ProcedureCallArgsOpt
+	{
+		$$ = &ast.CallStmt{Procedure: $2.(*ast.TableName), Args: $3.([]ast.ExprNode)}
+	}
+|	"SIGNAL" SignalCondition SignalInfoListOpt
+	{
+		$$ = &ast.SignalStmt{Condition: $2.(*ast.HandlerCondition), Items: $3.([]*ast.SignalInfo)}
+	}
+
+ProcedureCaseWhenList:
+	ProcedureCaseWhen
+	{
+		$$ = []*ast.CaseWhenClause{$1.(*ast.CaseWhenClause)}
+	}
+|	ProcedureCaseWhenList ProcedureCaseWhen
+	{
+		$$ = append($1.([]*ast.CaseWhenClause), $2.(*ast.CaseWhenClause))
+	}
+
+ProcedureCaseWhen:
+	"WHEN" Expression "THEN" ProcedureStmtList
+	{
+		$$ = &ast.CaseWhenClause{Expr: $2, Stmts: $4.([]ast.StmtNode)}
+	}
+
+ProcedureCaseElseOpt:
+	{
+		$$ = nil
+	}
+|	"ELSE" ProcedureStmtList
+	{
+		$$ = $2
+	}
+
+ProcedureCallArgsOpt:
+	{
+		$$ = []ast.ExprNode{}
+	}
+|	'(' ExpressionListOpt ')'
+	{
+		$$ = $2
+	}
+
+SignalCondition:
+	"SQLSTATE" stringLit
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLState, Value: $2}
+	}
+|	"SQLSTATE" "VALUE" stringLit
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLState, Value: $3}
+	}
+|	Identifier
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerConditionName, Value: $1}
+	}
+
+SignalInfoListOpt:
+	{
+		$$ = []*ast.SignalInfo{}
+	}
+|	"SET" SignalInfoList
+	{
+		$$ = $2
+	}
+
+SignalInfoList:
+	SignalInfo
+	{
+		$$ = []*ast.SignalInfo{$1.(*ast.SignalInfo)}
+	}
+|	SignalInfoList ',' SignalInfo
+	{
+		$$ = append($1.([]*ast.SignalInfo), $3.(*ast.SignalInfo))
 	}
-|	"OR" "REPLACE"
+
+SignalInfo:
+	Identifier eq Expression
 	{
-		$$ = true
+		$$ = &ast.SignalInfo{Name: strings.ToUpper($1), Value: $3}
 	}
 
-ViewAlgorithm:
-	/* EMPTY */
+ProcedureLabeledStmt:
+	"BEGIN" ProcedureStmtList "END" ProcedureLabelOpt
 	{
-		$$ = "UNDEFINED"
+		$$ = &ast.BlockStmt{Stmts: $2.([]ast.StmtNode)}
 	}
-|	"ALGORITHM" "=" "UNDEFINED"
+|	"WHILE" Expression "DO" ProcedureStmtList "END" "WHILE" ProcedureLabelOpt
 	{
-		$$ = strings.ToUpper($3)
+		$$ = &ast.WhileStmt{Cond: $2, Stmts: $4.([]ast.StmtNode)}
 	}
-|	"ALGORITHM" "=" "MERGE"
+|	"LOOP" ProcedureStmtList "END" "LOOP" ProcedureLabelOpt
 	{
-		$$ = strings.ToUpper($3)
+		$$ = &ast.LoopStmt{Stmts: $2.([]ast.StmtNode)}
 	}
-|	"ALGORITHM" "=" "TEMPTABLE"
+|	"REPEAT" ProcedureStmtList "UNTIL" Expression "END" "REPEAT" ProcedureLabelOpt
 	{
-		$$ = strings.ToUpper($3)
+		$$ = &ast.RepeatStmt{Stmts: $2.([]ast.StmtNode), Until: $4}
 	}
 
-ViewDefiner:
-	/* EMPTY */
+ProcedureLabelOpt:
 	{
-		$$ = nil
+		$$ = ""
 	}
-|   "DEFINER" "=" Username
+|	identifier
 	{
-		$$ = $3
+		$$ = $1
 	}
 
-ViewSQLSecurity:
-	/* EMPTY */
+ProcedureStmtList:
 	{
-		$$ = "DEFINER"
+		$$ = []ast.StmtNode{}
 	}
-|   "SQL" "SECURITY" "DEFINER"
-	 {
-		 $$ = $3
-	 }
-|   "SQL" "SECURITY" "INVOKER"
-	 {
-		 $$ = $3
-	 }
-
-ViewName:
-	TableName
+|	ProcedureStmtList ProcedureStatement ';'
 	{
-		$$ = $1.(*ast.TableName)
+		$$ = append($1.([]ast.StmtNode), $2)
 	}
 
-ViewFieldList:
-	/* Empty */
+ProcedureElseOpt:
 	{
 		$$ = nil
 	}
-|   '(' ColumnList ')'
+|	"ELSE" ProcedureStmtList
 	{
-		$$ = $2.([]model.CIStr)
+		$$ = $2
+	}
+|	"ELSEIF" Expression "THEN" ProcedureStmtList ProcedureElseOpt
+	{
+		x := &ast.IfStmt{Cond: $2, Then: $4.([]ast.StmtNode), ElseIf: true}
+		if $5 != nil {
+			x.Else = $5.([]ast.StmtNode)
+		}
+		$$ = []ast.StmtNode{x}
 	}
 
-ColumnList:
+ProcedureVarList:
 	Identifier
 	{
-		$$ = []model.CIStr{model.NewCIStr($1)}
+		$$ = []string{$1}
 	}
-|   ColumnList ',' Identifier
+|	ProcedureVarList ',' Identifier
 	{
-	$$ = append($1.([]model.CIStr), model.NewCIStr($3))
+		$$ = append($1.([]string), $3)
 	}
 
-ViewCheckOption:
-	/* EMPTY */
+HandlerConditionList:
+	HandlerCondition
 	{
-		$$ = nil
+		$$ = []*ast.HandlerCondition{$1.(*ast.HandlerCondition)}
 	}
-|   "WITH" "CASCADED" "CHECK" "OPTION"
+|	HandlerConditionList ',' HandlerCondition
 	{
-		$$ = $2
+		$$ = append($1.([]*ast.HandlerCondition), $3.(*ast.HandlerCondition))
 	}
-|   "WITH" "LOCAL" "CHECK" "OPTION"
+
+HandlerCondition:
+	intLit
 	{
-		$$ = $2
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerErrorCode, Value: fmt.Sprint(getUint64FromNUM($1))}
+	}
+|	"SQLSTATE" stringLit
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLState, Value: $2}
+	}
+|	"SQLSTATE" "VALUE" stringLit
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLState, Value: $3}
+	}
+|	Identifier
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerConditionName, Value: $1}
+	}
+|	"SQLWARNING"
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLWarning}
+	}
+|	"NOT" "FOUND"
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerNotFound}
+	}
+|	"SQLEXCEPTION"
+	{
+		$$ = &ast.HandlerCondition{Tp: ast.HandlerSQLException}
 	}
 
 /******************************************************************
@@ -2719,6 +3650,15 @@ DoStmt:
  *
  *******************************************************************/
 DeleteFromStmt:
+	DeleteFromStmtNoWith
+|	WithClause DeleteFromStmtNoWith
+	{
+		st := $2.(*ast.DeleteStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+DeleteFromStmtNoWith:
 	"DELETE" TableOptimizerHints PriorityOpt QuickOptional IgnoreOptional "FROM" TableName IndexHintListOpt WhereClauseOptional OrderByOptional LimitClause
 	{
 		// Single Table
@@ -2830,9 +3770,31 @@ DropTableStmt:
 	}
 
 DropViewStmt:
-	"DROP" "VIEW" "IF" "EXISTS" TableNameList
+	"DROP" "VIEW" TableNameList RestrictOrCascadeOpt
+	{
+		$$ = &ast.DropTableStmt{Tables: $3.([]*ast.TableName), IsView: true}
+	}
+|	"DROP" "VIEW" "IF" "EXISTS" TableNameList RestrictOrCascadeOpt
+	{
+		$$ = &ast.DropTableStmt{IfExists: true, Tables: $5.([]*ast.TableName), IsView: true}
+	}
+
+DropRoutineStmt:
+	"DROP" "PROCEDURE" IfExists TableName
+	{
+		$$ = &ast.DropRoutineStmt{Tp: ast.RoutineProcedure, IfExists: $3.(bool), Name: $4.(*ast.TableName)}
+	}
+|	"DROP" "FUNCTION" IfExists TableName
+	{
+		$$ = &ast.DropRoutineStmt{Tp: ast.RoutineFunction, IfExists: $3.(bool), Name: $4.(*ast.TableName)}
+	}
+|	"DROP" "TRIGGER" IfExists TableName
+	{
+		$$ = &ast.DropRoutineStmt{Tp: ast.RoutineTrigger, IfExists: $3.(bool), Name: $4.(*ast.TableName)}
+	}
+|	"DROP" "EVENT" IfExists TableName
 	{
-		$$ = &ast.DoStmt{}
+		$$ = &ast.DropRoutineStmt{Tp: ast.RoutineEvent, IfExists: $3.(bool), Name: $4.(*ast.TableName)}
 	}
 
 DropUserStmt:
@@ -3376,6 +4338,10 @@ IndexOptionList:
 				opt1.Comment = opt2.Comment
 			} else if opt2.Tp != 0 {
 				opt1.Tp = opt2.Tp
+			} else if opt2.KeyBlockSize > 0 {
+				opt1.KeyBlockSize = opt2.KeyBlockSize
+			} else if opt2.Visibility != ast.IndexVisibilityDefault {
+				opt1.Visibility = opt2.Visibility
 			}
 			$$ = opt1
 		}
@@ -3501,7 +4467,11 @@ UnReservedKeyword:
 | "NONE" | "SUPER" | "EXCLUSIVE" | "STATS_PERSISTENT" | "ROW_COUNT" | "COALESCE" | "MONTH" | "PROCESS" | "PROFILES"
 | "MICROSECOND" | "MINUTE" | "PLUGINS" | "QUERY" | "QUERIES" | "SECOND" | "SEPARATOR" | "SHARE" | "SHARED" | "SLOW" | "MAX_CONNECTIONS_PER_HOUR" | "MAX_QUERIES_PER_HOUR" | "MAX_UPDATES_PER_HOUR"
 | "MAX_USER_CONNECTIONS" | "REPLICATION" | "CLIENT" | "SLAVE" | "RELOAD" | "TEMPORARY" | "ROUTINE" | "EVENT" | "ALGORITHM" | "DEFINER" | "INVOKER" | "MERGE" | "TEMPTABLE" | "UNDEFINED" | "SECURITY" | "CASCADED" | "RECOVER" | "HISTORY" | "DIRECTORY" | "NODEGROUP"
-| "RTREE" | "INVISIBLE" | "VISIBLE" | "TYPE"
+| "RTREE" | "INVISIBLE" | "VISIBLE" | "TYPE" | "AT" | "CLOSE" | "COMPLETION" | "CONTAINS" | "ENDS" | "EVERY" | "FOLLOWS"
+| "FOUND" | "HANDLER" | "LANGUAGE" | "OPEN" | "PRECEDES" | "PRESERVE" | "RETURNS" | "SCHEDULE" | "STARTS"
+| "CIPHER" | "ISSUER" | "REQUIRE" | "SSL" | "SUBJECT" | "X509"
+| "ENFORCED" | "FOLLOWING" | "PRECEDING" | "UNBOUNDED"
+| "EXCHANGE" | "REORGANIZE" | "VALIDATION" | "WITHOUT"
 
 
 TiDBKeyword:
@@ -3556,6 +4526,10 @@ InsertValues:
 	{
 		$$ = &ast.InsertStmt{Columns: $2.([]*ast.ColumnName), Select: $4.(*ast.UnionStmt)}
 	}
+|	'(' ColumnNameListOpt ')' SelectStmtWithClause
+	{
+		$$ = &ast.InsertStmt{Columns: $2.([]*ast.ColumnName), Select: $4.(ast.ResultSetNode)}
+	}
 |	ValueSym ValuesList %prec insertValues
 	{
 		$$ = &ast.InsertStmt{Lists:  $2.([][]ast.ExprNode)}
@@ -3572,6 +4546,10 @@ InsertValues:
 	{
 		$$ = &ast.InsertStmt{Select: $1.(*ast.UnionStmt)}
 	}
+|	SelectStmtWithClause
+	{
+		$$ = &ast.InsertStmt{Select: $1.(ast.ResultSetNode)}
+	}
 |	"SET" ColumnSetValueList
 	{
 		$$ = &ast.InsertStmt{Setlist: $2.([]*ast.Assignment)}
@@ -3940,6 +4918,7 @@ SimpleExpr:
 	}
 |	Variable
 |	SumExpr
+|	WindowFuncCall
 |	'!' SimpleExpr %prec neg
 	{
 		$$ = &ast.UnaryOperationExpr{Op: opcode.Not, V: $2}
@@ -4492,6 +5471,182 @@ FunctionCallGeneric:
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
 
+/************************************************************************************
+ *  Window functions
+ *  See https://dev.mysql.com/doc/refman/8.0/en/window-functions-usage.html
+ ***********************************************************************************/
+WindowFuncCall:
+	FunctionCallGeneric WindowingClause
+	{
+		f := $1.(*ast.FuncCallExpr)
+		$$ = &ast.WindowFuncExpr{F: f.FnName.O, Args: f.Args, Spec: $2.(ast.WindowSpec)}
+	}
+|	SumExpr WindowingClause
+	{
+		f := $1.(*ast.AggregateFuncExpr)
+		$$ = &ast.WindowFuncExpr{F: f.F, Args: f.Args, Distinct: f.Distinct, Spec: $2.(ast.WindowSpec)}
+	}
+
+WindowingClause:
+	"OVER" WindowNameOrSpec
+	{
+		$$ = $2
+	}
+
+WindowNameOrSpec:
+	WindowName
+	{
+		$$ = ast.WindowSpec{Name: model.NewCIStr($1), OnlyAlias: true}
+	}
+|	WindowSpec
+
+WindowName:
+	Identifier
+
+WindowSpec:
+	'(' WindowSpecDetails ')'
+	{
+		$$ = $2
+	}
+
+WindowSpecDetails:
+	WindowExistingNameOpt WindowPartitionClauseOpt WindowOrderByClauseOpt WindowFrameClauseOpt
+	{
+		spec := ast.WindowSpec{Ref: model.NewCIStr($1)}
+		if $2 != nil {
+			spec.PartitionBy = $2.(*ast.PartitionByClause)
+		}
+		if $3 != nil {
+			spec.OrderBy = $3.(*ast.OrderByClause)
+		}
+		if $4 != nil {
+			spec.Frame = $4.(*ast.FrameClause)
+		}
+		$$ = spec
+	}
+
+WindowExistingNameOpt:
+	{
+		$$ = ""
+	}
+|	WindowName
+
+WindowPartitionClauseOpt:
+	{
+		$$ = nil
+	}
+|	"PARTITION" "BY" ByList
+	{
+		$$ = &ast.PartitionByClause{Items: $3.([]*ast.ByItem)}
+	}
+
+WindowOrderByClauseOpt:
+	{
+		$$ = nil
+	}
+|	"ORDER" "BY" ByList
+	{
+		$$ = &ast.OrderByClause{Items: $3.([]*ast.ByItem)}
+	}
+
+WindowFrameClauseOpt:
+	{
+		$$ = nil
+	}
+|	WindowFrameUnits WindowFrameExtent
+	{
+		$$ = &ast.FrameClause{
+			Type: $1.(ast.FrameType),
+			Extent: $2.(ast.FrameExtent),
+		}
+	}
+
+WindowFrameUnits:
+	"ROWS"
+	{
+		$$ = ast.FrameType(ast.Rows)
+	}
+|	"RANGE"
+	{
+		$$ = ast.FrameType(ast.Ranges)
+	}
+
+WindowFrameExtent:
+	WindowFrameStart
+	{
+		$$ = ast.FrameExtent{
+			Start: $1.(ast.FrameBound),
+			End: ast.FrameBound{Type: ast.CurrentRow},
+		}
+	}
+|	WindowFrameBetween
+
+WindowFrameStart:
+	"UNBOUNDED" "PRECEDING"
+	{
+		$$ = ast.FrameBound{Type: ast.Preceding, UnBounded: true}
+	}
+|	NumLiteral "PRECEDING"
+	{
+		$$ = ast.FrameBound{Type: ast.Preceding, Expr: ast.NewValueExpr($1)}
+	}
+|	"INTERVAL" Expression TimeUnit "PRECEDING"
+	{
+		$$ = ast.FrameBound{Type: ast.Preceding, Expr: $2, Unit: ast.NewValueExpr($3)}
+	}
+|	"CURRENT" "ROW"
+	{
+		$$ = ast.FrameBound{Type: ast.CurrentRow}
+	}
+
+WindowFrameBetween:
+	"BETWEEN" WindowFrameBound "AND" WindowFrameBound
+	{
+		$$ = ast.FrameExtent{Start: $2.(ast.FrameBound), End: $4.(ast.FrameBound)}
+	}
+
+WindowFrameBound:
+	WindowFrameStart
+|	"UNBOUNDED" "FOLLOWING"
+	{
+		$$ = ast.FrameBound{Type: ast.Following, UnBounded: true}
+	}
+|	NumLiteral "FOLLOWING"
+	{
+		$$ = ast.FrameBound{Type: ast.Following, Expr: ast.NewValueExpr($1)}
+	}
+|	"INTERVAL" Expression TimeUnit "FOLLOWING"
+	{
+		$$ = ast.FrameBound{Type: ast.Following, Expr: $2, Unit: ast.NewValueExpr($3)}
+	}
+
+WindowClauseOptional:
+	{
+		$$ = nil
+	}
+|	"WINDOW" WindowDefinitionList
+	{
+		$$ = $2.([]ast.WindowSpec)
+	}
+
+WindowDefinitionList:
+	WindowDefinition
+	{
+		$$ = []ast.WindowSpec{$1.(ast.WindowSpec)}
+	}
+|	WindowDefinitionList ',' WindowDefinition
+	{
+		$$ = append($1.([]ast.WindowSpec), $3.(ast.WindowSpec))
+	}
+
+WindowDefinition:
+	WindowName "AS" WindowSpec
+	{
+		spec := $3.(ast.WindowSpec)
+		spec.Name = model.NewCIStr($1)
+		$$ = spec
+	}
+
 FuncDatetimePrec:
 	{
 		$$ = nil
@@ -4909,6 +6064,17 @@ SelectStmtBasic:
 		}
 		$$ = st
 	}
+|	"SELECT" SelectStmtOpts SelectStmtFieldList "INTO" ProcedureVarList
+	{
+		// SELECT ... INTO var_list of stored programs.
+		st := &ast.SelectStmt {
+			SelectStmtOpts: $2.(*ast.SelectStmtOpts),
+			Distinct:      $2.(*ast.SelectStmtOpts).Distinct,
+			Fields:        $3.(*ast.FieldList),
+			IntoVars:      $5.([]string),
+		}
+		$$ = st
+	}
 
 SelectStmtFromDual:
 	SelectStmtBasic FromDual WhereClauseOptional OrderByOptional
@@ -4931,7 +6097,7 @@ SelectStmtFromDual:
 
 SelectStmtFromTable:
 	SelectStmtBasic "FROM"
-	TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause
+	TableRefsClause WhereClauseOptional SelectStmtGroup HavingClause WindowClauseOptional
 	{
 		st := $1.(*ast.SelectStmt)
 		st.From = $3.(*ast.TableRefsClause)
@@ -4940,7 +6106,7 @@ SelectStmtFromTable:
 		}
 		lastField := st.Fields.Fields[len(st.Fields.Fields)-1]
 		if lastField.Expr != nil && lastField.AsName.O == "" {
-			lastEnd := parser.endOffset(&yyS[yypt-4])
+			lastEnd := parser.endOffset(&yyS[yypt-5])
 			lastField.SetText(parser.src[lastField.Offset:lastEnd])
 		}
 		if $4 != nil {
@@ -4952,6 +6118,9 @@ SelectStmtFromTable:
 		if $6 != nil {
 			st.Having = $6.(*ast.HavingClause)
 		}
+		if $7 != nil {
+			st.WindowSpecs = $7.([]ast.WindowSpec)
+		}
 		$$ = st
 	}
 
@@ -5076,6 +6245,10 @@ TableFactor:
 	{
 		$$ = &ast.TableSource{Source: $2.(*ast.UnionStmt), AsName: $4.(model.CIStr)}
 	}
+|	'(' SelectStmtWithClause ')' TableAsName
+	{
+		$$ = &ast.TableSource{Source: $2.(ast.ResultSetNode), AsName: $4.(model.CIStr)}
+	}
 |	'(' TableRefs ')'
 	{
 		$$ = $2
@@ -5410,6 +6583,57 @@ SelectStmtGroup:
 |	GroupByClause
 
 // See https://dev.mysql.com/doc/refman/5.7/en/subqueries.html
+/************************************************************************************
+ *  Common table expressions
+ *  See https://dev.mysql.com/doc/refman/8.0/en/with.html
+ ***********************************************************************************/
+SelectStmtWithClause:
+	WithClause SelectStmt
+	{
+		st := $2.(*ast.SelectStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+|	WithClause UnionStmt
+	{
+		st := $2.(*ast.UnionStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+WithClause:
+	"WITH" WithList
+	{
+		$$ = &ast.WithClause{CTEs: $2.([]*ast.CommonTableExpression)}
+	}
+|	"WITH" "RECURSIVE" WithList
+	{
+		$$ = &ast.WithClause{IsRecursive: true, CTEs: $3.([]*ast.CommonTableExpression)}
+	}
+
+WithList:
+	CommonTableExpr
+	{
+		$$ = []*ast.CommonTableExpression{$1.(*ast.CommonTableExpression)}
+	}
+|	WithList ',' CommonTableExpr
+	{
+		$$ = append($1.([]*ast.CommonTableExpression), $3.(*ast.CommonTableExpression))
+	}
+
+CommonTableExpr:
+	Identifier ViewFieldList "AS" SubSelect
+	{
+		cte := &ast.CommonTableExpression{
+			Name:	model.NewCIStr($1),
+			Query:	$4.(*ast.SubqueryExpr),
+		}
+		if $2 != nil {
+			cte.ColNameList = $2.([]model.CIStr)
+		}
+		$$ = cte
+	}
+
 SubSelect:
 	'(' SelectStmt ')'
 	{
@@ -5429,6 +6653,14 @@ SubSelect:
 		s.SetText(src[yyS[yypt-1].offset:yyS[yypt].offset])
 		$$ = &ast.SubqueryExpr{Query: s}
 	}
+|	'(' SelectStmtWithClause ')'
+	{
+		s := $2.(ast.ResultSetNode)
+		src := parser.src
+		// See the implementation of yyParse function
+		s.SetText(src[yyS[yypt-1].offset:yyS[yypt].offset])
+		$$ = &ast.SubqueryExpr{Query: s}
+	}
 
 // See https://dev.mysql.com/doc/refman/5.7/en/innodb-locking-reads.html
 SelectLockOpt:
@@ -6397,12 +7629,16 @@ Statement:
 |	CreateIndexStmt
 |	CreateTableStmt
 |	CreateViewStmt
+|	CreateRoutineStmt
+|	CreateTriggerStmt
+|	CreateEventStmt
 |	CreateUserStmt
 |	DoStmt
 |	DropDatabaseStmt
 |	DropIndexStmt
 |	DropTableStmt
 |	DropViewStmt
+|	DropRoutineStmt
 |	DropUserStmt
 |	DropStatsStmt
 |	FlushStmt
@@ -6417,6 +7653,7 @@ Statement:
 |	ReplaceIntoStmt
 |	RevokeStmt
 |	SelectStmt
+|	SelectStmtWithClause
 |	UnionStmt
 |	SetStmt
 |	ShowStmt
@@ -6444,6 +7681,7 @@ TraceableStmt:
 
 ExplainableStmt:
 	SelectStmt
+|	SelectStmtWithClause
 |	DeleteFromStmt
 |	UpdateStmt
 |	InsertIntoStmt
@@ -6491,11 +7729,6 @@ TableElement:
 	{
 		$$ = $1.(*ast.Constraint)
 	}
-|	"CHECK" '(' Expression ')'
-	{
-		/* Nothing to do now */
-		$$ = nil
-	}
 
 TableElementList:
 	TableElement
@@ -7234,6 +8467,15 @@ StringName:
  * See https://dev.mysql.com/doc/refman/5.7/en/update.html
  ***********************************************************************************/
 UpdateStmt:
+	UpdateStmtNoWith
+|	WithClause UpdateStmtNoWith
+	{
+		st := $2.(*ast.UpdateStmt)
+		st.With = $1.(*ast.WithClause)
+		$$ = st
+	}
+
+UpdateStmtNoWith:
 	"UPDATE" TableOptimizerHints PriorityOpt IgnoreOptional TableRef "SET" AssignmentList WhereClauseOptional OrderByOptional LimitClause
 	{
 		var refs *ast.Join
@@ -7310,15 +8552,66 @@ CommaOpt:
  *  https://dev.mysql.com/doc/refman/5.7/en/account-management-sql.html
  ************************************************************************************/
 CreateUserStmt:
-	"CREATE" "USER" IfNotExists UserSpecList
+	"CREATE" "USER" IfNotExists UserSpecList RequireClauseOpt
 	{
  		// See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 		$$ = &ast.CreateUserStmt{
 			IfNotExists: $3.(bool),
 			Specs: $4.([]*ast.UserSpec),
+			TslOptions: $5.([]*ast.TslOption),
 		}
 	}
 
+RequireClauseOpt:
+	{
+		$$ = []*ast.TslOption{}
+	}
+|	"REQUIRE" RequireList
+	{
+		$$ = $2
+	}
+
+/* TslOptions are restored in reverse order, so the list is built reversely. */
+RequireList:
+	"NONE"
+	{
+		$$ = []*ast.TslOption{{Type: ast.TslNone}}
+	}
+|	"SSL"
+	{
+		$$ = []*ast.TslOption{{Type: ast.Ssl}}
+	}
+|	"X509"
+	{
+		$$ = []*ast.TslOption{{Type: ast.X509}}
+	}
+|	RequireListElement
+	{
+		$$ = []*ast.TslOption{$1.(*ast.TslOption)}
+	}
+|	RequireList "AND" RequireListElement
+	{
+		$$ = append([]*ast.TslOption{$3.(*ast.TslOption)}, $1.([]*ast.TslOption)...)
+	}
+|	RequireList RequireListElement
+	{
+		$$ = append([]*ast.TslOption{$2.(*ast.TslOption)}, $1.([]*ast.TslOption)...)
+	}
+
+RequireListElement:
+	"CIPHER" stringLit
+	{
+		$$ = &ast.TslOption{Type: ast.Cipher, Value: $2}
+	}
+|	"ISSUER" stringLit
+	{
+		$$ = &ast.TslOption{Type: ast.Issuer, Value: $2}
+	}
+|	"SUBJECT" stringLit
+	{
+		$$ = &ast.TslOption{Type: ast.Subject, Value: $2}
+	}
+
 /* See http://dev.mysql.com/doc/refman/5.7/en/alter-user.html */
 AlterUserStmt:
 	"ALTER" "USER" IfExists UserSpecList
@@ -45,7 +45,7 @@ func (s *testParserSuite) TestSimple(c *C) {
 
 	reservedKws := []string{
 		"add", "all", "alter", "analyze", "and", "as", "asc", "between", "bigint",
-		"binary", "blob", "both", "by", "cascade", "case", "change", "character", "check", "collate",
+		"binary", "blob", "both", "by", "call", "cascade", "case", "change", "character", "check", "collate",
 		"column", "constraint", "convert", "create", "cross", "current_date", "current_time",
 		"current_timestamp", "current_user", "database", "databases", "day_hour", "day_microsecond",
 		"day_minute", "day_second", "decimal", "default", "delete", "desc", "describe",
@@ -58,7 +58,7 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"minute_microsecond", "minute_second", "mod", "not", "no_write_to_binlog", "null", "numeric",
 		"on", "option", "or", "order", "outer", "partition", "precision", "primary", "procedure", "range", "read", "real",
 		"references", "regexp", "rename", "repeat", "replace", "revoke", "restrict", "right", "rlike",
-		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint",
+		"schema", "schemas", "second_microsecond", "select", "set", "show", "signal", "smallint",
 		"starting", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
 		"trailing", "true", "union", "unique", "unlock", "unsigned",
 		"update", "use", "using", "utc_date", "values", "varbinary", "varchar",
@@ -1699,11 +1699,11 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"drop table xxx restrict", true, "DROP TABLE `xxx`"},
 		{"drop table xxx, yyy cascade", true, "DROP TABLE `xxx`, `yyy`"},
 		{"drop table if exists xxx restrict", true, "DROP TABLE IF EXISTS `xxx`"},
-		// {"drop view", false, "DROP VIEW"},
-		// {"drop view xxx", true, "DROP VIEW `xxx`"},
-		// {"drop view xxx, yyy", true, "DROP VIEW `xxx`, `yyy`"},
-		// {"drop view if exists xxx", true, "DROP VIEW IF EXISTS `xxx`"},
-		// {"drop view if exists xxx, yyy", true, "DROP VIEW IF EXISTS `xxx`, `yyy`"},
+		{"drop view", false, "DROP VIEW"},
+		{"drop view xxx", true, "DROP VIEW `xxx`"},
+		{"drop view xxx, yyy", true, "DROP VIEW `xxx`, `yyy`"},
+		{"drop view if exists xxx", true, "DROP VIEW IF EXISTS `xxx`"},
+		{"drop view if exists xxx, yyy", true, "DROP VIEW IF EXISTS `xxx`, `yyy`"},
 		{"drop stats t", true, "DROP STATS `t`"},
 		// for issue 974
 		{`CREATE TABLE address (
@@ -1800,7 +1800,7 @@ func (s *testParserSuite) TestDDL(c *C) {
 		// for default value
 		{"CREATE TABLE sbtest (id INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, k integer UNSIGNED DEFAULT '0' NOT NULL, c char(120) DEFAULT '' NOT NULL, pad char(60) DEFAULT '' NOT NULL, PRIMARY KEY  (id) )", true, "CREATE TABLE `sbtest` (`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,`k` INT UNSIGNED DEFAULT '0' NOT NULL,`c` CHAR(120) DEFAULT '' NOT NULL,`pad` CHAR(60) DEFAULT '' NOT NULL,PRIMARY KEY(`id`))"},
 		{"create table test (create_date TIMESTAMP NOT NULL COMMENT '创建日期 create date' DEFAULT now());", true, "CREATE TABLE `test` (`create_date` TIMESTAMP NOT NULL COMMENT '创建日期 create date' DEFAULT CURRENT_TIMESTAMP())"},
-		{"create table ts (t int, v timestamp(3) default CURRENT_TIMESTAMP(3));", true, "CREATE TABLE `ts` (`t` INT,`v` TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3))"}, //TODO: The number yacc in parentheses has not been implemented yet.
+		{"create table ts (t int, v timestamp(3) default CURRENT_TIMESTAMP(3));", true, "CREATE TABLE `ts` (`t` INT,`v` TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3))"},
 		// Create table with primary key name.
 		{"create table if not exists `t` (`id` int not null auto_increment comment '消息ID', primary key `pk_id` (`id`) );", true, "CREATE TABLE IF NOT EXISTS `t` (`id` INT NOT NULL AUTO_INCREMENT COMMENT '消息ID',PRIMARY KEY(`id`))"},
 		// Create table with like.
@@ -1831,7 +1831,7 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"CREATE TABLE t (c TEXT) shard_row_id_bits = 1;", true, "CREATE TABLE `t` (`c` TEXT) SHARD_ROW_ID_BITS = 1"},
 		// {"CREATE TABLE t (c TEXT) shard_row_id_bits = 1, PRE_SPLIT_REGIONS = 1;", true, "CREATE TABLE `t` (`c` TEXT) SHARD_ROW_ID_BITS = 1 PRE_SPLIT_REGIONS = 1"},
 		// Create table with ON UPDATE CURRENT_TIMESTAMP(6), specify fraction part.
-		{"CREATE TABLE IF NOT EXISTS `general_log` (`event_time` timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),`user_host` mediumtext NOT NULL,`thread_id` bigint(20) unsigned NOT NULL,`server_id` int(10) unsigned NOT NULL,`command_type` varchar(64) NOT NULL,`argument` mediumblob NOT NULL) ENGINE=CSV DEFAULT CHARSET=utf8 COMMENT='General log'", true, "CREATE TABLE IF NOT EXISTS `general_log` (`event_time` TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),`user_host` MEDIUMTEXT NOT NULL,`thread_id` BIGINT(20) UNSIGNED NOT NULL,`server_id` INT(10) UNSIGNED NOT NULL,`command_type` VARCHAR(64) NOT NULL,`argument` MEDIUMBLOB NOT NULL) ENGINE = CSV DEFAULT CHARACTER SET = UTF8 COMMENT = 'General log'"}, //TODO: The number yacc in parentheses has not been implemented yet.
+		{"CREATE TABLE IF NOT EXISTS `general_log` (`event_time` timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),`user_host` mediumtext NOT NULL,`thread_id` bigint(20) unsigned NOT NULL,`server_id` int(10) unsigned NOT NULL,`command_type` varchar(64) NOT NULL,`argument` mediumblob NOT NULL) ENGINE=CSV DEFAULT CHARSET=utf8 COMMENT='General log'", true, "CREATE TABLE IF NOT EXISTS `general_log` (`event_time` TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),`user_host` MEDIUMTEXT NOT NULL,`thread_id` BIGINT(20) UNSIGNED NOT NULL,`server_id` INT(10) UNSIGNED NOT NULL,`command_type` VARCHAR(64) NOT NULL,`argument` MEDIUMBLOB NOT NULL) ENGINE = CSV DEFAULT CHARACTER SET = UTF8 COMMENT = 'General log'"},
 		// For reference_definition in column_definition.
 		{"CREATE TABLE followers ( f1 int NOT NULL REFERENCES user_profiles (uid) );", true, "CREATE TABLE `followers` (`f1` INT NOT NULL REFERENCES `user_profiles`(`uid`))"},
 
@@ -1882,11 +1882,20 @@ func (s *testParserSuite) TestDDL(c *C) {
 		// For drop table partition statement.
 		{"alter table t drop partition p1;", true, "ALTER TABLE `t` DROP PARTITION `p1`"},
 		{"alter table t drop partition p2;", true, "ALTER TABLE `t` DROP PARTITION `p2`"},
-		// {"alter table t drop partition p1, p2;", true, "ALTER TABLE `t` DROP PARTITION `p1`,`p2`"},
+		{"alter table t drop partition p1, p2;", true, "ALTER TABLE `t` DROP PARTITION `p1`,`p2`"},
+		{"alter table t drop partition p1, add column c int;", false, ""},
 		// {"alter table employees add partition partitions 1;", true, "ALTER TABLE `employees` ADD PARTITION PARTITIONS 1"},
 		// {"alter table employees add partition partitions 2;", true, "ALTER TABLE `employees` ADD PARTITION PARTITIONS 2"},
-		// {"alter table clients coalesce partition 3;", true, "ALTER TABLE `clients` COALESCE PARTITION 3"},
-		// {"alter table clients coalesce partition 4;", true, "ALTER TABLE `clients` COALESCE PARTITION 4"},
+		{"alter table clients coalesce partition 3;", true, "ALTER TABLE `clients` COALESCE PARTITION 3"},
+		{"alter table clients coalesce partition 4;", true, "ALTER TABLE `clients` COALESCE PARTITION 4"},
+		{"alter table t truncate partition p1;", true, "ALTER TABLE `t` TRUNCATE PARTITION `p1`"},
+		{"alter table t truncate partition p1, p2;", true, "ALTER TABLE `t` TRUNCATE PARTITION `p1`,`p2`"},
+		{"alter table t reorganize partition p1, p2 into (partition p3 values less than (2020));", true, "ALTER TABLE `t` REORGANIZE PARTITION `p1`,`p2` INTO (PARTITION `p3` VALUES LESS THAN (2020))"},
+		{"alter table t reorganize partition pmax into (partition p1 values less than (2020), partition pmax values less than maxvalue);", true, "ALTER TABLE `t` REORGANIZE PARTITION `pmax` INTO (PARTITION `p1` VALUES LESS THAN (2020), PARTITION `pmax` VALUES LESS THAN (MAXVALUE))"},
+		{"alter table t exchange partition p1 with table t1;", true, "ALTER TABLE `t` EXCHANGE PARTITION `p1` WITH TABLE `t1`"},
+		{"alter table t exchange partition p1 with table test.t1 with validation;", true, "ALTER TABLE `t` EXCHANGE PARTITION `p1` WITH TABLE `test`.`t1`"},
+		{"alter table t exchange partition p1 with table t1 without validation;", true, "ALTER TABLE `t` EXCHANGE PARTITION `p1` WITH TABLE `t1` WITHOUT VALIDATION"},
+		{"create table exchange (validation int, without int, reorganize int);", true, "CREATE TABLE `exchange` (`validation` INT,`without` INT,`reorganize` INT)"},
 		{"ALTER TABLE t DISABLE KEYS", true, "ALTER TABLE `t` DISABLE KEYS"},
 		{"ALTER TABLE t ENABLE KEYS", true, "ALTER TABLE `t` ENABLE KEYS"},
 		{"ALTER TABLE t MODIFY COLUMN a varchar(255)", true, "ALTER TABLE `t` MODIFY COLUMN `a` VARCHAR(255)"},
@@ -1936,9 +1945,9 @@ func (s *testParserSuite) TestDDL(c *C) {
 		{"ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` mediumtext CHARACTER SET UTF8MB4 COLLATE UTF8MB4_UNICODE_CI NOT NULL , ALGORITHM = DEFAULT;", true, "ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_unicode_ci NOT NULL, ALGORITHM = DEFAULT"},
 		{"ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` mediumtext CHARACTER SET UTF8MB4 COLLATE UTF8MB4_UNICODE_CI NOT NULL , ALGORITHM = INPLACE;", true, "ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_unicode_ci NOT NULL, ALGORITHM = INPLACE"},
 		{"ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` mediumtext CHARACTER SET UTF8MB4 COLLATE UTF8MB4_UNICODE_CI NOT NULL , ALGORITHM = COPY;", true, "ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_unicode_ci NOT NULL, ALGORITHM = COPY"},
-		// {"ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE UTF8MB4_UNICODE_CI NOT NULL, ALGORITHM = INSTANT;", true, "ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_unicode_ci NOT NULL, ALGORITHM = INSTANT"},
-		{"ALTER TABLE t CONVERT TO CHARACTER SET UTF8;", true, "ALTER TABLE `t` DEFAULT CHARACTER SET = UTF8"},
-		{"ALTER TABLE t CONVERT TO CHARSET UTF8;", true, "ALTER TABLE `t` DEFAULT CHARACTER SET = UTF8"},
+		{"ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE UTF8MB4_UNICODE_CI NOT NULL, ALGORITHM = INSTANT;", true, "ALTER TABLE `hello-world@dev`.`User` ADD COLUMN `name` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_unicode_ci NOT NULL, ALGORITHM = INSTANT"},
+		{"ALTER TABLE t CONVERT TO CHARACTER SET UTF8;", true, "ALTER TABLE `t` CONVERT TO CHARACTER SET UTF8"},
+		{"ALTER TABLE t CONVERT TO CHARSET UTF8;", true, "ALTER TABLE `t` CONVERT TO CHARACTER SET UTF8"},
 		{"ALTER TABLE t CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN;", true, "ALTER TABLE `t` CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN"},
 		{"ALTER TABLE t CONVERT TO CHARSET UTF8 COLLATE UTF8_BIN;", true, "ALTER TABLE `t` CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN"},
 		{"ALTER TABLE t FORCE", true, "ALTER TABLE `t` FORCE /* AlterTableForce is not supported */ "},
@@ -2149,10 +2158,10 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
 		// for create user
-		// {`CREATE USER 'ttt' REQUIRE X509;`, true, "CREATE USER `ttt`@`%` REQUIRE X509"},
-		// {`CREATE USER 'ttt' REQUIRE SSL;`, true, "CREATE USER `ttt`@`%` REQUIRE SSL"},
-		// {`CREATE USER 'ttt' REQUIRE NONE;`, true, "CREATE USER `ttt`@`%` REQUIRE NONE"},
-		// {`CREATE USER 'ttt' REQUIRE ISSUER '/C=SE/ST=Stockholm/L=Stockholm/O=MySQL/CN=CA/emailAddress=ca@example.com' AND CIPHER 'EDH-RSA-DES-CBC3-SHA';`, true, "CREATE USER `ttt`@`%` REQUIRE ISSUER '/C=SE/ST=Stockholm/L=Stockholm/O=MySQL/CN=CA/emailAddress=ca@example.com' AND CIPHER 'EDH-RSA-DES-CBC3-SHA'"},
+		{`CREATE USER 'ttt' REQUIRE X509;`, true, "CREATE USER `ttt`@`%` REQUIRE X509"},
+		{`CREATE USER 'ttt' REQUIRE SSL;`, true, "CREATE USER `ttt`@`%` REQUIRE SSL"},
+		{`CREATE USER 'ttt' REQUIRE NONE;`, true, "CREATE USER `ttt`@`%` REQUIRE NONE"},
+		{`CREATE USER 'ttt' REQUIRE ISSUER '/C=SE/ST=Stockholm/L=Stockholm/O=MySQL/CN=CA/emailAddress=ca@example.com' AND CIPHER 'EDH-RSA-DES-CBC3-SHA';`, true, "CREATE USER `ttt`@`%` REQUIRE ISSUER '/C=SE/ST=Stockholm/L=Stockholm/O=MySQL/CN=CA/emailAddress=ca@example.com' AND CIPHER 'EDH-RSA-DES-CBC3-SHA'"},
 		// {`CREATE USER 'ttt' WITH MAX_QUERIES_PER_HOUR 2;`, true, "CREATE USER `ttt`@`%` WITH MAX_QUERIES_PER_HOUR 2"},
 		// {`CREATE USER 'ttt'@'localhost' REQUIRE NONE WITH MAX_QUERIES_PER_HOUR 1 MAX_UPDATES_PER_HOUR 10 PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK;`, true, "CREATE USER `ttt`@`localhost` REQUIRE NONE WITH MAX_QUERIES_PER_HOUR 1 MAX_UPDATES_PER_HOUR 10 PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK"},
 		// {`CREATE USER 'u1'@'%' IDENTIFIED WITH 'mysql_native_password' AS '' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK ;`, true, "CREATE USER `u1`@`%` IDENTIFIED BY PASSWORD '' REQUIRE NONE PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK"},
@@ -2629,28 +2638,222 @@ func (s *testParserSuite) TestView(c *C) {
 	c.Assert(v.Algorithm, Equals, model.AlgorithmUndefined)
 	c.Assert(v.Select.Text(), Equals, "select * from t")
 	c.Assert(v.Security, Equals, model.SecurityDefiner)
-	// c.Assert(v.CheckOption, Equals, model.CheckOptionCascaded)
-
-	// src := `CREATE OR REPLACE ALGORITHM = UNDEFINED DEFINER = root@localhost
-	//               SQL SECURITY DEFINER
-	// 		      VIEW V(a,b,c) AS select c,d,e from t
-	//               WITH CASCADED CHECK OPTION;`
-
-	// var st ast.StmtNode
-	// st, err = p.ParseOneStmt(src, "", "")
-	// c.Assert(err, IsNil)
-	// v, ok = st.(*ast.CreateViewStmt)
-	// c.Assert(ok, IsTrue)
-	// c.Assert(v.OrReplace, IsTrue)
-	// c.Assert(v.Algorithm, Equals, model.AlgorithmUndefined)
-	// c.Assert(v.Definer.Username, Equals, "root")
-	// c.Assert(v.Definer.Hostname, Equals, "localhost")
-	// c.Assert(v.Cols[0], Equals, model.NewCIStr("a"))
-	// c.Assert(v.Cols[1], Equals, model.NewCIStr("b"))
-	// c.Assert(v.Cols[2], Equals, model.NewCIStr("c"))
-	// c.Assert(v.Select.Text(), Equals, "select c,d,e from t")
-	// c.Assert(v.Security, Equals, model.SecurityDefiner)
-	// c.Assert(v.CheckOption, Equals, model.CheckOptionCascaded)
+	c.Assert(v.CheckOption, Equals, model.CheckOptionCascaded)
+	c.Assert(v.Definer.CurrentUser, IsTrue)
+
+	src := `CREATE OR REPLACE ALGORITHM = UNDEFINED DEFINER = root@localhost
+	              SQL SECURITY DEFINER
+			      VIEW V(a,b,c) AS select c,d,e from t
+	              WITH CASCADED CHECK OPTION;`
+
+	var st ast.StmtNode
+	st, err = p.ParseOneStmt(src, "", "")
+	c.Assert(err, IsNil)
+	v, ok = st.(*ast.CreateViewStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(v.OrReplace, IsTrue)
+	c.Assert(v.Algorithm, Equals, model.AlgorithmUndefined)
+	c.Assert(v.Definer.Username, Equals, "root")
+	c.Assert(v.Definer.Hostname, Equals, "localhost")
+	c.Assert(v.Cols[0], Equals, model.NewCIStr("a"))
+	c.Assert(v.Cols[1], Equals, model.NewCIStr("b"))
+	c.Assert(v.Cols[2], Equals, model.NewCIStr("c"))
+	c.Assert(v.Select.Text(), Equals, "select c,d,e from t")
+	c.Assert(v.Security, Equals, model.SecurityDefiner)
+	c.Assert(v.CheckOption, Equals, model.CheckOptionCascaded)
+
+	st, err = p.ParseOneStmt("create algorithm = temptable sql security invoker view v as select * from t with local check option", "", "")
+	c.Assert(err, IsNil)
+	v = st.(*ast.CreateViewStmt)
+	c.Assert(v.Algorithm, Equals, model.AlgorithmTemptable)
+	c.Assert(v.Security, Equals, model.SecurityInvoker)
+	c.Assert(v.CheckOption, Equals, model.CheckOptionLocal)
+	c.Assert(v.Select.Text(), Equals, "select * from t")
+}
+
+func (s *testParserSuite) TestStoredProgram(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"create procedure p1() select 1", true, "CREATE PROCEDURE `p1`() SELECT 1"},
+		{"create procedure p1(in a int, out b int) begin set b = a; end", true, ""},
+		{"create definer = current_user procedure p1() begin end", true, ""},
+		{"create or replace procedure p1() select 1", false, ""},
+		{"create algorithm = merge procedure p1() select 1", false, ""},
+		{"create procedure p1(a int) begin declare b int default 0; while b < a do set b = b + 1; end while; end", true, ""},
+		{"create procedure p1() begin if 1 then select 1; end; end", false, ""},
+		// CALL
+		{"create procedure p1() begin call p2(); call test.p3(1, 'a'); call p4; end", true, ""},
+		// SIGNAL
+		{"create procedure p1(a int) begin if a < 0 then signal sqlstate '45000' set message_text = 'invalid', mysql_errno = 1644; end if; end", true, ""},
+		{"create procedure p1() begin signal sqlstate value '45000'; signal not_found; end", true, ""},
+		{"create procedure p1() begin signal; end", false, ""},
+		// SELECT ... INTO
+		{"create procedure p1(out n int) begin select count(*) into n from t1 where id > 1; end", true, ""},
+		{"create procedure p1() begin declare a, b int; select id, c1 from t1 order by id limit 1 into a, b; end", true, ""},
+		{"create procedure p1() begin declare a int; select 1 into a; end", true, ""},
+		// CASE statement
+		{"create procedure p1(a int) begin case a when 1 then select 1; when 2 then select 2; select 3; else begin end; end case; end", true, ""},
+		{"create procedure p1(a int) begin case when a > 1 then set @b = case a when 2 then 1 else 0 end; end case; end", true, ""},
+		{"create procedure p1(a int) begin case a else select 1; end case; end", false, ""},
+		{"create procedure p1(a int) begin case a when 1 then select 1; end; end", false, ""},
+		{"create function f1(a int) returns varchar(10) return concat(a, 'x')", true, ""},
+		{"create function f1(a int) return a", false, ""},
+		{"create function f1(in a int) returns int return a", false, ""},
+		{"create trigger tr1 after update on t1 for each row begin insert into t2 values (1); end", true, ""},
+		{"create trigger tr1 before delete on test.t1 for each row precedes tr0 delete from t2", true, ""},
+		{"create trigger tr1 before select on t1 for each row delete from t2", false, ""},
+		// SET NEW.col
+		{"create trigger tr before update on t for each row begin if new.a < 0 then set new.a = 0; end if; end", true, ""},
+		{"create trigger tr before insert on t for each row set new.c1 = concat(old.c1, 'x')", true, ""},
+		{"create trigger tr before insert on t for each row set t.c1 = 1", false, ""},
+		{"create event e1 on schedule every 1 hour do delete from t1", true, ""},
+		{"create event e1 on schedule every 1 hour starts now() ends now() + interval 1 day do delete from t1", true, ""},
+		{"create event e1 do delete from t1", false, ""},
+		{"drop procedure p1", true, ""},
+		{"drop function if exists f1", true, ""},
+		{"drop trigger test.tr1", true, ""},
+		{"drop event if exists e1", true, ""},
+		{"drop procedure", false, ""},
+	}
+	s.RunTest(c, table)
+
+	p := New()
+	st, err := p.ParseOneStmt("create definer = 'root'@'localhost' procedure test.p1(inout a int) sql security invoker begin set a = a + 1; end", "", "")
+	c.Assert(err, IsNil)
+	r, ok := st.(*ast.CreateRoutineStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(r.Tp, Equals, ast.RoutineProcedure)
+	c.Assert(r.Definer.Username, Equals, "root")
+	c.Assert(r.Definer.Hostname, Equals, "localhost")
+	c.Assert(r.Name.Schema.O, Equals, "test")
+	c.Assert(r.Name.Name.O, Equals, "p1")
+	c.Assert(r.Params, HasLen, 1)
+	c.Assert(r.Params[0].Mode, Equals, ast.ParamModeInOut)
+	c.Assert(r.Options, HasLen, 1)
+	c.Assert(r.Options[0].Security, Equals, model.SecurityInvoker)
+	c.Assert(r.Body.(*ast.BlockStmt).Stmts, HasLen, 1)
+
+	st, err = p.ParseOneStmt("create trigger tr1 after insert on t1 for each row follows tr0 delete from t2", "", "")
+	c.Assert(err, IsNil)
+	t, ok := st.(*ast.CreateTriggerStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(t.Definer.CurrentUser, IsTrue)
+	c.Assert(t.Time, Equals, ast.TriggerAfter)
+	c.Assert(t.Event, Equals, ast.TriggerInsert)
+	c.Assert(t.Table.Name.O, Equals, "t1")
+	c.Assert(t.Follows, IsTrue)
+	c.Assert(t.OtherTrigger, Equals, "tr0")
+
+	st, err = p.ParseOneStmt("drop event if exists e1", "", "")
+	c.Assert(err, IsNil)
+	d, ok := st.(*ast.DropRoutineStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(d.Tp, Equals, ast.RoutineEvent)
+	c.Assert(d.IfExists, IsTrue)
+}
+
+func (s *testParserSuite) TestMySQL80Syntax(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		// common table expressions
+		{"with cte as (select 1) select * from cte", true, ""},
+		{"with cte(a, b) as (select 1, 2), cte2 as (select a from cte) select * from cte2", true, ""},
+		{"with recursive cte(n) as (select 1 union all select n + 1 from cte where n < 5) select * from cte", true, ""},
+		{"with cte as (select 1) select * from cte union select 2", true, ""},
+		{"explain with cte as (select 1) select * from cte", true, ""},
+		{"with cte as select 1 select * from cte", false, ""},
+		{"insert into t1 with cte as (select 1) select * from cte", true, ""},
+		{"insert into t1(a) with cte as (select 1) select * from cte union select 2", true, ""},
+		{"with cte as (select 1) update t1 join cte set t1.a = 1", true, ""},
+		{"with cte as (select a from t2) delete from t1 where a in (select a from cte)", true, ""},
+		{"with cte as (select 1) delete t1 from t1 join cte", true, ""},
+		{"select * from (with cte as (select 1) select * from cte) dt", true, ""},
+		{"select * from t1 where a in (with cte as (select 1) select * from cte)", true, ""},
+		{"explain with cte as (select 1) update t1 set a = 1", true, ""},
+		{"with cte as (select 1) with cte2 as (select 2) update t1 set a = 1", false, ""},
+		// window functions
+		{"select row_number() over (partition by a order by b) from t", true, ""},
+		{"select a, sum(b) over w, rank() over (w order by c) from t window w as (partition by a)", true, ""},
+		{"select count(*) over () from t", true, ""},
+		{"select avg(a) over (order by b rows between unbounded preceding and current row) from t", true, ""},
+		{"select sum(a) over (order by b range between interval 1 day preceding and 1 following) from t", true, ""},
+		{"select lag(a, 1) over (order by b rows 2 preceding) from t", true, ""},
+		{"select sum(a) over (rows between b preceding and current row) from t", false, ""},
+		// check constraints
+		{"create table t (a int check (a > 0))", true, ""},
+		{"create table t (a int constraint c1 check (a > 0) not enforced not null)", true, ""},
+		{"create table t (a int check (a > 0) not null)", true, ""},
+		{"create table t (a int, constraint c1 check (a > 0) not enforced)", true, ""},
+		{"create table t (a int, check (a > 0) enforced)", true, ""},
+		{"alter table t add constraint c2 check (a > 1)", true, ""},
+		{"alter table t drop check c1", true, ""},
+		{"alter table t alter check c1 not enforced", true, ""},
+		// invisible columns and indexes
+		{"create table t (a int invisible, b int visible, index idx(a) invisible)", true, ""},
+		{"alter table t alter index idx invisible", true, ""},
+		{"alter table t alter column a set invisible", true, ""},
+		{"alter table t alter column a set default 1", true, ""},
+		// functional key parts
+		{"create table t (a int, b varchar(10), index idx((a + 1), b))", true, ""},
+		{"create index idx on t ((lower(b)))", true, ""},
+		{"alter table t add index idx((lower(b)))", true, ""},
+		// algorithm and rename column
+		{"alter table t add column c int, algorithm = instant", true, ""},
+		{"alter table t add column c int, algorithm = nocopy", false, ""},
+		{"alter table t rename column a to b", true, ""},
+		{"alter table t rename column a", false, ""},
+	}
+	s.RunTest(c, table)
+
+	p := New()
+	st, err := p.ParseOneStmt("with recursive cte(n) as (select 1) select * from cte", "", "")
+	c.Assert(err, IsNil)
+	sel, ok := st.(*ast.SelectStmt)
+	c.Assert(ok, IsTrue)
+	c.Assert(sel.With, NotNil)
+	c.Assert(sel.With.IsRecursive, IsTrue)
+	c.Assert(sel.With.CTEs, HasLen, 1)
+	c.Assert(sel.With.CTEs[0].Name.O, Equals, "cte")
+	c.Assert(sel.With.CTEs[0].ColNameList, HasLen, 1)
+
+	st, err = p.ParseOneStmt("with cte as (select 1) update t1 set a = 1", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.UpdateStmt).With.CTEs[0].Name.O, Equals, "cte")
+	st, err = p.ParseOneStmt("with cte as (select 1) delete from t1", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.DeleteStmt).With.CTEs[0].Name.O, Equals, "cte")
+	st, err = p.ParseOneStmt("insert into t1 with cte as (select 1) select * from cte", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(st.(*ast.InsertStmt).Select.(*ast.SelectStmt).With, NotNil)
+
+	st, err = p.ParseOneStmt("select sum(a) over w from t window w as (partition by b)", "", "")
+	c.Assert(err, IsNil)
+	sel = st.(*ast.SelectStmt)
+	c.Assert(sel.WindowSpecs, HasLen, 1)
+	c.Assert(sel.WindowSpecs[0].Name.O, Equals, "w")
+	w, ok := sel.Fields.Fields[0].Expr.(*ast.WindowFuncExpr)
+	c.Assert(ok, IsTrue)
+	c.Assert(w.F, Equals, "sum")
+	c.Assert(w.Spec.OnlyAlias, IsTrue)
+
+	st, err = p.ParseOneStmt("create table t (a int check (a > 0) not null, b int invisible, index idx((a + 1)))", "", "")
+	c.Assert(err, IsNil)
+	ct := st.(*ast.CreateTableStmt)
+	c.Assert(ct.Cols[0].Options, HasLen, 2)
+	c.Assert(ct.Cols[0].Options[0].Tp, Equals, ast.ColumnOptionCheck)
+	c.Assert(ct.Cols[0].Options[0].Enforced, IsTrue)
+	c.Assert(ct.Cols[0].Options[1].Tp, Equals, ast.ColumnOptionNotNull)
+	c.Assert(ct.Cols[1].Options[0].Tp, Equals, ast.ColumnOptionInvisible)
+	c.Assert(ct.Constraints[0].Keys[0].Column, IsNil)
+	c.Assert(ct.Constraints[0].Keys[0].Expr, NotNil)
+
+	st, err = p.ParseOneStmt("alter table t rename column a to b, algorithm = instant", "", "")
+	c.Assert(err, IsNil)
+	at := st.(*ast.AlterTableStmt)
+	c.Assert(at.Specs[0].Tp, Equals, ast.AlterTableRenameColumn)
+	c.Assert(at.Specs[0].OldColumnName.Name.O, Equals, "a")
+	c.Assert(at.Specs[0].NewColumnName.Name.O, Equals, "b")
+	c.Assert(at.Specs[1].Algorithm, Equals, ast.AlgorithmTypeInstant)
 }
 
 func (s *testParserSuite) TestTimestampDiffUnit(c *C) {
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pingcap/errors"
+)
+
+// DefaultDelimiter is the statement delimiter used until a DELIMITER command changes it.
+const DefaultDelimiter = ";"
+
+// Segment is a statement cut from a script by Split.
+type Segment struct {
+	// Text is the statement text, without the trailing delimiter.
+	Text string
+	// Start is the position of the first byte of Text in the script,
+	// End is the position right after the last byte of Text.
+	// Line and Col are 1-based, Col counts characters.
+	Start Pos
+	End   Pos
+}
+
+// PosAt returns the position in the script of the byte at offset i of Text.
+func (seg *Segment) PosAt(i int) Pos {
+	t := posTracker{s: seg.Text, p: Pos{Line: seg.Start.Line, Col: seg.Start.Col}}
+	p := t.at(i)
+	p.Offset += seg.Start.Offset
+	return p
+}
+
+// posTracker converts byte offsets to line and column.
+// The offsets must be passed in non-decreasing order.
+type posTracker struct {
+	s string
+	p Pos
+}
+
+func (t *posTracker) at(offset int) Pos {
+	for t.p.Offset < offset && t.p.Offset < len(t.s) {
+		ch, w := utf8.DecodeRuneInString(t.s[t.p.Offset:])
+		if ch == '\n' {
+			t.p.Line++
+			t.p.Col = 1
+		} else {
+			t.p.Col++
+		}
+		t.p.Offset += w
+	}
+	return t.p
+}
+
+// Split splits a script into statements with the lexer, so the delimiter in
+// strings, quoted identifiers and comments is ignored. The mysql client command
+// "DELIMITER xx" changes the delimiter of the following statements, and is not
+// returned as a statement. Comments before a statement are kept in its text,
+// segments which only contain comments are dropped.
+// When the default delimiter is used, the delimiters in the BEGIN ... END
+// blocks of CREATE PROCEDURE/FUNCTION/TRIGGER/EVENT are ignored too.
+func Split(sql string) ([]Segment, error) {
+	var (
+		segs      []Segment
+		delimiter = DefaultDelimiter
+		s         = NewScanner(sql)
+		t         = posTracker{s: sql, p: Pos{Line: 1, Col: 1}}
+
+		// begin is the offset after the last delimiter
+		begin    int
+		hasToken bool
+
+		// routine is true when the statement creates a stored program,
+		// decided is true once the type of the statement is known.
+		routine, decided bool
+		// depth is the nesting level of BEGIN ... END and CASE ... END,
+		// pendingEnd is true when the last token is END.
+		depth      int
+		pendingEnd bool
+	)
+
+	appendSegment := func(end int) {
+		if !hasToken {
+			return
+		}
+		text := strings.TrimRightFunc(sql[begin:end], unicode.IsSpace)
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		start := begin + len(text) - len(trimmed)
+		segs = append(segs, Segment{
+			Text:  trimmed,
+			Start: t.at(start),
+			End:   t.at(begin + len(text)),
+		})
+	}
+
+	// restart scans from the offset.
+	restart := func(offset int) {
+		s.r.p = Pos{Offset: offset}
+		s.specialComment = nil
+		begin = offset
+		hasToken = false
+		routine, decided = false, false
+		depth, pendingEnd = 0, false
+	}
+
+	for {
+		tok, pos, _ := s.scan()
+		if tok == 0 {
+			break
+		}
+
+		// The token comes from /*! ... */ or /*+ ... */, which is a part of
+		// the statement and never contains the delimiter.
+		if s.specialComment != nil {
+			hasToken = true
+			continue
+		}
+
+		end := s.r.p.Offset
+		if end <= pos.Offset {
+			// invalid character, force advance the lexer
+			s.r.peek()
+			s.r.inc()
+			end = s.r.p.Offset
+		}
+		raw := sql[pos.Offset:end]
+
+		if !hasToken && tok == identifier && strings.EqualFold(raw, "delimiter") {
+			line := sql[end:]
+			if i := strings.IndexByte(line, '\n'); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				return nil, errors.Errorf("DELIMITER must be followed by a 'delimiter' character or string at line %d",
+					t.at(pos.Offset).Line)
+			}
+			delimiter = fields[0]
+			restart(end + len(line))
+			continue
+		}
+
+		word := ""
+		if tok == identifier {
+			word = strings.ToUpper(raw)
+		}
+		if pendingEnd {
+			// END IF, END WHILE, END LOOP and END REPEAT do not close a block,
+			// the CASE of END CASE closes the CASE statement and opens nothing.
+			pendingEnd = false
+			switch word {
+			case "IF", "WHILE", "LOOP", "REPEAT":
+			case "CASE":
+				depth--
+				word = ""
+			default:
+				depth--
+			}
+		}
+		switch {
+		case !hasToken:
+			decided = word != "CREATE"
+		case !decided:
+			switch word {
+			case "PROCEDURE", "FUNCTION", "TRIGGER", "EVENT":
+				routine, decided = true, true
+			case "TABLE", "VIEW", "INDEX", "DATABASE", "SCHEMA", "USER":
+				decided = true
+			}
+		case routine:
+			switch word {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				pendingEnd = depth > 0
+			}
+		}
+
+		// The delimiter may start in the middle of a token (END$$),
+		// or span several tokens (//).
+		// The default delimiter in a compound statement is a part of it.
+		inBlock := depth > 0 && delimiter == DefaultDelimiter
+		found := -1
+		if !inBlock && !strings.ContainsAny(raw, "'\"`") {
+			for i := pos.Offset; i < end; i++ {
+				if strings.HasPrefix(sql[i:], delimiter) {
+					found = i
+					break
+				}
+			}
+		}
+		if found < 0 {
+			hasToken = true
+			continue
+		}
+
+		if found > pos.Offset {
+			hasToken = true
+		}
+		appendSegment(found)
+		restart(found + len(delimiter))
+	}
+
+	appendSegment(len(sql))
+	return segs, nil
+}
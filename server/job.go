@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/hanchuanchuan/inception-core/session"
+	"github.com/pingcap/errors"
+)
+
+// 异步任务状态
+const (
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobFailed   = "failed"
+)
+
+// Job 异步执行任务
+type Job struct {
+	ID     string
+	Status string
+	// 连接ID,对应/processlist中的ID
+	ConnID uint64
+
+	// 当前操作状态及进度,来自ProcessInfo
+	OperState string
+	Percent   float64
+	// 限流状态,来自ProcessInfo
+	ThrottleState string `json:",omitempty"`
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	Error   string           `json:",omitempty"`
+	Records []session.Record `json:",omitempty"`
+
+	mu     sync.Mutex
+	sess   session.Session
+	cancel context.CancelFunc
+}
+
+// snapshot 返回任务当前状态,运行中的任务同时更新进度
+func (j *Job) snapshot() *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.Status == JobRunning {
+		pi := j.sess.ShowProcess()
+		j.OperState = pi.OperState
+		j.Percent = pi.Percent
+		j.ThrottleState = pi.ThrottleState
+	}
+
+	return &Job{
+		ID:            j.ID,
+		Status:        j.Status,
+		ConnID:        j.ConnID,
+		OperState:     j.OperState,
+		Percent:       j.Percent,
+		ThrottleState: j.ThrottleState,
+		StartTime:     j.StartTime,
+		EndTime:       j.EndTime,
+		Error:         j.Error,
+		Records:       j.Records,
+	}
+}
+
+// startJob 后台执行SQL,返回任务信息
+func (s *Server) startJob(ctx context.Context, sess session.Session, done func(), sql string) *Job {
+	id, _ := uuid.NewV4()
+	ctx, cancel := context.WithCancel(ctx)
+	job := &Job{
+		ID:        id.String(),
+		Status:    JobRunning,
+		ConnID:    sess.ShowProcess().ID,
+		StartTime: time.Now(),
+		sess:      sess,
+		cancel:    cancel,
+	}
+
+	s.mu.Lock()
+	s.purgeJobs()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go func() {
+		defer done()
+		defer cancel()
+
+		result, err := sess.RunExecute(ctx, sql)
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+
+		pi := sess.ShowProcess()
+		job.OperState = pi.OperState
+		job.Percent = pi.Percent
+		job.ThrottleState = pi.ThrottleState
+		job.EndTime = time.Now()
+		job.Records = result
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = JobFinished
+		}
+	}()
+
+	return job
+}
+
+// purgeJobs 清理过期的已完成任务,调用方需持有锁
+func (s *Server) purgeJobs() {
+	now := time.Now()
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		expired := job.Status != JobRunning && now.Sub(job.EndTime) > s.JobExpire
+		job.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// handleJob 任务查询及终止: GET /jobs/{id}, POST /jobs/{id}/kill
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+
+	s.mu.RLock()
+	job, ok := s.jobs[parts[0]]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+	case len(parts) == 2 && parts[1] == "kill" && r.Method == http.MethodPost:
+		job.cancel()
+	default:
+		writeError(w, http.StatusNotFound, errors.New("invalid job path"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &Response{Data: job.snapshot()})
+}
@@ -0,0 +1,349 @@
+// Package server 提供HTTP/JSON审核服务.
+//
+// 接口列表:
+//
+//	POST /audit                     审核,对应Session.Audit
+//	POST /execute                   执行,对应Session.RunExecute. Async为true时返回任务ID
+//	POST /split                     拆分,对应Session.Split
+//	POST /print                     打印语法树,对应Session.Print
+//	GET  /processlist               进程列表
+//	GET  /jobs/{id}                 查询异步任务进度及结果
+//	POST /jobs/{id}/kill            终止异步任务
+//	GET  /osc                       osc进程列表
+//	POST /osc/{sha1}/{kill|pause|resume}  osc进程操作
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/session"
+	"github.com/hanchuanchuan/inception-core/util"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// 请求体最大长度
+const maxRequestSize = 64 << 20
+
+// Request 请求参数. 数据源及审核参数同session.SourceOptions
+type Request struct {
+	session.SourceOptions
+
+	// 审核/执行的SQL
+	SQL string
+	// 异步执行,仅/execute支持. 为true时立即返回任务ID,通过/jobs/{id}查询进度
+	Async bool
+}
+
+// Response 返回结果
+type Response struct {
+	// 错误信息,为空时表示请求成功
+	Error string `json:",omitempty"`
+	// 警告信息,如osc进程已终止
+	Warning string `json:",omitempty"`
+	// 返回数据,类型视接口而定:
+	// []session.Record, []session.SplitRecord, []session.PrintRecord,
+	// []util.ProcessInfo, []*util.OscProcessInfo 或 *Job
+	Data interface{} `json:",omitempty"`
+}
+
+// process 正在运行的会话
+type process struct {
+	sess   session.Session
+	cancel context.CancelFunc
+}
+
+// Server HTTP审核服务,同时实现util.SessionManager以支持进程列表和kill
+type Server struct {
+	mux *http.ServeMux
+
+	mu        sync.RWMutex
+	processes map[uint64]*process
+	jobs      map[string]*Job
+
+	// 异步任务完成后保留多久
+	JobExpire time.Duration
+}
+
+// New 创建HTTP审核服务
+func New() *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		processes: make(map[uint64]*process),
+		jobs:      make(map[string]*Job),
+		JobExpire: time.Hour,
+	}
+
+	s.mux.HandleFunc("/audit", s.handleAudit)
+	s.mux.HandleFunc("/execute", s.handleExecute)
+	s.mux.HandleFunc("/split", s.handleSplit)
+	s.mux.HandleFunc("/print", s.handlePrint)
+	s.mux.HandleFunc("/processlist", s.handleProcessList)
+	s.mux.HandleFunc("/jobs/", s.handleJob)
+	s.mux.HandleFunc("/osc", s.handleOscList)
+	s.mux.HandleFunc("/osc/", s.handleOsc)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe 监听指定地址并提供服务
+func (s *Server) ListenAndServe(addr string) error {
+	log.Infof("http server listening on %s", addr)
+	return http.ListenAndServe(addr, s)
+}
+
+// ShowProcessList implements util.SessionManager.
+func (s *Server) ShowProcessList() map[uint64]util.ProcessInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs := make(map[uint64]util.ProcessInfo, len(s.processes))
+	for id, p := range s.processes {
+		rs[id] = p.sess.ShowProcess()
+	}
+	return rs
+}
+
+// Kill implements util.SessionManager.
+func (s *Server) Kill(connectionID uint64, query bool) {
+	s.mu.RLock()
+	p, ok := s.processes[connectionID]
+	s.mu.RUnlock()
+	if ok {
+		log.Infof("con:%d killed", connectionID)
+		p.cancel()
+	}
+}
+
+// AddOscProcess implements util.SessionManager.
+func (s *Server) AddOscProcess(p *util.OscProcessInfo) {
+	config.GetGlobalConfig().AddOscProcess(p)
+}
+
+// ShowOscProcessList implements util.SessionManager.
+func (s *Server) ShowOscProcessList() map[string]*util.OscProcessInfo {
+	return config.GetGlobalConfig().ShowOscProcessList()
+}
+
+// newSession 创建会话并加入进程列表,返回的函数用以结束会话
+func (s *Server) newSession(req *Request) (session.Session, context.Context, func(), error) {
+	sess := session.NewInception()
+	if err := sess.LoadOptions(req.SourceOptions); err != nil {
+		return nil, nil, nil, err
+	}
+	sess.SetSessionManager(s)
+	sess.SetProcessInfo(req.SQL, time.Now(), mysql.ComQuery)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	id := sess.ShowProcess().ID
+
+	s.mu.Lock()
+	s.processes[id] = &process{sess: sess, cancel: cancel}
+	s.mu.Unlock()
+
+	done := func() {
+		cancel()
+		s.mu.Lock()
+		delete(s.processes, id)
+		s.mu.Unlock()
+	}
+	return sess, ctx, done, nil
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess, ctx, done, err := s.newSession(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer done()
+
+	result, err := sess.Audit(ctx, req.SQL)
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleExecute(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess, ctx, done, err := s.newSession(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Async {
+		job := s.startJob(ctx, sess, done, req.SQL)
+		writeJSON(w, http.StatusAccepted, &Response{Data: job.snapshot()})
+		return
+	}
+
+	defer done()
+	result, err := sess.RunExecute(ctx, req.SQL)
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleSplit(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess, ctx, done, err := s.newSession(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer done()
+
+	result, err := sess.Split(ctx, req.SQL)
+	writeResult(w, result, err)
+}
+
+func (s *Server) handlePrint(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	sess, ctx, done, err := s.newSession(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer done()
+
+	result, err := sess.Print(ctx, req.SQL)
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleProcessList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	pl := s.ShowProcessList()
+	keys := make([]int, 0, len(pl))
+	for k := range pl {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+
+	list := make([]util.ProcessInfo, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, pl[uint64(k)])
+	}
+	writeJSON(w, http.StatusOK, &Response{Data: list})
+}
+
+func (s *Server) handleOscList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	pl := s.ShowOscProcessList()
+	list := make([]*util.OscProcessInfo, 0, len(pl))
+	for _, pi := range pl {
+		list = append(list, pi)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeJSON(w, http.StatusOK, &Response{Data: list})
+}
+
+// handleOsc osc进程操作: /osc/{sha1}/{kill|pause|resume}
+func (s *Server) handleOsc(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/osc/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		writeError(w, http.StatusNotFound, errors.New("invalid osc path, expect /osc/{sha1}/{kill|pause|resume}"))
+		return
+	}
+
+	var fn func(string) (string, error)
+	switch parts[1] {
+	case "kill":
+		fn = session.KillOscProcess
+	case "pause":
+		fn = session.PauseOscProcess
+	case "resume":
+		fn = session.ResumeOscProcess
+	default:
+		writeError(w, http.StatusNotFound, errors.Errorf("unknown osc command: %s", parts[1]))
+		return
+	}
+
+	warn, err := fn(parts[0])
+	if err == session.ErrOscNotFound {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := &Response{}
+	resp.Warning = warn
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// readRequest 解析POST请求参数,失败时直接返回错误信息
+func readRequest(w http.ResponseWriter, r *http.Request) (*Request, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return nil, false
+	}
+
+	req := &Request{}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err := dec.Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid request: %v", err))
+		return nil, false
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("SQL is empty"))
+		return nil, false
+	}
+	return req, true
+}
+
+// writeResult 返回审核结果. 审核过程出错时同时返回已有结果和错误信息
+func writeResult(w http.ResponseWriter, data interface{}, err error) {
+	resp := &Response{Data: data}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &Response{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	"github.com/hanchuanchuan/inception-core/util"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testServerSuite{})
+
+type testServerSuite struct {
+	server *Server
+}
+
+func TestServer(t *testing.T) {
+	TestingT(t)
+}
+
+const snapshot = `create database test_inc;
+use test_inc;
+create table t1(id int primary key comment 'id') comment 't1';`
+
+func (s *testServerSuite) SetUpSuite(c *C) {
+	config.GetGlobalConfig().Inc.Lang = "en-US"
+	s.server = New()
+}
+
+func (s *testServerSuite) do(c *C, method string, path string, req interface{}, data interface{}) (int, *Response) {
+	var body bytes.Buffer
+	if req != nil {
+		c.Assert(json.NewEncoder(&body).Encode(req), IsNil)
+	}
+	w := httptest.NewRecorder()
+	s.server.ServeHTTP(w, httptest.NewRequest(method, path, &body))
+
+	resp := &Response{Data: data}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), resp), IsNil, Commentf("%s", w.Body.String()))
+	return w.Code, resp
+}
+
+func (s *testServerSuite) request(sql string) *Request {
+	return &Request{
+		SourceOptions: session.SourceOptions{Offline: true, Snapshot: snapshot},
+		SQL:           sql,
+	}
+}
+
+func (s *testServerSuite) TestAudit(c *C) {
+	var records []session.Record
+	code, resp := s.do(c, http.MethodPost, "/audit",
+		s.request("use test_inc;insert into t1(id) values(1);insert into t2(id) values(1);"), &records)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(resp.Error, Equals, "")
+	c.Assert(records, HasLen, 3)
+	c.Assert(records[1].ErrLevel, Equals, uint8(0))
+	c.Assert(records[1].AffectedRows, Equals, 1)
+	c.Assert(records[2].ErrLevel, Equals, uint8(2))
+	c.Assert(records[2].Findings[0].Name, Equals, "er_table_not_existed_error")
+
+	code, resp = s.do(c, http.MethodGet, "/audit", nil, nil)
+	c.Assert(code, Equals, http.StatusMethodNotAllowed)
+	c.Assert(resp.Error, Not(Equals), "")
+
+	code, _ = s.do(c, http.MethodPost, "/audit", &Request{}, nil)
+	c.Assert(code, Equals, http.StatusBadRequest)
+
+	// 未配置数据源
+	code, resp = s.do(c, http.MethodPost, "/audit", &Request{SQL: "select 1"}, nil)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(resp.Error, Not(Equals), "")
+}
+
+func (s *testServerSuite) TestSplitAndPrint(c *C) {
+	var splits []session.SplitRecord
+	code, _ := s.do(c, http.MethodPost, "/split",
+		s.request("use test_inc;insert into t1(id) values(1);alter table t1 add column c1 int;"), &splits)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(splits, HasLen, 2)
+	c.Assert(splits[1].IsDDL, IsTrue)
+
+	var prints []session.PrintRecord
+	code, _ = s.do(c, http.MethodPost, "/print", s.request("select 1;"), &prints)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(prints, HasLen, 1)
+	c.Assert(prints[0].QueryTree, Not(Equals), "")
+}
+
+func (s *testServerSuite) TestExecuteJob(c *C) {
+	job := &Job{}
+	req := s.request("use test_inc;")
+	req.Async = true
+	code, _ := s.do(c, http.MethodPost, "/execute", req, job)
+	c.Assert(code, Equals, http.StatusAccepted)
+	c.Assert(job.ID, Not(Equals), "")
+	id := job.ID
+
+	// 离线模式不支持执行,任务失败
+	for i := 0; i < 100; i++ {
+		job = &Job{}
+		code, _ = s.do(c, http.MethodGet, "/jobs/"+id, nil, job)
+		c.Assert(code, Equals, http.StatusOK)
+		if job.Status != JobRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(job.Status, Equals, JobFailed)
+	c.Assert(job.Error, Not(Equals), "")
+
+	code, _ = s.do(c, http.MethodPost, "/jobs/"+job.ID+"/kill", nil, nil)
+	c.Assert(code, Equals, http.StatusOK)
+
+	code, _ = s.do(c, http.MethodGet, "/jobs/not_exists", nil, nil)
+	c.Assert(code, Equals, http.StatusNotFound)
+}
+
+func (s *testServerSuite) TestProcessList(c *C) {
+	var list []util.ProcessInfo
+	code, _ := s.do(c, http.MethodGet, "/processlist", nil, &list)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(list, HasLen, 0)
+
+	req := s.request("select 1")
+	sess, _, done, err := s.server.newSession(req)
+	c.Assert(err, IsNil)
+
+	code, _ = s.do(c, http.MethodGet, "/processlist", nil, &list)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(list, HasLen, 1)
+	c.Assert(list[0].ID, Equals, sess.ShowProcess().ID)
+	c.Assert(list[0].Info, Equals, "select 1")
+
+	done()
+	code, _ = s.do(c, http.MethodGet, "/processlist", nil, &list)
+	c.Assert(list, HasLen, 0)
+}
+
+func (s *testServerSuite) TestOsc(c *C) {
+	code, _ := s.do(c, http.MethodPost, "/osc/not_exists/kill", nil, nil)
+	c.Assert(code, Equals, http.StatusNotFound)
+	code, _ = s.do(c, http.MethodPost, "/osc/not_exists/stop", nil, nil)
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	s.server.AddOscProcess(&util.OscProcessInfo{ID: 1, Sqlsha1: "abc", IsGhost: true})
+	defer delete(s.server.ShowOscProcessList(), "abc")
+
+	var list []*util.OscProcessInfo
+	code, _ = s.do(c, http.MethodGet, "/osc", nil, &list)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(list, HasLen, 1)
+
+	code, resp := s.do(c, http.MethodPost, "/osc/abc/pause", nil, nil)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(resp.Warning, Equals, "")
+	c.Assert(s.server.ShowOscProcessList()["abc"].Pause, IsTrue)
+
+	code, resp = s.do(c, http.MethodPost, "/osc/abc/pause", nil, nil)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(resp.Warning, Not(Equals), "")
+
+	code, _ = s.do(c, http.MethodPost, "/osc/abc/resume", nil, nil)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.server.ShowOscProcessList()["abc"].Pause, IsFalse)
+
+	code, _ = s.do(c, http.MethodPost, "/osc/abc/kill", nil, nil)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.server.ShowOscProcessList()["abc"].Killed, IsTrue)
+}
@@ -0,0 +1,546 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/types"
+)
+
+// ALTER TABLE的执行算法
+const (
+	AlgorithmInstant = "INSTANT"
+	AlgorithmInplace = "INPLACE"
+	AlgorithmCopy    = "COPY"
+)
+
+// ALTER TABLE执行期间的锁级别
+const (
+	LockNone      = "NONE"
+	LockShared    = "SHARED"
+	LockExclusive = "EXCLUSIVE"
+)
+
+// DDLAlgorithm 预测的ALTER TABLE执行算法和锁级别.
+// 多个变更子句时按代价最高的子句计算
+type DDLAlgorithm struct {
+	// INSTANT,INPLACE或COPY
+	Algorithm string
+	// 是否重建表,COPY时总是重建
+	Rebuild bool
+	// NONE允许并发读写,SHARED仅允许读,EXCLUSIVE阻塞读写
+	Lock string
+}
+
+func (a *DDLAlgorithm) String() string {
+	if a.Rebuild && a.Algorithm == AlgorithmInplace {
+		return fmt.Sprintf("ALGORITHM=%s(rebuild), LOCK=%s", a.Algorithm, a.Lock)
+	}
+	return fmt.Sprintf("ALGORITHM=%s, LOCK=%s", a.Algorithm, a.Lock)
+}
+
+// isOnline 是否可以在不重建表,不阻塞读写的情况下完成
+func (a *DDLAlgorithm) isOnline() bool {
+	return a.Algorithm == AlgorithmInstant ||
+		a.Algorithm == AlgorithmInplace && !a.Rebuild && a.Lock == LockNone
+}
+
+// specAlgorithm 单个变更子句的执行算法.
+// 对INSTANT操作,rebuild表示使用INPLACE执行时是否重建表
+type specAlgorithm struct {
+	algorithm string
+	rebuild   bool
+	lock      string
+}
+
+var algorithmRank = map[string]int{
+	AlgorithmInstant: 0,
+	AlgorithmInplace: 1,
+	AlgorithmCopy:    2,
+}
+
+var lockRank = map[string]int{
+	LockNone:      0,
+	LockShared:    1,
+	LockExclusive: 2,
+}
+
+func instantOp(rebuild bool) specAlgorithm {
+	return specAlgorithm{AlgorithmInstant, rebuild, LockNone}
+}
+
+func inplaceOp(rebuild bool, lock string) specAlgorithm {
+	return specAlgorithm{AlgorithmInplace, rebuild, lock}
+}
+
+func copyOp() specAlgorithm {
+	return specAlgorithm{AlgorithmCopy, true, LockShared}
+}
+
+// combineAlgorithm 合并各子句的执行算法
+func combineAlgorithm(ops []specAlgorithm) *DDLAlgorithm {
+	a := &DDLAlgorithm{Algorithm: AlgorithmInstant, Lock: LockNone}
+	rebuild := false
+	for _, op := range ops {
+		if algorithmRank[op.algorithm] > algorithmRank[a.Algorithm] {
+			a.Algorithm = op.algorithm
+		}
+		if lockRank[op.lock] > lockRank[a.Lock] {
+			a.Lock = op.lock
+		}
+		rebuild = rebuild || op.rebuild
+	}
+	// 只要有一个子句不支持INSTANT,所有子句都以INPLACE或COPY方式执行
+	a.Rebuild = a.Algorithm == AlgorithmCopy || a.Algorithm == AlgorithmInplace && rebuild
+	return a
+}
+
+// supportInstant 是否支持ALGORITHM=INSTANT
+func (s *session) supportInstant() bool {
+	switch s.dbType {
+	case DBTypeMariaDB:
+		return s.dbVersion >= 100302
+	case DBTypeTiDB:
+		return false
+	default:
+		return s.dbVersion >= 80012
+	}
+}
+
+// instantAddColumn 添加列是否可以INSTANT执行
+func (s *session) instantAddColumn(last bool) bool {
+	switch s.dbType {
+	case DBTypeMariaDB:
+		return s.dbVersion >= 100400 || last && s.dbVersion >= 100302
+	default:
+		return s.dbVersion >= 80029 || last && s.dbVersion >= 80012
+	}
+}
+
+// instantDropColumn 删除列或调整列顺序是否可以INSTANT执行
+func (s *session) instantDropColumn() bool {
+	if s.dbType == DBTypeMariaDB {
+		return s.dbVersion >= 100400
+	}
+	return s.dbVersion >= 80029
+}
+
+// metadataOp 仅修改元数据的操作,如修改默认值,注释
+func (s *session) metadataOp() specAlgorithm {
+	if s.supportInstant() {
+		return instantOp(false)
+	}
+	return inplaceOp(false, LockNone)
+}
+
+// predictAlterSpecs 根据变更前的表结构预测各子句的执行算法和锁级别
+// https://dev.mysql.com/doc/refman/8.0/en/innodb-online-ddl-operations.html
+func (s *session) predictAlterSpecs(t *TableInfo, specs []*ast.AlterTableSpec) []specAlgorithm {
+	var ops []specAlgorithm
+	// 删除主键的同时添加主键时,可以INPLACE执行
+	addPrimary := false
+	for _, spec := range specs {
+		if spec.Tp == ast.AlterTableAddConstraint &&
+			spec.Constraint.Tp == ast.ConstraintPrimaryKey {
+			addPrimary = true
+		}
+	}
+
+	for _, spec := range specs {
+		if s.dbType == DBTypeTiDB {
+			ops = append(ops, s.predictTiDBSpec(t, spec))
+			continue
+		}
+
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			for _, col := range spec.NewColumns {
+				ops = append(ops, s.predictAddColumn(t, spec, col))
+			}
+		case ast.AlterTableDropColumn:
+			if s.instantDropColumn() {
+				ops = append(ops, instantOp(true))
+			} else {
+				ops = append(ops, inplaceOp(true, LockNone))
+			}
+		case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+			if len(spec.NewColumns) > 0 {
+				ops = append(ops, s.predictChangeColumn(t, spec))
+			}
+		case ast.AlterTableRenameColumn:
+			if s.dbType == DBTypeMysql && s.dbVersion >= 80028 {
+				ops = append(ops, instantOp(false))
+			} else {
+				ops = append(ops, inplaceOp(false, LockNone))
+			}
+		case ast.AlterTableAlterColumn:
+			ops = append(ops, s.metadataOp())
+
+		case ast.AlterTableAddConstraint:
+			ops = append(ops, s.predictAddConstraint(spec.Constraint))
+		case ast.AlterTableDropPrimaryKey:
+			if addPrimary {
+				ops = append(ops, inplaceOp(true, LockNone))
+			} else {
+				ops = append(ops, copyOp())
+			}
+		case ast.AlterTableDropIndex, ast.AlterTableDropForeignKey,
+			ast.AlterTableAlterCheck:
+			ops = append(ops, inplaceOp(false, LockNone))
+		case ast.AlterTableRenameIndex, ast.AlterTableIndexInvisible,
+			ast.AlterTableDropCheck:
+			ops = append(ops, s.metadataOp())
+
+		case ast.AlterTableOption:
+			for _, opt := range spec.Options {
+				ops = append(ops, s.predictTableOption(t, opt))
+			}
+		case ast.AlterTableForce:
+			ops = append(ops, inplaceOp(true, LockNone))
+		case ast.AlterTableRenameTable:
+			ops = append(ops, s.metadataOp())
+
+		case ast.AlterTableAddPartitions:
+			if t.isRangePartition() || t.isListPartition() {
+				ops = append(ops, inplaceOp(false, LockNone))
+			} else {
+				ops = append(ops, inplaceOp(false, LockShared))
+			}
+		case ast.AlterTableDropPartition, ast.AlterTableTruncatePartition,
+			ast.AlterTableExchangePartition:
+			ops = append(ops, inplaceOp(false, LockNone))
+		case ast.AlterTableCoalescePartitions, ast.AlterTableReorganizePartition:
+			ops = append(ops, inplaceOp(false, LockShared))
+		case ast.AlterTablePartition:
+			ops = append(ops, copyOp())
+		}
+	}
+	return ops
+}
+
+// predictTiDBSpec TiDB的DDL均为在线执行,仅添加索引和需要重写数据的列类型变更需要回填数据
+func (s *session) predictTiDBSpec(t *TableInfo, spec *ast.AlterTableSpec) specAlgorithm {
+	switch spec.Tp {
+	case ast.AlterTableAddConstraint:
+		switch spec.Constraint.Tp {
+		case ast.ConstraintForeignKey, ast.ConstraintCheck:
+		default:
+			return inplaceOp(false, LockNone)
+		}
+	case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+		if len(spec.NewColumns) > 0 {
+			op := s.predictChangeColumn(t, spec)
+			if op.algorithm == AlgorithmCopy {
+				return inplaceOp(true, LockNone)
+			}
+		}
+	}
+	return instantOp(false)
+}
+
+// predictAddColumn 预测添加列的执行算法
+func (s *session) predictAddColumn(t *TableInfo, spec *ast.AlterTableSpec,
+	col *ast.ColumnDef) specAlgorithm {
+	for _, op := range col.Options {
+		switch op.Tp {
+		case ast.ColumnOptionAutoIncrement:
+			return inplaceOp(true, LockShared)
+		case ast.ColumnOptionGenerated:
+			if op.Stored {
+				return copyOp()
+			}
+			if s.supportInstant() {
+				return instantOp(false)
+			}
+			return inplaceOp(false, LockNone)
+		case ast.ColumnOptionPrimaryKey, ast.ColumnOptionUniqKey:
+			return inplaceOp(true, LockNone)
+		}
+	}
+
+	last := spec.Position == nil || spec.Position.Tp == ast.ColumnPositionNone
+	// 8.0.29之前,压缩表不支持INSTANT添加列
+	if s.instantAddColumn(last) &&
+		(s.dbType != DBTypeMysql || s.dbVersion >= 80029 ||
+			!strings.EqualFold(s.tableRowFormat(t), "COMPRESSED")) {
+		return instantOp(true)
+	}
+	return inplaceOp(true, LockNone)
+}
+
+// predictAddConstraint 预测添加索引或约束的执行算法
+func (s *session) predictAddConstraint(constraint *ast.Constraint) specAlgorithm {
+	switch constraint.Tp {
+	case ast.ConstraintPrimaryKey:
+		return inplaceOp(true, LockNone)
+	case ast.ConstraintFulltext:
+		return inplaceOp(false, LockShared)
+	case ast.ConstraintSpatial:
+		if s.dbType == DBTypeMysql && s.dbVersion < 50700 {
+			return copyOp()
+		}
+		return inplaceOp(false, LockShared)
+	case ast.ConstraintForeignKey, ast.ConstraintCheck:
+		return copyOp()
+	}
+	return inplaceOp(false, LockNone)
+}
+
+// predictTableOption 预测修改表选项的执行算法
+func (s *session) predictTableOption(t *TableInfo, opt *ast.TableOption) specAlgorithm {
+	switch opt.Tp {
+	case ast.TableOptionComment, ast.TableOptionAutoIncrement,
+		ast.TableOptionStatsPersistent:
+		return inplaceOp(false, LockNone)
+	case ast.TableOptionEngine:
+		// 原表引擎按InnoDB处理,ENGINE=InnoDB常用于重建表
+		if !strings.EqualFold(opt.StrValue, "InnoDB") {
+			return copyOp()
+		}
+		return inplaceOp(true, LockNone)
+	case ast.TableOptionCharset:
+		if opt.UintValue == ast.TableOptionCharsetWithConvertTo {
+			return copyOp()
+		}
+		return inplaceOp(true, LockNone)
+	}
+	return inplaceOp(true, LockNone)
+}
+
+// alterFieldInfo 生成MODIFY/CHANGE之后的列信息,用以和原列对比
+func alterFieldInfo(col *ast.ColumnDef) *FieldInfo {
+	f := &FieldInfo{
+		Field:   col.Name.Name.O,
+		Type:    col.Tp.InfoSchemaStr(),
+		Null:    "YES",
+		Default: columnDefaultValue(col),
+	}
+	for _, op := range col.Options {
+		switch op.Tp {
+		case ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey:
+			f.Null = "NO"
+		case ast.ColumnOptionNull:
+			f.Null = "YES"
+		case ast.ColumnOptionAutoIncrement:
+			f.Extra = "auto_increment"
+		case ast.ColumnOptionComment:
+			f.Comment = op.Expr.GetDatum().GetString()
+		case ast.ColumnOptionCollate:
+			f.Collation = op.StrValue
+		}
+	}
+	if f.Collation == "" {
+		f.Collation = col.Tp.Collate
+	}
+	return f
+}
+
+// predictChangeColumn 对比原列和新的列定义,预测MODIFY/CHANGE的执行算法
+func (s *session) predictChangeColumn(t *TableInfo, spec *ast.AlterTableSpec) specAlgorithm {
+	col := spec.NewColumns[0]
+	name := col.Name.Name.L
+	if spec.OldColumnName != nil {
+		name = spec.OldColumnName.Name.L
+	}
+
+	var origin *FieldInfo
+	for i := range t.Fields {
+		if !t.Fields[i].IsDeleted && strings.EqualFold(t.Fields[i].Field, name) {
+			origin = &t.Fields[i]
+			break
+		}
+	}
+	if origin == nil {
+		return inplaceOp(true, LockNone)
+	}
+
+	f := alterFieldInfo(col)
+
+	// 字符集或排序规则变化,需要转换数据
+	if col.Tp.Charset != "" && col.Tp.Charset != "binary" && origin.Collation != "" &&
+		!strings.HasPrefix(strings.ToLower(origin.Collation), strings.ToLower(col.Tp.Charset)+"_") ||
+		f.Collation != "" && origin.Collation != "" &&
+			!strings.EqualFold(f.Collation, origin.Collation) {
+		return copyOp()
+	}
+	if normalizeColumnExtra(origin.Extra) != normalizeColumnExtra(f.Extra) &&
+		strings.Contains(normalizeColumnExtra(origin.Extra)+normalizeColumnExtra(f.Extra), "auto_increment") {
+		return copyOp()
+	}
+
+	op := s.predictColumnType(t, origin, col)
+	if op.algorithm == AlgorithmCopy {
+		return op
+	}
+
+	reorder := spec.Position != nil && spec.Position.Tp != ast.ColumnPositionNone
+	if reorder {
+		if s.instantDropColumn() {
+			op = combineSpec(op, instantOp(true))
+		} else {
+			op = combineSpec(op, inplaceOp(true, LockNone))
+		}
+	}
+	if !strings.EqualFold(origin.Null, f.Null) {
+		op = combineSpec(op, inplaceOp(true, LockNone))
+	}
+	return op
+}
+
+// predictColumnType 预测列类型变更的执行算法
+func (s *session) predictColumnType(t *TableInfo, origin *FieldInfo, col *ast.ColumnDef) specAlgorithm {
+	oldType := normalizeColumnType(origin.Type)
+	newType := normalizeColumnType(col.Tp.InfoSchemaStr())
+	if oldType == newType {
+		return s.metadataOp()
+	}
+
+	oldBase := strings.ToLower(GetDataTypeBase(oldType))
+	newBase := strings.ToLower(GetDataTypeBase(newType))
+	if oldBase != newBase {
+		return copyOp()
+	}
+
+	switch newBase {
+	case "varchar", "varbinary":
+		// 扩展VARCHAR长度且长度字节数不变时,仅修改元数据
+		oldLen := GetDataTypeLength(oldType)[0]
+		newLen := GetDataTypeLength(newType)[0]
+		mb := 1
+		if newBase == "varchar" {
+			mb = s.bytesPerChar(t, origin)
+		}
+		if newLen < oldLen || lengthBytes(oldLen*mb) != lengthBytes(newLen*mb) {
+			return copyOp()
+		}
+		if s.dbType == DBTypeMysql && s.dbVersion < 50700 {
+			return copyOp()
+		}
+		if s.dbType == DBTypeMariaDB && s.dbVersion >= 100400 {
+			return instantOp(false)
+		}
+		return inplaceOp(false, LockNone)
+	case "enum", "set":
+		// 在末尾添加成员且存储大小不变时,仅修改元数据
+		if !isElemsAppended(oldType, col.Tp) {
+			return copyOp()
+		}
+		if s.dbType == DBTypeMysql && s.dbVersion < 50700 {
+			return copyOp()
+		}
+		return s.metadataOp()
+	}
+	return copyOp()
+}
+
+// isElemsAppended ENUM/SET是否仅在末尾添加了成员,且存储大小不变
+func isElemsAppended(oldType string, tp *types.FieldType) bool {
+	n := len(tp.Elems)
+	for k := n - 1; k > 0; k-- {
+		old := *tp
+		old.Elems = tp.Elems[:k]
+		if normalizeColumnType(old.InfoSchemaStr()) != oldType {
+			continue
+		}
+		if tp.Tp == mysql.TypeSet {
+			return (k+7)/8 == (n+7)/8
+		}
+		return k <= 255 && n <= 255 || k > 255 && n > 255
+	}
+	return false
+}
+
+func combineSpec(a, b specAlgorithm) specAlgorithm {
+	if algorithmRank[b.algorithm] > algorithmRank[a.algorithm] {
+		a.algorithm = b.algorithm
+	}
+	if lockRank[b.lock] > lockRank[a.lock] {
+		a.lock = b.lock
+	}
+	a.rebuild = a.rebuild || b.rebuild
+	return a
+}
+
+// checkAlterAlgorithm 预测ALTER TABLE的执行算法,
+// 检查显式指定的ALGORITHM和LOCK子句是否可以满足
+// 版本未知时不做预测
+func (s *session) checkAlterAlgorithm(t *TableInfo, specs []*ast.AlterTableSpec) {
+	if s.dbVersion == 0 {
+		return
+	}
+
+	ops := s.predictAlterSpecs(t, specs)
+	a := combineAlgorithm(ops)
+
+	tableName := t.Name
+	for _, spec := range specs {
+		if spec.Tp != ast.AlterTableAlgorithm {
+			continue
+		}
+		var requested string
+		switch spec.Algorithm {
+		case ast.AlgorithmTypeInstant:
+			// 不支持INSTANT的版本已经在检查ALGORITHM子句时提示
+			if !s.supportInstant() {
+				continue
+			}
+			requested = AlgorithmInstant
+		case ast.AlgorithmTypeInplace:
+			requested = AlgorithmInplace
+		case ast.AlgorithmTypeCopy:
+			requested = AlgorithmCopy
+		default:
+			continue
+		}
+
+		if algorithmRank[requested] < algorithmRank[a.Algorithm] {
+			s.appendErrorNo(ErrAlterAlgorithmNotSupported,
+				"ALGORITHM="+requested, tableName, "ALGORITHM="+a.Algorithm)
+			continue
+		}
+		if requested == AlgorithmInplace && a.Algorithm == AlgorithmInstant {
+			// INSTANT操作以INPLACE执行时可能需要重建表
+			for i := range ops {
+				if ops[i].algorithm == AlgorithmInstant {
+					ops[i].algorithm = AlgorithmInplace
+				}
+			}
+			a = combineAlgorithm(ops)
+		}
+		if requested == AlgorithmCopy {
+			a.Algorithm = AlgorithmCopy
+			a.Rebuild = true
+			if a.Lock == LockNone {
+				a.Lock = LockShared
+			}
+		}
+	}
+
+	for _, spec := range specs {
+		if spec.Tp != ast.AlterTableLock {
+			continue
+		}
+		var requested string
+		switch spec.LockType {
+		case ast.LockTypeNone:
+			requested = LockNone
+		case ast.LockTypeShared:
+			requested = LockShared
+		case ast.LockTypeExclusive:
+			requested = LockExclusive
+		default:
+			continue
+		}
+
+		if lockRank[requested] < lockRank[a.Lock] {
+			s.appendErrorNo(ErrAlterAlgorithmNotSupported,
+				"LOCK="+requested, tableName, "LOCK="+a.Lock)
+			continue
+		}
+		a.Lock = requested
+	}
+
+	s.myRecord.DDLAlgorithm = a
+}
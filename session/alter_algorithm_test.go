@@ -0,0 +1,98 @@
+package session_test
+
+import (
+	"fmt"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestAlterAlgorithm(c *C) {
+	snapshot := `{
+		"Version": "%s",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t1",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "c1", "Type": "varchar(20)", "Collation": "utf8mb4_general_ci", "Null": "YES"},
+				{"Field": "c2", "Type": "enum('a','b')", "Collation": "utf8mb4_general_ci", "Null": "YES"},
+				{"Field": "c3", "Type": "int(11)", "Null": "YES"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			]
+		}]
+	}`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnableNullable = true
+	cnf.Inc.CheckColumnComment = false
+	cnf.IncLevel.ER_ALTER_TABLE_ONCE = 0
+	cnf.Osc.OscOn = true
+	cnf.Osc.OscMinTableSize = 0
+	cnf.Osc.OscPreferNativeDDL = true
+
+	result := s.audit(c, fmt.Sprintf(snapshot, "8.0.30"), `use test_inc;
+	alter table t1 add column c4 int;
+	alter table t1 add index idx_c1(c1);
+	alter table t1 modify c1 varchar(30);
+	alter table t1 modify c1 varchar(100);
+	alter table t1 modify c3 int not null;
+	alter table t1 add index idx_c3(c3), algorithm=instant;
+	alter table t1 modify c2 enum('a','b','c');
+	alter table t1 modify c3 bigint, lock=none;
+	alter table t1 add column c5 int, algorithm=inplace;`)
+	c.Assert(len(result), Equals, 10)
+
+	expected := []string{
+		"ALGORITHM=INSTANT, LOCK=NONE",
+		"ALGORITHM=INPLACE, LOCK=NONE",
+		"ALGORITHM=INPLACE, LOCK=NONE",
+		"ALGORITHM=COPY, LOCK=SHARED",
+		"ALGORITHM=INPLACE(rebuild), LOCK=NONE",
+		"ALGORITHM=INPLACE, LOCK=NONE",
+		"ALGORITHM=INSTANT, LOCK=NONE",
+		"ALGORITHM=COPY, LOCK=SHARED",
+		"ALGORITHM=INPLACE(rebuild), LOCK=NONE",
+	}
+	for i, algorithm := range expected {
+		row := result[i+1]
+		c.Assert(row.DDLAlgorithm, NotNil, Commentf("%s", row.Sql))
+		c.Assert(row.DDLAlgorithm.String(), Equals, algorithm, Commentf("%s", row.Sql))
+		// 可以在线执行时不使用OSC
+		online := row.DDLAlgorithm.Algorithm == session.AlgorithmInstant ||
+			row.DDLAlgorithm.Algorithm == session.AlgorithmInplace &&
+				!row.DDLAlgorithm.Rebuild && row.DDLAlgorithm.Lock == session.LockNone
+		c.Assert(row.UseOsc, Equals, !online, Commentf("%s", row.Sql))
+	}
+
+	// 指定的算法不支持时默认为警告
+	c.Assert(result[6].ErrLevel, Equals, uint8(1))
+	c.Assert(result[6].ErrorMessage, Equals,
+		"ALGORITHM=INSTANT is not supported for this operation on table 't1'. Try ALGORITHM=INPLACE.")
+	c.Assert(result[8].ErrorMessage, Equals,
+		"LOCK=NONE is not supported for this operation on table 't1'. Try LOCK=SHARED.")
+
+	// 5.7不支持INSTANT,添加列需要重建表
+	result = s.audit(c, fmt.Sprintf(snapshot, "5.7.25"), `use test_inc;
+	alter table t1 add column c4 int;
+	alter table t1 modify c2 enum('a','b','c');`)
+	c.Assert(len(result), Equals, 3)
+	c.Assert(result[1].DDLAlgorithm.String(), Equals, "ALGORITHM=INPLACE(rebuild), LOCK=NONE")
+	c.Assert(result[1].UseOsc, IsTrue)
+	c.Assert(result[2].DDLAlgorithm.String(), Equals, "ALGORITHM=INPLACE, LOCK=NONE")
+	c.Assert(result[2].UseOsc, IsFalse)
+
+	// 默认关闭,仍按原有规则使用OSC
+	c.Assert(config.NewConfig().Osc.OscPreferNativeDDL, IsFalse)
+	cnf.Osc.OscPreferNativeDDL = false
+	result = s.audit(c, fmt.Sprintf(snapshot, "8.0.30"), `use test_inc;
+	alter table t1 add column c4 int;`)
+	c.Assert(result[1].DDLAlgorithm.String(), Equals, "ALGORITHM=INSTANT, LOCK=NONE")
+	c.Assert(result[1].UseOsc, IsTrue)
+}
@@ -0,0 +1,284 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/jinzhu/gorm"
+	"github.com/pingcap/errors"
+)
+
+// 备份存储方式
+const (
+	BackupStorageMySQL = "mysql"
+	BackupStorageFile  = "file"
+	BackupStorageS3    = "s3"
+)
+
+// ErrBackupNotFound 未找到opid对应的备份记录
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupInfo 备份记录,即$_$Inception_backup_information$_$表中的一行
+type BackupInfo struct {
+	OPID          string
+	StartFile     string
+	StartPosition int
+	EndFile       string
+	EndPosition   int
+	// 执行的SQL语句
+	Sql       string
+	Host      string
+	DBName    string
+	TableName string
+	Port      int
+	Time      time.Time
+	// 语句类型,如INSERT,ALTERTABLE
+	Type string
+}
+
+// RollbackStatement 回滚语句
+type RollbackStatement struct {
+	OPID      string
+	Statement string
+}
+
+// BackupSink 备份存储,保存备份记录和回滚语句.
+// db为备份库名,规则同执行结果中的BackupDBName
+type BackupSink interface {
+	// CreateDatabase 创建备份库
+	CreateDatabase(db string) error
+	// CreateTable 创建保存回滚语句的备份表
+	CreateTable(db string, table string) error
+	// CreateInfoTable 创建备份记录表.
+	// longDataType为false表示sql_statement字段为旧的text类型,写入时需要截断
+	CreateInfoTable(db string) (longDataType bool, err error)
+
+	// WriteInfo 写入备份记录
+	WriteInfo(db string, infos []*BackupInfo) error
+	// WriteRollback 写入回滚语句
+	WriteRollback(db string, table string, statements []RollbackStatement) error
+	// Flush 备份结束时调用,写入缓存的数据
+	Flush() error
+
+	// Rollback 获取opid的备份记录和回滚语句,回滚语句按执行的逆序返回
+	Rollback(db string, opid string) (*BackupInfo, []string, error)
+}
+
+// NewBackupSink 根据配置创建备份存储
+func NewBackupSink(inc config.Inc) (BackupSink, error) {
+	switch strings.ToLower(inc.BackupStorage) {
+	case "", BackupStorageMySQL:
+		if inc.BackupHost == "" || inc.BackupPort == 0 || inc.BackupUser == "" {
+			return nil, errors.New("invalid backup host info, please set backup_host, backup_port and backup_user")
+		}
+		addr := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=%s&parseTime=True&loc=Local&autocommit=1",
+			inc.BackupUser, inc.BackupPassword, inc.BackupHost, inc.BackupPort,
+			inc.DefaultCharset)
+		db, err := gorm.Open("mysql", addr)
+		if err != nil {
+			return nil, err
+		}
+		// 禁用日志记录器，不显示任何日志
+		db.LogMode(false)
+		return &mysqlBackupSink{db: db}, nil
+	case BackupStorageFile:
+		if inc.BackupDir == "" {
+			return nil, errors.New("backup_dir must be set when backup_storage is file")
+		}
+		return newFileBackupSink(inc.BackupDir), nil
+	case BackupStorageS3:
+		return newS3BackupSink(inc)
+	default:
+		return nil, errors.Errorf("unsupported backup_storage: %s", inc.BackupStorage)
+	}
+}
+
+// backupErrorMessage 备份出错时的错误信息
+func backupErrorMessage(err error) string {
+	if myErr, ok := errors.Cause(err).(*mysqlDriver.MySQLError); ok {
+		return myErr.Message
+	}
+	return err.Error()
+}
+
+// mysqlBackupSink 备份到MySQL备份库
+type mysqlBackupSink struct {
+	db *gorm.DB
+}
+
+func (m *mysqlBackupSink) CreateDatabase(db string) error {
+	sql := fmt.Sprintf("create database if not exists `%s`;", db)
+	if err := m.db.Exec(sql).Error; err != nil {
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok && myErr.Number == 1007 { /*ER_DB_CREATE_EXISTS*/
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mysqlBackupSink) CreateTable(db string, table string) error {
+	if err := m.db.Exec(mysqlCreateSqlFromTableInfo(db, table)).Error; err != nil {
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok && myErr.Number == 1050 { /*ER_TABLE_EXISTS_ERROR*/
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mysqlBackupSink) CreateInfoTable(db string) (bool, error) {
+	if err := m.db.Exec(mysqlCreateSqlBackupTable(db)).Error; err != nil {
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok && myErr.Number == 1050 { /*ER_TABLE_EXISTS_ERROR*/
+			// 获取sql_statement字段类型,用以兼容类型为text的旧表结构
+			return m.checkSqlStmtColumnType(db)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// checkSqlStmtColumnType 检查sql_statement字段类型,用以兼容类型为text的旧表结构
+func (m *mysqlBackupSink) checkSqlStmtColumnType(db string) (bool, error) {
+	sql := `select DATA_TYPE from information_schema.columns
+			where table_schema=? and table_name=? and column_name='sql_statement';`
+
+	var res string
+	rows, err := m.db.DB().Query(sql, db, remoteBackupTable)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rows.Scan(&res)
+	}
+	return res != "text", nil
+}
+
+func (m *mysqlBackupSink) WriteInfo(db string, infos []*BackupInfo) error {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	const rowSQL = "(?,?,?,?,?,?,?,?,?,?,NOW(),?),"
+	values := make([]interface{}, 0, len(infos)*11)
+	for _, info := range infos {
+		values = append(values,
+			info.OPID,
+			info.StartFile,
+			info.StartPosition,
+			info.EndFile,
+			info.EndPosition,
+			info.Sql,
+			info.Host,
+			info.DBName,
+			info.TableName,
+			info.Port,
+			info.Type)
+	}
+
+	sql := fmt.Sprintf("insert into `%s`.`%s` values%s", db, remoteBackupTable,
+		strings.TrimRight(strings.Repeat(rowSQL, len(infos)), ","))
+	return m.db.Exec(sql, values...).Error
+}
+
+func (m *mysqlBackupSink) WriteRollback(db string, table string, statements []RollbackStatement) error {
+	if len(statements) == 0 {
+		return nil
+	}
+
+	const rowSQL = "(?,?),"
+	values := make([]interface{}, 0, len(statements)*2)
+	for _, r := range statements {
+		values = append(values, r.Statement, r.OPID)
+	}
+
+	sql := fmt.Sprintf("insert into `%s`.`%s`(rollback_statement,opid_time) values%s", db, table,
+		strings.TrimRight(strings.Repeat(rowSQL, len(statements)), ","))
+	return m.db.Exec(sql, values...).Error
+}
+
+func (m *mysqlBackupSink) Flush() error {
+	return nil
+}
+
+func (m *mysqlBackupSink) Rollback(db string, opid string) (*BackupInfo, []string, error) {
+	info := &BackupInfo{OPID: opid}
+	query := fmt.Sprintf(`SELECT start_binlog_file, start_binlog_pos, end_binlog_file, end_binlog_pos,
+		sql_statement, host, dbname, tablename, port, time, type
+		FROM `+"`%s`.`%s`"+` WHERE opid_time = ?`, db, remoteBackupTable)
+	err := m.db.DB().QueryRow(query, opid).Scan(&info.StartFile, &info.StartPosition,
+		&info.EndFile, &info.EndPosition, &info.Sql, &info.Host, &info.DBName,
+		&info.TableName, &info.Port, &info.Time, &info.Type)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrBackupNotFound
+		}
+		return nil, nil, err
+	}
+
+	query = fmt.Sprintf("SELECT rollback_statement FROM `%s`.`%s` WHERE opid_time = ? ORDER BY id DESC",
+		db, info.TableName)
+	rows, err := m.db.DB().Query(query, opid)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return nil, nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	return info, statements, rows.Err()
+}
+
+// mysqlCreateSqlBackupTable 备份记录表的建表语句
+func mysqlCreateSqlBackupTable(dbname string) string {
+	var buf strings.Builder
+	buf.WriteString("CREATE TABLE  ")
+
+	buf.WriteString(fmt.Sprintf("`%s`.`%s`", dbname, remoteBackupTable))
+	buf.WriteString("(")
+
+	buf.WriteString("opid_time varchar(50),")
+	buf.WriteString("start_binlog_file varchar(512),")
+	buf.WriteString("start_binlog_pos int,")
+	buf.WriteString("end_binlog_file varchar(512),")
+	buf.WriteString("end_binlog_pos int,")
+	buf.WriteString("sql_statement mediumtext,")
+	buf.WriteString("host VARCHAR(64),")
+	buf.WriteString("dbname VARCHAR(64),")
+	buf.WriteString("tablename VARCHAR(64),")
+	buf.WriteString("port INT,")
+	buf.WriteString("time TIMESTAMP,")
+	buf.WriteString("type VARCHAR(20),")
+	buf.WriteString("PRIMARY KEY(opid_time)")
+
+	buf.WriteString(")ENGINE INNODB DEFAULT CHARSET UTF8MB4;")
+
+	return buf.String()
+}
+
+// mysqlCreateSqlFromTableInfo 备份表的建表语句
+func mysqlCreateSqlFromTableInfo(dbname string, table string) string {
+	var buf strings.Builder
+	buf.WriteString("CREATE TABLE if not exists ")
+	buf.WriteString(fmt.Sprintf("`%s`.`%s`", dbname, table))
+	buf.WriteString("(")
+
+	buf.WriteString("id bigint auto_increment primary key, ")
+	buf.WriteString("rollback_statement mediumtext, ")
+	buf.WriteString("opid_time varchar(50)")
+
+	buf.WriteString(") ENGINE INNODB DEFAULT CHARSET UTF8MB4;")
+
+	return buf.String()
+}
@@ -0,0 +1,315 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hanchuanchuan/inception-core/parser"
+	"github.com/pingcap/errors"
+)
+
+const (
+	// 回滚语句文件的扩展名,每个opid一个SQL文件,可以直接使用mysql客户端执行
+	backupRollbackExt = ".sql"
+	// 备份记录清单,每个备份库一个,以opid为key
+	backupManifest = "manifest.json"
+)
+
+// rollbackDelimiters 包含分号的回滚语句使用的分隔符,取语句中未出现的第一个
+var rollbackDelimiters = []string{"$$", "//", "$$$$", "////"}
+
+// objectStore 保存备份文件的存储,key格式为 备份库名/文件名
+type objectStore interface {
+	// Get 读取文件,不存在时返回os.ErrNotExist
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte) error
+}
+
+// objectBackupSink 以文件方式保存备份.
+// 每个opid的回滚语句保存为 备份库名/opid.sql, 备份记录保存在 备份库名/manifest.json.
+// 回滚语句先追加到本地目录dir, 如果存储不是本地目录, 则在Flush时上传.
+// 备份记录在Flush时回滚语句写入完成后才合并到清单,因此清单中存在的opid回滚语句一定完整.
+// 清单为读取后合并写入,同一备份库不能由多个进程同时写入
+type objectBackupSink struct {
+	mu sync.Mutex
+
+	dir   string
+	store objectStore
+	// 存储是否即为本地目录
+	local bool
+
+	// 待上传的回滚语句文件
+	pending map[string]bool
+	// 待保存的备份记录,key为备份库名和opid
+	infos map[string]map[string]*BackupInfo
+}
+
+// newFileBackupSink 备份到本地目录
+func newFileBackupSink(dir string) *objectBackupSink {
+	return &objectBackupSink{
+		dir:     dir,
+		store:   fileStore(dir),
+		local:   true,
+		pending: make(map[string]bool),
+		infos:   make(map[string]map[string]*BackupInfo),
+	}
+}
+
+func (o *objectBackupSink) CreateDatabase(db string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, err := o.localDir(db)
+	return err
+}
+
+// localDir 返回备份库对应的本地目录,不存在时自动创建.
+// 本地目录未指定时使用临时目录
+func (o *objectBackupSink) localDir(db string) (string, error) {
+	if o.dir == "" {
+		dir, err := ioutil.TempDir("", "inception-backup")
+		if err != nil {
+			return "", err
+		}
+		o.dir = dir
+	}
+	dir := filepath.Join(o.dir, db)
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+func (o *objectBackupSink) CreateTable(db string, table string) error {
+	return nil
+}
+
+func (o *objectBackupSink) CreateInfoTable(db string) (bool, error) {
+	return true, nil
+}
+
+// WriteInfo 缓存备份记录,在Flush时保存,同一opid的记录会被覆盖
+func (o *objectBackupSink) WriteInfo(db string, infos []*BackupInfo) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.infos[db] == nil {
+		o.infos[db] = make(map[string]*BackupInfo, len(infos))
+	}
+	for _, info := range infos {
+		o.infos[db][info.OPID] = info
+	}
+	return nil
+}
+
+func (o *objectBackupSink) WriteRollback(db string, table string, statements []RollbackStatement) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(statements) > 0 {
+		// 同一opid的语句连续写入
+		opid := statements[0].OPID
+		var buf bytes.Buffer
+		for len(statements) > 0 && statements[0].OPID == opid {
+			writeRollbackSQL(&buf, statements[0].Statement)
+			statements = statements[1:]
+		}
+
+		dir, err := o.localDir(db)
+		if err != nil {
+			return err
+		}
+		key := path.Join(db, opid+backupRollbackExt)
+		f, err := os.OpenFile(filepath.Join(dir, opid+backupRollbackExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(buf.Bytes())
+		if err1 := f.Close(); err == nil {
+			err = err1
+		}
+		if err != nil {
+			return err
+		}
+		if !o.local {
+			o.pending[key] = true
+		}
+	}
+	return nil
+}
+
+// writeRollbackSQL 写入一条回滚语句.
+// 以分号结尾且不含其他分号的语句直接写入,其他语句(如存储过程和多条语句组成的DDL回滚语句)
+// 使用DELIMITER包围,以便mysql客户端执行,读取时也作为一条语句返回
+func writeRollbackSQL(buf *bytes.Buffer, stmt string) {
+	stmt = strings.TrimSpace(stmt)
+	body := strings.TrimSuffix(stmt, ";")
+	if len(body) < len(stmt) && !strings.Contains(body, ";") {
+		buf.WriteString(stmt)
+		buf.WriteByte('\n')
+		return
+	}
+
+	delimiter := rollbackDelimiters[len(rollbackDelimiters)-1]
+	for _, d := range rollbackDelimiters {
+		if !strings.Contains(stmt, d) {
+			delimiter = d
+			break
+		}
+	}
+	fmt.Fprintf(buf, "DELIMITER %s\n%s%s\nDELIMITER ;\n", delimiter, stmt, delimiter)
+}
+
+// parseRollbackSQL 按写入时的分隔符拆分回滚语句,以默认分隔符结尾的语句保留分号
+func parseRollbackSQL(data string) ([]string, error) {
+	segs, err := parser.Split(data)
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		stmt := seg.Text
+		if strings.HasPrefix(data[seg.End.Offset:], parser.DefaultDelimiter) {
+			stmt += parser.DefaultDelimiter
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+// Flush 上传本地缓存的回滚语句文件,上传成功后删除本地文件,
+// 然后将备份记录合并到清单
+func (o *objectBackupSink) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.local {
+		if err := o.upload(); err != nil {
+			return err
+		}
+	}
+
+	dbs := make([]string, 0, len(o.infos))
+	for db := range o.infos {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	for _, db := range dbs {
+		manifest, err := o.loadManifest(db)
+		if err != nil {
+			return err
+		}
+		for opid, info := range o.infos[db] {
+			manifest[opid] = info
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		key := path.Join(db, backupManifest)
+		if err := o.store.Put(key, data); err != nil {
+			return errors.Annotatef(err, "upload %s", key)
+		}
+		delete(o.infos, db)
+	}
+	return nil
+}
+
+// loadManifest 读取备份库的备份记录清单,不存在时返回空清单
+func (o *objectBackupSink) loadManifest(db string) (map[string]*BackupInfo, error) {
+	manifest := make(map[string]*BackupInfo)
+	data, err := o.store.Get(path.Join(db, backupManifest))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Annotatef(err, "invalid backup manifest %s", db)
+	}
+	return manifest, nil
+}
+
+// upload 上传回滚语句文件,完成后清理临时目录
+func (o *objectBackupSink) upload() error {
+	keys := make([]string, 0, len(o.pending))
+	for key := range o.pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := filepath.Join(o.dir, filepath.FromSlash(key))
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := o.store.Put(key, data); err != nil {
+			return errors.Annotatef(err, "upload %s", key)
+		}
+		delete(o.pending, key)
+	}
+
+	if o.dir != "" {
+		os.RemoveAll(o.dir)
+		o.dir = ""
+	}
+	return nil
+}
+
+func (o *objectBackupSink) Rollback(db string, opid string) (*BackupInfo, []string, error) {
+	manifest, err := o.loadManifest(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, ok := manifest[opid]
+	if !ok {
+		return nil, nil, ErrBackupNotFound
+	}
+
+	data, err := o.store.Get(path.Join(db, opid+backupRollbackExt))
+	if os.IsNotExist(err) {
+		// 无回滚语句,如受影响行数为0
+		return info, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	statements, err := parseRollbackSQL(string(data))
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "invalid rollback file %s", opid)
+	}
+
+	// 按写入的逆序返回
+	for i, j := 0, len(statements)-1; i < j; i, j = i+1, j-1 {
+		statements[i], statements[j] = statements[j], statements[i]
+	}
+	return info, statements, nil
+}
+
+// fileStore 本地目录
+type fileStore string
+
+func (f fileStore) Get(key string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(f), filepath.FromSlash(key)))
+}
+
+// Put 先写入临时文件再重命名,避免写入中断时文件损坏
+func (f fileStore) Put(key string, data []byte) error {
+	name := filepath.Join(string(f), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
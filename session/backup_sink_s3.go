@@ -0,0 +1,194 @@
+package session
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/pingcap/errors"
+)
+
+// s3 请求超时时间
+const s3Timeout = time.Minute
+
+// newS3BackupSink 备份到S3兼容的对象存储(如MinIO).
+// 回滚语句先写入本地临时目录,备份结束时上传
+func newS3BackupSink(inc config.Inc) (*objectBackupSink, error) {
+	if inc.BackupS3Endpoint == "" || inc.BackupS3Bucket == "" {
+		return nil, errors.New("backup_s3_endpoint and backup_s3_bucket must be set when backup_storage is s3")
+	}
+	endpoint, err := url.Parse(inc.BackupS3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.Errorf("invalid backup_s3_endpoint: %s", inc.BackupS3Endpoint)
+	}
+
+	region := inc.BackupS3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	store := &s3Store{
+		endpoint:  endpoint,
+		bucket:    inc.BackupS3Bucket,
+		region:    region,
+		accessKey: inc.BackupS3AccessKey,
+		secretKey: inc.BackupS3SecretKey,
+		client:    &http.Client{Timeout: s3Timeout},
+	}
+	return &objectBackupSink{
+		store:   store,
+		pending: make(map[string]bool),
+		infos:   make(map[string]map[string]*BackupInfo),
+	}, nil
+}
+
+// s3Store S3兼容的对象存储,使用path-style访问和AWS Signature V4签名
+type s3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func (s *s3Store) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, os.ErrNotExist
+	case resp.StatusCode != http.StatusOK:
+		return nil, errors.Errorf("s3 get %s: %s %s", key, resp.Status, data)
+	}
+	return data, nil
+}
+
+func (s *s3Store) Put(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("s3 put %s: %s %s", key, resp.Status, body)
+	}
+	return nil
+}
+
+func (s *s3Store) do(method string, key string, data []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = s3EscapePath(u.Path)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.ContentLength = int64(len(data))
+	}
+
+	sum := sha256.Sum256(data)
+	signV4(req, hex.EncodeToString(sum[:]), s.accessKey, s.secretKey, s.region, time.Now())
+	return s.client.Do(req)
+}
+
+// signV4 使用AWS Signature Version 4签名请求,签名包含Host及请求中的所有header
+func signV4(req *http.Request, payloadHash string, accessKey string, secretKey string,
+	region string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k)
+		canonicalHeaders.WriteString(":")
+		canonicalHeaders.WriteString(headers[k])
+		canonicalHeaders.WriteString("\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath 按S3规则编码路径,保留'/'
+func s3EscapePath(p string) string {
+	var buf strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
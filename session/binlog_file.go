@@ -0,0 +1,249 @@
+package session
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hanchuanchuan/go-mysql/replication"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// BinlogOptions 本地binlog文件解析参数
+type BinlogOptions struct {
+	// binlog文件或目录. 指定目录时按文件名顺序解析目录下的所有binlog文件
+	Path string
+
+	// 起始文件及位置. 文件为空时从第一个文件开始,位置应为事务的起始位置
+	StartFile     string
+	StartPosition int64
+	// 结束文件及位置(不含该位置). 文件为空时解析到最后一个文件,位置为0时解析到文件末尾
+	StopFile     string
+	StopPosition int64
+
+	// 时间范围[StartTime, StopTime),零值时不限制
+	StartTime time.Time
+	StopTime  time.Time
+
+	// 线程号,为0时不限制
+	ThreadID uint32
+
+	// 表结构快照,格式同SourceOptions.Snapshot(建表语句或JSON).
+	// 仅解析快照中存在的表,表结构需和binlog中的列一致
+	Snapshot string
+}
+
+// BinlogRollbackRecord 解析binlog生成的回滚语句
+type BinlogRollbackRecord struct {
+	Sql       string
+	DBName    string
+	TableName string
+	ThreadID  uint32
+	// binlog事件的时间
+	Time time.Time
+	// binlog文件名及事件结束位置
+	File     string
+	Position uint32
+}
+
+// RollbackFromBinlog 解析本地binlog文件,生成回滚语句.
+// 回滚语句和备份生成的语句一致,并已按执行的逆序排列
+func (s *session) RollbackFromBinlog(ctx context.Context, opt BinlogOptions) ([]BinlogRollbackRecord, error) {
+	files, err := binlogFiles(opt.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("未找到binlog文件: %s", opt.Path)
+	}
+
+	start, stop := 0, len(files)-1
+	if opt.StartFile != "" {
+		if start = binlogFileIndex(files, opt.StartFile); start < 0 {
+			return nil, errors.Errorf("binlog文件'%s'不存在", opt.StartFile)
+		}
+	}
+	if opt.StopFile != "" {
+		if stop = binlogFileIndex(files, opt.StopFile); stop < 0 {
+			return nil, errors.Errorf("binlog文件'%s'不存在", opt.StopFile)
+		}
+	}
+	if start > stop {
+		return nil, errors.New("起始binlog文件不能晚于结束文件")
+	}
+
+	if s.opt == nil {
+		s.opt = &SourceOptions{Offline: true}
+		defer func() { s.opt = nil }()
+	}
+
+	s.init()
+	defer s.clear()
+
+	if err := s.loadSnapshot(opt.Snapshot); err != nil {
+		return nil, errors.Errorf("表结构快照解析失败: %v", err)
+	}
+
+	var (
+		records []BinlogRollbackRecord
+		current BinlogRollbackRecord
+	)
+	s.rollbackWriter = func(b []byte) {
+		r := current
+		r.Sql = string(b) + ";"
+		records = append(records, r)
+	}
+	defer func() { s.rollbackWriter = nil }()
+
+	// 到达结束时间后不再解析后续文件
+	finished := false
+	for i := start; i <= stop && !finished; i++ {
+		var offset, stopPosition int64
+		if i == start {
+			offset = opt.StartPosition
+		}
+		if i == stop {
+			stopPosition = opt.StopPosition
+		}
+
+		current.File = filepath.Base(files[i])
+		current.ThreadID = 0
+
+		p := replication.NewBinlogParser()
+		p.SetUseDecimal(true)
+
+		var parseErr error
+		err := p.ParseFile(files[i], offset, func(e *replication.BinlogEvent) error {
+			if err := checkClose(ctx); err != nil {
+				parseErr = err
+				p.Stop()
+				return nil
+			}
+
+			// 事件起始位置超出结束位置或时间时结束解析
+			if stopPosition > 0 && e.Header.LogPos >= e.Header.EventSize &&
+				int64(e.Header.LogPos-e.Header.EventSize) >= stopPosition {
+				p.Stop()
+				return nil
+			}
+			eventTime := time.Unix(int64(e.Header.Timestamp), 0)
+			if !opt.StopTime.IsZero() && e.Header.EventType != replication.FORMAT_DESCRIPTION_EVENT &&
+				!eventTime.Before(opt.StopTime) {
+				finished = true
+				p.Stop()
+				return nil
+			}
+
+			if event, ok := e.Event.(*replication.QueryEvent); ok {
+				current.ThreadID = event.SlaveProxyID
+				return nil
+			}
+
+			event, ok := e.Event.(*replication.RowsEvent)
+			if !ok {
+				return nil
+			}
+			if opt.ThreadID > 0 && current.ThreadID != opt.ThreadID {
+				return nil
+			}
+			if !opt.StartTime.IsZero() && eventTime.Before(opt.StartTime) {
+				return nil
+			}
+
+			st := s.getSnapshotTable(string(event.Table.Schema), string(event.Table.Table))
+			if st == nil {
+				return nil
+			}
+			t := &st.TableInfo
+			clearDeleteColumns(t)
+
+			current.DBName = string(event.Table.Schema)
+			current.TableName = string(event.Table.Table)
+			current.Time = eventTime
+			current.Position = e.Header.LogPos
+
+			var err error
+			switch e.Header.EventType {
+			case replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+				_, err = s.generateDeleteSql(t, event, e)
+			case replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+				_, err = s.generateInsertSql(t, event, e)
+			case replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+				_, err = s.generateUpdateSql(t, event, e)
+			}
+			if err != nil {
+				parseErr = errors.Errorf("%s:%d %v", current.File, e.Header.LogPos, err)
+				p.Stop()
+			}
+			return nil
+		})
+		if err == nil {
+			err = parseErr
+		}
+		if err != nil {
+			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+			return nil, err
+		}
+	}
+
+	// 按执行的逆序回滚
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// binlogFiles 返回binlog文件列表. 指定目录时返回目录下按文件名排序的binlog文件
+func binlogFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(infos))
+	for _, info := range infos {
+		name := filepath.Join(path, info.Name())
+		if info.Mode().IsRegular() && isBinlogFile(name) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isBinlogFile 根据文件头判断是否为binlog文件,以跳过index等文件
+func isBinlogFile(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	b := make([]byte, len(replication.BinLogFileHeader))
+	if _, err := io.ReadFull(f, b); err != nil {
+		return false
+	}
+	return bytes.Equal(b, replication.BinLogFileHeader)
+}
+
+func binlogFileIndex(files []string, name string) int {
+	for i, f := range files {
+		if filepath.Base(f) == filepath.Base(name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,276 @@
+package session_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hanchuanchuan/go-mysql/mysql"
+	"github.com/hanchuanchuan/go-mysql/replication"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testBinlogFileSuite{})
+
+type testBinlogFileSuite struct {
+	dir  string
+	file string
+}
+
+func TestBinlogFile(t *testing.T) {
+	TestingT(t)
+}
+
+const binlogSnapshot = `create database test_inc;
+use test_inc;
+create table t1(id int primary key, c1 varchar(32));`
+
+// binlogBuilder 生成测试用的binlog文件(row格式,无checksum)
+type binlogBuilder struct {
+	buf bytes.Buffer
+	ts  uint32
+}
+
+func newBinlogBuilder() *binlogBuilder {
+	b := &binlogBuilder{}
+	b.buf.Write(replication.BinLogFileHeader)
+
+	body := make([]byte, 57)
+	binary.LittleEndian.PutUint16(body, 4)
+	copy(body[2:], "5.7.25-log")
+	body[56] = byte(replication.EventHeaderSize)
+	lengths := make([]byte, 40)
+	lengths[replication.QUERY_EVENT-1] = 13
+	lengths[replication.TABLE_MAP_EVENT-1] = 8
+	lengths[replication.WRITE_ROWS_EVENTv2-1] = 10
+	lengths[replication.UPDATE_ROWS_EVENTv2-1] = 10
+	lengths[replication.DELETE_ROWS_EVENTv2-1] = 10
+	body = append(body, lengths...)
+	// checksum关闭
+	body = append(body, replication.BINLOG_CHECKSUM_ALG_OFF, 0, 0, 0, 0)
+	b.event(replication.FORMAT_DESCRIPTION_EVENT, body)
+	return b
+}
+
+func (b *binlogBuilder) event(tp replication.EventType, body []byte) {
+	size := replication.EventHeaderSize + len(body)
+	header := make([]byte, replication.EventHeaderSize)
+	binary.LittleEndian.PutUint32(header, b.ts)
+	header[4] = byte(tp)
+	binary.LittleEndian.PutUint32(header[5:], 1)
+	binary.LittleEndian.PutUint32(header[9:], uint32(size))
+	binary.LittleEndian.PutUint32(header[13:], uint32(b.buf.Len()+size))
+	b.buf.Write(header)
+	b.buf.Write(body)
+}
+
+// begin 开始事务,返回事务的起始位置
+func (b *binlogBuilder) begin(threadID uint32, ts uint32) int64 {
+	b.ts = ts
+	pos := int64(b.buf.Len())
+
+	body := make([]byte, 13)
+	binary.LittleEndian.PutUint32(body, threadID)
+	body[8] = byte(len("test_inc"))
+	body = append(body, "test_inc"...)
+	body = append(body, 0)
+	body = append(body, "BEGIN"...)
+	b.event(replication.QUERY_EVENT, body)
+
+	// t1(id int, c1 varchar(32))
+	body = []byte{1, 0, 0, 0, 0, 0, 0, 0}
+	body = append(body, byte(len("test_inc")))
+	body = append(body, "test_inc"...)
+	body = append(body, 0, 2)
+	body = append(body, "t1"...)
+	body = append(body, 0, 2, mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR, 2, 32, 0, 2)
+	b.event(replication.TABLE_MAP_EVENT, body)
+	return pos
+}
+
+func (b *binlogBuilder) rows(tp replication.EventType, rows ...[]interface{}) {
+	body := []byte{1, 0, 0, 0, 0, 0, 1, 0, 2, 0, 2, 3}
+	if tp == replication.UPDATE_ROWS_EVENTv2 {
+		body = append(body, 3)
+	}
+	for _, row := range rows {
+		body = append(body, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(body[len(body)-4:], uint32(row[0].(int)))
+		body = append(body, byte(len(row[1].(string))))
+		body = append(body, row[1].(string)...)
+	}
+	b.event(tp, body)
+}
+
+func (b *binlogBuilder) commit() {
+	b.event(replication.XID_EVENT, make([]byte, 8))
+}
+
+func (s *testBinlogFileSuite) SetUpSuite(c *C) {
+	config.GetGlobalConfig().Inc.Lang = "en-US"
+	s.dir = c.MkDir()
+
+	b := newBinlogBuilder()
+	b.begin(10, 1000)
+	b.rows(replication.WRITE_ROWS_EVENTv2, []interface{}{1, "a"}, []interface{}{2, "b"})
+	b.commit()
+	b.begin(11, 2000)
+	b.rows(replication.UPDATE_ROWS_EVENTv2, []interface{}{1, "a"}, []interface{}{1, "c"})
+	b.commit()
+	b.begin(10, 3000)
+	b.rows(replication.DELETE_ROWS_EVENTv2, []interface{}{2, "b"})
+	b.commit()
+
+	s.file = filepath.Join(s.dir, "mysql-bin.000001")
+	c.Assert(ioutil.WriteFile(s.file, b.buf.Bytes(), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "mysql-bin.index"),
+		[]byte("./mysql-bin.000001\n./mysql-bin.000002\n"), 0644), IsNil)
+
+	b = newBinlogBuilder()
+	b.begin(12, 4000)
+	b.rows(replication.WRITE_ROWS_EVENTv2, []interface{}{3, "d"})
+	b.commit()
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "mysql-bin.000002"), b.buf.Bytes(), 0644), IsNil)
+}
+
+func (s *testBinlogFileSuite) rollback(c *C, opt session.BinlogOptions) []string {
+	if opt.Path == "" {
+		opt.Path = s.file
+	}
+	if opt.Snapshot == "" {
+		opt.Snapshot = binlogSnapshot
+	}
+
+	records, err := session.NewInception().RollbackFromBinlog(context.Background(), opt)
+	c.Assert(err, IsNil)
+	sqls := make([]string, len(records))
+	for i, r := range records {
+		sqls[i] = r.Sql
+	}
+	return sqls
+}
+
+func (s *testBinlogFileSuite) TestRollback(c *C) {
+	sqls := s.rollback(c, session.BinlogOptions{})
+	c.Assert(sqls, DeepEquals, []string{
+		"INSERT INTO `test_inc`.`t1`(`id`,`c1`) VALUES(2,'b');",
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1;",
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=2;",
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=1;",
+	})
+
+	records, err := session.NewInception().RollbackFromBinlog(context.Background(),
+		session.BinlogOptions{Path: s.file, Snapshot: binlogSnapshot})
+	c.Assert(err, IsNil)
+	r := records[1]
+	c.Assert(r.DBName, Equals, "test_inc")
+	c.Assert(r.TableName, Equals, "t1")
+	c.Assert(r.ThreadID, Equals, uint32(11))
+	c.Assert(r.File, Equals, "mysql-bin.000001")
+	c.Assert(r.Time.Unix(), Equals, int64(2000))
+
+	// 快照中不存在的表不解析
+	records, err = session.NewInception().RollbackFromBinlog(context.Background(),
+		session.BinlogOptions{Path: s.file, Snapshot: "create database test_inc;"})
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+
+	// 表结构和binlog不一致
+	_, err = session.NewInception().RollbackFromBinlog(context.Background(),
+		session.BinlogOptions{Path: s.file, Snapshot: "create table test_inc.t1(id int primary key);"})
+	c.Assert(err, NotNil)
+}
+
+func (s *testBinlogFileSuite) TestRollbackGuard(c *C) {
+	inc := &config.GetGlobalConfig().Inc
+	defer func(guard, minimal bool) {
+		inc.EnableRollbackGuard, inc.EnableMinimalRollback = guard, minimal
+	}(inc.EnableRollbackGuard, inc.EnableMinimalRollback)
+
+	inc.EnableRollbackGuard = true
+	sqls := s.rollback(c, session.BinlogOptions{ThreadID: 11})
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1 AND `c1`='c';",
+	})
+
+	inc.EnableMinimalRollback = true
+	sqls = s.rollback(c, session.BinlogOptions{ThreadID: 11})
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `c1`='a' WHERE `id`=1 AND `c1`='c';",
+	})
+
+	// 无主键时where条件已包含全部列
+	inc.EnableMinimalRollback = false
+	sqls = s.rollback(c, session.BinlogOptions{ThreadID: 11,
+		Snapshot: "create table test_inc.t1(id int, c1 varchar(32));"})
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1 AND `c1`='c';",
+	})
+}
+
+func (s *testBinlogFileSuite) TestFilter(c *C) {
+	sqls := s.rollback(c, session.BinlogOptions{ThreadID: 11})
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1;",
+	})
+
+	sqls = s.rollback(c, session.BinlogOptions{
+		StartTime: time.Unix(2000, 0),
+		StopTime:  time.Unix(3000, 0),
+	})
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1;",
+	})
+
+	b := newBinlogBuilder()
+	b.begin(10, 1000)
+	b.rows(replication.WRITE_ROWS_EVENTv2, []interface{}{1, "a"})
+	b.commit()
+	start := b.begin(11, 2000)
+	b.rows(replication.WRITE_ROWS_EVENTv2, []interface{}{2, "b"})
+	b.commit()
+	stop := b.begin(12, 3000)
+	b.rows(replication.WRITE_ROWS_EVENTv2, []interface{}{3, "c"})
+	b.commit()
+	file := filepath.Join(c.MkDir(), "mysql-bin.000003")
+	c.Assert(ioutil.WriteFile(file, b.buf.Bytes(), 0644), IsNil)
+
+	sqls = s.rollback(c, session.BinlogOptions{
+		Path:          file,
+		StartPosition: start,
+		StopPosition:  stop,
+	})
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=2;",
+	})
+}
+
+func (s *testBinlogFileSuite) TestDirectory(c *C) {
+	sqls := s.rollback(c, session.BinlogOptions{Path: s.dir})
+	c.Assert(sqls, HasLen, 5)
+	c.Assert(sqls[0], Equals, "DELETE FROM `test_inc`.`t1` WHERE `id`=3;")
+
+	sqls = s.rollback(c, session.BinlogOptions{Path: s.dir, StartFile: "mysql-bin.000002"})
+	c.Assert(sqls, DeepEquals, []string{
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=3;",
+	})
+
+	sqls = s.rollback(c, session.BinlogOptions{Path: s.dir, StopFile: "mysql-bin.000001", ThreadID: 12})
+	c.Assert(sqls, HasLen, 0)
+
+	_, err := session.NewInception().RollbackFromBinlog(context.Background(),
+		session.BinlogOptions{Path: s.dir, StartFile: "mysql-bin.000009"})
+	c.Assert(err, NotNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = session.NewInception().RollbackFromBinlog(ctx,
+		session.BinlogOptions{Path: s.dir, Snapshot: binlogSnapshot})
+	c.Assert(err, NotNil)
+}
@@ -152,6 +152,17 @@ type SourceOptions struct {
 	// 事务支持,一次执行多少条
 	TranBatch int
 
+	// 离线审核,不连接数据库,表结构从Snapshot中加载
+	Offline bool
+	// 表结构快照,支持CREATE DATABASE/CREATE TABLE语句或JSON格式(SchemaSnapshot)
+	Snapshot string
+
+	// 审核规则配置集,对应配置文件中的[profiles.xxx],未指定时按主机和库名自动匹配
+	Profile string
+	// 会话级审核参数和审核级别,优先级高于配置集. key为配置文件中的参数名
+	Inc      map[string]interface{}
+	IncLevel map[string]int8
+
 	// // 扩展参数,支持一次性会话设置
 	// extendParams string
 }
@@ -216,6 +227,8 @@ type TableInfo struct {
 
 	// 表是否为新增
 	IsNew bool
+	// 是否为视图
+	IsView bool
 	// 列是否为新增
 	IsNewColumns bool
 
@@ -233,6 +246,18 @@ type TableInfo struct {
 
 	// 字符集&排序规则
 	Collation string
+
+	// 行格式,如DYNAMIC,COMPACT
+	RowFormat string
+
+	// 分区方式,如RANGE,RANGE COLUMNS,LIST,HASH,KEY,未分区时为空
+	PartitionType string
+	// 分区函数使用的列
+	PartitionColumns []string
+	// 分区列表,按分区顺序
+	Partitions []PartitionInfo
+	// 分区信息是否已加载
+	partitionLoaded bool
 }
 
 // IndexInfo 索引信息
@@ -245,6 +270,8 @@ type IndexInfo struct {
 	Seq        int    `gorm:"Column:Seq_in_index"`
 	ColumnName string `gorm:"Column:Column_name"`
 	IndexType  string `gorm:"Column:Index_type"`
+	// 前缀索引长度,整列索引时为0
+	SubPart int `gorm:"Column:Sub_part"`
 
 	IsDeleted bool `gorm:"-"`
 }
@@ -265,6 +292,15 @@ func (t *TableInfo) copy() *TableInfo {
 	p.Name = t.Name
 	p.AsName = t.AsName
 	p.AlterCount = t.AlterCount
+	p.IsView = t.IsView
+	p.Collation = t.Collation
+	p.RowFormat = t.RowFormat
+
+	p.PartitionType = t.PartitionType
+	p.PartitionColumns = t.PartitionColumns
+	p.Partitions = make([]PartitionInfo, len(t.Partitions))
+	copy(p.Partitions, t.Partitions)
+	p.partitionLoaded = t.partitionLoaded
 
 	p.Fields = make([]FieldInfo, len(t.Fields))
 	copy(p.Fields, t.Fields)
@@ -89,6 +89,7 @@ func (s *session) init() {
 	s.haveCommit = false
 	s.threadID = 0
 	s.isClusterNode = false
+	s.profile = ""
 
 	s.tableCacheList = make(map[string]*TableInfo)
 	s.dbCacheList = make(map[string]*DBInfo)
@@ -135,12 +136,15 @@ func (s *session) clear() {
 	s.backupDBCacheList = nil
 	s.backupTableCacheList = nil
 	s.sqlFingerprint = nil
+	s.snapshot = nil
 
 	s.incLevel = nil
+	s.profile = ""
 
 	s.recordSets = nil
 	s.printSets = nil
 	s.splitSets = nil
+	s.backupSink = nil
 }
 
 func (s *session) Audit(ctx context.Context, sql string) ([]Record, error) {
@@ -190,7 +194,8 @@ func (s *session) QueryTree(ctx context.Context, sql string) ([]PrintRecord, err
 	return s.Print(ctx, sql)
 }
 
-// QueryTree 打印语法树
+// Print 打印语法树.
+// 解析失败时,失败的语句记录在结果中,同时返回解析错误
 func (s *session) Print(ctx context.Context, sql string) ([]PrintRecord, error) {
 	if s.opt == nil {
 		return nil, errors.New("未配置数据源信息!")
@@ -203,11 +208,13 @@ func (s *session) Print(ctx context.Context, sql string) ([]PrintRecord, error)
 		log.Error(err)
 	}
 	if s.printSets == nil {
-		return []PrintRecord{}, nil
+		return []PrintRecord{}, err
 	}
-	return s.printSets.records, nil
+	return s.printSets.records, err
 }
 
+// Split 拆分SQL语句.
+// 解析失败时,失败的语句记录在结果中,同时返回解析错误
 func (s *session) Split(ctx context.Context, sql string) ([]SplitRecord, error) {
 	if s.opt == nil {
 		return nil, errors.New("未配置数据源信息!")
@@ -222,9 +229,9 @@ func (s *session) Split(ctx context.Context, sql string) ([]SplitRecord, error)
 
 	s.addNewSplitNode()
 	if s.splitSets == nil {
-		return []SplitRecord{}, nil
+		return []SplitRecord{}, err
 	}
-	return s.splitSets.records, nil
+	return s.splitSets.records, err
 }
 
 func (s *session) LoadOptions(opt SourceOptions) error {
@@ -235,16 +242,14 @@ func (s *session) LoadOptions(opt SourceOptions) error {
 
 func (s *session) audit(ctx context.Context, sql string) (err error) {
 
-	sqlList := strings.Split(sql, "\n")
-
 	// tidb执行的SQL关闭general日志
 	logging := s.inc.GeneralLog
 
 	defer func() {
 		if s.sessionVars.StmtCtx.AffectedRows() == 0 {
-			if s.opt != nil && s.opt.Print {
+			if s.opt != nil && s.opt.Print && s.printSets != nil {
 				s.sessionVars.StmtCtx.AddAffectedRows(uint64(s.printSets.rc.count))
-			} else if s.opt != nil && s.opt.Split {
+			} else if s.opt != nil && s.opt.Split && s.splitSets != nil {
 				s.sessionVars.StmtCtx.AddAffectedRows(uint64(s.splitSets.rc.count))
 			} else {
 				if s.recordSets == nil {
@@ -270,9 +275,6 @@ func (s *session) audit(ctx context.Context, sql string) (err error) {
 
 	charsetInfo, collation := s.sessionVars.GetCharsetInfo()
 
-	lineCount := len(sqlList) - 1
-	// batchSize := 1
-
 	tmp := s.processInfo.Load()
 	if tmp != nil {
 		pi := tmp.(util.ProcessInfo)
@@ -305,132 +307,172 @@ func (s *session) audit(ctx context.Context, sql string) (err error) {
 		s.sqlFingerprint = make(map[string]*Record, 64)
 	}
 
-	var buf []string
+	segments, err := parser.Split(sql)
+	if err != nil {
+		log.Errorf("con:%d 解析失败! %s", connID, err)
+		s.appendParseError(strings.TrimSpace(sql), Position{}, err)
+		return err
+	}
 
-	quotaIsDouble := true
-	for i, sql_line := range sqlList {
+	// 自动合并同一张表的ALTER语句
+	if s.inc.AutoMergeAlterTable && !s.opt.Print && !s.opt.Split {
+		if stmts, ok := s.parseAuditStmts(ctx, segments, charsetInfo, collation); ok {
+			for _, st := range s.mergeAlterTables(stmts) {
+				if stop, err := s.checkStmt(ctx, st); stop {
+					return err
+				}
+			}
+			return nil
+		}
+	}
 
-		// 100行解析一次
-		// 如果以分号结尾,或者是最后一行,就做解析
-		// strings.HasSuffix(sql_line, ";")
-		// && batchSize >= 100)
+	for i := range segments {
+		seg := &segments[i]
 
-		if strings.Count(sql_line, "'")%2 == 1 {
-			quotaIsDouble = !quotaIsDouble
+		stmtNodes, err := s.ParseSQL(ctx, seg.Text, charsetInfo, collation)
+
+		if err == nil && len(stmtNodes) == 0 {
+			// 未成功解析时，添加异常判断
+			err = errors.New("解析失败! 可能是解析器bug,请联系作者.")
 		}
 
-		if ((strings.HasSuffix(sql_line, ";") || strings.HasSuffix(sql_line, ";\r")) &&
-			quotaIsDouble) || i == lineCount {
-			// batchSize = 1
-			buf = append(buf, sql_line)
-			s1 := strings.Join(buf, "\n")
+		if err != nil {
+			log.Errorf("con:%d 解析失败! %s", connID, err)
+			log.Error(seg.Text)
+			pos, _ := segmentPosition(seg, 0, seg.Text)
+			s.appendParseError(seg.Text, pos, err)
+			return err
+		}
 
-			s1 = strings.TrimRight(s1, ";")
+		offset := 0
+		for _, stmtNode := range stmtNodes {
+			//  是ASCII码160的特殊空格
+			currentSQL := strings.Trim(stmtNode.Text(), " ;\t\n\v\f\r ")
 
-			stmtNodes, err := s.ParseSQL(ctx, s1, charsetInfo, collation)
+			var pos Position
+			pos, offset = segmentPosition(seg, offset, currentSQL)
 
-			if err == nil && len(stmtNodes) == 0 {
-				tmpSQL := strings.TrimSpace(s1)
-				// 未成功解析时，添加异常判断
-				if tmpSQL != "" &&
-					!strings.HasPrefix(tmpSQL, "#") &&
-					!strings.HasPrefix(tmpSQL, "--") &&
-					!strings.HasPrefix(tmpSQL, "/*") {
-					err = errors.New("解析失败! 可能是解析器bug,请联系作者.")
-				}
+			switch stmtNode.(type) {
+			case *ast.InceptionStartStmt,
+				*ast.InceptionCommitStmt:
+				continue
 			}
 
-			if err != nil {
-				log.Errorf("con:%d 解析失败! %s", connID, err)
-				log.Error(s1)
-				if s.opt != nil && s.opt.Print {
-					s.printSets.Append(2, strings.TrimSpace(s1), "", err.Error())
-				} else if s.opt != nil && s.opt.Split {
-					s.addNewSplitNode()
-					s.splitSets.Append(strings.TrimSpace(s1), err.Error())
-				} else {
-					s.recordSets.Append(&Record{
-						Sql:          strings.TrimSpace(s1),
-						ErrLevel:     2,
-						ErrorMessage: err.Error(),
-					})
-				}
+			st := &auditStmt{
+				node:    stmtNode,
+				sql:     currentSQL,
+				pos:     pos,
+				percent: float64(i) / float64(len(segments)),
+			}
+			if stop, err := s.checkStmt(ctx, st); stop {
 				return err
 			}
+		}
+	}
 
-			for i, stmtNode := range stmtNodes {
-				//  是ASCII码160的特殊空格
-				currentSQL := strings.Trim(stmtNode.Text(), " ;\t\n\v\f\r ")
-
-				switch stmtNode.(type) {
-				case *ast.InceptionStartStmt,
-					*ast.InceptionCommitStmt:
-					continue
-				}
-
-				s.myRecord = &Record{
-					Sql:   currentSQL,
-					Buf:   new(bytes.Buffer),
-					Type:  stmtNode,
-					Stage: StageCheck,
-				}
-
-				s.SetMyProcessInfo(currentSQL, time.Now(), float64(i)/float64(lineCount+1))
+	return nil
 
-				var result []sqlexec.RecordSet
-				var err error
-				if s.opt != nil && s.opt.Print {
-					result, err = s.printCommand(ctx, stmtNode, currentSQL)
-				} else if s.opt != nil && s.opt.Split {
-					result, err = s.splitCommand(ctx, stmtNode, currentSQL)
-				} else {
-					result, err = s.processCommand(ctx, stmtNode, currentSQL)
-				}
-				if err != nil {
-					return err
-				}
-				if result != nil {
-					return nil
-				}
+}
 
-				// 进程Killed
-				if err := checkClose(ctx); err != nil {
-					log.Warn("Killed: ", err)
-					s.appendErrorMessage("Operation has been killed!")
-					if s.opt != nil && s.opt.Print {
-						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-					} else if s.opt != nil && s.opt.Split {
-						s.addNewSplitNode()
-						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
-					} else {
-						s.recordSets.Append(s.myRecord)
-					}
-					return err
-				}
+// checkStmt 审核单条语句,返回是否中止审核
+func (s *session) checkStmt(ctx context.Context, st *auditStmt) (bool, error) {
+	stmtNode, currentSQL := st.node, st.sql
+	s.myRecord = &Record{
+		Sql:       currentSQL,
+		Buf:       new(bytes.Buffer),
+		Type:      stmtNode,
+		Stage:     StageCheck,
+		Position:  st.pos,
+		MergedSQL: st.merged,
+	}
+
+	s.SetMyProcessInfo(currentSQL, time.Now(), st.percent)
+
+	var result []sqlexec.RecordSet
+	var err error
+	if s.opt != nil && s.opt.Print {
+		result, err = s.printCommand(ctx, stmtNode, currentSQL)
+	} else if s.opt != nil && s.opt.Split {
+		result, err = s.splitCommand(ctx, stmtNode, currentSQL)
+	} else {
+		result, err = s.processCommand(ctx, stmtNode, currentSQL)
+	}
+	if err != nil {
+		return true, err
+	}
+	if result != nil {
+		return true, nil
+	}
+
+	// 进程Killed
+	if err := checkClose(ctx); err != nil {
+		log.Warn("Killed: ", err)
+		s.appendErrorMessage("Operation has been killed!")
+		if s.opt != nil && s.opt.Print {
+			s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+		} else if s.opt != nil && s.opt.Split {
+			s.addNewSplitNode()
+			s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+		} else {
+			s.recordSets.Append(s.myRecord)
+		}
+		return true, err
+	}
 
-				// if s.opt != nil && (s.opt.Print || s.opt.split) {
-				if s.opt != nil && s.opt.Print {
-					// s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-				} else {
-					// 远程操作时隐藏本地的set命令
-					if _, ok := stmtNode.(*ast.InceptionSetStmt); ok && s.myRecord.ErrLevel == 0 {
-						log.Info(currentSQL)
-					} else {
-						s.recordSets.Append(s.myRecord)
-					}
-				}
+	// if s.opt != nil && (s.opt.Print || s.opt.split) {
+	if s.opt != nil && s.opt.Print {
+		// s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
+	} else {
+		// 远程操作时隐藏本地的set命令
+		if _, ok := stmtNode.(*ast.InceptionSetStmt); ok && s.myRecord.ErrLevel == 0 {
+			log.Info(currentSQL)
+		} else {
+			s.recordSets.Append(s.myRecord)
+			if !s.opt.Split {
+				s.notify(EventChecked, s.myRecord)
 			}
-
-			buf = nil
-
-		} else if i < lineCount {
-			buf = append(buf, sql_line)
-			// batchSize++
 		}
 	}
+	return false, nil
+}
 
-	return nil
+// appendParseError 记录解析失败的语句
+func (s *session) appendParseError(sql string, pos Position, err error) {
+	if s.opt != nil && s.opt.Print {
+		s.printSets.Append(2, sql, "", err.Error())
+	} else if s.opt != nil && s.opt.Split {
+		s.addNewSplitNode()
+		s.splitSets.Append(sql, err.Error())
+	} else {
+		s.recordSets.Append(&Record{
+			Sql:          sql,
+			ErrLevel:     2,
+			ErrorMessage: err.Error(),
+			Findings:     []Finding{{Level: 2, Message: err.Error()}},
+			Position:     pos,
+		})
+	}
+}
 
+// segmentPosition 从片段的offset处开始查找语句,返回语句的位置和查找结束的偏移量.
+// 未找到时返回整个片段的位置
+func segmentPosition(seg *parser.Segment, offset int, sql string) (Position, int) {
+	start, end := 0, len(seg.Text)
+	if i := strings.Index(seg.Text[offset:], sql); sql != "" && i >= 0 {
+		start = offset + i
+		end = start + len(sql)
+		offset = end
+	}
+
+	begin, stop := seg.PosAt(start), seg.PosAt(end)
+	return Position{
+		Offset:    begin.Offset,
+		EndOffset: stop.Offset,
+		Line:      begin.Line,
+		Column:    begin.Col,
+		EndLine:   stop.Line,
+		EndColumn: stop.Col,
+	}, offset
 }
 
 // checkOptions 校验配置信息
@@ -440,6 +482,11 @@ func (s *session) checkOptions() error {
 		return errors.New("未配置数据源信息!")
 	}
 
+	s.setInc, s.setLevels = nil, nil
+	if err := s.loadProfile(s.matchProfile(s.opt.DB)); err != nil {
+		return err
+	}
+
 	if s.opt.Split || s.opt.Check || s.opt.Print {
 		s.opt.Execute = false
 		s.opt.Backup = false
@@ -458,6 +505,11 @@ func (s *session) checkOptions() error {
 		s.opt.Check = false
 	}
 
+	// 离线审核时不连接数据库,表结构从快照加载
+	if s.opt.Offline {
+		return s.checkOfflineOptions()
+	}
+
 	// 不再检查密码是否为空
 	if s.opt.Host == "" || s.opt.Port == 0 || s.opt.User == "" {
 		log.Warningf("%#v", s.opt)
@@ -515,22 +567,22 @@ func (s *session) checkOptions() error {
 	}
 
 	if s.opt.Backup {
-		// 不再检查密码是否为空
-		if s.inc.BackupHost == "" || s.inc.BackupPort == 0 || s.inc.BackupUser == "" {
-			return errors.New(s.getErrorMessage(ER_INVALID_BACKUP_HOST_INFO))
+		switch strings.ToLower(s.inc.BackupStorage) {
+		case "", BackupStorageMySQL:
+			// 不再检查密码是否为空
+			if s.inc.BackupHost == "" || s.inc.BackupPort == 0 || s.inc.BackupUser == "" {
+				return errors.New(s.getErrorMessage(ER_INVALID_BACKUP_HOST_INFO))
+			}
 		}
 
-		addr = fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=%s&parseTime=True&loc=Local&autocommit=1",
-			s.inc.BackupUser, s.inc.BackupPassword, s.inc.BackupHost, s.inc.BackupPort,
-			s.inc.DefaultCharset)
-		backupdb, err := gorm.Open("mysql", addr)
-
+		sink, err := NewBackupSink(s.inc)
 		if err != nil {
 			return fmt.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
 		}
-
-		backupdb.LogMode(false)
-		s.backupdb = backupdb
+		s.backupSink = sink
+		if m, ok := sink.(*mysqlBackupSink); ok {
+			s.backupdb = m.db
+		}
 	}
 
 	tmp := s.processInfo.Load()
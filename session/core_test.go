@@ -132,3 +132,26 @@ func (s *testInceptionSuite) TestDropTable(c *C) {
 		}
 	}
 }
+
+func (s *testOfflineSuite) TestSplitPrintParseError(c *C) {
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: "create database test_inc;\ncreate table test_inc.t1(id int primary key);",
+	})
+	sql := `use test_inc;
+	insert into t1 values(1);
+	insert into t1 valuesx(2);`
+
+	split, err := core.Split(context.Background(), sql)
+	c.Assert(err, NotNil)
+	c.Assert(len(split) > 0, IsTrue)
+	c.Assert(split[len(split)-1].ErrorMessage, Not(Equals), "")
+
+	print, err := core.Print(context.Background(), sql)
+	c.Assert(err, NotNil)
+	c.Assert(len(print) > 0, IsTrue)
+	last := print[len(print)-1]
+	c.Assert(last.ErrLevel, Equals, uint8(2))
+	c.Assert(last.ErrorMessage, Not(Equals), "")
+}
@@ -0,0 +1,305 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	log "github.com/sirupsen/logrus"
+)
+
+// 阻塞DDL的事务的处理方式
+const (
+	DDLBlockerRefuse = "refuse"
+	DDLBlockerWait   = "wait"
+	DDLBlockerRetry  = "retry"
+)
+
+// 阻塞DDL的事务的来源
+const (
+	BlockerMetadataLock = "metadata_lock"
+	BlockerLongTrx      = "long_trx"
+)
+
+// 锁等待超时重试的最大间隔
+const maxLockRetryInterval = 30 * time.Second
+
+// DDLBlocker 执行DDL前发现的可能阻塞DDL的事务
+type DDLBlocker struct {
+	ThreadID int64
+	// 当前执行的语句,事务空闲时为空
+	Query string
+	// 事务已运行时间(秒)
+	Time int64
+	// metadata_lock: 持有表的元数据锁; long_trx: 长事务
+	Source string
+}
+
+func (b DDLBlocker) String() string {
+	return fmt.Sprintf("thread %d running %ds", b.ThreadID, b.Time)
+}
+
+// ddlTableNames 返回DDL语句需要获取元数据锁的表
+func (s *session) ddlTableNames(record *Record) []*ast.TableName {
+	var tables []*ast.TableName
+	switch node := record.Type.(type) {
+	case *ast.AlterTableStmt:
+		tables = append(tables, node.Table)
+	case *ast.CreateIndexStmt:
+		tables = append(tables, node.Table)
+	case *ast.DropIndexStmt:
+		tables = append(tables, node.Table)
+	case *ast.TruncateTableStmt:
+		tables = append(tables, node.Table)
+	case *ast.DropTableStmt:
+		if !node.IsView {
+			tables = append(tables, node.Tables...)
+		}
+	case *ast.RenameTableStmt:
+		for _, t := range node.TableToTables {
+			tables = append(tables, t.OldTable)
+		}
+		if len(tables) == 0 && node.OldTable != nil {
+			tables = append(tables, node.OldTable)
+		}
+	}
+	return tables
+}
+
+// queryDDLBlockers 查询持有表元数据锁的事务,以及运行时间超过ddl_long_trx_time的长事务.
+// 可以查询元数据锁时(mdl为true)持有表元数据锁的事务已包含了相关的长事务,不再单独查询;
+// 否则MySQL 8.0通过data_locks查询在该表上加锁的长事务,更低的版本无法区分表,查询所有长事务.
+// 查询失败时(如没有权限)仅记录日志,不影响执行
+func (s *session) queryDDLBlockers(tables []*ast.TableName, mdl bool) []DDLBlocker {
+	var blockers []DDLBlocker
+	found := make(map[int64]bool)
+	add := func(list []DDLBlocker) {
+		for _, b := range list {
+			if !found[b.ThreadID] {
+				found[b.ThreadID] = true
+				blockers = append(blockers, b)
+			}
+		}
+	}
+
+	if mdl {
+		for _, t := range tables {
+			db, name := s.blockerTableName(t)
+			sql := fmt.Sprintf(`SELECT DISTINCT t.PROCESSLIST_ID,
+	IFNULL(p.INFO, ''),
+	IFNULL(TIMESTAMPDIFF(SECOND, x.trx_started, NOW()), IFNULL(p.TIME, 0))
+FROM performance_schema.metadata_locks m
+	JOIN performance_schema.threads t ON t.THREAD_ID = m.OWNER_THREAD_ID
+	LEFT JOIN information_schema.PROCESSLIST p ON p.ID = t.PROCESSLIST_ID
+	LEFT JOIN information_schema.INNODB_TRX x ON x.trx_mysql_thread_id = t.PROCESSLIST_ID
+WHERE m.OBJECT_TYPE = 'TABLE' AND m.LOCK_STATUS = 'GRANTED'
+	AND m.OBJECT_SCHEMA = '%s' AND m.OBJECT_NAME = '%s'
+	AND t.PROCESSLIST_ID <> CONNECTION_ID();`, db, name)
+			add(s.queryBlockers(sql, BlockerMetadataLock))
+		}
+		return blockers
+	}
+
+	if s.inc.DDLLongTrxTime == 0 {
+		return blockers
+	}
+
+	if s.dbType == DBTypeMysql && s.dbVersion >= 80000 {
+		for _, t := range tables {
+			db, name := s.blockerTableName(t)
+			sql := fmt.Sprintf(`SELECT DISTINCT x.trx_mysql_thread_id, IFNULL(x.trx_query, ''),
+	TIMESTAMPDIFF(SECOND, x.trx_started, NOW())
+FROM information_schema.INNODB_TRX x
+	JOIN performance_schema.data_locks l ON l.ENGINE_TRANSACTION_ID = x.trx_id
+WHERE l.OBJECT_SCHEMA = '%s' AND l.OBJECT_NAME = '%s'
+	AND x.trx_mysql_thread_id <> CONNECTION_ID()
+	AND x.trx_started <= NOW() - INTERVAL %d SECOND;`, db, name, s.inc.DDLLongTrxTime)
+			add(s.queryBlockers(sql, BlockerLongTrx))
+		}
+		return blockers
+	}
+
+	sql := fmt.Sprintf(`SELECT trx_mysql_thread_id, IFNULL(trx_query, ''),
+	TIMESTAMPDIFF(SECOND, trx_started, NOW())
+FROM information_schema.INNODB_TRX
+WHERE trx_mysql_thread_id <> CONNECTION_ID()
+	AND trx_started <= NOW() - INTERVAL %d SECOND;`, s.inc.DDLLongTrxTime)
+	add(s.queryBlockers(sql, BlockerLongTrx))
+	return blockers
+}
+
+func (s *session) blockerTableName(t *ast.TableName) (string, string) {
+	db := t.Schema.O
+	if db == "" {
+		db = s.dbName
+	}
+	return db, t.Name.O
+}
+
+// mdlInstrumentEnabled 是否可以通过performance_schema.metadata_locks查询表的元数据锁.
+// MySQL 5.7默认未开启wait/lock/metadata/sql/mdl,warn为true时给出警告
+func (s *session) mdlInstrumentEnabled(warn bool) bool {
+	// performance_schema.metadata_locks从MySQL 5.7开始支持
+	if s.dbType != DBTypeMysql || s.dbVersion < 50700 {
+		return false
+	}
+
+	sql := `SELECT ENABLED FROM performance_schema.setup_instruments
+WHERE NAME = 'wait/lock/metadata/sql/mdl';`
+	if s.isMiddleware() {
+		sql = s.opt.MiddlewareExtend + sql
+	}
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Warnf("con:%d 查询元数据锁的instrument失败: %v", s.sessionVars.ConnectionID, err)
+		return false
+	}
+
+	var enabled string
+	for rows.Next() {
+		if err := rows.Scan(&enabled); err != nil {
+			log.Warnf("con:%d 查询元数据锁的instrument失败: %v", s.sessionVars.ConnectionID, err)
+			return false
+		}
+	}
+
+	if strings.ToUpper(enabled) != "YES" {
+		if warn {
+			s.appendWarning(ErrMDLInstrumentDisabled)
+		}
+		return false
+	}
+	return true
+}
+
+func (s *session) queryBlockers(sql string, source string) []DDLBlocker {
+	if s.isMiddleware() {
+		sql = s.opt.MiddlewareExtend + sql
+	}
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Warnf("con:%d 查询阻塞DDL的事务失败: %v", s.sessionVars.ConnectionID, err)
+		return nil
+	}
+
+	var blockers []DDLBlocker
+	for rows.Next() {
+		b := DDLBlocker{Source: source}
+		if err := rows.Scan(&b.ThreadID, &b.Query, &b.Time); err != nil {
+			log.Warnf("con:%d 查询阻塞DDL的事务失败: %v", s.sessionVars.ConnectionID, err)
+			return blockers
+		}
+		blockers = append(blockers, b)
+	}
+	return blockers
+}
+
+// checkDDLBlockers 执行DDL(或OSC)前检查阻塞的事务,
+// refuse时直接拒绝执行,wait时等待阻塞的事务结束. 返回false时不再执行
+func (s *session) checkDDLBlockers(record *Record) bool {
+	if !s.useDDLBlockerCheck(record) {
+		return true
+	}
+
+	mdl := s.mdlInstrumentEnabled(true)
+	deadline := time.Now().Add(time.Duration(s.inc.DDLBlockerWaitTime) * time.Second)
+	for {
+		blocked, abort := s.ddlBlocked(record, mdl, deadline)
+		if !blocked {
+			return true
+		}
+		if abort {
+			return false
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// ddlBlocked 查询阻塞DDL的事务. refuse或超过deadline时记录错误并返回abort
+func (s *session) ddlBlocked(record *Record, mdl bool, deadline time.Time) (blocked, abort bool) {
+	tables := s.ddlTableNames(record)
+	blockers := s.queryDDLBlockers(tables, mdl)
+	if len(blockers) == 0 {
+		return false, false
+	}
+	record.DDLBlockers = blockers
+
+	if strings.ToLower(s.inc.DDLBlockerAction) == DDLBlockerRefuse || !time.Now().Before(deadline) {
+		s.appendErrorNo(ErrDDLBlocked, tables[0].Name.O, len(blockers), formatBlockers(blockers))
+		return true, true
+	}
+
+	log.Infof("con:%d 等待阻塞DDL的事务结束: %s", s.sessionVars.ConnectionID, formatBlockers(blockers))
+	return true, false
+}
+
+// useDDLBlockerCheck 是否在执行前(以及OSC切换表前)检查阻塞的事务
+func (s *session) useDDLBlockerCheck(record *Record) bool {
+	action := strings.ToLower(s.inc.DDLBlockerAction)
+	return (action == DDLBlockerRefuse || action == DDLBlockerWait) &&
+		s.dbType != DBTypeTiDB && len(s.ddlTableNames(record)) > 0
+}
+
+// execDDLWithLockRetry 以较短的lock_wait_timeout执行DDL,避免长时间等待元数据锁阻塞其他会话.
+// 锁等待超时后记录阻塞的事务,并按指数退避重试
+func (s *session) execDDLWithLockRetry(record *Record, isTran bool) (res sql.Result, err error) {
+	exec := s.exec
+	if isTran {
+		exec = s.execDDL
+	}
+
+	_, err = exec(fmt.Sprintf("SET SESSION lock_wait_timeout = %d", s.inc.DDLLockWaitTimeout), false)
+	if err != nil {
+		return
+	}
+	defer exec("SET SESSION lock_wait_timeout = DEFAULT", false)
+
+	mdl := s.mdlInstrumentEnabled(true)
+	interval := time.Second
+	for i := 0; ; i++ {
+		res, err = exec(record.Sql, false)
+		myErr, ok := err.(*mysqlDriver.MySQLError)
+		if !ok || myErr.Number != mysql.ErrLockWaitTimeout || i >= int(s.inc.DDLLockRetryCount) {
+			return
+		}
+
+		if blockers := s.queryDDLBlockers(s.ddlTableNames(record), mdl); len(blockers) > 0 {
+			record.DDLBlockers = blockers
+		}
+		log.Warnf("con:%d 锁等待超时,%v后第%d次重试: %s", s.sessionVars.ConnectionID,
+			interval, i+1, formatBlockers(record.DDLBlockers))
+
+		time.Sleep(interval)
+		interval *= 2
+		if interval > maxLockRetryInterval {
+			interval = maxLockRetryInterval
+		}
+	}
+}
+
+// useDDLLockRetry 是否以锁等待超时重试的方式执行
+func (s *session) useDDLLockRetry(record *Record) bool {
+	return strings.ToLower(s.inc.DDLBlockerAction) == DDLBlockerRetry &&
+		s.dbType != DBTypeTiDB && s.inc.DDLLockWaitTimeout > 0 &&
+		len(s.ddlTableNames(record)) > 0
+}
+
+func formatBlockers(blockers []DDLBlocker) string {
+	list := make([]string, 0, len(blockers))
+	for _, b := range blockers {
+		list = append(list, b.String())
+	}
+	return strings.Join(list, ", ")
+}
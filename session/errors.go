@@ -24,6 +24,7 @@ import (
 	"github.com/hanchuanchuan/inception-core/config"
 	"github.com/hanchuanchuan/inception-core/mysql"
 	"github.com/hanchuanchuan/inception-core/terror"
+	"github.com/pingcap/errors"
 )
 
 //go:generate stringer -type=ErrorCode
@@ -36,6 +37,18 @@ var (
 		mysql.MySQLErrName[mysql.ErrTruncatedWrongValue])
 	ErrWrongTypeForVar = terror.ClassVariable.New(mysql.ErrWrongTypeForVar,
 		mysql.MySQLErrName[mysql.ErrWrongTypeForVar])
+
+	// ErrOscNotFound osc进程不存在
+	ErrOscNotFound = errors.New("osc process not found")
+
+	// ErrRollbackConflict 回滚的数据在执行后已变更
+	ErrRollbackConflict = errors.New("rollback conflict")
+
+	// ErrRollbackAuditFailed 回滚语句审核存在错误
+	ErrRollbackAuditFailed = errors.New("rollback audit failed")
+
+	// ErrRollbackIncomplete 回滚语句无法完全恢复原操作,如删除或清空分区的数据
+	ErrRollbackIncomplete = errors.New("rollback incomplete")
 )
 
 const (
@@ -207,6 +220,44 @@ const (
 	ErrJoinNoOnCondition
 	ErrImplicitTypeConversion
 	ErrUseValueExpr
+	ErrRollbackRowNotFound
+	ErrRollbackRowExists
+	ErrViewSelectStar
+	ErrViewSecurityInvoker
+	ErrViewAlgorithmTemptable
+	ErrViewColumnCount
+	ErrRoutineDefiner
+	ErrRoutinePrefix
+	ErrTriggerOnOscTable
+	ErrGrantAllOnGlobal
+	ErrUserHostWildcard
+	ErrPasswordWeak
+	ErrUserRequireSsl
+	ErrFeatureRequireVersion
+	ErrDatabaseNamePattern
+	ErrTableNamePattern
+	ErrColumnNamePattern
+	ErrIndexNamePattern
+	ErrUniqIndexNamePattern
+	ErrForeignKeyNamePattern
+	ErrViewNamePattern
+	ErrRedundantIndex
+	ErrRowSizeTooLarge
+	ErrInnodbRowSizeTooLarge
+	ErrIndexKeyTooLong
+	ErrPartitionKeyNotInUniqueKey
+	ErrTooManyPartitions
+	ErrPartitionRangeNotIncreasing
+	ErrPartitionNotExisted
+	ErrDupPartitionName
+	ErrNotPartitionedTable
+	ErrPartitionMgmtNotSupported
+	ErrPartitionDataLoss
+	ErrPartitionExchange
+	ErrAlterAlgorithmNotSupported
+	ErrDDLBlocked
+	ErrThrottleAbort
+	ErrMDLInstrumentDisabled
 	ER_ERROR_LAST
 )
 
@@ -381,6 +432,44 @@ var ErrorsDefault = map[ErrorCode]string{
 	ErrJoinNoOnCondition:           "set the on clause for join statement.",
 	ErrImplicitTypeConversion:      "Implicit type conversion is not allowed(column '%s.%s',type '%s').",
 	ErrUseValueExpr:                "Please confirm if you want to use value expression in where condition.",
+	ErrRollbackRowNotFound:         "Rollback conflict: the row has been changed or deleted since execution.",
+	ErrRollbackRowExists:           "Rollback conflict: the row already exists.",
+	ErrViewSelectStar:              "Select only star is not allowed in view '%s'.",
+	ErrViewSecurityInvoker:         "View '%s' must be created with SQL SECURITY INVOKER.",
+	ErrViewAlgorithmTemptable:      "ALGORITHM=TEMPTABLE is not allowed for view '%s'.",
+	ErrViewColumnCount:             "View '%s': SELECT and field list have different column counts.",
+	ErrRoutineDefiner:              "Definer of %s '%s' must be the current user.",
+	ErrRoutinePrefix:               "%s name '%s' must start with '%s'.",
+	ErrTriggerOnOscTable:           "Trigger is not allowed on table '%s' which may be altered by online schema change.",
+	ErrGrantAllOnGlobal:            "Grant all privileges on *.* to user '%s' is not allowed.",
+	ErrUserHostWildcard:            "Wildcard host is not allowed for user '%s'.",
+	ErrPasswordWeak:                "Password of user '%s' is too weak, at least %d characters with upper case, lower case, digit and special character are required.",
+	ErrUserRequireSsl:              "User '%s' must be created with REQUIRE SSL.",
+	ErrFeatureRequireVersion:       "%s requires MySQL %s or later.",
+	ErrDatabaseNamePattern:         "Database name '%s' does not match naming pattern '%s'.",
+	ErrTableNamePattern:            "Table name '%s' does not match naming pattern '%s'.",
+	ErrColumnNamePattern:           "Column name '%s' does not match naming pattern '%s'.",
+	ErrIndexNamePattern:            "Index name '%s' does not match naming pattern '%s'.",
+	ErrUniqIndexNamePattern:        "Unique index name '%s' does not match naming pattern '%s'.",
+	ErrForeignKeyNamePattern:       "Foreign key name '%s' does not match naming pattern '%s'.",
+	ErrViewNamePattern:             "View name '%s' does not match naming pattern '%s'.",
+	ErrRedundantIndex:              "Index '%s' on table '%s' is redundant with index '%s', suggest: %s",
+	ErrRowSizeTooLarge:             "Row size of table '%s' is %d bytes, exceeds the maximum row size of %d bytes (not counting BLOBs).",
+	ErrInnodbRowSizeTooLarge:       "Row size of table '%s' is %d bytes in row format %s, exceeds the InnoDB inline limit of %d bytes.",
+	ErrIndexKeyTooLong:             "Key length of index '%s' on table '%s' is %d bytes, exceeds the maximum of %d bytes.",
+	ErrPartitionKeyNotInUniqueKey:  "Index '%s' on table '%s' must include all columns in the table's partitioning function.",
+	ErrTooManyPartitions:           "Table '%s' has %d partitions, exceeds the maximum of %d.",
+	ErrPartitionRangeNotIncreasing: "VALUES LESS THAN value must be strictly increasing for each partition, partition '%s' of table '%s'.",
+	ErrPartitionNotExisted:         "Partition '%s' doesn't exist in table '%s'.",
+	ErrDupPartitionName:            "Duplicate partition name '%s' in table '%s'.",
+	ErrNotPartitionedTable:         "Partition management on a not partitioned table '%s' is not possible.",
+	ErrPartitionMgmtNotSupported:   "%s PARTITION is not supported for %s partitioned table '%s'.",
+	ErrPartitionDataLoss:           "%s PARTITION %s of table '%s' will lose about %d rows.",
+	ErrPartitionExchange:           "Table '%s' can't be exchanged with partition '%s' of table '%s': %s.",
+	ErrAlterAlgorithmNotSupported:  "%s is not supported for this operation on table '%s'. Try %s.",
+	ErrDDLBlocked:                  "DDL on table '%s' is blocked by %d transaction(s): %s.",
+	ErrThrottleAbort:               "Execution aborted by throttler: %s.",
+	ErrMDLInstrumentDisabled:       "Instrument 'wait/lock/metadata/sql/mdl' is disabled, transactions holding the metadata lock can't be detected.",
 	ER_ERROR_LAST:                  "TheLastError,ByeBye",
 }
 
@@ -547,6 +636,100 @@ var ErrorsChinese = map[ErrorCode]string{
 	ErrJoinNoOnCondition:                   "join语句请指定on子句.",
 	ErrImplicitTypeConversion:              "不允许隐式类型转换(列'%s.%s',类型'%s').",
 	ErrUseValueExpr:                        "请确认是否要在where条件中使用值表达式.",
+	ErrRollbackRowNotFound:                 "回滚冲突: 数据在执行后已被修改或删除.",
+	ErrRollbackRowExists:                   "回滚冲突: 数据已存在.",
+	ErrViewSelectStar:                      "视图'%s'不允许'select *'语法.",
+	ErrViewSecurityInvoker:                 "视图'%s'必须指定SQL SECURITY INVOKER.",
+	ErrViewAlgorithmTemptable:              "视图'%s'不允许ALGORITHM=TEMPTABLE.",
+	ErrViewColumnCount:                     "视图'%s'的列数和SELECT的列数不一致.",
+	ErrRoutineDefiner:                      "%s'%s'的定义者必须为当前用户.",
+	ErrRoutinePrefix:                       "%s名'%s'需要以'%s'开头.",
+	ErrTriggerOnOscTable:                   "表'%s'可能使用pt-osc/gh-ost变更,不允许创建触发器.",
+	ErrGrantAllOnGlobal:                    "不允许授予用户'%s'全局的所有权限(ALL ON *.*).",
+	ErrUserHostWildcard:                    "用户'%s'的主机不允许使用通配符'%%'.",
+	ErrPasswordWeak:                        "用户'%s'的密码强度不足,至少%d位且须同时包含大写字母,小写字母,数字和特殊字符.",
+	ErrUserRequireSsl:                      "创建用户'%s'时须指定REQUIRE SSL.",
+	ErrFeatureRequireVersion:               "%s需要MySQL %s及以上版本.",
+	ErrDatabaseNamePattern:                 "库名'%s'不符合命名规范'%s'.",
+	ErrTableNamePattern:                    "表名'%s'不符合命名规范'%s'.",
+	ErrColumnNamePattern:                   "列名'%s'不符合命名规范'%s'.",
+	ErrIndexNamePattern:                    "索引名'%s'不符合命名规范'%s'.",
+	ErrUniqIndexNamePattern:                "唯一索引名'%s'不符合命名规范'%s'.",
+	ErrForeignKeyNamePattern:               "外键名'%s'不符合命名规范'%s'.",
+	ErrViewNamePattern:                     "视图名'%s'不符合命名规范'%s'.",
+	ErrRedundantIndex:                      "索引'%s'(表'%s')与索引'%s'重复或冗余,建议: %s",
+	ErrRowSizeTooLarge:                     "表'%s'的行大小为%d字节,超出最大行大小%d字节(不含BLOB).",
+	ErrInnodbRowSizeTooLarge:               "表'%s'的行大小为%d字节(行格式%s),超出InnoDB单行限制%d字节.",
+	ErrIndexKeyTooLong:                     "索引'%s'(表'%s')的长度为%d字节,超出最大长度%d字节.",
+	ErrPartitionKeyNotInUniqueKey:          "索引'%s'(表'%s')必须包含分区函数使用的所有列.",
+	ErrTooManyPartitions:                   "表'%s'的分区数为%d,超出最大分区数%d.",
+	ErrPartitionRangeNotIncreasing:         "分区'%s'(表'%s')的VALUES LESS THAN值必须严格递增.",
+	ErrPartitionNotExisted:                 "分区'%s'在表'%s'中不存在.",
+	ErrDupPartitionName:                    "分区名'%s'(表'%s')重复.",
+	ErrNotPartitionedTable:                 "表'%s'不是分区表,无法进行分区管理.",
+	ErrPartitionMgmtNotSupported:           "%s PARTITION不支持%s分区的表'%s'.",
+	ErrPartitionDataLoss:                   "%s PARTITION %s(表'%s')将丢失约%d行数据.",
+	ErrPartitionExchange:                   "表'%s'不能与分区'%s'(表'%s')交换: %s.",
+	ErrAlterAlgorithmNotSupported:          "%s不支持表'%s'的此变更,请使用%s.",
+	ErrDDLBlocked:                          "表'%s'的DDL被%d个事务阻塞: %s.",
+	ErrThrottleAbort:                       "执行被限流终止: %s.",
+	ErrMDLInstrumentDisabled:               "未开启instrument 'wait/lock/metadata/sql/mdl',无法检查持有元数据锁的事务.",
+}
+
+// columnArgIndex 错误信息中列名参数的位置
+var columnArgIndex = map[ErrorCode]int{
+	ER_BAD_FIELD_ERROR:             0,
+	ER_FIELD_SPECIFIED_TWICE:       0,
+	ER_BAD_NULL_ERROR:              0,
+	ER_INVALID_DATA_TYPE:           0,
+	ER_NOT_ALLOWED_NULLABLE:        0,
+	ER_DUP_FIELDNAME:               0,
+	ER_WRONG_COLUMN_NAME:           0,
+	ER_WRONG_KEY_COLUMN:            0,
+	ER_COLUMN_HAVE_NO_COMMENT:      0,
+	ER_USE_TEXT_OR_BLOB:            0,
+	ER_COLUMN_EXISTED:              0,
+	ER_COLUMN_NOT_EXISTED:          0,
+	ER_INVALID_DEFAULT:             0,
+	ER_BLOB_USED_AS_KEY:            0,
+	ER_VARCHAR_TO_TEXT_LEN:         0,
+	ER_CHAR_TO_VARCHAR_LEN:         0,
+	ER_KEY_COLUMN_DOES_NOT_EXITS:   0,
+	ER_TIMESTAMP_DEFAULT:           0,
+	ER_CHARSET_ON_COLUMN:           1,
+	ER_AUTO_INCR_ID_WARNING:        0,
+	ER_BLOB_CANT_HAVE_DEFAULT:      0,
+	ER_NON_UNIQ_ERROR:              0,
+	ER_WITH_DEFAULT_ADD_COLUMN:     0,
+	ER_TEXT_NOT_NULLABLE_ERROR:     0,
+	ER_INVALID_ON_UPDATE:           0,
+	ER_PK_COLS_NOT_INT:             0,
+	ER_CHANGE_COLUMN_TYPE:          0,
+	ErrColumnsMustHaveIndexTypeErr: 0,
+	ErrDataTooLong:                 0,
+	ErrJsonTypeSupport:             0,
+	ErrMixOfGroupFuncAndFields:     1,
+	ErrFieldNotInGroupBy:           2,
+	ErCantChangeColumnPosition:     0,
+	ErCantChangeColumn:             0,
+	ER_DATETIME_DEFAULT:            0,
+	ErrFloatDoubleToDecimal:        0,
+	ErrImplicitTypeConversion:      1,
+}
+
+// indexArgIndex 错误信息中索引名参数的位置
+var indexArgIndex = map[ErrorCode]int{
+	ER_TOO_MANY_KEY_PARTS:         0,
+	ER_WRONG_NAME_FOR_INDEX:       0,
+	ER_TOO_LONG_KEY:               0,
+	ER_DUP_KEYNAME:                0,
+	ER_TOO_LONG_INDEX_COMMENT:     0,
+	ER_DUP_INDEX:                  0,
+	ER_INDEX_NAME_IDX_PREFIX:      0,
+	ER_INDEX_NAME_UNIQ_PREFIX:     0,
+	ErrRedundantIndex:             0,
+	ErrIndexKeyTooLong:            0,
+	ErrPartitionKeyNotInUniqueKey: 0,
 }
 
 func GetErrorLevel(code ErrorCode) uint8 {
@@ -585,6 +768,23 @@ func GetErrorLevel(code ErrorCode) uint8 {
 		ER_PK_COLS_NOT_INT,
 		ER_PK_TOO_MANY_PARTS,
 		ER_SELECT_ONLY_STAR,
+		ErrViewSelectStar,
+		ErrViewSecurityInvoker,
+		ErrViewAlgorithmTemptable,
+		ErrRoutineDefiner,
+		ErrRoutinePrefix,
+		ErrUserHostWildcard,
+		ErrUserRequireSsl,
+		ErrDatabaseNamePattern,
+		ErrTableNamePattern,
+		ErrColumnNamePattern,
+		ErrIndexNamePattern,
+		ErrUniqIndexNamePattern,
+		ErrForeignKeyNamePattern,
+		ErrViewNamePattern,
+		ErrRedundantIndex,
+		ErrInnodbRowSizeTooLarge,
+		ErrPartitionDataLoss,
 		ER_TABLE_CHARSET_MUST_NULL,
 		ER_TABLE_CHARSET_MUST_UTF8,
 		ER_TABLE_MUST_HAVE_COMMENT,
@@ -1041,6 +1241,82 @@ func (e ErrorCode) String() string {
 		return "er_implicit_type_conversion"
 	case ErrUseValueExpr:
 		return "er_use_value_expr"
+	case ErrRollbackRowNotFound:
+		return "er_rollback_row_not_found"
+	case ErrRollbackRowExists:
+		return "er_rollback_row_exists"
+	case ErrViewSelectStar:
+		return "er_view_select_star"
+	case ErrViewSecurityInvoker:
+		return "er_view_security_invoker"
+	case ErrViewAlgorithmTemptable:
+		return "er_view_algorithm_temptable"
+	case ErrViewColumnCount:
+		return "er_view_column_count"
+	case ErrRoutineDefiner:
+		return "er_routine_definer"
+	case ErrRoutinePrefix:
+		return "er_routine_prefix"
+	case ErrTriggerOnOscTable:
+		return "er_trigger_on_osc_table"
+	case ErrGrantAllOnGlobal:
+		return "er_grant_all_on_global"
+	case ErrUserHostWildcard:
+		return "er_user_host_wildcard"
+	case ErrPasswordWeak:
+		return "er_password_weak"
+	case ErrUserRequireSsl:
+		return "er_user_require_ssl"
+	case ErrFeatureRequireVersion:
+		return "er_feature_require_version"
+	case ErrDatabaseNamePattern:
+		return "er_database_name_pattern"
+	case ErrTableNamePattern:
+		return "er_table_name_pattern"
+	case ErrColumnNamePattern:
+		return "er_column_name_pattern"
+	case ErrIndexNamePattern:
+		return "er_index_name_pattern"
+	case ErrUniqIndexNamePattern:
+		return "er_uniq_index_name_pattern"
+	case ErrForeignKeyNamePattern:
+		return "er_foreign_key_name_pattern"
+	case ErrViewNamePattern:
+		return "er_view_name_pattern"
+	case ErrRedundantIndex:
+		return "er_redundant_index"
+	case ErrRowSizeTooLarge:
+		return "er_row_size_too_large"
+	case ErrInnodbRowSizeTooLarge:
+		return "er_innodb_row_size_too_large"
+	case ErrIndexKeyTooLong:
+		return "er_index_key_too_long"
+	case ErrPartitionKeyNotInUniqueKey:
+		return "er_partition_key_not_in_unique_key"
+	case ErrTooManyPartitions:
+		return "er_too_many_partitions"
+	case ErrPartitionRangeNotIncreasing:
+		return "er_partition_range_not_increasing"
+	case ErrPartitionNotExisted:
+		return "er_partition_not_existed"
+	case ErrDupPartitionName:
+		return "er_dup_partition_name"
+	case ErrNotPartitionedTable:
+		return "er_not_partitioned_table"
+	case ErrPartitionMgmtNotSupported:
+		return "er_partition_mgmt_not_supported"
+	case ErrPartitionDataLoss:
+		return "er_partition_data_loss"
+	case ErrPartitionExchange:
+		return "er_partition_exchange"
+	case ErrAlterAlgorithmNotSupported:
+		return "er_alter_algorithm_not_supported"
+	case ErrDDLBlocked:
+		return "er_ddl_blocked"
+	case ErrThrottleAbort:
+		return "er_throttle_abort"
+	case ErrMDLInstrumentDisabled:
+		return "er_mdl_instrument_disabled"
 	case ER_ERROR_LAST:
 		return "er_error_last"
 	}
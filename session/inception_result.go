@@ -67,9 +67,9 @@ type Record struct {
 	// sql的hash值,osc使用
 	Sqlsha1 string
 
-	Buf *bytes.Buffer
+	Buf *bytes.Buffer `json:"-"`
 
-	Type ast.StmtNode
+	Type ast.StmtNode `json:"-"`
 
 	// 备份相关
 	ExecTimestamp int64
@@ -82,7 +82,7 @@ type Record struct {
 
 	DBName    string
 	TableName string
-	TableInfo *TableInfo
+	TableInfo *TableInfo `json:"-"`
 
 	// ddl回滚
 	DDLRollback string
@@ -93,10 +93,55 @@ type Record struct {
 	// 是否开启OSC
 	UseOsc bool
 
+	// 预测的ALTER TABLE执行算法和锁级别
+	DDLAlgorithm *DDLAlgorithm `json:",omitempty"`
+
+	// 执行DDL前发现的阻塞DDL的事务
+	DDLBlockers []DDLBlocker `json:",omitempty"`
+
 	// update多表时,记录多余的表
 	// update多表时,默认set第一列的表为主表,其余表才会记录到该处
 	// 仅在发现多表操作时,初始化该参数
-	MultiTables map[string]*TableInfo
+	MultiTables map[string]*TableInfo `json:"-"`
+
+	// 结构化的错误/警告信息,和ErrorMessage一一对应
+	Findings []Finding
+
+	// 语句在原始SQL中的位置
+	Position Position
+
+	// 自动合并ALTER TABLE时,合并前的原始语句
+	MergedSQL []string
+}
+
+// Position 语句在原始SQL中的位置.
+// 偏移量按字节计算,行号和列号从1开始,列号按字符计算.
+// 结束位置为语句最后一个字符之后的位置
+type Position struct {
+	Offset    int
+	EndOffset int
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+}
+
+// Finding 审核发现的问题.
+// 自定义规则(Rule)也通过Finding返回审核结果
+type Finding struct {
+	// 错误码. 通过错误信息直接添加时为0,
+	// 自定义规则可使用内置错误码,也可以使用大于ER_ERROR_LAST的自定义错误码
+	Code ErrorCode
+	// 错误码名称,即Code.String()
+	Name string
+	// 审核级别,1为警告,2为错误. 自定义规则返回0时使用错误码的自定义级别或默认级别
+	Level uint8
+	// 错误/警告信息,自定义规则返回空时使用错误码对应的信息
+	Message string
+	// 涉及的列
+	Column string
+	// 涉及的索引
+	Index string
 }
 
 type PrintRecord struct {
@@ -108,6 +153,8 @@ type PrintRecord struct {
 	ErrorMessage string
 	// 语法树
 	QueryTree string
+	// 结构化的错误/警告信息
+	Findings []Finding
 }
 
 type SplitRecord struct {
@@ -119,11 +166,22 @@ type SplitRecord struct {
 	ErrorMessage string
 	// ddl标志位
 	IsDDL bool
+	// 结构化的错误/警告信息
+	Findings []Finding
 }
 
 func (r *Record) appendErrorMessage(msg string) {
 	r.ErrLevel = 2
 
+	r.writeMessage(msg)
+	r.Findings = append(r.Findings, Finding{
+		Level:   2,
+		Message: msg,
+	})
+}
+
+// writeMessage 写入错误信息,不以句号或感叹号结尾时自动补全句号
+func (r *Record) writeMessage(msg string) {
 	r.Buf.WriteString(msg)
 	if !strings.HasSuffix(msg, ".") && !strings.HasSuffix(msg, "!") {
 		r.Buf.WriteString(".")
@@ -134,24 +192,42 @@ func (r *Record) appendErrorMessage(msg string) {
 func (r *Record) appendErrorNo(lang string, number ErrorCode, values ...interface{}) {
 	r.ErrLevel = uint8(Max(int(r.ErrLevel), int(GetErrorLevel(number))))
 
-	if len(values) == 0 {
-		r.Buf.WriteString(GetErrorMessage(number, lang))
-	} else {
-		r.Buf.WriteString(fmt.Sprintf(GetErrorMessage(number, lang), values...))
-	}
+	f := newFinding(lang, number, GetErrorLevel(number), values...)
+	r.Buf.WriteString(f.Message)
 	r.Buf.WriteString("\n")
+	r.Findings = append(r.Findings, f)
 }
 
 // appendWarning 添加警告. 错误级别指定为警告
 func (r *Record) appendWarning(lang string, number ErrorCode, values ...interface{}) {
 	r.ErrLevel = uint8(Max(int(r.ErrLevel), 1))
 
+	f := newFinding(lang, number, 1, values...)
+	r.Buf.WriteString(f.Message)
+	r.Buf.WriteString("\n")
+	r.Findings = append(r.Findings, f)
+}
+
+// newFinding 根据错误码生成审核结果,并根据参数解析涉及的列或索引
+func newFinding(lang string, number ErrorCode, level uint8, values ...interface{}) Finding {
+	f := Finding{
+		Code:  number,
+		Name:  number.String(),
+		Level: level,
+	}
+
 	if len(values) == 0 {
-		r.Buf.WriteString(GetErrorMessage(number, lang))
+		f.Message = GetErrorMessage(number, lang)
 	} else {
-		r.Buf.WriteString(fmt.Sprintf(GetErrorMessage(number, lang), values...))
+		f.Message = fmt.Sprintf(GetErrorMessage(number, lang), values...)
 	}
-	r.Buf.WriteString("\n")
+
+	if i, ok := columnArgIndex[number]; ok && i < len(values) {
+		f.Column = fmt.Sprint(values[i])
+	} else if i, ok := indexArgIndex[number]; ok && i < len(values) {
+		f.Index = fmt.Sprint(values[i])
+	}
+	return f
 }
 
 // cut 清理无须返回的字段
@@ -649,7 +725,8 @@ func (r *PrintRecord) List() []interface{} {
 	return columns
 }
 
-func (s *PrintSets) Append(errLevel int64, sql, tree, errmsg string) {
+// Append 添加打印结果. 未指定findings时根据errmsg生成
+func (s *PrintSets) Append(errLevel int64, sql, tree, errmsg string, findings ...Finding) {
 	row := make([]types.Datum, s.rc.fieldCount)
 
 	row[0].SetInt64(int64(s.rc.count + 1))
@@ -662,12 +739,17 @@ func (s *PrintSets) Append(errLevel int64, sql, tree, errmsg string) {
 		row[4].SetString(errmsg)
 	}
 
+	if len(findings) == 0 && errmsg != "" {
+		findings = []Finding{{Level: uint8(errLevel), Message: errmsg}}
+	}
+
 	s.records = append(s.records, PrintRecord{
 		ID:           s.rc.count + 1,
 		SQL:          sql,
 		ErrLevel:     uint8(errLevel),
 		ErrorMessage: errmsg,
 		QueryTree:    tree,
+		Findings:     findings,
 	})
 
 	s.rc.data = append(s.rc.data, row)
@@ -718,7 +800,8 @@ func NewSplitSets() *SplitSets {
 	return t
 }
 
-func (s *SplitSets) Append(sql string, errmsg string) {
+// Append 添加拆分结果. 未指定findings时根据errmsg生成
+func (s *SplitSets) Append(sql string, errmsg string, findings ...Finding) {
 	row := make([]types.Datum, s.rc.fieldCount)
 
 	row[0].SetInt64(s.id)
@@ -738,7 +821,11 @@ func (s *SplitSets) Append(sql string, errmsg string) {
 	}
 	if errmsg != "" {
 		record.ErrLevel = 2
+		if len(findings) == 0 {
+			findings = []Finding{{Level: 2, Message: errmsg}}
+		}
 	}
+	record.Findings = findings
 
 	s.records = append(s.records, record)
 
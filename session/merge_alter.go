@@ -0,0 +1,224 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/format"
+	"github.com/hanchuanchuan/inception-core/parser"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// auditStmt 待审核的语句
+type auditStmt struct {
+	node ast.StmtNode
+	sql  string
+	pos  Position
+	// 审核进度
+	percent float64
+	// 自动合并时,合并前的原始语句
+	merged []string
+}
+
+// alterObjects ALTER语句新增,删除和引用的列和索引
+type alterObjects struct {
+	addColumns  map[string]bool
+	dropColumns map[string]bool
+	addIndexes  map[string]bool
+
+	// 依赖已有的列和索引
+	refColumns []string
+	refIndexes []string
+}
+
+func newAlterObjects(specs []*ast.AlterTableSpec) *alterObjects {
+	o := &alterObjects{
+		addColumns:  make(map[string]bool),
+		dropColumns: make(map[string]bool),
+		addIndexes:  make(map[string]bool),
+	}
+	for _, spec := range specs {
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			for _, col := range spec.NewColumns {
+				o.addColumns[col.Name.Name.L] = true
+			}
+		case ast.AlterTableDropColumn:
+			o.refColumns = append(o.refColumns, spec.OldColumnName.Name.L)
+			o.dropColumns[spec.OldColumnName.Name.L] = true
+		case ast.AlterTableModifyColumn, ast.AlterTableAlterColumn:
+			if len(spec.NewColumns) > 0 {
+				o.refColumns = append(o.refColumns, spec.NewColumns[0].Name.Name.L)
+			}
+		case ast.AlterTableChangeColumn:
+			o.refColumns = append(o.refColumns, spec.OldColumnName.Name.L)
+			o.dropColumns[spec.OldColumnName.Name.L] = true
+			if len(spec.NewColumns) > 0 {
+				o.addColumns[spec.NewColumns[0].Name.Name.L] = true
+			}
+		case ast.AlterTableRenameColumn:
+			o.refColumns = append(o.refColumns, spec.OldColumnName.Name.L)
+			o.dropColumns[spec.OldColumnName.Name.L] = true
+			o.addColumns[spec.NewColumnName.Name.L] = true
+		case ast.AlterTableAddConstraint:
+			name := strings.ToLower(spec.Constraint.Name)
+			if spec.Constraint.Tp == ast.ConstraintPrimaryKey {
+				name = "primary"
+			}
+			o.addIndexes[name] = true
+		case ast.AlterTableDropPrimaryKey:
+			o.refIndexes = append(o.refIndexes, "primary")
+		case ast.AlterTableDropIndex, ast.AlterTableDropForeignKey, ast.AlterTableIndexInvisible:
+			o.refIndexes = append(o.refIndexes, strings.ToLower(spec.Name))
+		case ast.AlterTableRenameIndex:
+			o.refIndexes = append(o.refIndexes, spec.FromKey.L)
+			o.addIndexes[spec.ToKey.L] = true
+		}
+	}
+	return o
+}
+
+// conflictWith 语句是否依赖head新增的列或索引,或重新添加head删除的列.
+// 这类变更在一条ALTER语句中无法完成,因此不能合并
+func (o *alterObjects) conflictWith(head *alterObjects) bool {
+	for _, name := range o.refColumns {
+		if head.addColumns[name] {
+			return true
+		}
+	}
+	for _, name := range o.refIndexes {
+		if head.addIndexes[name] {
+			return true
+		}
+	}
+	for name := range o.addColumns {
+		if head.dropColumns[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// isMergeableAlter 是否可以和其他ALTER语句合并.
+// 分区操作不能和其他操作同时进行,重命名表会改变之后语句的表名
+func isMergeableAlter(node *ast.AlterTableStmt) bool {
+	for _, spec := range node.Specs {
+		if isPartitionSpec(spec) || spec.Tp == ast.AlterTablePartition ||
+			spec.Tp == ast.AlterTableRenameTable {
+			return false
+		}
+	}
+	return len(node.Specs) > 0
+}
+
+// parseAuditStmts 解析所有语句,用以自动合并ALTER语句.
+// 解析失败或包含SET语句(可能修改sql_mode)时返回false,此时按原有方式逐段解析
+func (s *session) parseAuditStmts(ctx context.Context, segments []parser.Segment,
+	charsetInfo, collation string) ([]*auditStmt, bool) {
+	var stmts []*auditStmt
+	for i := range segments {
+		seg := &segments[i]
+
+		stmtNodes, err := s.ParseSQL(ctx, seg.Text, charsetInfo, collation)
+		if err != nil || len(stmtNodes) == 0 {
+			return nil, false
+		}
+
+		offset := 0
+		for _, stmtNode := range stmtNodes {
+			currentSQL := strings.Trim(stmtNode.Text(), " ;\t\n\v\f\r ")
+
+			var pos Position
+			pos, offset = segmentPosition(seg, offset, currentSQL)
+
+			switch stmtNode.(type) {
+			case *ast.InceptionStartStmt,
+				*ast.InceptionCommitStmt:
+				continue
+			case *ast.SetStmt:
+				return nil, false
+			}
+
+			stmts = append(stmts, &auditStmt{
+				node:    stmtNode,
+				sql:     currentSQL,
+				pos:     pos,
+				percent: float64(i) / float64(len(segments)),
+			})
+		}
+	}
+	return stmts, true
+}
+
+// mergeAlterTables 合并同一张表的多条ALTER语句,合并到该表的第一条ALTER语句.
+// 仅合并之间只有其他表的ALTER语句的语句,以保证语句的执行顺序不变
+func (s *session) mergeAlterTables(stmts []*auditStmt) []*auditStmt {
+	dbName := s.dbName
+	heads := make(map[string]*auditStmt)
+
+	result := make([]*auditStmt, 0, len(stmts))
+	for _, st := range stmts {
+		switch node := st.node.(type) {
+		case *ast.UseStmt:
+			dbName = node.DBName
+		case *ast.AlterTableStmt:
+			if !isMergeableAlter(node) {
+				heads = make(map[string]*auditStmt)
+				break
+			}
+
+			db := node.Table.Schema.O
+			if db == "" {
+				db = dbName
+			}
+			key := fmt.Sprintf("%s.%s", db, node.Table.Name.O)
+			if s.IgnoreCase() {
+				key = strings.ToLower(key)
+			}
+
+			head, ok := heads[key]
+			if ok && s.mergeAlterTable(head, st) {
+				continue
+			}
+			heads[key] = st
+		default:
+			heads = make(map[string]*auditStmt)
+		}
+		result = append(result, st)
+	}
+	return result
+}
+
+// mergeAlterTable 将语句的变更合并到head,无法合并时返回false
+func (s *session) mergeAlterTable(head *auditStmt, st *auditStmt) bool {
+	headNode := head.node.(*ast.AlterTableStmt)
+	node := st.node.(*ast.AlterTableStmt)
+
+	if newAlterObjects(node.Specs).conflictWith(newAlterObjects(headNode.Specs)) {
+		return false
+	}
+
+	specs := headNode.Specs
+	merged := &ast.AlterTableStmt{
+		Table: headNode.Table,
+		Specs: append(append([]*ast.AlterTableSpec{}, specs...), node.Specs...),
+	}
+
+	var builder strings.Builder
+	if err := merged.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder)); err != nil {
+		log.Warnf("con:%d 合并ALTER语句失败: %v", s.sessionVars.ConnectionID, err)
+		return false
+	}
+
+	if head.merged == nil {
+		head.merged = []string{head.sql}
+	}
+	head.merged = append(head.merged, st.sql)
+
+	headNode.Specs = merged.Specs
+	head.sql = builder.String()
+	headNode.SetText(head.sql)
+	return true
+}
@@ -0,0 +1,67 @@
+package session_test
+
+import (
+	"github.com/hanchuanchuan/inception-core/config"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestAutoMergeAlterTable(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id', c1 int comment 'c1') comment 't1';
+	create table t2(id int primary key comment 'id') comment 't2';`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnableNullable = true
+	cnf.Inc.MergeAlterTable = true
+	cnf.IncLevel.ER_ALTER_TABLE_ONCE = 1
+	cnf.Inc.AutoMergeAlterTable = true
+
+	result := s.audit(c, snapshot, `use test_inc;
+	alter table t1 add column c2 int comment 'c2';
+	alter table t2 add column c1 int comment 'c1';
+	alter table t1 add index idx_c2(c2), drop column c1;
+	alter table t1 modify column c2 bigint comment 'c2';
+	insert into t1(id) values(1);
+	alter table t1 add column c3 int comment 'c3';`)
+	c.Assert(len(result), Equals, 6)
+
+	// 之间只有其他表的ALTER语句时合并,添加索引可以使用前面新增的列
+	row := result[1]
+	c.Assert(row.ErrLevel, Equals, uint8(0), Commentf("%v", row.ErrorMessage))
+	c.Assert(row.Sql, Equals,
+		"ALTER TABLE `t1` ADD COLUMN `c2` INT COMMENT 'c2', ADD INDEX `idx_c2`(`c2`), DROP COLUMN `c1`")
+	c.Assert(row.MergedSQL, DeepEquals, []string{
+		"alter table t1 add column c2 int comment 'c2'",
+		"alter table t1 add index idx_c2(c2), drop column c1",
+	})
+	c.Assert(result[2].Sql, Equals, "alter table t2 add column c1 int comment 'c1'")
+	c.Assert(result[2].MergedSQL, IsNil)
+
+	// 修改前面新增的列时不能合并
+	c.Assert(result[3].Sql, Equals, "alter table t1 modify column c2 bigint comment 'c2'")
+	c.Assert(result[3].ErrorMessage, Equals, "Merge the alter statement for table 't1' to ONE.")
+
+	// 中间有其他语句时不合并
+	c.Assert(result[5].Sql, Equals, "alter table t1 add column c3 int comment 'c3'")
+	c.Assert(result[5].ErrorMessage, Equals, "Merge the alter statement for table 't1' to ONE.")
+
+	// SET语句可能修改sql_mode,此时不合并
+	result = s.audit(c, snapshot, `use test_inc;
+	set names utf8mb4;
+	alter table t1 add column c2 int comment 'c2';
+	alter table t1 add column c3 int comment 'c3';`)
+	c.Assert(len(result), Equals, 4)
+	c.Assert(result[3].ErrorMessage, Equals, "Merge the alter statement for table 't1' to ONE.")
+
+	// 合并后保留CURRENT_TIMESTAMP的小数秒精度
+	result = s.audit(c, snapshot, `use test_inc;
+	alter table t1 add column c2 datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment 'c2';
+	alter table t1 add column c3 int comment 'c3';`)
+	c.Assert(len(result), Equals, 2)
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[1].Sql, Equals, "ALTER TABLE `t1` ADD COLUMN `c2` DATETIME(3) NOT NULL "+
+		"DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'c2', ADD COLUMN `c3` INT COMMENT 'c3'")
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"github.com/hanchuanchuan/inception-core/util"
+)
+
+// EventType 事件类型
+type EventType int
+
+const (
+	// EventChecked 语句审核完成
+	EventChecked EventType = iota
+	// EventExecuted 语句执行完成(成功或失败),包含受影响行数和执行用时
+	EventExecuted
+	// EventBackup 语句备份完成
+	EventBackup
+	// EventOscProgress osc执行进度
+	EventOscProgress
+)
+
+var eventNames = [...]string{"checked", "executed", "backup", "osc_progress"}
+
+func (t EventType) String() string {
+	if int(t) < len(eventNames) {
+		return eventNames[t]
+	}
+	return "unknown"
+}
+
+// Event 审核/执行过程中的事件
+type Event struct {
+	Type EventType
+	// 语句的审核/执行结果,为事件发生时的副本.
+	// EventOscProgress时仅包含语句,表名等审核阶段确定的字段
+	Record *Record
+	// osc进度信息,仅EventOscProgress时有值
+	Osc *util.OscProcessInfo
+}
+
+// Observer 事件观察者,用以实时获取每条语句的审核/执行/备份结果和osc进度.
+// OnEvent在审核/执行过程中同步调用,耗时操作需要自行异步处理
+type Observer interface {
+	OnEvent(e *Event)
+}
+
+// ObserverFunc 函数形式的Observer
+type ObserverFunc func(e *Event)
+
+// OnEvent implements Observer.
+func (f ObserverFunc) OnEvent(e *Event) {
+	f(e)
+}
+
+// RegisterObserver 注册事件观察者,需要在Audit/RunExecute前调用
+func (s *session) RegisterObserver(observers ...Observer) {
+	for _, o := range observers {
+		if o != nil {
+			s.observers = append(s.observers, o)
+		}
+	}
+}
+
+// notify 通知语句相关事件
+func (s *session) notify(tp EventType, records ...*Record) {
+	if len(s.observers) == 0 {
+		return
+	}
+	for _, r := range records {
+		s.emit(&Event{Type: tp, Record: r.snapshot()})
+	}
+}
+
+// notifyOsc 通知osc进度. osc输出在单独的goroutine中解析,
+// 此时主流程仍可能修改记录,因此仅复制审核阶段确定的字段
+func (s *session) notifyOsc(record *Record, p *util.OscProcessInfo) {
+	if len(s.observers) == 0 {
+		return
+	}
+	pi := *p
+	s.emit(&Event{Type: EventOscProgress, Record: &Record{
+		Sql:       record.Sql,
+		Sqlsha1:   record.Sqlsha1,
+		SeqNo:     record.SeqNo,
+		DBName:    record.DBName,
+		TableName: record.TableName,
+		Position:  record.Position,
+	}, Osc: &pi})
+}
+
+func (s *session) emit(e *Event) {
+	s.observerMu.Lock()
+	defer s.observerMu.Unlock()
+	for _, o := range s.observers {
+		o.OnEvent(e)
+	}
+}
+
+// snapshot 返回记录的副本,错误信息从Buf中获取
+func (r *Record) snapshot() *Record {
+	c := *r
+	c.cut()
+	c.Findings = append([]Finding(nil), r.Findings...)
+	return &c
+}
@@ -0,0 +1,87 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testObserverSuite{})
+
+type testObserverSuite struct{}
+
+func TestObserver(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *testObserverSuite) SetUpSuite(c *C) {
+	config.GetGlobalConfig().Inc.Lang = "en-US"
+}
+
+func (s *testObserverSuite) TestCheckedEvent(c *C) {
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: "create database test_inc;",
+	})
+
+	var events []*session.Event
+	core.RegisterObserver(session.ObserverFunc(func(e *session.Event) {
+		events = append(events, e)
+	}))
+
+	result, err := core.Audit(context.Background(), `use test_inc;
+	create table t1(id int primary key comment 'id') comment 't1';
+	insert into t2(id) values(1);`)
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 3)
+
+	c.Assert(events, HasLen, 3)
+	for i, e := range events {
+		c.Assert(e.Type, Equals, session.EventChecked)
+		c.Assert(e.Type.String(), Equals, "checked")
+		c.Assert(e.Record.SeqNo, Equals, i)
+		c.Assert(e.Record.Sql, Equals, result[i].Sql)
+		c.Assert(e.Record.ErrLevel, Equals, result[i].ErrLevel)
+		c.Assert(e.Record.ErrorMessage, Equals, result[i].ErrorMessage)
+		c.Assert(e.Record.Buf, IsNil)
+	}
+	c.Assert(events[2].Record.Findings, HasLen, 1)
+}
+
+func (s *testObserverSuite) TestSocketObserver(c *C) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		forms []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		forms = append(forms, r.PostForm.Get("event"))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	url := session.URL
+	session.URL = ts.URL
+	defer func() { session.URL = url }()
+
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{Offline: true})
+	core.RegisterObserver(&session.SocketObserver{User: "test"})
+	_, err := core.Audit(context.Background(), "create database test_inc;")
+	c.Assert(err, IsNil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(paths, DeepEquals, []string{"/room/test"})
+	c.Assert(forms, DeepEquals, []string{"checked"})
+}
@@ -0,0 +1,409 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/model"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/util"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// 离线审核未指定版本号时的默认版本
+const defaultOfflineVersion = "5.7.25"
+
+// SchemaSnapshot 离线审核使用的表结构快照(JSON格式)
+type SchemaSnapshot struct {
+	// 数据库版本号,如 5.7.25-log, 为空时默认为5.7.25
+	Version string
+
+	Databases []string
+	Tables    []*SnapshotTable
+}
+
+// SnapshotTable 快照中的表信息,兼容TableInfo的JSON格式
+type SnapshotTable struct {
+	TableInfo
+
+	// 表估计行数,用以代替information_schema.tables.TABLE_ROWS
+	TableRows uint
+	// 外键名称
+	ForeignKeys []string
+	// 存储引擎和表注释
+	Engine  string
+	Comment string
+}
+
+// snapshotCache 快照解析后的库表信息
+type snapshotCache struct {
+	dbs    map[string]string
+	tables map[string]*SnapshotTable
+}
+
+// isOffline 是否离线审核
+func (s *session) isOffline() bool {
+	return s.opt != nil && s.opt.Offline
+}
+
+// checkOfflineOptions 离线审核时校验配置信息,并加载表结构快照
+func (s *session) checkOfflineOptions() error {
+	if s.opt.Execute || s.opt.Backup {
+		return errors.New("离线审核仅支持审核,不支持执行和备份!")
+	}
+
+	if err := s.loadSnapshot(s.opt.Snapshot); err != nil {
+		return fmt.Errorf("con:%d 表结构快照解析失败: %v", s.sessionVars.ConnectionID, err)
+	}
+
+	tmp := s.processInfo.Load()
+	if tmp != nil {
+		pi := tmp.(util.ProcessInfo)
+		if s.opt.Check {
+			pi.Command = "CHECK"
+		}
+		s.processInfo.Store(pi)
+	}
+
+	return nil
+}
+
+// loadSnapshot 解析表结构快照
+func (s *session) loadSnapshot(snapshot string) error {
+	s.snapshot = &snapshotCache{
+		dbs:    make(map[string]string),
+		tables: make(map[string]*SnapshotTable),
+	}
+
+	var version string
+	snapshot = strings.TrimSpace(snapshot)
+	if strings.HasPrefix(snapshot, "{") {
+		var ss SchemaSnapshot
+		if err := json.Unmarshal([]byte(snapshot), &ss); err != nil {
+			return err
+		}
+		for _, db := range ss.Databases {
+			s.snapshot.dbs[s.snapshotDBKey(db)] = db
+		}
+		for _, t := range ss.Tables {
+			if t == nil || t.Name == "" {
+				continue
+			}
+			if t.Schema == "" {
+				t.Schema = s.opt.DB
+			}
+			if t.Schema == "" {
+				return fmt.Errorf("表'%s'未指定数据库", t.Name)
+			}
+			s.addSnapshotTable(t)
+		}
+		version = ss.Version
+	} else if snapshot != "" {
+		if err := s.loadSnapshotDDL(snapshot); err != nil {
+			return err
+		}
+	}
+
+	if version == "" {
+		version = defaultOfflineVersion
+	}
+	s.dbVersion = 0
+	s.setDBVersion(version)
+	s.innodbLargePrefix = s.dbVersion > 50700
+
+	return nil
+}
+
+// loadSnapshotDDL 解析建库建表语句
+func (s *session) loadSnapshotDDL(snapshot string) error {
+	charsetInfo, collation := s.sessionVars.GetCharsetInfo()
+	s.parser.SetSQLMode(s.sessionVars.SQLMode)
+	stmtNodes, _, err := s.parser.Parse(snapshot, charsetInfo, collation)
+	if err != nil {
+		return err
+	}
+
+	// 快照语句仅用于构建表结构,不做审核,因此使用临时的审核结果
+	dbName := s.dbName
+	s.myRecord = &Record{Buf: new(bytes.Buffer)}
+	s.recordSets = NewRecordSets()
+	defer func() {
+		s.dbName = dbName
+		s.myRecord = nil
+		s.recordSets = nil
+	}()
+
+	s.dbName = s.opt.DB
+	for _, stmtNode := range stmtNodes {
+		switch node := stmtNode.(type) {
+		case *ast.CreateDatabaseStmt:
+			s.snapshot.dbs[s.snapshotDBKey(node.Name)] = node.Name
+		case *ast.UseStmt:
+			s.dbName = node.DBName
+		case *ast.CreateTableStmt:
+			t, err := s.buildSnapshotTable(node)
+			if err != nil {
+				return err
+			}
+			s.addSnapshotTable(t)
+		case *ast.CreateViewStmt:
+			if node.ViewName.Schema.O == "" {
+				node.ViewName.Schema = model.NewCIStr(s.dbName)
+			}
+			sel, _ := node.Select.(ast.ResultSetNode)
+			t := s.buildViewInfo(node, sel)
+			if t == nil {
+				return fmt.Errorf("视图'%s'的列数和SELECT的列数不一致", node.ViewName.Name.O)
+			}
+			s.addSnapshotTable(&SnapshotTable{TableInfo: *t})
+		default:
+			log.Warnf("con:%d 表结构快照中忽略的语句: %s",
+				s.sessionVars.ConnectionID, strings.TrimSpace(stmtNode.Text()))
+		}
+	}
+	return nil
+}
+
+// buildSnapshotTable 根据建表语句构建表结构
+func (s *session) buildSnapshotTable(node *ast.CreateTableStmt) (*SnapshotTable, error) {
+	schema := node.Table.Schema.O
+	if schema == "" {
+		schema = s.dbName
+	}
+	if schema == "" {
+		return nil, fmt.Errorf("表'%s'未指定数据库", node.Table.Name.O)
+	}
+
+	if node.ReferTable != nil {
+		referSchema := node.ReferTable.Schema.O
+		if referSchema == "" {
+			referSchema = s.dbName
+		}
+		refer, ok := s.snapshot.tables[s.snapshotTableKey(referSchema, node.ReferTable.Name.O)]
+		if !ok {
+			return nil, fmt.Errorf("表'%s.%s'不存在", referSchema, node.ReferTable.Name.O)
+		}
+		t := &SnapshotTable{TableInfo: *refer.TableInfo.copy()}
+		t.Schema = schema
+		t.Name = node.Table.Name.O
+		for _, index := range t.Indexes {
+			index.Table = t.Name
+		}
+		return t, nil
+	}
+
+	// 设置主键和唯一键标志位
+	for _, ct := range node.Constraints {
+		var flag uint
+		switch ct.Tp {
+		case ast.ConstraintPrimaryKey:
+			flag = mysql.PriKeyFlag
+		case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
+			flag = mysql.UniqueKeyFlag
+		default:
+			continue
+		}
+		for _, col := range ct.Keys {
+			for _, field := range node.Cols {
+				if col.Column != nil && field.Name.Name.L == col.Column.Name.L {
+					field.Tp.Flag |= flag
+					break
+				}
+			}
+		}
+	}
+
+	t := &SnapshotTable{TableInfo: *s.buildTableInfo(node)}
+	t.Schema = schema
+	t.IsNewColumns = false
+	for _, opt := range node.Options {
+		switch opt.Tp {
+		case ast.TableOptionEngine:
+			t.Engine = opt.StrValue
+		case ast.TableOptionComment:
+			t.Comment = opt.StrValue
+		}
+	}
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.Null == "" {
+			if f.Key == "PRI" {
+				f.Null = "NO"
+			} else {
+				f.Null = "YES"
+			}
+		}
+		f.IsNew = false
+		// 和SHOW FULL FIELDS的结果保持一致
+		f.Tp = nil
+		f.Default = columnDefaultValue(node.Cols[i])
+	}
+
+	for _, field := range node.Cols {
+		for _, op := range field.Options {
+			switch op.Tp {
+			case ast.ColumnOptionPrimaryKey:
+				t.addSnapshotIndex("PRIMARY", 0, "BTREE", field.Name.Name.O)
+			case ast.ColumnOptionUniqKey:
+				t.addSnapshotIndex("", 0, "BTREE", field.Name.Name.O)
+			}
+		}
+	}
+
+	for _, ct := range node.Constraints {
+		var (
+			nonUnique = 1
+			indexType = "BTREE"
+			name      = ct.Name
+		)
+		switch ct.Tp {
+		case ast.ConstraintPrimaryKey:
+			nonUnique = 0
+			name = "PRIMARY"
+		case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
+			nonUnique = 0
+		case ast.ConstraintKey, ast.ConstraintIndex:
+		case ast.ConstraintFulltext:
+			indexType = "FULLTEXT"
+		case ast.ConstraintSpatial:
+			indexType = "SPATIAL"
+		case ast.ConstraintForeignKey:
+			if ct.Name != "" {
+				t.ForeignKeys = append(t.ForeignKeys, ct.Name)
+			}
+			continue
+		default:
+			continue
+		}
+
+		columns := make([]string, 0, len(ct.Keys))
+		for _, col := range ct.Keys {
+			columns = append(columns, indexColumnName(col))
+		}
+		t.addSnapshotIndex(name, nonUnique, indexType, columns...)
+
+		// 前缀索引长度
+		rows := t.Indexes[len(t.Indexes)-len(columns):]
+		for i, col := range ct.Keys {
+			rows[i].SubPart = indexSubPart(col)
+		}
+	}
+
+	return t, nil
+}
+
+// addSnapshotIndex 添加索引信息,未指定索引名时以第一列命名
+func (t *SnapshotTable) addSnapshotIndex(name string, nonUnique int,
+	indexType string, columns ...string) {
+	if len(columns) == 0 {
+		return
+	}
+
+	if name == "" {
+		name = columns[0]
+		for i := 2; t.hasIndex(name); i++ {
+			name = fmt.Sprintf("%s_%d", columns[0], i)
+		}
+	}
+
+	for i, col := range columns {
+		t.Indexes = append(t.Indexes, &IndexInfo{
+			Table:      t.Name,
+			NonUnique:  nonUnique,
+			IndexName:  name,
+			Seq:        i + 1,
+			ColumnName: col,
+			IndexType:  indexType,
+		})
+	}
+}
+
+func (t *SnapshotTable) hasIndex(name string) bool {
+	for _, index := range t.Indexes {
+		if strings.EqualFold(index.IndexName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) addSnapshotTable(t *SnapshotTable) {
+	t.IsNew = false
+	t.IsDeleted = false
+	for _, index := range t.Indexes {
+		if index.Table == "" {
+			index.Table = t.Name
+		}
+	}
+
+	if _, ok := s.snapshot.dbs[s.snapshotDBKey(t.Schema)]; !ok {
+		s.snapshot.dbs[s.snapshotDBKey(t.Schema)] = t.Schema
+	}
+	s.snapshot.tables[s.snapshotTableKey(t.Schema, t.Name)] = t
+}
+
+func (s *session) snapshotDBKey(db string) string {
+	if s.IgnoreCase() {
+		return strings.ToLower(db)
+	}
+	return db
+}
+
+func (s *session) snapshotTableKey(db string, tableName string) string {
+	key := fmt.Sprintf("%s.%s", db, tableName)
+	if s.IgnoreCase() {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// snapshotDBExists 快照中的库是否存在
+func (s *session) snapshotDBExists(db string) bool {
+	if s.snapshot == nil {
+		return false
+	}
+	_, ok := s.snapshot.dbs[s.snapshotDBKey(db)]
+	return ok
+}
+
+// getSnapshotTable 获取快照中的表信息
+func (s *session) getSnapshotTable(db string, tableName string) *SnapshotTable {
+	if s.snapshot == nil {
+		return nil
+	}
+	return s.snapshot.tables[s.snapshotTableKey(db, tableName)]
+}
+
+// queryTableFromSnapshot 从快照获取表的列信息
+func (s *session) queryTableFromSnapshot(db string, tableName string, reportNotExists bool) []FieldInfo {
+	t := s.getSnapshotTable(db, tableName)
+	if t == nil {
+		if reportNotExists {
+			s.appendErrorNo(ER_TABLE_NOT_EXISTED_ERROR, fmt.Sprintf("%s.%s", db, tableName))
+		}
+		return nil
+	}
+
+	rows := make([]FieldInfo, len(t.Fields))
+	copy(rows, t.Fields)
+	return rows
+}
+
+// queryIndexFromSnapshot 从快照获取表的索引信息
+func (s *session) queryIndexFromSnapshot(db string, tableName string) []*IndexInfo {
+	t := s.getSnapshotTable(db, tableName)
+	if t == nil {
+		return nil
+	}
+
+	rows := make([]*IndexInfo, 0, len(t.Indexes))
+	for _, index := range t.Indexes {
+		row := *index
+		rows = append(rows, &row)
+	}
+	return rows
+}
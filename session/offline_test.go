@@ -0,0 +1,294 @@
+package session_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testOfflineSuite{})
+
+type testOfflineSuite struct {
+	maxDDLAffectRows uint
+}
+
+func TestOffline(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *testOfflineSuite) SetUpSuite(c *C) {
+	inc := &config.GetGlobalConfig().Inc
+	inc.Lang = "en-US"
+	inc.EnableDropTable = true
+
+	s.maxDDLAffectRows = inc.MaxDDLAffectRows
+	inc.MaxDDLAffectRows = 1000
+}
+
+func (s *testOfflineSuite) TearDownSuite(c *C) {
+	config.GetGlobalConfig().Inc.MaxDDLAffectRows = s.maxDDLAffectRows
+}
+
+func (s *testOfflineSuite) audit(c *C, snapshot string, sql string) []session.Record {
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: snapshot,
+	})
+	result, err := core.Audit(context.Background(), sql)
+	c.Assert(err, IsNil)
+	return result
+}
+
+// saveConfig 保存审核相关配置,返回恢复配置的函数,用法: defer saveConfig()()
+func saveConfig() func() {
+	cnf := config.GetGlobalConfig()
+	inc, osc, level, profiles := cnf.Inc, cnf.Osc, cnf.IncLevel, cnf.Profiles
+	return func() { cnf.Inc, cnf.Osc, cnf.IncLevel, cnf.Profiles = inc, osc, level, profiles }
+}
+
+func (s *testOfflineSuite) TestSnapshotDDL(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		c1 varchar(20) not null default '' comment 'c1',
+		key ix_c1(c1)) comment 't1';`
+
+	result := s.audit(c, snapshot, `use test_inc;
+	insert into t1(id,c1) values(1,'a');
+	alter table t1 add column c2 int not null default 0 comment 'c2';
+	alter table t1 add index ix_c1(c1);
+	insert into t2(id) values(1);
+	use test_inc2;`)
+
+	c.Assert(len(result), Equals, 6)
+	c.Assert(result[0].ErrLevel, Equals, uint8(0), Commentf("%v", result[0].ErrorMessage))
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[2].ErrLevel, Equals, uint8(0), Commentf("%v", result[2].ErrorMessage))
+	c.Assert(result[3].ErrLevel, Equals, uint8(2))
+	c.Assert(result[3].ErrorMessage, Matches, "(?s).*ix_c1.*")
+	c.Assert(result[4].ErrLevel, Equals, uint8(2))
+	c.Assert(result[4].ErrorMessage, Matches, "(?s).*test_inc.t2.*")
+	c.Assert(result[5].ErrLevel, Equals, uint8(2))
+	c.Assert(result[5].ErrorMessage, Matches, "(?s).*test_inc2.*")
+}
+
+func (s *testOfflineSuite) TestSnapshotJSON(c *C) {
+	snapshot := `{
+		"Version": "8.0.18",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t1",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "c1", "Type": "varchar(20)", "Null": "YES"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			],
+			"TableRows": 5000
+		}]
+	}`
+
+	result := s.audit(c, snapshot, `use test_inc;
+	update t1 set c1 = 'a' where id = 1;
+	update t1 set c2 = 'a' where id = 1;
+	drop table t1;`)
+
+	c.Assert(len(result), Equals, 4)
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[2].ErrLevel, Equals, uint8(2))
+	c.Assert(strings.Contains(result[2].ErrorMessage, "c2"), IsTrue)
+	c.Assert(result[3].AffectedRows, Equals, 5000)
+	c.Assert(result[3].ErrLevel, Equals, uint8(1))
+}
+
+func (s *testOfflineSuite) TestOfflineExecute(c *C) {
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline: true,
+	})
+	_, err := core.RunExecute(context.Background(), "create database test_inc;")
+	c.Assert(err, NotNil)
+}
+
+func (s *testOfflineSuite) TestFindings(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		c1 varchar(20) not null default '' comment 'c1',
+		key ix_c1(c1)) comment 't1';`
+
+	result := s.audit(c, snapshot, `use test_inc;
+	alter table t1 add index ix_c1(c1);
+	update t1 set c2 = 1 where id = 1;
+	insert into t2 values(1);`)
+
+	c.Assert(len(result), Equals, 4)
+	c.Assert(result[0].Findings, HasLen, 0)
+
+	c.Assert(result[1].Findings, HasLen, 1)
+	f := result[1].Findings[0]
+	c.Assert(f.Code, Equals, session.ER_DUP_INDEX)
+	c.Assert(f.Name, Equals, "er_dup_index")
+	c.Assert(f.Level, Equals, uint8(2))
+	c.Assert(f.Index, Equals, "ix_c1")
+	c.Assert(f.Column, Equals, "")
+	c.Assert(result[1].ErrorMessage, Equals, f.Message)
+
+	c.Assert(len(result[2].Findings) > 0, IsTrue)
+	f = result[2].Findings[0]
+	c.Assert(f.Code, Equals, session.ER_COLUMN_NOT_EXISTED)
+	c.Assert(f.Column, Equals, "c2")
+
+	messages := make([]string, 0, len(result[3].Findings))
+	for _, f := range result[3].Findings {
+		messages = append(messages, f.Message)
+	}
+	c.Assert(strings.Join(messages, "\n"), Equals, result[3].ErrorMessage)
+}
+
+func (s *testOfflineSuite) TestStatementPosition(c *C) {
+	snapshot := "create database test_inc;\n" +
+		"create table test_inc.t1(id int primary key comment 'id', c1 varchar(20) comment 'c1') comment 't1';"
+
+	sql := "use test_inc;\n" +
+		"insert into t1 values(1,\"a';b\"); insert into t1(`id`) values(2);\n" +
+		"-- it's a comment;\n" +
+		"update t1 set c1 = 'x;\ny' where id = 1;\n" +
+		"DELIMITER $$\n" +
+		"delete from t1 where id = 1$$\n" +
+		"DELIMITER ;\n" +
+		"insert into t2 values(3);"
+
+	result := s.audit(c, snapshot, sql)
+	c.Assert(result, HasLen, 6)
+
+	expected := []struct {
+		sql string
+		pos session.Position
+	}{
+		{"use test_inc", session.Position{0, 12, 1, 1, 1, 13}},
+		{"insert into t1 values(1,\"a';b\")", session.Position{14, 45, 2, 1, 2, 32}},
+		{"insert into t1(`id`) values(2)", session.Position{47, 77, 2, 34, 2, 64}},
+		{"-- it's a comment;\nupdate t1 set c1 = 'x;\ny' where id = 1",
+			session.Position{79, 136, 3, 1, 5, 16}},
+		{"delete from t1 where id = 1", session.Position{151, 178, 7, 1, 7, 28}},
+		{"insert into t2 values(3)", session.Position{193, 217, 9, 1, 9, 25}},
+	}
+	for i, e := range expected {
+		r := result[i]
+		c.Assert(r.Sql, Equals, e.sql)
+		c.Assert(r.Position, Equals, e.pos, Commentf("%s", r.Sql))
+		c.Assert(sql[r.Position.Offset:r.Position.EndOffset], Equals, r.Sql)
+		if i < len(expected)-1 {
+			c.Assert(r.ErrLevel, Equals, uint8(0), Commentf("%s", r.ErrorMessage))
+		}
+	}
+	c.Assert(result[5].ErrLevel, Equals, uint8(2))
+
+	// 解析失败时返回语句的位置
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: snapshot,
+	})
+	result, err := core.Audit(context.Background(), "use test_inc;\n  select * fro t1;")
+	c.Assert(err, NotNil)
+	c.Assert(result, HasLen, 2)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].Position, Equals, session.Position{16, 31, 2, 3, 2, 18})
+}
+
+func (s *testOfflineSuite) TestCreateView(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		c1 varchar(20) not null default '' comment 'c1') comment 't1';
+	create view v0 as select id from t1;`
+
+	inc := &config.GetGlobalConfig().Inc
+	defer saveConfig()()
+
+	inc.EnableCreateView = false
+	result := s.audit(c, snapshot, `use test_inc;
+	create view v1 as select id from t1;`)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].ErrorMessage, Equals, "命令禁止! 无法创建视图'v1'.")
+
+	inc.EnableCreateView = true
+	result = s.audit(c, snapshot, `use test_inc;
+	create sql security invoker view v1(a, b) as select id, concat(c1, 'x') from t1 where id > 0;
+	select a, b from v1 where a = 1;
+	create view v2 as select * from t1;
+	create algorithm=temptable sql security invoker view v3 as select c2 from t1;
+	create sql security invoker view v4(a) as select id, c1 from t1;
+	create sql security invoker view v0 as select c1 from t1;
+	create or replace sql security invoker view v0 as select c1 from t1;
+	drop view v0;
+	drop view v5;`)
+	c.Assert(len(result), Equals, 10)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[2].ErrLevel, Equals, uint8(0), Commentf("%v", result[2].ErrorMessage))
+
+	c.Assert(result[3].ErrLevel, Equals, uint8(1))
+	c.Assert(result[3].ErrorMessage, Equals, "Select only star is not allowed in view 'v2'.")
+
+	c.Assert(result[4].ErrLevel, Equals, uint8(2))
+	c.Assert(result[4].ErrorMessage, Matches, "(?s).*ALGORITHM=TEMPTABLE is not allowed for view 'v3'.*")
+	c.Assert(result[4].ErrorMessage, Matches, "(?s).*c2.*")
+
+	c.Assert(result[5].ErrLevel, Equals, uint8(2))
+	c.Assert(result[5].ErrorMessage, Equals, "View 'v4': SELECT and field list have different column counts.")
+
+	c.Assert(result[6].ErrLevel, Equals, uint8(2))
+	c.Assert(result[7].ErrLevel, Equals, uint8(0), Commentf("%v", result[7].ErrorMessage))
+	c.Assert(result[8].ErrLevel, Equals, uint8(0), Commentf("%v", result[8].ErrorMessage))
+	c.Assert(result[9].ErrLevel, Equals, uint8(2))
+	c.Assert(result[9].ErrorMessage, Matches, "(?s).*test_inc.v5.*")
+
+	// 快照中已有的表和视图按其类型校验
+	inc.EnableDropTable = true
+	result = s.audit(c, snapshot, `use test_inc;
+	create or replace sql security invoker view t1 as select 1 as id;
+	drop view t1;
+	drop table v0;
+	create table t2(id int primary key comment 'id') comment 't2';
+	drop view t2;`)
+	c.Assert(len(result), Equals, 6)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].ErrorMessage, Equals, "'test_inc.t1' is not VIEW.")
+	c.Assert(result[2].ErrLevel, Equals, uint8(2))
+	c.Assert(result[2].ErrorMessage, Equals, "'test_inc.t1' is not VIEW.")
+	c.Assert(result[3].ErrLevel, Equals, uint8(2))
+	c.Assert(result[3].ErrorMessage, Equals, "'test_inc.v0' is not BASE TABLE.")
+	c.Assert(result[5].ErrLevel, Equals, uint8(2))
+	c.Assert(result[5].ErrorMessage, Equals, "'test_inc.t2' is not VIEW.")
+}
+
+func (s *testOfflineSuite) TestSplitView(c *C) {
+	defer saveConfig()()
+	config.GetGlobalConfig().Inc.EnableCreateView = true
+
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: "create database test_inc;\ncreate table test_inc.t1(id int primary key);",
+	})
+	result, err := core.Split(context.Background(), `use test_inc;
+	create view v1 as select id from t1;
+	insert into t1 values(1);`)
+	c.Assert(err, IsNil)
+
+	var sqls []string
+	for _, r := range result {
+		sqls = append(sqls, r.SQL)
+	}
+	c.Assert(strings.Join(sqls, "\n"), Matches, "(?s).*create view v1.*")
+}
@@ -61,11 +61,12 @@ var regOscPercent *regexp.Regexp = regexp.MustCompile(`^Copying .*? (\d+)% (\d+:
 var regGhostPercent *regexp.Regexp = regexp.MustCompile(`^Copy:.*?(\d+).\d+%;.*?ETA: (.*)?`)
 
 func (s *session) checkAlterUseOsc(t *TableInfo) {
-	if (s.osc.OscOn || s.ghost.GhostOn) && (s.osc.OscMinTableSize == 0 || t.TableSize >= s.osc.OscMinTableSize) {
-		s.myRecord.UseOsc = true
-	} else {
-		s.myRecord.UseOsc = false
-	}
+	s.myRecord.UseOsc = s.tableUseOsc(t)
+}
+
+// tableUseOsc 表变更时是否会使用pt-osc或gh-ost
+func (s *session) tableUseOsc(t *TableInfo) bool {
+	return (s.osc.OscOn || s.ghost.GhostOn) && (s.osc.OscMinTableSize == 0 || t.TableSize >= s.osc.OscMinTableSize)
 }
 
 func (s *session) mysqlComputeSqlSha1(r *Record) {
@@ -115,6 +116,14 @@ func (s *session) mysqlExecuteAlterTableOsc(r *Record) {
 		buf.WriteString(" --print ")
 	}
 	buf.WriteString(fmt.Sprintf(" --set-vars lock_wait_timeout=%d", s.osc.OscLockWaitTimeout))
+	// pt-osc无法在切换表前暂停检查,按ddl_blocker_action限制切换表时锁等待的重试次数
+	if s.useDDLBlockerCheck(r) {
+		tries := 1
+		if strings.ToLower(s.inc.DDLBlockerAction) == DDLBlockerWait && s.osc.OscLockWaitTimeout > 0 {
+			tries = Max(1, int(s.inc.DDLBlockerWaitTime)/s.osc.OscLockWaitTimeout)
+		}
+		buf.WriteString(fmt.Sprintf(" --tries swap_tables:%d:1 ", tries))
+	}
 	buf.WriteString(" --charset=utf8 ")
 	buf.WriteString(" --chunk-time ")
 	buf.WriteString(fmt.Sprintf("%g ", s.osc.OscChunkTime))
@@ -321,6 +330,12 @@ func (s *session) mysqlExecuteAlterTableGhost(r *Record) {
 	migrationContext.ThrottleAdditionalFlagFile = s.ghost.GhostThrottleAdditionalFlagFile
 	// flag.StringVar(&migrationContext.ThrottleAdditionalFlagFile, "throttle-additional-flag-file", "/tmp/gh-ost.throttle", "operation pauses when this file exists; hint: keep default, use for throttling multiple gh-ost operations")
 	migrationContext.PostponeCutOverFlagFile = s.ghost.GhostPostponeCutOverFlagFile
+	// 切换表前检查阻塞的事务. 未指定postpone文件时使用临时文件暂停切换,检查通过后再继续切换
+	checkCutOver := s.useDDLBlockerCheck(r) && migrationContext.PostponeCutOverFlagFile == ""
+	if checkCutOver {
+		migrationContext.PostponeCutOverFlagFile = fmt.Sprintf("/tmp/gh-ost.%d.%d.postpone",
+			s.sessionVars.ConnectionID, time.Now().UnixNano())
+	}
 	// flag.StringVar(&migrationContext.PostponeCutOverFlagFile, "postpone-cut-over-flag-file", "", "while this file exists, migration will postpone the final stage of swapping tables, and will keep on syncing the ghost table. Cut-over/swapping would be ready to perform the moment the file is deleted.")
 	// migrationContext.PanicFlagFile = s.Ghost.GhostPanicFlagFile
 	// flag.StringVar(&migrationContext.PanicFlagFile, "panic-flag-file", "", "when this file is created, gh-ost will immediately terminate, without cleanup")
@@ -533,6 +548,12 @@ func (s *session) mysqlExecuteAlterTableGhost(r *Record) {
 	buf := bytes.NewBufferString("")
 	migrator := logic.NewMigrator(migrationContext)
 
+	var mdl, cutOverAborted bool
+	var cutOverDeadline, nextCutOverCheck time.Time
+	if checkCutOver {
+		mdl = s.mdlInstrumentEnabled(false)
+	}
+
 	//实时循环读取输出流中的一行内容
 	f := func(reader *bufio.Reader) {
 		statusTick := time.Tick(100 * time.Millisecond)
@@ -545,7 +566,11 @@ func (s *session) mysqlExecuteAlterTableGhost(r *Record) {
 				buf.WriteString(line)
 				buf.WriteString("\n")
 
+				percent := p.Percent
 				s.mysqlAnalyzeGhostOutput(line, p)
+				if p.Percent != percent {
+					s.notifyOsc(r, p)
+				}
 				if p.Killed {
 					migrationContext.PanicAbort <- fmt.Errorf("Execute has been abort in percent: %d, remain time: %s",
 						p.Percent, p.RemainTime)
@@ -559,6 +584,25 @@ func (s *session) mysqlExecuteAlterTableGhost(r *Record) {
 					atomic.StoreInt64(&migrationContext.ThrottleCommandedByUser, 0)
 				}
 			}
+
+			if !done && checkCutOver && time.Now().After(nextCutOverCheck) &&
+				atomic.LoadInt64(&migrationContext.IsPostponingCutOver) == 1 &&
+				atomic.LoadInt64(&migrationContext.UserCommandedUnpostponeFlag) == 0 {
+				if cutOverDeadline.IsZero() {
+					cutOverDeadline = time.Now().Add(time.Duration(s.inc.DDLBlockerWaitTime) * time.Second)
+				}
+				blocked, abort := s.ddlBlocked(r, mdl, cutOverDeadline)
+				if !blocked {
+					atomic.StoreInt64(&migrationContext.UserCommandedUnpostponeFlag, 1)
+				} else if abort {
+					// 错误已记录,Migrate返回后不再重复添加
+					cutOverAborted = true
+					migrationContext.PanicAbort <- fmt.Errorf("Cut-over has been abort: %s",
+						formatBlockers(r.DDLBlockers))
+					done = true
+				}
+				nextCutOverCheck = time.Now().Add(time.Second)
+			}
 			if done {
 				break
 			}
@@ -584,11 +628,18 @@ func (s *session) mysqlExecuteAlterTableGhost(r *Record) {
 	if err := migrator.Migrate(); err != nil {
 		log.Error(err)
 		done = true
-		s.appendErrorMessage(err.Error())
+		if !cutOverAborted {
+			s.appendErrorMessage(err.Error())
+		}
 	}
 
 	done = true
 
+	// 终止切换时保留postpone文件,避免gh-ost在清理后继续切换
+	if checkCutOver && !cutOverAborted {
+		os.Remove(migrationContext.PostponeCutOverFlagFile)
+	}
+
 	if s.hasError() {
 		r.StageStatus = StatusExecFail
 	} else {
@@ -666,7 +717,11 @@ func (s *session) execCommand(r *Record, commandName string, params []string) bo
 			}
 			buf.WriteString(line)
 			buf.WriteString("\n")
+			percent := p.Percent
 			s.mysqlAnalyzeOscOutput(line, p)
+			if p.Percent != percent {
+				s.notifyOsc(r, p)
+			}
 			if p.Killed {
 				if err := cmd.Process.Kill(); err != nil {
 					s.appendErrorMessage(err.Error())
@@ -31,10 +31,16 @@ type chanData struct {
 	e      *replication.BinlogEvent
 	gtid   []byte
 	opid   string
-	table  string
+	table  backupTable
 	record *Record
 }
 
+// backupTable 保存回滚语句的备份表
+type backupTable struct {
+	db   string
+	name string
+}
+
 func (s *session) processChan(wg *sync.WaitGroup) {
 	for {
 		r := <-s.ch
@@ -42,7 +48,7 @@ func (s *session) processChan(wg *sync.WaitGroup) {
 		if r == nil {
 			// log.Info("剩余ch", len(s.ch), "cap ch", cap(s.ch), "通道关闭,跳出循环")
 			// log.Info("ProcessChan,close")
-			s.flush(s.lastBackupTable, s.myRecord)
+			s.flush(s.lastRollbackTable, s.myRecord)
 			wg.Done()
 			break
 		}
@@ -69,24 +75,25 @@ func (s *session) getNextBackupRecord() *Record {
 
 		if r.TableInfo != nil {
 
-			lastBackupTable := fmt.Sprintf("`%s`.`%s`", s.getRemoteBackupDBName(r), r.TableInfo.Name)
+			lastBackupTable := backupTable{db: s.getRemoteBackupDBName(r), name: r.TableInfo.Name}
 
-			if s.lastBackupTable == "" {
-				s.lastBackupTable = lastBackupTable
+			if s.lastRollbackTable.db == "" {
+				s.lastRollbackTable = lastBackupTable
 			}
 
 			if s.checkSqlIsDDL(r) {
-				if s.lastBackupTable != lastBackupTable {
-					s.ch <- &chanData{sql: nil, table: s.lastBackupTable, record: s.myRecord}
-					s.lastBackupTable = lastBackupTable
+				if s.lastRollbackTable != lastBackupTable {
+					s.ch <- &chanData{sql: nil, table: s.lastRollbackTable, record: s.myRecord}
+					s.lastRollbackTable = lastBackupTable
 				}
 
 				s.ch <- &chanData{sqlStr: r.DDLRollback, opid: r.OPID,
-					table: s.lastBackupTable, record: r}
+					table: s.lastRollbackTable, record: r}
 
 				if r.StageStatus != StatusExecFail {
 					r.StageStatus = StatusBackupOK
 				}
+				s.notify(EventBackup, r)
 
 				continue
 
@@ -101,9 +108,9 @@ func (s *session) getNextBackupRecord() *Record {
 					continue
 				}
 
-				if s.lastBackupTable != lastBackupTable {
-					s.ch <- &chanData{sql: nil, table: s.lastBackupTable, record: s.myRecord}
-					s.lastBackupTable = lastBackupTable
+				if s.lastRollbackTable != lastBackupTable {
+					s.ch <- &chanData{sql: nil, table: s.lastRollbackTable, record: s.myRecord}
+					s.lastRollbackTable = lastBackupTable
 				}
 
 				// 先置默认值为备份失败,在备份完成后置为成功
@@ -186,6 +193,7 @@ func (s *session) parserBinlog(ctx context.Context) {
 	defer func() {
 		close(s.ch)
 		wg.Wait()
+		s.lastRollbackTable = backupTable{}
 
 		// log.Info("操作完成", "rows", i)
 		// kwargs := map[string]interface{}{"ok": "1"}
@@ -241,7 +249,7 @@ func (s *session) parserBinlog(ctx context.Context) {
 		Pos: uint32(record.StartPosition)}
 	stopPosition := mysql.Position{Name: record.EndFile,
 		Pos: uint32(record.EndPosition)}
-	s.lastBackupTable = fmt.Sprintf("`%s`.`%s`", record.BackupDBName, record.TableInfo.Name)
+	s.lastRollbackTable = backupTable{db: record.BackupDBName, name: record.TableInfo.Name}
 	startTime := time.Now()
 
 	logSync, err := b.StartSync(startPosition)
@@ -352,6 +360,7 @@ func (s *session) parserBinlog(ctx context.Context) {
 			}
 
 			record.BackupCostTime = fmt.Sprintf("%.3f", time.Since(startTime).Seconds())
+			s.notify(EventBackup, record)
 
 			next := s.getNextBackupRecord()
 			if next != nil {
@@ -372,6 +381,7 @@ func (s *session) parserBinlog(ctx context.Context) {
 					record.StageStatus = StatusBackupOK
 				}
 				record.BackupCostTime = fmt.Sprintf("%.3f", time.Since(startTime).Seconds())
+				s.notify(EventBackup, record)
 
 				changeRows = 0
 				next := s.getNextBackupRecord()
@@ -470,38 +480,31 @@ func (s *session) checkUpdateFilter(event *replication.RowsEvent,
 
 // 解析的sql写入缓存,并定期入库
 func (s *session) myWrite(b []byte, binEvent *replication.BinlogEvent,
-	opid string, table string, record *Record) {
+	opid string, table backupTable, record *Record) {
 
 	b = append(b, ";"...)
 
-	s.insertBuffer = append(s.insertBuffer, string(b), opid)
+	s.rollbackBuffer = append(s.rollbackBuffer, RollbackStatement{OPID: opid, Statement: string(b)})
 
-	if len(s.insertBuffer) >= 1000 {
+	if len(s.rollbackBuffer) >= 500 {
 		s.flush(table, record)
 	}
 }
 
 // 解析的sql写入缓存,并定期入库
-func (s *session) myWriteDDL(sql string, opid string, table string, record *Record) {
+func (s *session) myWriteDDL(sql string, opid string, table backupTable, record *Record) {
 
-	s.insertBuffer = append(s.insertBuffer, sql, opid)
+	s.rollbackBuffer = append(s.rollbackBuffer, RollbackStatement{OPID: opid, Statement: sql})
 
-	if len(s.insertBuffer) >= 1000 {
+	if len(s.rollbackBuffer) >= 500 {
 		s.flush(table, record)
 	}
 }
 
-// flush用以写入当前insert缓存,并清空缓存.
-func (s *session) flush(table string, record *Record) {
-	// log.Info("flush ", len(s.insertBuffer))
-	if len(s.insertBuffer) > 0 {
-
-		const rowSQL = "(?,?),"
-		sql := "insert into %s(rollback_statement,opid_time) values%s"
-		values := strings.TrimRight(strings.Repeat(rowSQL, len(s.insertBuffer)/2), ",")
-		sql = fmt.Sprintf(sql, table, values)
-		err := s.backupdb.Exec(sql,
-			s.insertBuffer...).Error
+// flush用以写入当前回滚语句缓存,并清空缓存.
+func (s *session) flush(table backupTable, record *Record) {
+	if len(s.rollbackBuffer) > 0 {
+		err := s.backupSink.WriteRollback(table.db, table.name, s.rollbackBuffer)
 		if err != nil {
 			record.StageStatus = StatusBackupFail
 			if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
@@ -509,14 +512,14 @@ func (s *session) flush(table string, record *Record) {
 			} else {
 				s.appendErrorMessage(err.Error())
 			}
-			log.Errorf("con:%d %v sql:%s params:%v",
-				s.sessionVars.ConnectionID, err, sql, s.insertBuffer)
+			log.Errorf("con:%d %v table:`%s`.`%s` rows:%d",
+				s.sessionVars.ConnectionID, err, table.db, table.name, len(s.rollbackBuffer))
 		}
-		s.backupTotalRows += len(s.insertBuffer) / 2
+		s.backupTotalRows += len(s.rollbackBuffer)
 		s.SetMyProcessInfo(record.Sql, time.Now(),
 			float64(s.backupTotalRows)/float64(s.totalChangeRows))
 	}
-	s.insertBuffer = nil
+	s.rollbackBuffer = nil
 }
 
 func (s *session) checkError(e error) {
@@ -533,6 +536,12 @@ func (s *session) checkError(e error) {
 }
 
 func (s *session) write(b []byte, binEvent *replication.BinlogEvent) {
+	// 解析本地binlog文件时直接返回回滚语句
+	if s.rollbackWriter != nil {
+		s.rollbackWriter(b)
+		return
+	}
+
 	// 此处执行状态不确定的记录
 	if s.myRecord.StageStatus == StatusExecFail {
 		log.Info("auto fix record:", s.myRecord.OPID)
@@ -540,7 +549,7 @@ func (s *session) write(b []byte, binEvent *replication.BinlogEvent) {
 		s.totalChangeRows++
 	}
 	s.ch <- &chanData{sql: b, e: binEvent, opid: s.myRecord.OPID,
-		table: s.lastBackupTable, record: s.myRecord}
+		table: s.lastRollbackTable, record: s.myRecord}
 }
 
 func (s *session) generateInsertSql(t *TableInfo, e *replication.RowsEvent,
@@ -725,6 +734,10 @@ func (s *session) generateUpdateSql(t *TableInfo, e *replication.RowsEvent,
 
 	// 最小化回滚语句, 当开启时,update语句中未变更的值不再记录到回滚语句中
 	minimalMode := s.inc.EnableMinimalRollback
+	// where条件中加入更新后的列值. 无主键时where条件已包含全部列
+	guardMode := s.inc.EnableRollbackGuard && t.hasPrimary
+	// 最小化模式下变更的列
+	changed := make(map[int]bool)
 
 	if !minimalMode {
 		for i, col := range t.Fields {
@@ -776,6 +789,7 @@ func (s *session) generateUpdateSql(t *TableInfo, e *replication.RowsEvent,
 						if j < len(t.Fields) {
 							sets = append(sets, fmt.Sprintf(setValue, t.Fields[j].Field))
 						}
+						changed[j] = true
 					}
 				} else {
 					if t.Fields[j].isUnsigned() {
@@ -819,6 +833,30 @@ func (s *session) generateUpdateSql(t *TableInfo, e *replication.RowsEvent,
 				}
 			}
 
+			// 回滚的列需匹配更新后的值,数据在执行后被修改时回滚不生效
+			if guardMode {
+				for j, d := range rows {
+					if _, ok := t.primarys[j]; ok || j >= len(t.Fields) {
+						continue
+					}
+					if minimalMode && !changed[j] || !rollbackGuardable(&t.Fields[j]) {
+						continue
+					}
+					if t.Fields[j].isUnsigned() {
+						d = processValue(d, GetDataTypeBase(t.Fields[j].Type))
+					}
+					oldValues = append(oldValues, d)
+
+					if d == nil {
+						columnNames = append(columnNames,
+							fmt.Sprintf(c_null, t.Fields[j].Field))
+					} else {
+						columnNames = append(columnNames,
+							fmt.Sprintf(c, t.Fields[j].Field))
+					}
+				}
+			}
+
 			if minimalMode {
 				sql = fmt.Sprintf(template, e.Table.Schema, e.Table.Table,
 					strings.Join(sets, ","))
@@ -835,12 +873,27 @@ func (s *session) generateUpdateSql(t *TableInfo, e *replication.RowsEvent,
 
 			oldValues = nil
 			newValues = nil
+			changed = make(map[int]bool)
 		}
 	}
 
 	return string(buf), nil
 }
 
+// rollbackGuardable 列值能否用于回滚语句的等值条件.
+// 浮点数存在精度问题,json列无法和字符串直接比较
+func rollbackGuardable(f *FieldInfo) bool {
+	tp := strings.ToLower(GetDataTypeBase(f.Type))
+	if i := strings.Index(tp, " "); i > 0 {
+		tp = tp[:i]
+	}
+	switch tp {
+	case "float", "double", "real", "json":
+		return false
+	}
+	return true
+}
+
 func interpolateParams(query string, args []driver.Value, hexBlob bool) ([]byte, error) {
 	// Number of ? should be same to len(args)
 	if strings.Count(query, "?") != len(args) {
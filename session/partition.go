@@ -0,0 +1,783 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/format"
+	"github.com/hanchuanchuan/inception-core/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// MySQL允许的最大分区数
+const maxPartitions = 8192
+
+// TO_DAYS('1970-01-01')
+const daysBeforeUnixEpoch = 719528
+
+var partitionColumnRegexp = regexp.MustCompile("`([^`]+)`")
+
+// PartitionInfo 分区信息
+type PartitionInfo struct {
+	Name string
+	// 分区值. RANGE分区为上限值,LIST分区为值列表,多个值以逗号分隔
+	Description string
+	// 分区估计行数
+	TableRows uint
+}
+
+// isPartitioned 是否分区表
+func (t *TableInfo) isPartitioned() bool {
+	return t.PartitionType != ""
+}
+
+func (t *TableInfo) isRangePartition() bool {
+	return strings.HasPrefix(t.PartitionType, "RANGE")
+}
+
+func (t *TableInfo) isListPartition() bool {
+	return strings.HasPrefix(t.PartitionType, "LIST")
+}
+
+// findPartition 返回分区的位置,分区名不区分大小写,不存在时返回-1
+func (t *TableInfo) findPartition(name string) int {
+	for i, p := range t.Partitions {
+		if strings.EqualFold(p.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// partitionDefinition 返回分区定义,用以生成回滚语句
+func (t *TableInfo) partitionDefinition(p PartitionInfo) string {
+	switch {
+	case t.isRangePartition():
+		return fmt.Sprintf("PARTITION `%s` VALUES LESS THAN (%s)", p.Name, p.Description)
+	case t.isListPartition():
+		return fmt.Sprintf("PARTITION `%s` VALUES IN (%s)", p.Name, p.Description)
+	default:
+		return fmt.Sprintf("PARTITION `%s`", p.Name)
+	}
+}
+
+// partitionColumnVisitor 收集分区表达式中的列
+type partitionColumnVisitor struct {
+	columns []string
+}
+
+func (v *partitionColumnVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if col, ok := in.(*ast.ColumnNameExpr); ok {
+		v.columns = append(v.columns, col.Name.Name.O)
+	}
+	return in, false
+}
+
+func (v *partitionColumnVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// appendColumn 添加列名,忽略重复列
+func appendColumn(columns []string, name string) []string {
+	for _, col := range columns {
+		if strings.EqualFold(col, name) {
+			return columns
+		}
+	}
+	return append(columns, name)
+}
+
+// buildPartitionInfo 根据建表语句的分区定义设置表的分区信息
+func (s *session) buildPartitionInfo(t *TableInfo, p *ast.PartitionOptions) {
+	tp := p.Tp.String()
+	if p.Linear {
+		tp = "LINEAR " + tp
+	}
+	if p.Expr == nil && (p.Tp == model.PartitionTypeRange || p.Tp == model.PartitionTypeList) {
+		tp += " COLUMNS"
+	}
+	t.PartitionType = tp
+
+	t.PartitionColumns = nil
+	if p.Expr != nil {
+		v := &partitionColumnVisitor{}
+		p.Expr.Accept(v)
+		for _, col := range v.columns {
+			t.PartitionColumns = appendColumn(t.PartitionColumns, col)
+		}
+	} else {
+		for _, col := range p.ColumnNames {
+			t.PartitionColumns = appendColumn(t.PartitionColumns, col.Name.O)
+		}
+	}
+
+	t.Partitions = buildPartitionDefs(p.Definitions, p.Num, 0)
+	t.partitionLoaded = true
+}
+
+// buildPartitionDefs 返回分区列表. 未定义分区时按MySQL的规则命名为p0,p1...
+func buildPartitionDefs(defs []*ast.PartitionDefinition, num uint64, offset int) []PartitionInfo {
+	if len(defs) == 0 {
+		parts := make([]PartitionInfo, 0, num)
+		for i := 0; i < int(num); i++ {
+			parts = append(parts, PartitionInfo{Name: fmt.Sprintf("p%d", offset+i)})
+		}
+		return parts
+	}
+
+	parts := make([]PartitionInfo, 0, len(defs))
+	for _, def := range defs {
+		parts = append(parts, PartitionInfo{
+			Name:        def.Name.O,
+			Description: partitionDescription(def.Clause),
+		})
+	}
+	return parts
+}
+
+// partitionDescription 返回分区值,格式和information_schema.PARTITIONS保持一致
+func partitionDescription(clause ast.PartitionDefinitionClause) string {
+	switch c := clause.(type) {
+	case *ast.PartitionDefinitionClauseLessThan:
+		values := make([]string, 0, len(c.Exprs))
+		for _, expr := range c.Exprs {
+			values = append(values, partitionValue(expr))
+		}
+		return strings.Join(values, ",")
+	case *ast.PartitionDefinitionClauseIn:
+		values := make([]string, 0, len(c.Values))
+		for _, exprs := range c.Values {
+			items := make([]string, 0, len(exprs))
+			for _, expr := range exprs {
+				items = append(items, partitionValue(expr))
+			}
+			if len(items) == 1 {
+				values = append(values, items[0])
+			} else {
+				values = append(values, "("+strings.Join(items, ",")+")")
+			}
+		}
+		return strings.Join(values, ",")
+	}
+	return ""
+}
+
+// partitionValue 返回分区值,常用的日期函数会计算为整数
+func partitionValue(expr ast.ExprNode) string {
+	if fn, ok := expr.(*ast.FuncCallExpr); ok {
+		if v, ok := evalPartitionFunc(fn); ok {
+			return strconv.FormatInt(v, 10)
+		}
+	}
+
+	var builder strings.Builder
+	if err := expr.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder)); err != nil {
+		log.Error(err)
+	}
+	return builder.String()
+}
+
+// evalPartitionFunc 计算分区函数TO_DAYS,TO_SECONDS,YEAR的值
+func evalPartitionFunc(fn *ast.FuncCallExpr) (int64, bool) {
+	if len(fn.Args) != 1 {
+		return 0, false
+	}
+	arg, ok := fn.Args[0].(*ast.ValueExpr)
+	if !ok {
+		return 0, false
+	}
+
+	var d time.Time
+	var err error
+	value := arg.GetString()
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", "20060102"} {
+		if d, err = time.Parse(layout, value); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, false
+	}
+
+	secs := d.Unix()
+	days := secs / 86400
+	if secs%86400 < 0 {
+		days--
+	}
+
+	switch fn.FnName.L {
+	case "to_days":
+		return days + daysBeforeUnixEpoch, true
+	case "to_seconds":
+		return (days+daysBeforeUnixEpoch)*86400 + secs - days*86400, true
+	case "year":
+		return int64(d.Year()), true
+	}
+	return 0, false
+}
+
+// splitPartitionValues 按逗号拆分分区值,忽略引号内的逗号
+func splitPartitionValues(desc string) []string {
+	var values []string
+	var quote rune
+	start := 0
+	for i, c := range desc {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			values = append(values, strings.TrimSpace(desc[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, strings.TrimSpace(desc[start:]))
+}
+
+func isQuotedValue(v string) bool {
+	return len(v) >= 2 && (v[0] == '\'' || v[0] == '"') && v[len(v)-1] == v[0]
+}
+
+// comparePartitionValue 比较两个分区值,无法比较时返回false
+func comparePartitionValue(a, b string) (int, bool) {
+	aMax := strings.EqualFold(a, "MAXVALUE")
+	bMax := strings.EqualFold(b, "MAXVALUE")
+	switch {
+	case aMax && bMax:
+		return 0, true
+	case aMax:
+		return 1, true
+	case bMax:
+		return -1, true
+	}
+
+	if x, err := strconv.ParseInt(a, 10, 64); err == nil {
+		y, err := strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if isQuotedValue(a) && isQuotedValue(b) {
+		return strings.Compare(a[1:len(a)-1], b[1:len(b)-1]), true
+	}
+	return 0, false
+}
+
+// comparePartitionBound 比较RANGE分区的上限值,多列时依次比较
+func comparePartitionBound(a, b string) (int, bool) {
+	x := splitPartitionValues(a)
+	y := splitPartitionValues(b)
+	if len(x) != len(y) {
+		return 0, false
+	}
+	for i := range x {
+		cmp, ok := comparePartitionValue(x[i], y[i])
+		if !ok || cmp != 0 {
+			return cmp, ok
+		}
+	}
+	return 0, true
+}
+
+// loadPartitions 加载表的分区信息.
+// 离线审核时以快照为准,新建的表以建表语句为准
+func (s *session) loadPartitions(t *TableInfo) {
+	if t.partitionLoaded || t.IsNew {
+		return
+	}
+	t.partitionLoaded = true
+
+	if s.isOffline() {
+		if st := s.getSnapshotTable(t.Schema, t.Name); st != nil {
+			t.PartitionType = st.PartitionType
+			t.PartitionColumns = st.PartitionColumns
+			t.Partitions = make([]PartitionInfo, len(st.Partitions))
+			copy(t.Partitions, st.Partitions)
+		}
+		return
+	}
+
+	sql := fmt.Sprintf(`select PARTITION_NAME,max(PARTITION_METHOD),
+		IFNULL(max(PARTITION_EXPRESSION),''),IFNULL(max(PARTITION_DESCRIPTION),''),
+		IFNULL(sum(TABLE_ROWS),0) from information_schema.PARTITIONS
+		where table_schema='%s' and table_name='%s' and PARTITION_NAME is not null
+		group by PARTITION_NAME order by min(PARTITION_ORDINAL_POSITION);`, t.Schema, t.Name)
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
+			s.appendErrorMessage(myErr.Message)
+		} else {
+			s.appendErrorMessage(err.Error())
+		}
+		return
+	}
+
+	t.Partitions = nil
+	for rows.Next() {
+		var p PartitionInfo
+		var method, expr string
+		if err := rows.Scan(&p.Name, &method, &expr, &p.Description, &p.TableRows); err != nil {
+			s.appendErrorMessage(err.Error())
+			return
+		}
+		t.PartitionType = method
+		t.Partitions = append(t.Partitions, p)
+
+		if t.PartitionColumns == nil {
+			for _, m := range partitionColumnRegexp.FindAllStringSubmatch(expr, -1) {
+				t.PartitionColumns = appendColumn(t.PartitionColumns, m[1])
+			}
+			if t.PartitionColumns == nil && expr != "" && !strings.Contains(expr, "(") {
+				for _, col := range strings.Split(expr, ",") {
+					t.PartitionColumns = appendColumn(t.PartitionColumns, strings.TrimSpace(col))
+				}
+			}
+		}
+	}
+}
+
+// checkPartitioned 检查是否为分区表
+func (s *session) checkPartitioned(t *TableInfo) bool {
+	s.loadPartitions(t)
+	if !t.isPartitioned() {
+		s.appendErrorNo(ErrNotPartitionedTable, t.Name)
+		return false
+	}
+	return true
+}
+
+// checkPartitionList 检查分区数,分区名和RANGE分区值是否递增.
+// 分区定义无效时返回false
+func (s *session) checkPartitionList(t *TableInfo, parts []PartitionInfo) bool {
+	valid := true
+	limit := s.inc.MaxPartitions
+	if limit == 0 || limit > maxPartitions {
+		limit = maxPartitions
+	}
+	if uint(len(parts)) > limit {
+		s.appendErrorNo(ErrTooManyPartitions, t.Name, len(parts), limit)
+		valid = false
+	}
+
+	names := make(map[string]bool, len(parts))
+	for i, p := range parts {
+		key := strings.ToLower(p.Name)
+		if names[key] {
+			s.appendErrorNo(ErrDupPartitionName, p.Name, t.Name)
+			valid = false
+		}
+		names[key] = true
+
+		if i > 0 && t.isRangePartition() {
+			if cmp, ok := comparePartitionBound(parts[i-1].Description, p.Description); ok && cmp >= 0 {
+				s.appendErrorNo(ErrPartitionRangeNotIncreasing, p.Name, t.Name)
+				valid = false
+			}
+		}
+	}
+	return valid
+}
+
+// checkPartitionUniqueKeys 主键和唯一索引必须包含分区函数使用的所有列
+func (s *session) checkPartitionUniqueKeys(t *TableInfo) {
+	if t == nil || !t.isPartitioned() {
+		return
+	}
+
+	for _, d := range buildIndexDefs(t.Indexes) {
+		if !d.unique || d.functional {
+			continue
+		}
+		for _, col := range t.PartitionColumns {
+			found := false
+			for _, c := range d.columns {
+				if strings.EqualFold(c.name, col) && c.subPart == 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				s.appendErrorNo(ErrPartitionKeyNotInUniqueKey, d.name, t.Name)
+				break
+			}
+		}
+	}
+}
+
+// checkCreatePartition 审核建表语句的分区定义
+func (s *session) checkCreatePartition(t *TableInfo) {
+	if t == nil || !t.isPartitioned() {
+		return
+	}
+	s.checkPartitionList(t, t.Partitions)
+	s.checkPartitionUniqueKeys(t)
+}
+
+// isPartitionSpec 是否为分区管理操作
+func isPartitionSpec(alter *ast.AlterTableSpec) bool {
+	switch alter.Tp {
+	case ast.AlterTableAddPartitions,
+		ast.AlterTableDropPartition,
+		ast.AlterTableTruncatePartition,
+		ast.AlterTableCoalescePartitions,
+		ast.AlterTableReorganizePartition,
+		ast.AlterTableExchangePartition:
+		return true
+	}
+	return false
+}
+
+// checkPartitionAffectRows 记录分区操作影响的行数
+func (s *session) checkPartitionAffectRows(op string, rows uint) {
+	s.myRecord.AffectedRows = int(rows)
+	if s.inc.MaxDDLAffectRows > 0 && rows > s.inc.MaxDDLAffectRows {
+		s.appendErrorNo(ER_CHANGE_TOO_MUCH_ROWS,
+			op+" partition", rows, s.inc.MaxDDLAffectRows)
+	}
+}
+
+// checkAddPartition 审核添加分区
+func (s *session) checkAddPartition(t *TableInfo, c *ast.AlterTableSpec) {
+	if !s.checkPartitioned(t) {
+		return
+	}
+
+	var added []PartitionInfo
+	if len(c.PartDefinitions) > 0 {
+		added = buildPartitionDefs(c.PartDefinitions, 0, 0)
+	} else if t.isRangePartition() || t.isListPartition() {
+		s.appendErrorMessage(fmt.Sprintf("For %s partitions each partition must be defined",
+			t.PartitionType))
+		return
+	} else {
+		added = buildPartitionDefs(nil, c.Num, len(t.Partitions))
+	}
+
+	parts := make([]PartitionInfo, 0, len(t.Partitions)+len(added))
+	parts = append(parts, t.Partitions...)
+	parts = append(parts, added...)
+	if !s.checkPartitionList(t, parts) {
+		return
+	}
+
+	if s.opt.Execute && len(added) > 0 {
+		if t.isRangePartition() || t.isListPartition() {
+			names := make([]string, 0, len(added))
+			for _, p := range added {
+				names = append(names, fmt.Sprintf("`%s`", p.Name))
+			}
+			s.appendPartitionRollback(fmt.Sprintf("DROP PARTITION %s", strings.Join(names, ",")))
+		} else {
+			s.appendPartitionRollback(fmt.Sprintf("COALESCE PARTITION %d", len(added)))
+		}
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	t.Partitions = parts
+}
+
+// checkDropPartition 审核删除和清空分区,分区数据会丢失
+func (s *session) checkDropPartition(t *TableInfo, c *ast.AlterTableSpec) {
+	op := "DROP"
+	if c.Tp == ast.AlterTableTruncatePartition {
+		op = "TRUNCATE"
+	}
+
+	if !s.checkPartitioned(t) {
+		return
+	}
+	if op == "DROP" && !t.isRangePartition() && !t.isListPartition() {
+		s.appendErrorNo(ErrPartitionMgmtNotSupported, op, t.PartitionType, t.Name)
+		return
+	}
+
+	var rows uint
+	var names []string
+	dropped := make(map[int]bool)
+	found := true
+	for _, name := range c.PartitionNames {
+		i := t.findPartition(name.O)
+		if i < 0 {
+			s.appendErrorNo(ErrPartitionNotExisted, name.O, t.Name)
+			found = false
+			continue
+		}
+		if !dropped[i] {
+			dropped[i] = true
+			rows += t.Partitions[i].TableRows
+			names = append(names, fmt.Sprintf("`%s`", t.Partitions[i].Name))
+		}
+	}
+	if !found {
+		return
+	}
+
+	if op == "DROP" && len(dropped) == len(t.Partitions) {
+		s.appendErrorMessage("Cannot remove all partitions, use DROP TABLE instead")
+		return
+	}
+
+	s.checkPartitionAffectRows(op, rows)
+	s.appendErrorNo(ErrPartitionDataLoss, op, strings.Join(names, ","), t.Name, rows)
+
+	if op == "TRUNCATE" {
+		return
+	}
+
+	if s.opt.Execute {
+		s.dropPartitionRollback(t, dropped)
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	parts := make([]PartitionInfo, 0, len(t.Partitions)-len(dropped))
+	for i, p := range t.Partitions {
+		if !dropped[i] {
+			parts = append(parts, p)
+		}
+	}
+	t.Partitions = parts
+}
+
+// appendPartitionRollback 添加分区操作的回滚子句.
+// ALTER TABLE仅允许一个分区操作,每个子句单独生成一条回滚语句
+func (s *session) appendPartitionRollback(clause string) {
+	s.partitionRollbackBuffer = append(s.partitionRollbackBuffer, clause)
+}
+
+// dropPartitionRollback 生成删除分区的回滚语句,仅恢复分区定义,不恢复数据.
+// RANGE分区时被删除的分区需要从其后的分区中拆分出来,其后无分区时重新添加
+func (s *session) dropPartitionRollback(t *TableInfo, dropped map[int]bool) {
+	var defs []string
+	for i, p := range t.Partitions {
+		if dropped[i] {
+			defs = append(defs, t.partitionDefinition(p))
+			continue
+		}
+		if t.isRangePartition() && len(defs) > 0 {
+			defs = append(defs, t.partitionDefinition(p))
+			s.appendPartitionRollback(fmt.Sprintf("REORGANIZE PARTITION `%s` INTO (%s)",
+				p.Name, strings.Join(defs, ", ")))
+			defs = nil
+		}
+	}
+	if len(defs) > 0 {
+		s.appendPartitionRollback(fmt.Sprintf("ADD PARTITION (%s)", strings.Join(defs, ", ")))
+	}
+}
+
+// checkCoalescePartition 审核减少HASH/KEY分区
+func (s *session) checkCoalescePartition(t *TableInfo, c *ast.AlterTableSpec) {
+	if !s.checkPartitioned(t) {
+		return
+	}
+	if t.isRangePartition() || t.isListPartition() {
+		s.appendErrorNo(ErrPartitionMgmtNotSupported, "COALESCE", t.PartitionType, t.Name)
+		return
+	}
+
+	n := int(c.Num)
+	if n == 0 {
+		s.appendErrorMessage("At least one partition must be coalesced")
+		return
+	}
+	if n >= len(t.Partitions) {
+		s.appendErrorMessage("Cannot remove all partitions, use DROP TABLE instead")
+		return
+	}
+
+	if s.opt.Execute {
+		s.appendPartitionRollback(fmt.Sprintf("ADD PARTITION PARTITIONS %d", n))
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	t.Partitions = t.Partitions[:len(t.Partitions)-n]
+}
+
+// checkReorganizePartition 审核重组分区
+func (s *session) checkReorganizePartition(t *TableInfo, c *ast.AlterTableSpec) {
+	if !s.checkPartitioned(t) {
+		return
+	}
+
+	var positions []int
+	found := true
+	for _, name := range c.PartitionNames {
+		i := t.findPartition(name.O)
+		if i < 0 {
+			s.appendErrorNo(ErrPartitionNotExisted, name.O, t.Name)
+			found = false
+			continue
+		}
+		positions = append(positions, i)
+	}
+	if !found || len(positions) == 0 {
+		return
+	}
+	sort.Ints(positions)
+
+	added := buildPartitionDefs(c.PartDefinitions, 0, 0)
+	if t.isRangePartition() {
+		for i := 1; i < len(positions); i++ {
+			if positions[i] != positions[i-1]+1 {
+				s.appendErrorMessage("When reorganizing a set of partitions they must be in consecutive order")
+				return
+			}
+		}
+		// 仅最后一个分区可以扩大范围
+		last := positions[len(positions)-1]
+		if last != len(t.Partitions)-1 && len(added) > 0 {
+			cmp, ok := comparePartitionBound(added[len(added)-1].Description, t.Partitions[last].Description)
+			if ok && cmp != 0 {
+				s.appendErrorMessage("Reorganize of range partitions cannot change total ranges except for last partition where it can extend the range")
+				return
+			}
+		}
+	}
+
+	reorganized := make(map[int]bool, len(positions))
+	for _, i := range positions {
+		reorganized[i] = true
+	}
+
+	var rows uint
+	var oldDefs []string
+	parts := make([]PartitionInfo, 0, len(t.Partitions)-len(positions)+len(added))
+	for i, p := range t.Partitions {
+		if !reorganized[i] {
+			parts = append(parts, p)
+			continue
+		}
+		if i == positions[0] {
+			parts = append(parts, added...)
+		}
+		rows += p.TableRows
+		oldDefs = append(oldDefs, t.partitionDefinition(p))
+	}
+	if !s.checkPartitionList(t, parts) {
+		return
+	}
+
+	// 重组分区需要复制分区内的数据
+	s.checkPartitionAffectRows("REORGANIZE", rows)
+
+	if s.opt.Execute {
+		names := make([]string, 0, len(added))
+		for _, p := range added {
+			names = append(names, fmt.Sprintf("`%s`", p.Name))
+		}
+		s.appendPartitionRollback(fmt.Sprintf("REORGANIZE PARTITION %s INTO (%s)",
+			strings.Join(names, ","), strings.Join(oldDefs, ", ")))
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	t.Partitions = parts
+}
+
+// sameTableDefinition 两个表的列和索引定义是否相同
+func sameTableDefinition(t, other *TableInfo) bool {
+	var fields, otherFields []FieldInfo
+	for _, f := range t.Fields {
+		if !f.IsDeleted {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range other.Fields {
+		if !f.IsDeleted {
+			otherFields = append(otherFields, f)
+		}
+	}
+	if len(fields) != len(otherFields) {
+		return false
+	}
+	for i, f := range fields {
+		o := otherFields[i]
+		if !strings.EqualFold(f.Field, o.Field) || !strings.EqualFold(f.Type, o.Type) ||
+			f.Null != o.Null || !strings.EqualFold(f.Collation, o.Collation) {
+			return false
+		}
+	}
+
+	indexes := buildIndexDefs(t.Indexes)
+	otherIndexes := buildIndexDefs(other.Indexes)
+	if len(indexes) != len(otherIndexes) {
+		return false
+	}
+	for i, d := range indexes {
+		o := otherIndexes[i]
+		if !strings.EqualFold(d.name, o.name) || d.unique != o.unique || !d.isDuplicateOf(o) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkExchangePartition 审核分区和表交换数据
+func (s *session) checkExchangePartition(t *TableInfo, c *ast.AlterTableSpec) {
+	if !s.checkPartitioned(t) {
+		return
+	}
+
+	i := t.findPartition(c.Name)
+	if i < 0 {
+		s.appendErrorNo(ErrPartitionNotExisted, c.Name, t.Name)
+		return
+	}
+
+	schema := c.NewTable.Schema.O
+	if schema == "" {
+		schema = s.dbName
+	}
+	other := s.getTableFromCache(schema, c.NewTable.Name.O, true)
+	if other == nil {
+		return
+	}
+	s.loadPartitions(other)
+
+	reason := ""
+	switch {
+	case other.IsView:
+		reason = "it is a view"
+	case other.isPartitioned():
+		reason = "it is partitioned"
+	case !sameTableDefinition(t, other):
+		reason = "tables have different definitions"
+	}
+	if reason != "" {
+		s.appendErrorNo(ErrPartitionExchange, other.Name, c.Name, t.Name, reason)
+		return
+	}
+
+	s.checkPartitionAffectRows("EXCHANGE", t.Partitions[i].TableRows)
+
+	// 交换操作的回滚语句为其自身
+	if s.opt.Execute {
+		s.appendPartitionRollback(fmt.Sprintf("EXCHANGE PARTITION `%s` WITH TABLE `%s`.`%s`",
+			t.Partitions[i].Name, other.Schema, other.Name))
+	}
+}
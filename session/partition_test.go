@@ -0,0 +1,122 @@
+package session_test
+
+import (
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestPartition(c *C) {
+	snapshot := `{
+		"Version": "5.7.25",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t_log",
+			"Fields": [
+				{"Field": "id", "Type": "bigint(20)", "Null": "NO", "Key": "PRI"},
+				{"Field": "created", "Type": "datetime", "Null": "NO", "Key": "PRI"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"},
+				{"IndexName": "PRIMARY", "Seq": 2, "ColumnName": "created", "IndexType": "BTREE"}
+			],
+			"PartitionType": "RANGE",
+			"PartitionColumns": ["created"],
+			"Partitions": [
+				{"Name": "p2019", "Description": "737425", "TableRows": 100},
+				{"Name": "p2020", "Description": "737790", "TableRows": 200},
+				{"Name": "pmax", "Description": "MAXVALUE", "TableRows": 50}
+			],
+			"TableRows": 350
+		}, {
+			"Schema": "test_inc",
+			"Name": "t_plain",
+			"Fields": [
+				{"Field": "id", "Type": "bigint(20)", "Null": "NO", "Key": "PRI"},
+				{"Field": "created", "Type": "datetime", "Null": "NO", "Key": "PRI"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"},
+				{"IndexName": "PRIMARY", "Seq": 2, "ColumnName": "created", "IndexType": "BTREE"}
+			]
+		}, {
+			"Schema": "test_inc",
+			"Name": "t_other",
+			"Fields": [
+				{"Field": "id", "Type": "bigint(20)", "Null": "NO", "Key": "PRI"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			]
+		}, {
+			"Schema": "test_inc",
+			"Name": "t_hash",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			],
+			"PartitionType": "HASH",
+			"PartitionColumns": ["id"],
+			"Partitions": [{"Name": "p0"}, {"Name": "p1"}, {"Name": "p2"}, {"Name": "p3"}]
+		}]
+	}`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnablePartitionTable = true
+	cnf.Inc.MaxPartitions = 4
+	cnf.IncLevel.ER_ALTER_TABLE_ONCE = 0
+
+	result := s.audit(c, snapshot, `use test_inc;
+	create table t1(id int, c int, primary key(id)) partition by range(c) (
+		partition p0 values less than (10), partition p1 values less than (5),
+		partition p1 values less than maxvalue);
+	create table t2(id int primary key) partition by hash(id) partitions 5;
+	alter table t_log add partition (partition p2021 values less than (to_days('2021-01-01')));
+	alter table t_log drop partition p2019;
+	alter table t_log truncate partition p2020, p9;
+	alter table t_hash drop partition p0;
+	alter table t_plain add partition (partition p0 values less than (1));
+	alter table t_log reorganize partition pmax into (
+		partition p2021 values less than (to_days('2021-01-01')), partition pmax values less than maxvalue);
+	alter table t_log exchange partition p2020 with table t_plain;
+	alter table t_log exchange partition p2020 with table t_other;
+	alter table t_log add unique key uniq_id(id);
+	alter table t_hash coalesce partition 1;`)
+	c.Assert(len(result), Equals, 13)
+
+	msg := result[1].ErrorMessage
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(strings.Contains(msg, "Index 'PRIMARY' on table 't1' must include all columns in the table's partitioning function."), IsTrue, Commentf("%v", msg))
+	c.Assert(strings.Contains(msg, "VALUES LESS THAN value must be strictly increasing for each partition, partition 'p1' of table 't1'."), IsTrue, Commentf("%v", msg))
+	c.Assert(strings.Contains(msg, "Duplicate partition name 'p1' in table 't1'."), IsTrue, Commentf("%v", msg))
+
+	c.Assert(result[2].ErrorMessage, Equals, "Table 't2' has 5 partitions, exceeds the maximum of 4.")
+
+	// 最后一个分区为MAXVALUE时不能再添加分区
+	c.Assert(result[3].ErrorMessage, Equals,
+		"VALUES LESS THAN value must be strictly increasing for each partition, partition 'p2021' of table 't_log'.")
+
+	c.Assert(result[4].ErrLevel, Equals, uint8(1))
+	c.Assert(result[4].ErrorMessage, Equals, "DROP PARTITION `p2019` of table 't_log' will lose about 100 rows.")
+	c.Assert(result[4].AffectedRows, Equals, 100)
+	c.Assert(result[4].UseOsc, IsFalse)
+
+	c.Assert(result[5].ErrorMessage, Equals, "Partition 'p9' doesn't exist in table 't_log'.")
+	c.Assert(result[6].ErrorMessage, Equals, "DROP PARTITION is not supported for HASH partitioned table 't_hash'.")
+	c.Assert(result[7].ErrorMessage, Equals, "Partition management on a not partitioned table 't_plain' is not possible.")
+
+	c.Assert(result[8].ErrLevel, Equals, uint8(0), Commentf("%v", result[8].ErrorMessage))
+	c.Assert(result[8].AffectedRows, Equals, 50)
+	c.Assert(result[9].ErrLevel, Equals, uint8(0), Commentf("%v", result[9].ErrorMessage))
+	c.Assert(result[10].ErrorMessage, Equals,
+		"Table 't_other' can't be exchanged with partition 'p2020' of table 't_log': tables have different definitions.")
+	c.Assert(result[11].ErrorMessage, Equals,
+		"Index 'uniq_id' on table 't_log' must include all columns in the table's partitioning function.")
+	c.Assert(result[12].ErrLevel, Equals, uint8(0), Commentf("%v", result[12].ErrorMessage))
+}
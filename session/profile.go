@@ -0,0 +1,145 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// matchProfile 匹配当前会话的审核规则配置集
+// 调用参数指定的配置集优先,其次按主机匹配,最后按库名匹配
+func (s *session) matchProfile(db string) string {
+	if s.opt.Profile != "" {
+		return s.opt.Profile
+	}
+
+	profiles := config.GetGlobalConfig().Profiles
+	if len(profiles) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if s.opt.Host != "" {
+		hosts := []string{strings.ToLower(s.opt.Host),
+			fmt.Sprintf("%s:%d", strings.ToLower(s.opt.Host), s.opt.Port)}
+		for _, name := range names {
+			if matchAnyPattern(profiles[name].Hosts, hosts...) {
+				return name
+			}
+		}
+	}
+
+	if db != "" {
+		for _, name := range names {
+			if matchAnyPattern(profiles[name].Schemas, strings.ToLower(db)) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// matchAnyPattern 判断值是否匹配任一通配符(*,?)规则
+func matchAnyPattern(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, v := range values {
+			if ok, _ := path.Match(pattern, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadProfile 加载会话级审核参数和审核级别,不影响全局配置
+// 优先级: 全局配置 < 配置集 < 调用参数中的Inc/IncLevel < inception set设置的会话级参数
+func (s *session) loadProfile(name string) error {
+	cnf := config.GetGlobalConfig()
+
+	s.inc = cnf.Inc
+	s.parseIncLevel()
+	s.profile = name
+
+	if name != "" {
+		p, ok := cnf.Profiles[name]
+		if !ok {
+			return fmt.Errorf("审核规则配置集'%s'不存在", name)
+		}
+		log.Debugf("con:%d profile: %s", s.sessionVars.ConnectionID, name)
+		if err := s.applyIncOptions(p.Inc, p.IncLevel); err != nil {
+			return fmt.Errorf("审核规则配置集'%s'无效: %v", name, err)
+		}
+	}
+
+	if err := s.applyIncOptions(s.opt.Inc, s.opt.IncLevel); err != nil {
+		return err
+	}
+	if err := s.applyIncOptions(s.setInc, s.setLevels); err != nil {
+		return err
+	}
+
+	s.inc.Lang = strings.Replace(strings.ToLower(s.inc.Lang), "-", "_", 1)
+	return nil
+}
+
+// applyIncOptions 覆盖会话的审核参数和审核级别
+func (s *session) applyIncOptions(inc map[string]interface{}, levels map[string]int8) error {
+	for k, v := range inc {
+		err := s.setVariableValue(reflect.TypeOf(s.inc), reflect.ValueOf(&s.inc).Elem(),
+			k, ast.NewValueExpr(v))
+		if err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+	}
+
+	for k, v := range levels {
+		if err := s.setSessionLevel(k, ast.NewValueExpr(int64(v))); err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+	}
+	return nil
+}
+
+// setSessionLevel 设置会话级的审核级别,不影响全局配置
+func (s *session) setSessionLevel(name string, value *ast.ValueExpr) error {
+	key := ""
+	for k := range s.incLevel {
+		if strings.EqualFold(k, name) {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		return errors.New("无效参数")
+	}
+
+	sVal, err := value.ToString()
+	if err != nil {
+		return err
+	}
+	if sVal == "" {
+		return ErrWrongTypeForVar.GenWithStackByArgs(name)
+	}
+	sVal, err = s.checkInt64SystemVar(name, sVal, 0, 2)
+	if err != nil {
+		return err
+	}
+
+	level, _ := strconv.ParseInt(sVal, 10, 64)
+	s.incLevel[key] = uint8(level)
+	return nil
+}
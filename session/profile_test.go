@@ -0,0 +1,97 @@
+package session_test
+
+import (
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+func (s *testOfflineSuite) TestProfile(c *C) {
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.CheckTableComment = false
+	cnf.Profiles = map[string]config.Profile{
+		"strict": {
+			Schemas:  []string{"prod_*"},
+			Inc:      map[string]interface{}{"check_table_comment": true},
+			IncLevel: map[string]int8{"er_table_must_have_comment": 2},
+		},
+		"legacy": {
+			Hosts: []string{"10.0.0.*"},
+			Inc:   map[string]interface{}{"check_table_comment": false},
+		},
+	}
+
+	audit := func(opt session.SourceOptions, sql string) ([]session.Record, error) {
+		opt.Offline = true
+		opt.Snapshot = "create database test_inc;\ncreate database prod_inc;"
+		core := session.NewInception()
+		core.LoadOptions(opt)
+		return core.Audit(context.Background(), sql)
+	}
+
+	sql := `use test_inc;
+	create table t1(id int primary key comment 'id');
+	use prod_inc;
+	create table t1(id int primary key comment 'id');
+	inception set level er_table_must_have_comment = 1;
+	create table t2(id int primary key comment 'id');
+	use test_inc;
+	create table t2(id int primary key comment 'id');`
+
+	// 按库名匹配配置集
+	result, err := audit(session.SourceOptions{}, sql)
+	c.Assert(err, IsNil)
+	c.Assert(len(result), Equals, 7)
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[3].ErrLevel, Equals, uint8(2))
+	c.Assert(result[3].ErrorMessage, Equals, "Set comments for table 't1'.")
+	c.Assert(result[4].ErrLevel, Equals, uint8(1))
+	c.Assert(result[6].ErrLevel, Equals, uint8(0), Commentf("%v", result[6].ErrorMessage))
+
+	// 会话级设置不影响全局配置
+	c.Assert(cnf.Inc.CheckTableComment, IsFalse)
+	c.Assert(cnf.IncLevel.ER_TABLE_MUST_HAVE_COMMENT, Equals, config.NewConfig().IncLevel.ER_TABLE_MUST_HAVE_COMMENT)
+
+	// inception set设置的会话级参数在切换配置集后仍然生效
+	result, err = audit(session.SourceOptions{}, `use test_inc;
+	inception set check_table_comment = 1;
+	use prod_inc;
+	use test_inc;
+	create table t2(id int primary key comment 'id');`)
+	c.Assert(err, IsNil)
+	c.Assert(len(result), Equals, 4)
+	c.Assert(result[3].ErrorMessage, Equals, "Set comments for table 't2'.")
+
+	result, err = audit(session.SourceOptions{}, `use test_inc;
+	inception set check_table_comment = 1;
+	inception set level er_table_must_have_comment = 1;
+	use prod_inc;
+	create table t1(id int primary key comment 'id');`)
+	c.Assert(err, IsNil)
+	c.Assert(len(result), Equals, 3)
+	c.Assert(result[2].ErrLevel, Equals, uint8(1))
+	c.Assert(result[2].ErrorMessage, Equals, "Set comments for table 't1'.")
+
+	// 按主机匹配的配置集优先于库名
+	result, err = audit(session.SourceOptions{Host: "10.0.0.1", Port: 3306}, sql)
+	c.Assert(err, IsNil)
+	c.Assert(result[3].ErrLevel, Equals, uint8(0), Commentf("%v", result[3].ErrorMessage))
+
+	// 调用参数优先级最高
+	result, err = audit(session.SourceOptions{
+		Profile:  "strict",
+		IncLevel: map[string]int8{"er_table_must_have_comment": 1},
+	}, sql)
+	c.Assert(err, IsNil)
+	c.Assert(result[1].ErrLevel, Equals, uint8(1))
+	c.Assert(result[3].ErrLevel, Equals, uint8(1))
+
+	_, err = audit(session.SourceOptions{Profile: "unknown"}, sql)
+	c.Assert(err, ErrorMatches, "审核规则配置集'unknown'不存在")
+
+	_, err = audit(session.SourceOptions{Inc: map[string]interface{}{"no_such_option": 1}}, sql)
+	c.Assert(err, ErrorMatches, "no_such_option: 无效参数")
+}
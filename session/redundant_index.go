@@ -0,0 +1,320 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/types"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// RedundantIndex 冗余索引.
+// 索引与其他索引完全重复,或为其他索引的最左前缀时视为冗余
+type RedundantIndex struct {
+	Schema string
+	Table  string
+
+	// 冗余的索引及其列,前缀索引的列格式为 列名(长度)
+	IndexName string
+	Columns   []string
+
+	// 覆盖该索引的索引及其列
+	CoveredBy      string
+	CoveredColumns []string
+
+	// 是否与覆盖索引完全重复
+	Duplicate bool
+
+	// 建议的删除语句
+	Suggestion string
+}
+
+// indexColumn 索引列,subPart为前缀索引长度,0表示整列
+type indexColumn struct {
+	name    string
+	subPart int
+}
+
+func (c indexColumn) String() string {
+	if c.subPart > 0 {
+		return fmt.Sprintf("%s(%d)", c.name, c.subPart)
+	}
+	return c.name
+}
+
+// indexDef 按索引名汇总的索引定义
+type indexDef struct {
+	name    string
+	unique  bool
+	tp      string
+	columns []indexColumn
+
+	// 包含函数索引(表达式)的列,不参与比较
+	functional bool
+}
+
+func (d *indexDef) isPrimary() bool {
+	return strings.EqualFold(d.name, "PRIMARY")
+}
+
+func (d *indexDef) columnNames() []string {
+	names := make([]string, len(d.columns))
+	for i, col := range d.columns {
+		names[i] = col.String()
+	}
+	return names
+}
+
+// isLeftPrefixOf 索引的列是否为other的最左前缀.
+// 前缀索引仅在other对应列为整列或前缀不短于自身时被覆盖
+func (d *indexDef) isLeftPrefixOf(other *indexDef) bool {
+	if len(d.columns) > len(other.columns) {
+		return false
+	}
+	for i, col := range d.columns {
+		c := other.columns[i]
+		if !strings.EqualFold(col.name, c.name) {
+			return false
+		}
+		if c.subPart > 0 && (col.subPart == 0 || col.subPart > c.subPart) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDuplicateOf 索引的列(含前缀长度)是否和other完全相同
+func (d *indexDef) isDuplicateOf(other *indexDef) bool {
+	return len(d.columns) == len(other.columns) &&
+		d.isLeftPrefixOf(other) && other.isLeftPrefixOf(d)
+}
+
+// indexSubPart 返回索引列的前缀长度,未指定时为0
+func indexSubPart(col *ast.IndexColName) int {
+	if col.Column == nil || col.Length == types.UnspecifiedLength {
+		return 0
+	}
+	return col.Length
+}
+
+// buildIndexDefs 按索引名汇总索引信息,忽略已删除的索引,保持索引的定义顺序
+func buildIndexDefs(indexes []*IndexInfo) []*indexDef {
+	var defs []*indexDef
+	found := make(map[string]*indexDef)
+	rows := make(map[*indexDef][]*IndexInfo)
+	for _, row := range indexes {
+		if row.IsDeleted {
+			continue
+		}
+		key := strings.ToLower(row.IndexName)
+		d, ok := found[key]
+		if !ok {
+			tp := strings.ToUpper(row.IndexType)
+			if tp != "FULLTEXT" && tp != "SPATIAL" {
+				tp = "BTREE"
+			}
+			d = &indexDef{
+				name:   row.IndexName,
+				unique: row.NonUnique == 0,
+				tp:     tp,
+			}
+			found[key] = d
+			defs = append(defs, d)
+		}
+		rows[d] = append(rows[d], row)
+	}
+
+	for _, d := range defs {
+		cols := rows[d]
+		sort.SliceStable(cols, func(i, j int) bool {
+			return cols[i].Seq < cols[j].Seq
+		})
+		for _, row := range cols {
+			if row.ColumnName == "" {
+				d.functional = true
+			}
+			d.columns = append(d.columns, indexColumn{name: row.ColumnName, subPart: row.SubPart})
+		}
+	}
+	return defs
+}
+
+// isRedundantWith 索引是否被other覆盖. i,j为两个索引的定义顺序,
+// 完全重复且唯一性相同时,保留先定义的索引
+func (d *indexDef) isRedundantWith(other *indexDef, i, j int) bool {
+	if d.isPrimary() || d.functional || other.functional || d.tp != other.tp {
+		return false
+	}
+
+	if !d.isLeftPrefixOf(other) {
+		return false
+	}
+
+	duplicate := d.isDuplicateOf(other)
+	// 全文索引不能使用最左前缀
+	if !duplicate && d.tp == "FULLTEXT" {
+		return false
+	}
+
+	// 唯一索引仅在与其他唯一索引(含主键)完全重复时冗余
+	if d.unique {
+		if !duplicate || !other.unique {
+			return false
+		}
+		return other.isPrimary() || i > j
+	}
+
+	if duplicate && !other.unique {
+		return i > j
+	}
+	return true
+}
+
+// findRedundantIndexes 分析表的索引,返回冗余索引及建议的删除语句
+func findRedundantIndexes(t *TableInfo) []RedundantIndex {
+	defs := buildIndexDefs(t.Indexes)
+
+	var result []RedundantIndex
+	for i, d := range defs {
+		for j, other := range defs {
+			if i == j || !d.isRedundantWith(other, i, j) {
+				continue
+			}
+			result = append(result, RedundantIndex{
+				Schema:         t.Schema,
+				Table:          t.Name,
+				IndexName:      d.name,
+				Columns:        d.columnNames(),
+				CoveredBy:      other.name,
+				CoveredColumns: other.columnNames(),
+				Duplicate:      d.isDuplicateOf(other),
+				Suggestion: fmt.Sprintf("DROP INDEX `%s` ON `%s`.`%s`;",
+					d.name, t.Schema, t.Name),
+			})
+			break
+		}
+	}
+	return result
+}
+
+// indexNameSet 返回表的现有索引名,用以区分本次新增的索引
+func indexNameSet(t *TableInfo) map[string]bool {
+	names := make(map[string]bool)
+	if t == nil {
+		return names
+	}
+	for _, row := range t.Indexes {
+		if !row.IsDeleted {
+			names[strings.ToLower(row.IndexName)] = true
+		}
+	}
+	return names
+}
+
+// checkRedundantIndex 审核变更后的索引是否冗余.
+// existing为变更前已有的索引,仅提示和本次新增索引相关的冗余
+func (s *session) checkRedundantIndex(t *TableInfo, existing map[string]bool) {
+	if t == nil {
+		return
+	}
+
+	for _, r := range findRedundantIndexes(t) {
+		if existing[strings.ToLower(r.IndexName)] &&
+			existing[strings.ToLower(r.CoveredBy)] {
+			continue
+		}
+		s.appendErrorNo(ErrRedundantIndex, r.IndexName, t.Name, r.CoveredBy, r.Suggestion)
+	}
+}
+
+// RedundantIndexes 分析数据源中已有表的索引,返回冗余索引及建议的删除语句.
+// 未指定表时分析DB参数指定库的所有表,离线模式时基于表结构快照分析
+func (s *session) RedundantIndexes(ctx context.Context, tables ...string) ([]RedundantIndex, error) {
+	if s.opt == nil {
+		return nil, errors.New("未配置数据源信息!")
+	}
+	if s.opt.DB == "" {
+		return nil, errors.New("未指定数据库名")
+	}
+
+	s.init()
+	defer s.clear()
+	s.opt.Check = true
+
+	s.recordSets = NewRecordSets()
+	s.myRecord = &Record{Buf: new(bytes.Buffer)}
+	if err := s.checkOptions(); err != nil {
+		return nil, err
+	}
+	if s.isOffline() {
+		// 加载快照时会重置审核结果
+		s.recordSets = NewRecordSets()
+		s.myRecord = &Record{Buf: new(bytes.Buffer)}
+	}
+
+	s.dbName = s.opt.DB
+	if len(tables) == 0 {
+		tables = s.listTables(s.opt.DB)
+	}
+
+	var result []RedundantIndex
+	for _, name := range tables {
+		t := s.getTableFromCache(s.opt.DB, name, true)
+		if t == nil || t.IsView {
+			continue
+		}
+		result = append(result, findRedundantIndexes(t)...)
+	}
+
+	if s.myRecord.ErrLevel == 2 {
+		msg := strings.TrimSpace(s.myRecord.Buf.String())
+		log.Errorf("con:%d %s", s.sessionVars.ConnectionID, msg)
+		return result, errors.New(msg)
+	}
+	return result, nil
+}
+
+// listTables 返回库中的所有表(不含视图),按表名排序
+func (s *session) listTables(db string) []string {
+	var tables []string
+
+	if s.isOffline() {
+		if s.snapshot == nil {
+			return nil
+		}
+		for _, t := range s.snapshot.tables {
+			if !t.IsView && s.snapshotDBKey(t.Schema) == s.snapshotDBKey(db) {
+				tables = append(tables, t.Name)
+			}
+		}
+		sort.Strings(tables)
+		return tables
+	}
+
+	sql := fmt.Sprintf(`select TABLE_NAME from information_schema.tables
+		where table_schema='%s' and table_type='BASE TABLE' order by TABLE_NAME;`, db)
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		s.appendErrorMessage(err.Error())
+		return nil
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			s.appendErrorMessage(err.Error())
+			return nil
+		}
+		tables = append(tables, name)
+	}
+	return tables
+}
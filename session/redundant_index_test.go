@@ -0,0 +1,77 @@
+package session_test
+
+import (
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+func (s *testOfflineSuite) TestRedundantIndex(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key, a int, b int, c int, name varchar(50),
+		key idx_a(a), key idx_ab(a,b), unique key uniq_id(id),
+		key idx_name(name(10)), key idx_name_full(name), key idx_c(c));
+	create table t2(id int primary key, x int, y int, key idx_x(x));`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.CheckRedundantIndex = true
+
+	result := s.audit(c, snapshot, `use test_inc;
+	create table t3(id int primary key, a int, b int, key idx_a(a), key idx_ab(a,b));
+	alter table t1 add index idx_bc(b,c);
+	alter table t1 add index idx_cb(c,b);
+	alter table t2 drop index idx_x, add index idx_xy(x,y);
+	create index idx_ab2 on t3(a,b);
+	create unique index uniq_a on t3(a);`)
+	c.Assert(len(result), Equals, 7)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(1))
+	c.Assert(result[1].ErrorMessage, Equals,
+		"Index 'idx_a' on table 't3' is redundant with index 'idx_ab', suggest: DROP INDEX `idx_a` ON `test_inc`.`t3`;")
+	c.Assert(result[1].Findings[0].Index, Equals, "idx_a")
+
+	// 已有的冗余索引不重复提示
+	c.Assert(result[2].ErrLevel, Equals, uint8(0), Commentf("%v", result[2].ErrorMessage))
+	c.Assert(result[3].ErrorMessage, Equals,
+		"Index 'idx_c' on table 't1' is redundant with index 'idx_cb', suggest: DROP INDEX `idx_c` ON `test_inc`.`t1`;")
+	c.Assert(result[4].ErrLevel, Equals, uint8(0), Commentf("%v", result[4].ErrorMessage))
+	c.Assert(result[5].ErrorMessage, Equals,
+		"Index 'idx_ab2' on table 't3' is redundant with index 'idx_ab', suggest: DROP INDEX `idx_ab2` ON `test_inc`.`t3`;")
+	// 唯一索引为其他索引的最左前缀时不冗余
+	c.Assert(strings.Contains(result[6].ErrorMessage, "redundant"), IsFalse,
+		Commentf("%v", result[6].ErrorMessage))
+
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		DB:       "test_inc",
+		Offline:  true,
+		Snapshot: snapshot,
+	})
+	report, err := core.RedundantIndexes(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(len(report), Equals, 3)
+
+	c.Assert(report[0].Table, Equals, "t1")
+	c.Assert(report[0].IndexName, Equals, "idx_a")
+	c.Assert(report[0].CoveredBy, Equals, "idx_ab")
+	c.Assert(report[0].Duplicate, IsFalse)
+
+	c.Assert(report[1].IndexName, Equals, "uniq_id")
+	c.Assert(report[1].CoveredBy, Equals, "PRIMARY")
+	c.Assert(report[1].Duplicate, IsTrue)
+
+	c.Assert(report[2].IndexName, Equals, "idx_name")
+	c.Assert(report[2].Columns, DeepEquals, []string{"name(10)"})
+	c.Assert(report[2].CoveredBy, Equals, "idx_name_full")
+	c.Assert(report[2].Suggestion, Equals, "DROP INDEX `idx_name` ON `test_inc`.`t1`;")
+
+	report, err = core.RedundantIndexes(context.Background(), "t2", "t9")
+	c.Assert(err, NotNil)
+	c.Assert(len(report), Equals, 0)
+}
@@ -0,0 +1,543 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/parser"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// RollbackOptions 回滚参数.
+// 回滚前会检查冲突: delete和insert的回滚语句检查行是否存在.
+// update的回滚语句仅在备份时开启了enable_rollback_guard(where条件包含更新后的列值)时,
+// 才能发现行在执行后被再次修改,否则只检查行是否存在,并标记为Unverified
+type RollbackOptions struct {
+	// 待回滚的opid,即执行结果中的Record.OPID. 按传入的逆序回滚
+	OPIDs []string
+	// 备份库名,即执行结果中的Record.BackupDBName.
+	// 为空时根据数据源的Host,Port和DB参数确定
+	BackupDBName string
+
+	// 仅审核并返回回滚计划,不执行
+	DryRun bool
+	// 存在冲突时仍然执行. 审核错误时不会执行
+	Force bool
+}
+
+// RollbackRecord 回滚计划中的一条回滚语句
+type RollbackRecord struct {
+	// 原操作的opid,备份库名,库名,语句及类型
+	OPID         string
+	BackupDBName string
+	DBName       string
+	OriginalSql  string
+	Type         string
+
+	// 回滚语句
+	Sql string
+	// 回滚语句的审核结果,执行后为执行结果
+	Record Record
+	// 数据自原操作执行后是否已变更,如待回滚的行已被删除或修改
+	Conflict bool
+	// 是否无法检查行在执行后被修改. update的回滚语句where条件仅包含主键时为true,
+	// 即备份时未开启enable_rollback_guard
+	Unverified bool
+	// 回滚语句是否无法完全恢复原操作,如DROP PARTITION的回滚语句仅恢复分区定义,不恢复数据
+	Partial bool
+}
+
+// RollbackSummary 回滚前的校验结果,按opid汇总
+type RollbackSummary struct {
+	OPID         string
+	BackupDBName string
+	// 回滚语句数
+	Statements int
+	// 执行后已被修改或删除的行数,即冲突的回滚语句数.
+	// 开启enable_rollback_guard时,update的回滚语句会校验更新后的全部列值
+	Changed int
+	// 无法检查是否已被修改的回滚语句数,这部分语句不计入Changed
+	Unverified int
+	// 原操作是否无法完全恢复
+	Partial bool
+}
+
+// SummarizeRollback 按opid汇总回滚计划中的冲突数,用以在回滚前确认数据变更情况
+func SummarizeRollback(plan []RollbackRecord) []RollbackSummary {
+	var result []RollbackSummary
+	index := make(map[string]int)
+	for _, r := range plan {
+		key := r.BackupDBName + "." + r.OPID
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, RollbackSummary{OPID: r.OPID, BackupDBName: r.BackupDBName})
+		}
+		result[i].Statements++
+		if r.Conflict {
+			result[i].Changed++
+		}
+		if r.Unverified {
+			result[i].Unverified++
+		}
+		if r.Partial {
+			result[i].Partial = true
+		}
+	}
+	return result
+}
+
+// rollbackTarget 待回滚的opid及其备份库
+type rollbackTarget struct {
+	db   string
+	opid string
+}
+
+// Rollback 从备份中读取opid的回滚语句,按执行的逆序回放.
+// 回滚语句执行前先审核并检查冲突,存在审核错误或冲突时不执行,
+// 并分别返回ErrRollbackAuditFailed和ErrRollbackConflict.
+// 原操作无法完全恢复时(如删除或清空分区),返回回滚计划的同时返回ErrRollbackIncomplete
+func (s *session) Rollback(ctx context.Context, opt RollbackOptions) ([]RollbackRecord, error) {
+	if s.opt == nil {
+		return nil, errors.New("未配置数据源信息!")
+	}
+	if len(opt.OPIDs) == 0 {
+		return nil, errors.New("未指定待回滚的opid")
+	}
+
+	db := opt.BackupDBName
+	if db == "" {
+		if s.opt.DB == "" {
+			return nil, errors.New("未指定备份库名或数据库名")
+		}
+		db = s.getRemoteBackupDBName(&Record{TableInfo: &TableInfo{Schema: s.opt.DB}})
+	}
+
+	targets := make([]rollbackTarget, len(opt.OPIDs))
+	for i, opid := range opt.OPIDs {
+		targets[i] = rollbackTarget{db: db, opid: opid}
+	}
+	return s.rollback(ctx, targets, opt)
+}
+
+// RollbackByTicket 回滚一次执行的所有操作. records为执行结果,未备份的语句会被跳过.
+// opt中的OPIDs和BackupDBName参数无效
+func (s *session) RollbackByTicket(ctx context.Context, records []Record, opt RollbackOptions) ([]RollbackRecord, error) {
+	if s.opt == nil {
+		return nil, errors.New("未配置数据源信息!")
+	}
+
+	var targets []rollbackTarget
+	for _, r := range records {
+		if r.OPID != "" && r.BackupDBName != "" {
+			targets = append(targets, rollbackTarget{db: r.BackupDBName, opid: r.OPID})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("未找到已备份的语句")
+	}
+	return s.rollback(ctx, targets, opt)
+}
+
+func (s *session) rollback(ctx context.Context, targets []rollbackTarget, opt RollbackOptions) ([]RollbackRecord, error) {
+	plan, incomplete, err := s.rollbackPlan(targets)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		if incomplete {
+			return plan, ErrRollbackIncomplete
+		}
+		return plan, nil
+	}
+
+	// 回滚语句按原操作的库执行,如DROP TABLE的回滚语句不包含库名.
+	// index为各语句对应的回滚计划,切换库的语句为-1
+	var (
+		statements []string
+		index      []int
+		db         string
+	)
+	for i, r := range plan {
+		if r.DBName != "" && r.DBName != db {
+			db = r.DBName
+			statements = append(statements, fmt.Sprintf("USE `%s`;", db))
+			index = append(index, -1)
+		}
+		statements = append(statements, r.Sql)
+		index = append(index, i)
+	}
+	sql := strings.Join(statements, "\n")
+
+	// 审核会修改数据源参数,执行前需要还原
+	source := *s.opt
+	defer func() { *s.opt = source }()
+
+	records, unverified, err := s.auditRollback(ctx, sql)
+	setRollbackRecords(plan, index, records)
+	for j, v := range unverified {
+		if j < len(index) && index[j] >= 0 {
+			plan[index[j]].Unverified = v
+		}
+	}
+	conflict := false
+	for i := range plan {
+		for _, f := range plan[i].Record.Findings {
+			if f.Code == ErrRollbackRowNotFound || f.Code == ErrRollbackRowExists {
+				plan[i].Conflict = true
+				conflict = true
+			}
+		}
+	}
+	if err == nil && opt.DryRun && incomplete {
+		err = ErrRollbackIncomplete
+	}
+	if err != nil || opt.DryRun {
+		return plan, err
+	}
+
+	for _, r := range records {
+		if r.ErrLevel == 2 {
+			return plan, ErrRollbackAuditFailed
+		}
+	}
+	if conflict && !opt.Force {
+		return plan, ErrRollbackConflict
+	}
+
+	*s.opt = source
+	records, err = s.RunExecute(ctx, sql)
+	setRollbackRecords(plan, index, records)
+	if err == nil && incomplete {
+		err = ErrRollbackIncomplete
+	}
+	return plan, err
+}
+
+// setRollbackRecords 按语句顺序设置回滚计划的审核或执行结果
+func setRollbackRecords(plan []RollbackRecord, index []int, records []Record) {
+	for j, r := range records {
+		if j < len(index) && index[j] >= 0 {
+			plan[index[j]].Record = r
+		}
+	}
+}
+
+// rollbackPlan 从备份中读取回滚语句,opid按传入的逆序排列.
+// incomplete为是否存在无法完全恢复的原操作
+func (s *session) rollbackPlan(targets []rollbackTarget) (plan []RollbackRecord, incomplete bool, err error) {
+	// 备份按会话的审核参数写入,配置集可能指定了不同的备份存储
+	if err := s.loadProfile(s.matchProfile(s.opt.DB)); err != nil {
+		return nil, false, err
+	}
+	sink, err := NewBackupSink(s.inc)
+	if err != nil {
+		return nil, false, err
+	}
+	if m, ok := sink.(*mysqlBackupSink); ok {
+		defer m.db.Close()
+	}
+
+	for i := len(targets) - 1; i >= 0; i-- {
+		t := targets[i]
+		info, statements, err := sink.Rollback(t.db, t.opid)
+		if err != nil {
+			return nil, false, errors.Annotatef(err, "opid %s", t.opid)
+		}
+		partial := isPartialRollback(info)
+		if partial {
+			incomplete = true
+		}
+		for _, stmt := range statements {
+			for _, sql := range splitRollbackStatement(stmt) {
+				plan = append(plan, RollbackRecord{
+					OPID:         t.opid,
+					BackupDBName: t.db,
+					DBName:       info.DBName,
+					OriginalSql:  info.Sql,
+					Type:         info.Type,
+					Sql:          sql,
+					Partial:      partial,
+				})
+			}
+		}
+	}
+	return plan, incomplete, nil
+}
+
+// isPartialRollback 原操作是否无法通过回滚语句完全恢复.
+// DROP PARTITION的回滚语句仅恢复分区定义,TRUNCATE PARTITION没有回滚语句,分区数据均无法恢复
+func isPartialRollback(info *BackupInfo) bool {
+	if info == nil || info.Type != "ALTERTABLE" {
+		return false
+	}
+	stmt, err := parser.New().ParseOneStmt(info.Sql, "", "")
+	if err != nil {
+		return false
+	}
+	alter, ok := stmt.(*ast.AlterTableStmt)
+	if !ok {
+		return false
+	}
+	for _, spec := range alter.Specs {
+		if spec.Tp == ast.AlterTableDropPartition || spec.Tp == ast.AlterTableTruncatePartition {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRollbackStatement 拆分由多条语句组成的回滚语句,如授权的回滚语句为先回收再授予原有权限.
+// 拆分后按原顺序执行,每条语句对应回滚计划中的一项
+func splitRollbackStatement(stmt string) []string {
+	segs, err := parser.Split(stmt)
+	if err != nil || len(segs) <= 1 {
+		return []string{stmt}
+	}
+	result := make([]string, len(segs))
+	for i, seg := range segs {
+		result[i] = seg.Text + ";"
+	}
+	return result
+}
+
+// auditRollback 审核回滚语句,并检查待回滚的数据是否已变更.
+// unverified为各语句是否无法检查行在执行后被修改
+func (s *session) auditRollback(ctx context.Context, sql string) (records []Record, unverified []bool, err error) {
+	s.init()
+	defer s.clear()
+
+	s.opt.Check = true
+	err = s.audit(ctx, sql)
+	if err != nil {
+		log.Error(err)
+		return s.makeNewResult(), nil, err
+	}
+
+	// 离线审核时无法查询数据,跳过冲突检查
+	if !s.isOffline() && s.recordSets != nil {
+		unverified = s.checkRollbackConflicts(ctx)
+	}
+	return s.makeNewResult(), unverified, nil
+}
+
+// checkRollbackConflicts 按回滚顺序检查每条DML回滚语句涉及的行.
+// delete/update应至少匹配一行,insert的主键应不存在.
+// 多条回滚语句涉及同一行时,仅检查第一条,后续语句依赖前面的回滚结果.
+// 返回各语句是否无法检查行在执行后被修改
+func (s *session) checkRollbackConflicts(ctx context.Context) []bool {
+	checked := make(map[string]bool)
+	unverified := make([]bool, len(s.recordSets.records))
+	for i, r := range s.recordSets.records {
+		if checkClose(ctx) != nil {
+			return unverified
+		}
+		if r.ErrLevel == 2 {
+			continue
+		}
+		switch r.Type.(type) {
+		case *ast.DeleteStmt, *ast.UpdateStmt, *ast.InsertStmt:
+		default:
+			continue
+		}
+
+		check, err := newRollbackCheck(r.Sql, s.primaryKeyColumns)
+		if err != nil {
+			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+			continue
+		}
+		if check == nil {
+			continue
+		}
+		unverified[i] = check.unverified
+		if check.key != "" {
+			if checked[check.key] {
+				continue
+			}
+			checked[check.key] = true
+		}
+
+		var count int
+		rows, err := s.raw(check.sql)
+		if err != nil {
+			log.Errorf("con:%d %v sql:%s", s.sessionVars.ConnectionID, err, check.sql)
+			r.appendErrorMessage(backupErrorMessage(err))
+			continue
+		}
+		for rows.Next() {
+			rows.Scan(&count)
+		}
+		rows.Close()
+
+		if check.insert && count > 0 {
+			r.appendErrorNo(s.inc.Lang, ErrRollbackRowExists)
+		} else if !check.insert && count == 0 {
+			r.appendErrorNo(s.inc.Lang, ErrRollbackRowNotFound)
+		}
+	}
+	return unverified
+}
+
+// primaryKeyColumns 返回表的主键列(无主键时为唯一键列),表不在缓存中时返回nil
+func (s *session) primaryKeyColumns(db string, table string) []string {
+	key := fmt.Sprintf("%s.%s", db, table)
+	if s.IgnoreCase() {
+		key = strings.ToLower(key)
+	}
+	t, ok := s.tableCacheList[key]
+	if !ok || t.IsDeleted {
+		return nil
+	}
+
+	configPrimaryKey(t)
+	if !t.hasPrimary {
+		return nil
+	}
+	var columns []string
+	for i, f := range t.Fields {
+		if t.primarys[i] && !f.IsDeleted {
+			columns = append(columns, f.Field)
+		}
+	}
+	return columns
+}
+
+// rollbackCheck 回滚语句的冲突检查
+type rollbackCheck struct {
+	// 查询回滚语句匹配行数的语句
+	sql string
+	// 涉及的行,由表名和主键值组成. 无法确定时为空
+	key string
+	// 是否为insert语句. insert时行应不存在,否则行应存在
+	insert bool
+	// update语句的where条件仅包含主键,无法发现行在执行后被修改
+	unverified bool
+}
+
+// newRollbackCheck 根据回滚语句生成冲突检查. 不支持的语句返回nil
+func newRollbackCheck(sql string, primaryKeys func(db string, table string) []string) (*rollbackCheck, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		from   sqlparser.TableExprs
+		where  *sqlparser.Where
+		table  sqlparser.TableName
+		values map[string]sqlparser.Expr
+		check  = &rollbackCheck{}
+	)
+
+	switch node := stmt.(type) {
+	case *sqlparser.Delete:
+		from, where = node.TableExprs, node.Where
+	case *sqlparser.Update:
+		from, where = node.TableExprs, node.Where
+	case *sqlparser.Insert:
+		// 回滚生成的insert语句均为单行
+		rows, ok := node.Rows.(sqlparser.Values)
+		if !ok || len(rows) != 1 || len(rows[0]) != len(node.Columns) {
+			return nil, nil
+		}
+		table = node.Table
+		values = make(map[string]sqlparser.Expr, len(node.Columns))
+		for i, col := range node.Columns {
+			values[col.Lowered()] = rows[0][i]
+		}
+		from = sqlparser.TableExprs{&sqlparser.AliasedTableExpr{Expr: table}}
+		check.insert = true
+	default:
+		return nil, nil
+	}
+
+	if !check.insert {
+		if len(from) != 1 || where == nil {
+			return nil, nil
+		}
+		t, ok := from[0].(*sqlparser.AliasedTableExpr)
+		if !ok {
+			return nil, nil
+		}
+		if table, ok = t.Expr.(sqlparser.TableName); !ok {
+			return nil, nil
+		}
+		values = equalValues(where.Expr)
+	}
+
+	var (
+		keys []string
+		expr sqlparser.Expr
+	)
+	for _, col := range primaryKeys(table.Qualifier.String(), table.Name.String()) {
+		v, ok := values[strings.ToLower(col)]
+		if !ok {
+			keys = nil
+			break
+		}
+		keys = append(keys, fmt.Sprintf("%s=%s", col, sqlparser.String(v)))
+
+		cmp := &sqlparser.ComparisonExpr{
+			Operator: sqlparser.EqualStr,
+			Left:     &sqlparser.ColName{Name: sqlparser.NewColIdent(col)},
+			Right:    v,
+		}
+		if expr == nil {
+			expr = cmp
+		} else {
+			expr = &sqlparser.AndExpr{Left: expr, Right: cmp}
+		}
+	}
+	if len(keys) > 0 {
+		check.key = fmt.Sprintf("%s:%s", sqlparser.String(table), strings.Join(keys, ","))
+	}
+	if _, ok := stmt.(*sqlparser.Update); ok && len(keys) > 0 && len(values) == len(keys) {
+		check.unverified = true
+	}
+
+	if check.insert {
+		// 无主键时无法判断数据是否已存在
+		if len(keys) == 0 {
+			return nil, nil
+		}
+		where = sqlparser.NewWhere(sqlparser.WhereStr, expr)
+	}
+
+	sel := &sqlparser.Select{
+		SelectExprs: sqlparser.SelectExprs{new(sqlparser.StarExpr)},
+		From:        from,
+		Where:       where,
+	}
+	rw := &Rewrite{SQL: sqlparser.String(sel), Stmt: sel}
+	check.sql = rw.select2Count()
+	return check, nil
+}
+
+// equalValues 返回where条件中and连接的等值条件,key为小写列名
+func equalValues(expr sqlparser.Expr) map[string]sqlparser.Expr {
+	values := make(map[string]sqlparser.Expr)
+	var walk func(e sqlparser.Expr)
+	walk = func(e sqlparser.Expr) {
+		switch node := e.(type) {
+		case *sqlparser.AndExpr:
+			walk(node.Left)
+			walk(node.Right)
+		case *sqlparser.ParenExpr:
+			walk(node.Expr)
+		case *sqlparser.ComparisonExpr:
+			if node.Operator != sqlparser.EqualStr && node.Operator != sqlparser.NullSafeEqualStr {
+				return
+			}
+			if col, ok := node.Left.(*sqlparser.ColName); ok {
+				values[col.Name.Lowered()] = node.Right
+			}
+		}
+	}
+	walk(expr)
+	return values
+}
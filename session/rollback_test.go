@@ -0,0 +1,444 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/parser"
+	"github.com/hanchuanchuan/inception-core/sessionctx/variable"
+	"github.com/hanchuanchuan/inception-core/util/auth"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testRollbackSuite{})
+
+type testRollbackSuite struct {
+	inc config.Inc
+}
+
+func TestRollback(t *testing.T) {
+	TestingT(t)
+}
+
+const rollbackSnapshot = `create database test_inc;
+create table test_inc.t1(id int primary key, c1 varchar(32));`
+
+func (s *testRollbackSuite) SetUpSuite(c *C) {
+	s.inc = config.GetGlobalConfig().Inc
+
+	inc := &config.GetGlobalConfig().Inc
+	inc.Lang = "en-US"
+	inc.BackupStorage = BackupStorageFile
+	inc.BackupDir = c.MkDir()
+
+	sink, err := NewBackupSink(*inc)
+	c.Assert(err, IsNil)
+	db := "127_0_0_1_3306_test_inc"
+	c.Assert(sink.WriteRollback(db, "t1", []RollbackStatement{
+		{OPID: "1_1_00000001", Statement: "DELETE FROM `test_inc`.`t1` WHERE `id`=1;"},
+		{OPID: "1_1_00000001", Statement: "DELETE FROM `test_inc`.`t1` WHERE `id`=2;"},
+		{OPID: "1_1_00000002", Statement: "UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1;"},
+		{OPID: "1_1_00000003", Statement: "DELETE FROM `test_inc`.`t2` WHERE `id`=1;"},
+	}), IsNil)
+	c.Assert(sink.WriteInfo(db, []*BackupInfo{
+		{OPID: "1_1_00000001", DBName: "test_inc", TableName: "t1", Type: "INSERT",
+			Sql: "insert into t1 values(1,'a'),(2,'b')"},
+		{OPID: "1_1_00000002", DBName: "test_inc", TableName: "t1", Type: "UPDATE",
+			Sql: "update t1 set c1='c' where id=1"},
+		{OPID: "1_1_00000003", DBName: "test_inc", TableName: "t2", Type: "INSERT",
+			Sql: "insert into t2 values(1)"},
+	}), IsNil)
+	c.Assert(sink.Flush(), IsNil)
+}
+
+func (s *testRollbackSuite) TearDownSuite(c *C) {
+	config.GetGlobalConfig().Inc = s.inc
+}
+
+func (s *testRollbackSuite) newSession(c *C) Session {
+	se := NewInception()
+	c.Assert(se.LoadOptions(SourceOptions{
+		Host:     "127.0.0.1",
+		Port:     3306,
+		DB:       "test_inc",
+		Offline:  true,
+		Snapshot: rollbackSnapshot,
+	}), IsNil)
+	return se
+}
+
+// backupDDL 按执行时的方式保存DDL的回滚语句,record需要设置OPID,DDLRollback和TableInfo
+func (s *testRollbackSuite) backupDDL(c *C, record *Record) {
+	sink, err := NewBackupSink(config.GetGlobalConfig().Inc)
+	c.Assert(err, IsNil)
+
+	se := s.newSession(c).(*session)
+	se.backupSink = sink
+	se.mysqlExecuteBackupSqlForDDL(record)
+	c.Assert(record.StageStatus, Equals, StatusBackupOK)
+
+	c.Assert(sink.WriteInfo(record.BackupDBName, []*BackupInfo{
+		{OPID: record.OPID, DBName: record.TableInfo.Schema, TableName: record.TableInfo.Name, Type: "DDL"},
+	}), IsNil)
+	c.Assert(sink.Flush(), IsNil)
+}
+
+func (s *testRollbackSuite) TestDryRun(c *C) {
+	plan, err := s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000001", "1_1_00000002"},
+		DryRun: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(plan, HasLen, 3)
+
+	sqls := make([]string, len(plan))
+	for i, r := range plan {
+		sqls[i] = r.Sql
+		c.Assert(r.BackupDBName, Equals, "127_0_0_1_3306_test_inc")
+		c.Assert(r.Record.ErrLevel, Equals, uint8(0), Commentf("%s", r.Record.ErrorMessage))
+		c.Assert(r.Record.Sql, Equals, strings.TrimSuffix(r.Sql, ";"))
+		c.Assert(r.Conflict, IsFalse)
+	}
+	c.Assert(sqls, DeepEquals, []string{
+		"UPDATE `test_inc`.`t1` SET `id`=1, `c1`='a' WHERE `id`=1;",
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=2;",
+		"DELETE FROM `test_inc`.`t1` WHERE `id`=1;",
+	})
+	c.Assert(plan[0].OPID, Equals, "1_1_00000002")
+	c.Assert(plan[0].Type, Equals, "UPDATE")
+	c.Assert(plan[1].OriginalSql, Equals, "insert into t1 values(1,'a'),(2,'b')")
+
+	// 按执行结果回滚,未备份的语句跳过
+	plan, err = s.newSession(c).RollbackByTicket(context.Background(), []Record{
+		{OPID: "1_1_00000001", BackupDBName: "127_0_0_1_3306_test_inc"},
+		{OPID: "1_1_00000002"},
+	}, RollbackOptions{DryRun: true})
+	c.Assert(err, IsNil)
+	c.Assert(plan, HasLen, 2)
+	c.Assert(plan[0].Sql, Equals, "DELETE FROM `test_inc`.`t1` WHERE `id`=2;")
+}
+
+func (s *testRollbackSuite) TestProfileBackupDir(c *C) {
+	cnf := config.GetGlobalConfig()
+	defer func(profiles map[string]config.Profile) { cnf.Profiles = profiles }(cnf.Profiles)
+
+	// 配置集指定的备份目录
+	dir := c.MkDir()
+	cnf.Profiles = map[string]config.Profile{
+		"p1": {Inc: map[string]interface{}{"backup_dir": dir}},
+	}
+	inc := cnf.Inc
+	inc.BackupDir = dir
+	sink, err := NewBackupSink(inc)
+	c.Assert(err, IsNil)
+	db := "127_0_0_1_3306_test_inc"
+	c.Assert(sink.WriteRollback(db, "t1", []RollbackStatement{
+		{OPID: "1_1_00000030", Statement: "DELETE FROM `test_inc`.`t1` WHERE `id`=3;"},
+	}), IsNil)
+	c.Assert(sink.WriteInfo(db, []*BackupInfo{
+		{OPID: "1_1_00000030", DBName: "test_inc", TableName: "t1", Type: "INSERT",
+			Sql: "insert into t1 values(3,'c')"},
+	}), IsNil)
+	c.Assert(sink.Flush(), IsNil)
+
+	se := s.newSession(c).(*session)
+	se.opt.Profile = "p1"
+	plan, err := se.Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000030"},
+		DryRun: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(plan, HasLen, 1)
+	c.Assert(plan[0].Sql, Equals, "DELETE FROM `test_inc`.`t1` WHERE `id`=3;")
+}
+
+func (s *testRollbackSuite) TestDDLRollback(c *C) {
+	// DROP TABLE的回滚语句为SHOW CREATE TABLE的结果,不包含库名
+	s.backupDDL(c, &Record{
+		OPID: "1_1_00000010",
+		DDLRollback: "CREATE TABLE `t2` (\n" +
+			"  `id` int(11) NOT NULL,\n" +
+			"  PRIMARY KEY (`id`)\n" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+		TableInfo: &TableInfo{Schema: "test_inc", Name: "t2"},
+	})
+
+	plan, err := s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000010"},
+		DryRun: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(plan, HasLen, 1)
+	c.Assert(plan[0].DBName, Equals, "test_inc")
+	c.Assert(plan[0].Record.ErrLevel, Not(Equals), uint8(2), Commentf("%s", plan[0].Record.ErrorMessage))
+	c.Assert(plan[0].Record.Sql, Matches, "(?s)CREATE TABLE `t2`.*")
+}
+
+func (s *testRollbackSuite) TestRollbackFailed(c *C) {
+	se := s.newSession(c)
+
+	// 审核失败时不执行
+	plan, err := se.Rollback(context.Background(), RollbackOptions{
+		OPIDs:        []string{"1_1_00000003"},
+		BackupDBName: "127_0_0_1_3306_test_inc",
+	})
+	c.Assert(err, Equals, ErrRollbackAuditFailed)
+	c.Assert(plan, HasLen, 1)
+	c.Assert(plan[0].Record.ErrLevel, Equals, uint8(2))
+
+	// 离线模式不支持执行
+	_, err = se.Rollback(context.Background(), RollbackOptions{OPIDs: []string{"1_1_00000001"}})
+	c.Assert(err, NotNil)
+
+	_, err = se.Rollback(context.Background(), RollbackOptions{OPIDs: []string{"1_1_00000009"}})
+	c.Assert(err, ErrorMatches, "opid 1_1_00000009: backup not found")
+
+	_, err = se.RollbackByTicket(context.Background(), []Record{{OPID: "1_1_00000001"}}, RollbackOptions{})
+	c.Assert(err, NotNil)
+
+	_, err = NewInception().Rollback(context.Background(), RollbackOptions{OPIDs: []string{"1_1_00000001"}})
+	c.Assert(err, NotNil)
+}
+
+func (s *testRollbackSuite) TestRollbackCheck(c *C) {
+	keys := func(db string, table string) []string {
+		if table == "t1" {
+			return []string{"id"}
+		}
+		if table == "t2" {
+			return []string{"a", "b"}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		sql        string
+		check      string
+		key        string
+		insert     bool
+		unverified bool
+	}{
+		{"DELETE FROM `test_inc`.`t1` WHERE `id`=1;",
+			"select count(*) from test_inc.t1 where id = 1", "test_inc.t1:id=1", false, false},
+		{"UPDATE `test_inc`.`t1` SET `id`=2, `c1`='a' WHERE `id`=1 AND `c1`='b';",
+			"select count(*) from test_inc.t1 where id = 1 and c1 = 'b'", "test_inc.t1:id=1", false, false},
+		// 未开启enable_rollback_guard时,where条件仅包含主键
+		{"UPDATE `test_inc`.`t1` SET `id`=2, `c1`='a' WHERE `id`=1;",
+			"select count(*) from test_inc.t1 where id = 1", "test_inc.t1:id=1", false, true},
+		{"INSERT INTO `test_inc`.`t1`(`id`,`c1`) VALUES(1,'a');",
+			"select count(*) from test_inc.t1 where id = 1", "test_inc.t1:id=1", true, false},
+		{"INSERT INTO `test_inc`.`t2`(`a`,`b`,`c`) VALUES(1,'x',NULL);",
+			"select count(*) from test_inc.t2 where a = 1 and b = 'x'", "test_inc.t2:a=1,b='x'", true, false},
+		// 无主键时仅检查行是否存在
+		{"DELETE FROM `test_inc`.`t3` WHERE `a`=1 AND `b` IS NULL;",
+			"select count(*) from test_inc.t3 where a = 1 and b is null", "", false, false},
+	}
+
+	for _, t := range tests {
+		check, err := newRollbackCheck(t.sql, keys)
+		c.Assert(err, IsNil)
+		c.Assert(check, NotNil, Commentf("%s", t.sql))
+		c.Assert(check.sql, Equals, t.check)
+		c.Assert(check.key, Equals, t.key)
+		c.Assert(check.insert, Equals, t.insert)
+		c.Assert(check.unverified, Equals, t.unverified, Commentf("%s", t.sql))
+	}
+
+	// 无主键的insert无法检查
+	check, err := newRollbackCheck("INSERT INTO `test_inc`.`t3`(`a`) VALUES(1);", keys)
+	c.Assert(err, IsNil)
+	c.Assert(check, IsNil)
+	check, err = newRollbackCheck("ALTER TABLE `test_inc`.`t1` DROP COLUMN `c1`;", keys)
+	c.Assert(err, IsNil)
+	c.Assert(check, IsNil)
+}
+
+func (s *testRollbackSuite) TestSummarize(c *C) {
+	summary := SummarizeRollback([]RollbackRecord{
+		{OPID: "1_1_00000002", BackupDBName: "db", Conflict: true},
+		{OPID: "1_1_00000001", BackupDBName: "db"},
+		{OPID: "1_1_00000001", BackupDBName: "db", Conflict: true},
+		{OPID: "1_1_00000001", BackupDBName: "db", Unverified: true},
+	})
+	c.Assert(summary, DeepEquals, []RollbackSummary{
+		{OPID: "1_1_00000002", BackupDBName: "db", Statements: 1, Changed: 1},
+		{OPID: "1_1_00000001", BackupDBName: "db", Statements: 3, Changed: 1, Unverified: 1},
+	})
+	c.Assert(SummarizeRollback(nil), HasLen, 0)
+
+	for tp, ok := range map[string]bool{
+		"int(11)":                true,
+		"varchar(32)":            true,
+		"datetime":               true,
+		"float(10,2) unsigned":   false,
+		"double":                 false,
+		"json":                   false,
+		"decimal(10,2) unsigned": true,
+	} {
+		c.Assert(rollbackGuardable(&FieldInfo{Type: tp}), Equals, ok, Commentf("%s", tp))
+	}
+}
+
+func (s *testRollbackSuite) TestPartitionRollback(c *C) {
+	sink, err := NewBackupSink(config.GetGlobalConfig().Inc)
+	c.Assert(err, IsNil)
+	db := "127_0_0_1_3306_test_inc"
+	c.Assert(sink.WriteRollback(db, "t1", []RollbackStatement{
+		{OPID: "1_1_00000020", Statement: "ALTER TABLE `test_inc`.`t1` ADD PARTITION (PARTITION `p1` VALUES LESS THAN (20));"},
+	}), IsNil)
+	c.Assert(sink.WriteInfo(db, []*BackupInfo{
+		{OPID: "1_1_00000020", DBName: "test_inc", TableName: "t1", Type: "ALTERTABLE",
+			Sql: "alter table t1 drop partition p1"},
+		{OPID: "1_1_00000021", DBName: "test_inc", TableName: "t1", Type: "ALTERTABLE",
+			Sql: "alter table t1 truncate partition p0"},
+	}), IsNil)
+	c.Assert(sink.Flush(), IsNil)
+
+	// 删除分区的回滚语句仅恢复分区定义
+	plan, err := s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000020"},
+		DryRun: true,
+	})
+	c.Assert(err, Equals, ErrRollbackIncomplete)
+	c.Assert(plan, HasLen, 1)
+	c.Assert(plan[0].Partial, IsTrue)
+	c.Assert(SummarizeRollback(plan)[0].Partial, IsTrue)
+
+	// 清空分区没有回滚语句
+	plan, err = s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000021"},
+		DryRun: true,
+	})
+	c.Assert(err, Equals, ErrRollbackIncomplete)
+	c.Assert(plan, HasLen, 0)
+
+	c.Assert(isPartialRollback(&BackupInfo{Type: "ALTERTABLE", Sql: "alter table t1 add column c2 int"}), IsFalse)
+	c.Assert(isPartialRollback(&BackupInfo{Type: "DELETE", Sql: "delete from t1 partition (p0)"}), IsFalse)
+}
+
+func (s *testRollbackSuite) TestDropPartitionRollback(c *C) {
+	se := NewInception().(*session)
+	c.Assert(se.LoadOptions(SourceOptions{
+		DB:      "test_inc",
+		Offline: true,
+		Check:   true,
+		Snapshot: `create database test_inc;
+create table test_inc.t_log(id int, created date, primary key(id, created))
+partition by range(to_days(created)) (
+	partition p2019 values less than (to_days('2020-01-01')),
+	partition p2020 values less than (to_days('2021-01-01')),
+	partition pmax values less than maxvalue);`,
+	}), IsNil)
+	se.init()
+	defer se.clear()
+	c.Assert(se.checkOptions(), IsNil)
+	se.dbName = "test_inc"
+	se.opt.Check, se.opt.Execute, se.opt.Backup = false, true, true
+	se.recordSets = NewRecordSets()
+
+	// 每个分区操作单独生成一条回滚语句
+	sql := "alter table t_log drop partition p2019, pmax"
+	stmt, err := parser.New().ParseOneStmt(sql, "", "")
+	c.Assert(err, IsNil)
+	se.myRecord = &Record{Sql: sql, Buf: new(bytes.Buffer), Type: stmt}
+	se.checkAlterTable(stmt.(*ast.AlterTableStmt), sql)
+	c.Assert(se.myRecord.DDLRollback, Equals,
+		"ALTER TABLE `test_inc`.`t_log` ADD PARTITION (PARTITION `pmax` VALUES LESS THAN (MAXVALUE));\n"+
+			"ALTER TABLE `test_inc`.`t_log` REORGANIZE PARTITION `p2020` INTO ("+
+			"PARTITION `p2019` VALUES LESS THAN (737790), PARTITION `p2020` VALUES LESS THAN (738156));")
+}
+
+func (s *testRollbackSuite) TestDropRoutineRollback(c *C) {
+	inc := &config.GetGlobalConfig().Inc
+	defer func(v bool) { inc.EnableCreateRoutine = v }(inc.EnableCreateRoutine)
+	inc.EnableCreateRoutine = true
+
+	// SHOW CREATE PROCEDURE的结果,过程体中包含多条以分号结尾的语句
+	body := "CREATE PROCEDURE `sp_p1`(in a int, out b int)\n" +
+		"begin\n" +
+		"\tdeclare c int default 0;\n" +
+		"\twhile c < a do\n" +
+		"\t\tset c = c + 1;\n" +
+		"\tend while;\n" +
+		"\tset b = c;\n" +
+		"end;"
+	s.backupDDL(c, &Record{
+		OPID:        "1_1_00000011",
+		DDLRollback: body,
+		TableInfo:   &TableInfo{Schema: "test_inc", Name: "sp_p1"},
+	})
+
+	plan, err := s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:  []string{"1_1_00000011"},
+		DryRun: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(plan, HasLen, 1)
+	c.Assert(plan[0].Sql, Equals, body)
+	c.Assert(plan[0].Record.ErrLevel, Not(Equals), uint8(2), Commentf("%s", plan[0].Record.ErrorMessage))
+	c.Assert(plan[0].Record.Sql, Matches, "(?s)CREATE PROCEDURE `sp_p1`.*set b = c;\nend")
+}
+
+func (s *testRollbackSuite) TestRevokeRollback(c *C) {
+	se := &session{sessionVars: variable.NewSessionVars()}
+	p := parser.New()
+
+	cases := []struct {
+		sql    string
+		expect string
+	}{
+		{"grant select, insert on test.* to u1", "REVOKE SELECT, INSERT ON `test`.* FROM"},
+		{"grant select (c1) on test.t1 to u1 with grant option", "REVOKE SELECT (`c1`), GRANT OPTION ON `test`.`t1` FROM"},
+		{"grant all on table *.* to u1", "REVOKE ALL ON TABLE *.* FROM"},
+		{"grant reload on *.* to u1", ""},
+	}
+	for _, t := range cases {
+		stmt, err := p.ParseOneStmt(t.sql, "", "")
+		c.Assert(err, IsNil)
+		c.Assert(se.buildRevokeRollback(stmt.(*ast.GrantStmt)), Equals, t.expect, Commentf("%s", t.sql))
+	}
+
+	c.Assert(quoteUser(&auth.UserIdentity{Username: "a`b", Hostname: "%"}), Equals, "`a``b`@`%`")
+}
+
+func (s *testRollbackSuite) TestGrantRollback(c *C) {
+	// GRANT的回滚语句: 先回收本次授予的权限,再授予原有权限
+	se := s.newSession(c).(*session)
+	se.opt.Execute = true
+	se.myRecord = &Record{DBName: "mysql", TableName: "user"}
+	se.setAccountRollback([]string{
+		"REVOKE SELECT, INSERT ON `test_inc`.* FROM `u1`@`127.0.0.1`;",
+		"GRANT USAGE ON *.* TO `u1`@`127.0.0.1`;",
+		"GRANT SELECT ON `test_inc`.* TO `u1`@`127.0.0.1`;",
+	})
+	record := se.myRecord
+	record.OPID = "1_1_00000012"
+	s.backupDDL(c, record)
+	c.Assert(record.BackupDBName, Equals, "127_0_0_1_3306_mysql")
+
+	sink := newFileBackupSink(config.GetGlobalConfig().Inc.BackupDir)
+	_, statements, err := sink.Rollback(record.BackupDBName, record.OPID)
+	c.Assert(err, IsNil)
+	c.Assert(statements, DeepEquals, []string{record.DDLRollback})
+
+	plan, err := s.newSession(c).Rollback(context.Background(), RollbackOptions{
+		OPIDs:        []string{record.OPID},
+		BackupDBName: record.BackupDBName,
+		DryRun:       true,
+	})
+	c.Assert(err, IsNil)
+	sqls := make([]string, len(plan))
+	for i, r := range plan {
+		sqls[i] = r.Sql
+		c.Assert(r.Record.Sql, Equals, strings.TrimSuffix(r.Sql, ";"))
+	}
+	c.Assert(sqls, DeepEquals, []string{
+		"REVOKE SELECT, INSERT ON `test_inc`.* FROM `u1`@`127.0.0.1`;",
+		"GRANT USAGE ON *.* TO `u1`@`127.0.0.1`;",
+		"GRANT SELECT ON `test_inc`.* TO `u1`@`127.0.0.1`;",
+	})
+}
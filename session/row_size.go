@@ -0,0 +1,338 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+)
+
+const (
+	// 最大行大小(不含BLOB/TEXT),所有存储引擎均受此限制
+	maxRowSize = 65535
+	// innodb_page_size为16K时,单行的最大长度(不含溢出页)
+	maxInnodbRowSize = 8126
+
+	// DYNAMIC/COMPRESSED行格式下,大字段在行内仅保留20字节指针(按40字节估算)
+	innodbDynamicInlineSize = 40
+	// COMPACT/REDUNDANT行格式下,大字段在行内保留768字节前缀和20字节指针
+	innodbCompactInlineSize = 788
+)
+
+// columnStorage 列的存储信息
+type columnStorage struct {
+	// server层行格式下占用的字节数,BLOB/TEXT仅计算长度和指针部分
+	rowBytes int
+	// 数据的最大字节数(不含长度字节)
+	maxBytes int
+	// 是否为变长列
+	variable bool
+	// 是否为BLOB/TEXT等大字段
+	blob bool
+}
+
+// tableRowFormat 返回表的行格式,未指定时按数据库版本返回默认行格式
+func (s *session) tableRowFormat(t *TableInfo) string {
+	format := strings.ToUpper(t.RowFormat)
+	if format == "" || format == "DEFAULT" {
+		// 5.7.9开始innodb_default_row_format默认为DYNAMIC
+		if s.dbVersion >= 50709 {
+			return "DYNAMIC"
+		}
+		return "COMPACT"
+	}
+	return format
+}
+
+// rowFormatName 返回ROW_FORMAT选项对应的行格式名称
+func rowFormatName(format uint64) string {
+	switch format {
+	case ast.RowFormatDynamic:
+		return "DYNAMIC"
+	case ast.RowFormatFixed:
+		return "FIXED"
+	case ast.RowFormatCompressed:
+		return "COMPRESSED"
+	case ast.RowFormatRedundant:
+		return "REDUNDANT"
+	case ast.RowFormatCompact:
+		return "COMPACT"
+	case ast.RowFormatDefault:
+		return "DEFAULT"
+	default:
+		// TokuDB等其他引擎的行格式
+		return "OTHER"
+	}
+}
+
+// bytesPerChar 返回列字符集的单字符最大字节数
+func (s *session) bytesPerChar(t *TableInfo, f *FieldInfo) int {
+	collation := f.Collation
+	if collation == "" {
+		collation = t.Collation
+	}
+	cs := s.inc.DefaultCharset
+	if collation != "" {
+		cs = strings.SplitN(collation, "_", 2)[0]
+	}
+	if n, ok := charSets[strings.ToLower(cs)]; ok {
+		return n
+	}
+	return 1
+}
+
+// lengthBytes 变长列的长度字节数
+func lengthBytes(maxBytes int) int {
+	if maxBytes < 256 {
+		return 1
+	}
+	return 2
+}
+
+// getColumnStorage 计算列的存储信息
+// https://dev.mysql.com/doc/refman/8.0/en/storage-requirements.html
+func (s *session) getColumnStorage(t *TableInfo, f *FieldInfo) columnStorage {
+	length := GetDataTypeLength(f.Type)[0]
+	if length < 0 {
+		length = 0
+	}
+
+	switch strings.ToLower(GetDataTypeBase(f.Type)) {
+	case "char":
+		mb := s.bytesPerChar(t, f)
+		n := length * mb
+		if length == 0 {
+			n = mb
+		}
+		// 多字节字符集的char在InnoDB中按变长存储
+		return columnStorage{rowBytes: n, maxBytes: n, variable: mb > 1}
+	case "binary":
+		if length == 0 {
+			length = 1
+		}
+		return columnStorage{rowBytes: length, maxBytes: length}
+	case "varchar":
+		n := length * s.bytesPerChar(t, f)
+		return columnStorage{rowBytes: n + lengthBytes(n), maxBytes: n, variable: true}
+	case "varbinary":
+		return columnStorage{rowBytes: length + lengthBytes(length), maxBytes: length, variable: true}
+	case "tinyblob", "tinytext":
+		return columnStorage{rowBytes: 9, maxBytes: 1<<8 - 1, variable: true, blob: true}
+	case "blob", "text":
+		return columnStorage{rowBytes: 10, maxBytes: 1<<16 - 1, variable: true, blob: true}
+	case "mediumblob", "mediumtext":
+		return columnStorage{rowBytes: 11, maxBytes: 1<<24 - 1, variable: true, blob: true}
+	case "longblob", "longtext", "json",
+		"geometry", "point", "linestring", "polygon",
+		"multipoint", "multilinestring", "multipolygon", "geometrycollection":
+		return columnStorage{rowBytes: 12, maxBytes: 1<<32 - 1, variable: true, blob: true}
+	case "enum":
+		if length > 255 {
+			return columnStorage{rowBytes: 2, maxBytes: 2}
+		}
+		return columnStorage{rowBytes: 1, maxBytes: 1}
+	case "set":
+		n := (length + 7) / 8
+		if n > 4 {
+			n = 8
+		}
+		return columnStorage{rowBytes: n, maxBytes: n}
+	default:
+		n := f.getDataBytes(s.dbVersion, s.inc.DefaultCharset)
+		if n < 0 {
+			n = 0
+		}
+		return columnStorage{rowBytes: n, maxBytes: n}
+	}
+}
+
+// getRowSize 计算表的server层行大小(不含BLOB/TEXT数据)
+func (s *session) getRowSize(t *TableInfo) int {
+	size := 0
+	nullable := 0
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.IsDeleted {
+			continue
+		}
+		size += s.getColumnStorage(t, f).rowBytes
+		if f.Null != "NO" && f.Key != "PRI" {
+			nullable++
+		}
+	}
+	return size + (nullable+7)/8
+}
+
+// getInnodbRowSize 计算表在InnoDB中的行内最大长度.
+// 大字段在行内仅保留前缀或指针,因此不同行格式的结果不同
+func (s *session) getInnodbRowSize(t *TableInfo, rowFormat string) int {
+	inline := innodbCompactInlineSize
+	if rowFormat == "DYNAMIC" || rowFormat == "COMPRESSED" {
+		inline = innodbDynamicInlineSize
+	}
+
+	// 记录头
+	size := 5
+	if rowFormat == "REDUNDANT" {
+		size = 6
+	}
+	// 隐藏列DB_TRX_ID和DB_ROLL_PTR
+	size += 13
+
+	hasPrimary := false
+	nullable := 0
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.IsDeleted {
+			continue
+		}
+		if f.Key == "PRI" {
+			hasPrimary = true
+		} else if f.Null != "NO" {
+			nullable++
+		}
+
+		st := s.getColumnStorage(t, f)
+		if !st.variable {
+			size += st.maxBytes
+		} else if st.blob || st.maxBytes > 255 {
+			if st.maxBytes > inline {
+				size += inline
+			} else {
+				size += st.maxBytes
+			}
+			size += 2
+		} else {
+			size += st.maxBytes + 1
+		}
+	}
+	// 无主键时的隐藏列DB_ROW_ID
+	if !hasPrimary {
+		size += 6
+	}
+	return size + (nullable+7)/8
+}
+
+// getIndexKeyLength 计算索引列的长度,返回各列长度
+func (s *session) getIndexKeyLength(t *TableInfo, d *indexDef) []int {
+	lengths := make([]int, 0, len(d.columns))
+	for _, col := range d.columns {
+		var f *FieldInfo
+		for i := range t.Fields {
+			if !t.Fields[i].IsDeleted && strings.EqualFold(t.Fields[i].Field, col.name) {
+				f = &t.Fields[i]
+				break
+			}
+		}
+		if f == nil {
+			lengths = append(lengths, 0)
+			continue
+		}
+
+		n := 0
+		switch strings.ToLower(GetDataTypeBase(f.Type)) {
+		case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+			chars := GetDataTypeLength(f.Type)[0]
+			if col.subPart > 0 {
+				chars = col.subPart
+			}
+			n = chars * s.bytesPerChar(t, f)
+		case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+			n = GetDataTypeLength(f.Type)[0]
+			if col.subPart > 0 {
+				n = col.subPart
+			}
+		default:
+			n = s.getColumnStorage(t, f).maxBytes
+		}
+		if n < 0 {
+			n = 0
+		}
+		lengths = append(lengths, n)
+	}
+	return lengths
+}
+
+// isIndexColumnsChanged 索引列的类型或排序规则是否有变化
+func isIndexColumnsChanged(t, origin *TableInfo, d *indexDef) bool {
+	for _, col := range d.columns {
+		var before, after *FieldInfo
+		for i := range origin.Fields {
+			if !origin.Fields[i].IsDeleted && strings.EqualFold(origin.Fields[i].Field, col.name) {
+				before = &origin.Fields[i]
+				break
+			}
+		}
+		for i := range t.Fields {
+			if !t.Fields[i].IsDeleted && strings.EqualFold(t.Fields[i].Field, col.name) {
+				after = &t.Fields[i]
+				break
+			}
+		}
+		if before == nil || after == nil {
+			continue
+		}
+		if !strings.EqualFold(before.Type, after.Type) ||
+			!strings.EqualFold(before.Collation, after.Collation) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTableLimits 审核表的行大小和索引长度.
+// origin为alter前的表结构,为nil时表示新建表.
+// alter时仅在行大小增长时提示,并重新计算列类型或字符集有变化的已有索引
+func (s *session) checkTableLimits(t *TableInfo, origin *TableInfo) {
+	if t == nil || t.IsView || s.dbType == DBTypeTiDB {
+		return
+	}
+
+	rowSize := s.getRowSize(t)
+	if rowSize > maxRowSize &&
+		(origin == nil || rowSize > s.getRowSize(origin)) {
+		s.appendErrorNo(ErrRowSizeTooLarge, t.Name, rowSize, maxRowSize)
+	}
+
+	rowFormat := s.tableRowFormat(t)
+	switch rowFormat {
+	case "DYNAMIC", "COMPRESSED", "COMPACT", "REDUNDANT":
+		size := s.getInnodbRowSize(t, rowFormat)
+		if size > maxInnodbRowSize &&
+			(origin == nil || size > s.getInnodbRowSize(origin, s.tableRowFormat(origin))) {
+			s.appendErrorNo(ErrInnodbRowSizeTooLarge, t.Name, size, rowFormat, maxInnodbRowSize)
+		}
+	}
+
+	if origin == nil {
+		return
+	}
+
+	// 未开启innodb_large_prefix或行格式不支持时,单列长度不能超过767
+	columnLimit := maxKeyLength
+	if s.innodbLargePrefix && (rowFormat == "DYNAMIC" || rowFormat == "COMPRESSED") {
+		columnLimit = maxKeyLength57
+	}
+
+	// 本次新增的索引已在添加时校验
+	existing := indexNameSet(origin)
+	for _, d := range buildIndexDefs(t.Indexes) {
+		if !existing[strings.ToLower(d.name)] || d.functional || d.tp != "BTREE" ||
+			!isIndexColumnsChanged(t, origin, d) {
+			continue
+		}
+
+		total := 0
+		tooLong := false
+		for _, n := range s.getIndexKeyLength(t, d) {
+			if n > columnLimit {
+				s.appendErrorNo(ErrIndexKeyTooLong, d.name, t.Name, n, columnLimit)
+				tooLong = true
+				break
+			}
+			total += n
+		}
+		if !tooLong && total > maxKeyLength57 {
+			s.appendErrorNo(ErrIndexKeyTooLong, d.name, t.Name, total, maxKeyLength57)
+		}
+	}
+}
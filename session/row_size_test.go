@@ -0,0 +1,99 @@
+package session_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestTableLimits(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key, name varchar(255) not null,
+		key idx_name(name)) default charset utf8 row_format=compact;
+	create table t2(id int primary key, name varchar(255) not null,
+		key idx_name(name)) default charset utf8;`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnableSetCharset = true
+	cnf.Inc.EnableSetCollation = true
+	cnf.Inc.CheckColumnComment = false
+	cnf.Inc.CheckTableComment = false
+	cnf.Inc.EnableNullable = true
+
+	var cols []string
+	for i := 0; i < 12; i++ {
+		cols = append(cols, fmt.Sprintf("c%d varchar(255) not null", i))
+	}
+	wide := strings.Join(cols, ",")
+
+	result := s.audit(c, snapshot, fmt.Sprintf(`use test_inc;
+	create table t3(id int primary key, c1 varchar(65533)) default charset latin1;
+	create table t4(id int primary key, %s) default charset utf8 row_format=compact;
+	create table t5(id int primary key, %s) default charset utf8;
+	alter table t1 convert to character set utf8mb4;
+	alter table t2 convert to character set utf8mb4;`, wide, wide))
+	c.Assert(len(result), Equals, 6)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(strings.Contains(result[1].ErrorMessage,
+		"Row size of table 't3' is 65540 bytes, exceeds the maximum row size of 65535 bytes"), IsTrue,
+		Commentf("%v", result[1].ErrorMessage))
+
+	c.Assert(result[2].ErrLevel, Equals, uint8(1), Commentf("%v", result[2].ErrorMessage))
+	c.Assert(result[2].ErrorMessage, Equals,
+		"Row size of table 't4' is 9226 bytes in row format COMPACT, exceeds the InnoDB inline limit of 8126 bytes.")
+
+	// DYNAMIC行格式时大字段仅在行内保留指针
+	c.Assert(result[3].ErrLevel, Equals, uint8(0), Commentf("%v", result[3].ErrorMessage))
+
+	// COMPACT行格式时单列索引长度不能超过767
+	c.Assert(result[4].ErrLevel, Equals, uint8(2))
+	c.Assert(result[4].ErrorMessage, Equals,
+		"Key length of index 'idx_name' on table 't1' is 1020 bytes, exceeds the maximum of 767 bytes.")
+	c.Assert(result[4].Findings[0].Index, Equals, "idx_name")
+
+	c.Assert(result[5].ErrLevel, Equals, uint8(0), Commentf("%v", result[5].ErrorMessage))
+}
+
+func (s *testOfflineSuite) TestColumnCharsetKeyLength(c *C) {
+	snapshot := `{
+		"Version": "5.6.40",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t1",
+			"Collation": "utf8_general_ci",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "b", "Type": "varchar(255)", "Collation": "utf8_general_ci", "Null": "NO", "Key": "MUL"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"},
+				{"IndexName": "idx_b", "Seq": 1, "ColumnName": "b", "IndexType": "BTREE"}
+			]
+		}]
+	}`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnableSetCharset = true
+	cnf.Inc.EnableSetCollation = true
+	cnf.Inc.EnableColumnCharset = true
+	cnf.Inc.CheckColumnComment = false
+	cnf.Inc.EnableNullable = true
+
+	// 仅指定列级字符集时,按其默认排序规则计算索引长度
+	result := s.audit(c, snapshot, `use test_inc;
+	alter table t1 modify b varchar(255) character set utf8mb4 not null;`)
+	c.Assert(len(result), Equals, 2)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(strings.Contains(result[1].ErrorMessage,
+		"Key length of index 'idx_b' on table 't1' is 1020 bytes, exceeds the maximum of 767 bytes."), IsTrue,
+		Commentf("%v", result[1].ErrorMessage))
+}
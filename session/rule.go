@@ -0,0 +1,88 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rule 自定义审核规则.
+// 通过Session.RegisterRule注册,每条语句完成内置审核后调用,
+// 审核结果和内置规则一样写入Record.ErrorMessage和ErrLevel
+type Rule interface {
+	// Name 规则名称
+	Name() string
+	// Check 审核语句. table为语句操作的表结构,未解析到表时为nil
+	Check(stmt ast.StmtNode, table *TableInfo) []Finding
+}
+
+// RegisterRule 注册自定义审核规则,需要在Audit/RunExecute前调用
+func (s *session) RegisterRule(rules ...Rule) {
+	for _, rule := range rules {
+		if rule != nil {
+			s.rules = append(s.rules, rule)
+		}
+	}
+}
+
+// checkCustomRules 执行自定义审核规则
+func (s *session) checkCustomRules(stmtNode ast.StmtNode) {
+	for _, rule := range s.rules {
+		for _, f := range s.runRule(rule, stmtNode) {
+			s.appendFinding(f)
+		}
+	}
+}
+
+// runRule 执行单个规则,规则异常时作为审核错误返回
+func (s *session) runRule(rule Rule, stmtNode ast.StmtNode) (findings []Finding) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("con:%d rule %s panic: %v", s.sessionVars.ConnectionID, rule.Name(), r)
+			findings = []Finding{{
+				Level:   2,
+				Message: fmt.Sprintf("审核规则'%s'执行失败: %v", rule.Name(), r),
+			}}
+		}
+	}()
+	return rule.Check(stmtNode, s.myRecord.TableInfo)
+}
+
+// appendFinding 添加自定义规则的审核结果
+func (s *session) appendFinding(f Finding) {
+	r := s.myRecord
+
+	level := f.Level
+	if level == 0 {
+		if v, ok := s.incLevel[f.Code.String()]; ok {
+			level = v
+		} else {
+			level = GetErrorLevel(f.Code)
+		}
+	}
+	if level == 0 {
+		return
+	} else if level > 2 {
+		level = 2
+	}
+
+	if f.Message == "" {
+		f.Message = s.getErrorMessage(f.Code)
+	}
+	if f.Name == "" {
+		f.Name = f.Code.String()
+	}
+	f.Level = level
+
+	r.ErrLevel = uint8(Max(int(r.ErrLevel), int(level)))
+	s.recordSets.MaxLevel = uint8(Max(int(s.recordSets.MaxLevel), int(r.ErrLevel)))
+	if s.stage == StageBackup {
+		r.Buf.WriteString("Backup: ")
+	} else if s.stage == StageExec {
+		r.Buf.WriteString("Execute: ")
+	}
+	r.Buf.WriteString(f.Message)
+	r.Buf.WriteString("\n")
+	r.Findings = append(r.Findings, f)
+}
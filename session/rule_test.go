@@ -0,0 +1,108 @@
+package session_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testRuleSuite{})
+
+type testRuleSuite struct{}
+
+func TestRule(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *testRuleSuite) SetUpSuite(c *C) {
+	inc := &config.GetGlobalConfig().Inc
+	inc.Lang = "en-US"
+}
+
+// tableNameRule 表名必须以t_开头
+type tableNameRule struct{}
+
+func (r *tableNameRule) Name() string {
+	return "table_name_prefix"
+}
+
+func (r *tableNameRule) Check(stmt ast.StmtNode, table *session.TableInfo) []session.Finding {
+	if _, ok := stmt.(*ast.CreateTableStmt); !ok || table == nil {
+		return nil
+	}
+	if !strings.HasPrefix(table.Name, "t_") {
+		return []session.Finding{{
+			Code:    session.ER_ERROR_LAST + 1,
+			Level:   1,
+			Message: fmt.Sprintf("table '%s' must start with 't_'.", table.Name),
+		}}
+	}
+	return nil
+}
+
+// deleteRule 禁止delete语句,使用内置错误码
+type deleteRule struct{}
+
+func (r *deleteRule) Name() string {
+	return "forbid_delete"
+}
+
+func (r *deleteRule) Check(stmt ast.StmtNode, table *session.TableInfo) []session.Finding {
+	if _, ok := stmt.(*ast.DeleteStmt); ok {
+		return []session.Finding{{Code: session.ER_NOT_SUPPORTED_YET}}
+	}
+	return nil
+}
+
+type panicRule struct{}
+
+func (r *panicRule) Name() string {
+	return "panic"
+}
+
+func (r *panicRule) Check(stmt ast.StmtNode, table *session.TableInfo) []session.Finding {
+	if _, ok := stmt.(*ast.UpdateStmt); ok {
+		panic("unexpected")
+	}
+	return nil
+}
+
+func (s *testRuleSuite) TestCustomRule(c *C) {
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		Offline:  true,
+		Snapshot: "create database test_inc;",
+	})
+	core.RegisterRule(&tableNameRule{}, &deleteRule{}, &panicRule{})
+
+	result, err := core.Audit(context.Background(), `use test_inc;
+	create table t_1(id int primary key comment 'id') comment 't_1';
+	create table tt1(id int primary key comment 'id') comment 'tt1';
+	delete from t_1 where id = 1;
+	update t_1 set id = 2 where id = 1;`)
+	c.Assert(err, IsNil)
+	c.Assert(len(result), Equals, 5)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+
+	c.Assert(result[2].ErrLevel, Equals, uint8(1))
+	c.Assert(result[2].ErrorMessage, Equals, "table 'tt1' must start with 't_'.")
+	c.Assert(result[2].Findings, HasLen, 1)
+	c.Assert(result[2].Findings[0].Code, Equals, session.ER_ERROR_LAST+1)
+	c.Assert(result[2].Findings[0].Level, Equals, uint8(1))
+
+	c.Assert(result[3].ErrLevel, Equals, uint8(2))
+	c.Assert(result[3].ErrorMessage, Equals,
+		session.GetErrorMessage(session.ER_NOT_SUPPORTED_YET, "en_us"))
+	c.Assert(result[3].Findings, HasLen, 1)
+	c.Assert(result[3].Findings[0].Name, Equals, session.ER_NOT_SUPPORTED_YET.String())
+
+	c.Assert(result[4].ErrLevel, Equals, uint8(2))
+	c.Assert(result[4].ErrorMessage, Matches, ".*panic.*")
+}
@@ -0,0 +1,519 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/format"
+	"github.com/pingcap/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// 整数类型的显示宽度不影响存储,对比时忽略
+var intDisplayWidthRegexp = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// CURRENT_TIMESTAMP及其同义函数,可指定小数秒精度
+var nowFuncRegexp = regexp.MustCompile(`^(?i)(current_timestamp|now|localtime|localtimestamp)(\(\s*(\d*)\s*\))?$`)
+
+// TableDiff 期望的表结构和现有表结构的差异
+type TableDiff struct {
+	Schema string
+	Table  string
+
+	// 生成的变更语句.表不存在时为建表语句,无差异时为空
+	SQL string
+	// 未对比的定义,如外键,需要手动变更
+	Warnings []string
+}
+
+// tableOptions 表选项,用以对比
+type tableOptions struct {
+	engine    string
+	collation string
+	comment   string
+	rowFormat string
+}
+
+// SchemaDiff 对比期望的建表语句和现有表结构,生成变更语句并审核.
+// 每张表最多生成一条ALTER TABLE语句,返回各表的差异和变更语句的审核结果.
+// 列改名无法识别,会按删除列和添加列处理.
+// 外键不做对比,期望的建表语句中的外键在TableDiff.Warnings中提示
+func (s *session) SchemaDiff(ctx context.Context, sql string) ([]TableDiff, []Record, error) {
+	if s.opt == nil {
+		return nil, nil, errors.New("未配置数据源信息!")
+	}
+
+	diffs, err := s.schemaDiff(sql)
+	if err != nil {
+		return diffs, nil, err
+	}
+
+	var buf bytes.Buffer
+	var dbName string
+	for _, d := range diffs {
+		if d.SQL == "" {
+			continue
+		}
+		if d.Schema != dbName {
+			dbName = d.Schema
+			fmt.Fprintf(&buf, "use `%s`;\n", dbName)
+		}
+		buf.WriteString(d.SQL)
+		buf.WriteString("\n")
+	}
+	if buf.Len() == 0 {
+		return diffs, nil, nil
+	}
+
+	records, err := s.Audit(ctx, buf.String())
+	return diffs, records, err
+}
+
+// schemaDiff 解析期望的建表语句,逐表生成变更语句
+func (s *session) schemaDiff(sql string) ([]TableDiff, error) {
+	s.init()
+	defer s.clear()
+	s.opt.Check = true
+
+	s.recordSets = NewRecordSets()
+	s.myRecord = &Record{Buf: new(bytes.Buffer)}
+	if err := s.checkOptions(); err != nil {
+		return nil, err
+	}
+
+	charsetInfo, collation := s.sessionVars.GetCharsetInfo()
+	s.parser.SetSQLMode(s.sessionVars.SQLMode)
+	stmtNodes, _, err := s.parser.Parse(sql, charsetInfo, collation)
+	if err != nil {
+		return nil, err
+	}
+
+	s.dbName = s.opt.DB
+	var diffs []TableDiff
+	found := make(map[string]bool)
+	for _, stmtNode := range stmtNodes {
+		switch node := stmtNode.(type) {
+		case *ast.UseStmt:
+			s.dbName = node.DBName
+		case *ast.CreateTableStmt:
+			if node.Table.Schema.O == "" {
+				node.Table.Schema.O = s.dbName
+				node.Table.Schema.L = strings.ToLower(s.dbName)
+			}
+			if node.Table.Schema.O == "" {
+				return diffs, fmt.Errorf("表'%s'未指定数据库", node.Table.Name.O)
+			}
+			if node.ReferTable != nil || node.Select != nil {
+				return diffs, fmt.Errorf("表'%s'不支持CREATE TABLE LIKE和CREATE TABLE SELECT",
+					node.Table.Name.O)
+			}
+
+			key := s.snapshotTableKey(node.Table.Schema.O, node.Table.Name.O)
+			if found[key] {
+				return diffs, fmt.Errorf("表'%s.%s'重复定义", node.Table.Schema.O, node.Table.Name.O)
+			}
+			found[key] = true
+
+			d, err := s.diffTable(node)
+			if err != nil {
+				return diffs, err
+			}
+			diffs = append(diffs, d)
+		default:
+			return diffs, fmt.Errorf("仅支持建表语句: %s", strings.TrimSpace(stmtNode.Text()))
+		}
+	}
+	return diffs, nil
+}
+
+// diffTable 对比单表,表不存在时返回建表语句
+func (s *session) diffTable(node *ast.CreateTableStmt) (TableDiff, error) {
+	d := TableDiff{
+		Schema: node.Table.Schema.O,
+		Table:  node.Table.Name.O,
+	}
+
+	s.myRecord = &Record{Buf: new(bytes.Buffer)}
+	var t *TableInfo
+	if s.checkDBExists(d.Schema, false) {
+		t = s.getTableFromCache(d.Schema, d.Table, false)
+	}
+	var origin *tableOptions
+	if t != nil {
+		origin = s.queryTableOptions(t)
+	}
+	if s.myRecord.ErrLevel == 2 {
+		msg := strings.TrimSpace(s.myRecord.Buf.String())
+		log.Errorf("con:%d %s", s.sessionVars.ConnectionID, msg)
+		return d, errors.New(msg)
+	}
+
+	if t == nil {
+		d.SQL = strings.TrimRight(strings.TrimSpace(node.Text()), ";") + ";"
+		return d, nil
+	}
+
+	// 期望的表结构仅用于对比,构建时的审核结果由之后的审核给出
+	desired, err := s.buildSnapshotTable(node)
+	if err != nil {
+		return d, err
+	}
+	s.myRecord = &Record{Buf: new(bytes.Buffer)}
+
+	for _, ct := range node.Constraints {
+		if ct.Tp == ast.ConstraintForeignKey {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("外键'%s'未对比,需要手动变更", ct.Name))
+		}
+	}
+
+	var specs []string
+	specs = append(specs, diffIndexes(t, &desired.TableInfo, true)...)
+	specs = append(specs, diffColumns(t, desired, node)...)
+	specs = append(specs, diffIndexes(t, &desired.TableInfo, false)...)
+	specs = append(specs, diffTableOptions(origin, desired, node)...)
+
+	if len(specs) > 0 {
+		d.SQL = fmt.Sprintf("ALTER TABLE `%s`.`%s` %s;", d.Schema, d.Table, strings.Join(specs, ", "))
+	}
+	return d, nil
+}
+
+// queryTableOptions 获取表的存储引擎,排序规则,注释和行格式
+func (s *session) queryTableOptions(t *TableInfo) *tableOptions {
+	if s.isOffline() {
+		opt := &tableOptions{}
+		if st := s.getSnapshotTable(t.Schema, t.Name); st != nil {
+			opt.engine = st.Engine
+			opt.collation = st.Collation
+			opt.comment = st.Comment
+			opt.rowFormat = st.RowFormat
+		}
+		return opt
+	}
+
+	sql := fmt.Sprintf(`select IFNULL(ENGINE,''),IFNULL(TABLE_COLLATION,''),
+		IFNULL(TABLE_COMMENT,''),IFNULL(ROW_FORMAT,'') from information_schema.tables
+		where table_schema='%s' and table_name='%s';`, t.Schema, t.Name)
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
+			s.appendErrorMessage(myErr.Message)
+		} else {
+			s.appendErrorMessage(err.Error())
+		}
+		return nil
+	}
+
+	opt := &tableOptions{}
+	for rows.Next() {
+		if err := rows.Scan(&opt.engine, &opt.collation, &opt.comment, &opt.rowFormat); err != nil {
+			s.appendErrorMessage(err.Error())
+			return nil
+		}
+	}
+	return opt
+}
+
+// normalizeColumnType 格式化列类型,忽略大小写和整数类型的显示宽度
+func normalizeColumnType(tp string) string {
+	return intDisplayWidthRegexp.ReplaceAllString(strings.ToLower(strings.TrimSpace(tp)), "$1")
+}
+
+// normalizeColumnDefault 格式化默认值,数值按值比较,CURRENT_TIMESTAMP的同义函数统一处理
+func normalizeColumnDefault(v *string) string {
+	if v == nil {
+		return "<nil>"
+	}
+	value := *v
+	if m := nowFuncRegexp.FindStringSubmatch(value); m != nil {
+		if m[3] == "" || m[3] == "0" {
+			return "CURRENT_TIMESTAMP"
+		}
+		return "CURRENT_TIMESTAMP(" + m[3] + ")"
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return value
+}
+
+// normalizeColumnExtra 格式化列的Extra信息,仅对比自增,ON UPDATE和可见性
+func normalizeColumnExtra(extra string) string {
+	extra = strings.ToLower(extra)
+	var flags []string
+	for _, flag := range []string{"auto_increment", "on update", "invisible"} {
+		if strings.Contains(extra, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// columnDefaultValue 返回列定义中的默认值,和SHOW FULL FIELDS的结果保持一致
+func columnDefaultValue(field *ast.ColumnDef) *string {
+	var value *string
+	for _, op := range field.Options {
+		if op.Tp != ast.ColumnOptionDefaultValue {
+			continue
+		}
+		switch v := op.Expr.(type) {
+		case *ast.FuncCallExpr:
+			value = new(string)
+			*value = v.FnName.O
+			// 小数秒精度,如CURRENT_TIMESTAMP(3)
+			if len(v.Args) > 0 {
+				*value += fmt.Sprintf("(%v)", v.Args[0].GetDatum().GetValue())
+			}
+		case *ast.ValueExpr:
+			if v.GetValue() == nil {
+				value = nil
+			} else if str, err := v.GetDatum().ToString(); err == nil {
+				value = &str
+			}
+		default:
+			var builder strings.Builder
+			op.Expr.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder))
+			str := builder.String()
+			value = &str
+		}
+	}
+	return value
+}
+
+// isColumnChanged 列定义是否有变化
+func isColumnChanged(origin, desired *FieldInfo) bool {
+	if normalizeColumnType(origin.Type) != normalizeColumnType(desired.Type) ||
+		!strings.EqualFold(origin.Null, desired.Null) ||
+		normalizeColumnDefault(origin.Default) != normalizeColumnDefault(desired.Default) ||
+		normalizeColumnExtra(origin.Extra) != normalizeColumnExtra(desired.Extra) ||
+		origin.Comment != desired.Comment {
+		return true
+	}
+	return desired.Collation != "" && origin.Collation != "" &&
+		!strings.EqualFold(origin.Collation, desired.Collation)
+}
+
+// restoreColumnDef 生成列定义,索引在之后单独添加
+func restoreColumnDef(field *ast.ColumnDef) string {
+	col := *field
+	col.Options = make([]*ast.ColumnOption, 0, len(field.Options))
+	primary, hasNull := false, false
+	for _, op := range field.Options {
+		switch op.Tp {
+		case ast.ColumnOptionPrimaryKey:
+			primary = true
+			continue
+		case ast.ColumnOptionUniqKey:
+			continue
+		case ast.ColumnOptionNull, ast.ColumnOptionNotNull:
+			hasNull = true
+		}
+		col.Options = append(col.Options, op)
+	}
+	// 主键列隐式为NOT NULL
+	if primary && !hasNull {
+		col.Options = append([]*ast.ColumnOption{{Tp: ast.ColumnOptionNotNull}}, col.Options...)
+	}
+
+	var builder strings.Builder
+	col.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder))
+	return builder.String()
+}
+
+// diffColumns 生成列的变更子句.
+// 先删除多余的列,再按期望的顺序添加缺少的列,修改有变化或位置不同的列
+func diffColumns(t *TableInfo, desired *SnapshotTable, node *ast.CreateTableStmt) []string {
+	var specs []string
+
+	desiredNames := make(map[string]bool, len(desired.Fields))
+	for _, f := range desired.Fields {
+		desiredNames[strings.ToLower(f.Field)] = true
+	}
+
+	// 变更后的列顺序,用以判断列的位置是否变化
+	var current []string
+	origins := make(map[string]*FieldInfo, len(t.Fields))
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.IsDeleted {
+			continue
+		}
+		key := strings.ToLower(f.Field)
+		if !desiredNames[key] {
+			specs = append(specs, fmt.Sprintf("DROP COLUMN `%s`", f.Field))
+			continue
+		}
+		origins[key] = f
+		current = append(current, key)
+	}
+
+	position := func(i int) string {
+		if i == 0 {
+			return " FIRST"
+		}
+		return fmt.Sprintf(" AFTER `%s`", desired.Fields[i-1].Field)
+	}
+
+	var modifies []string
+	for i := range desired.Fields {
+		f := &desired.Fields[i]
+		key := strings.ToLower(f.Field)
+		def := node.Cols[i]
+
+		origin, ok := origins[key]
+		if !ok {
+			pos := ""
+			if i < len(current) {
+				pos = position(i)
+			}
+			specs = append(specs, "ADD COLUMN "+restoreColumnDef(def)+pos)
+			current = append(current[:i], append([]string{key}, current[i:]...)...)
+			continue
+		}
+
+		moved := current[i] != key
+		if moved {
+			for j := i + 1; j < len(current); j++ {
+				if current[j] == key {
+					current = append(current[:j], current[j+1:]...)
+					break
+				}
+			}
+			current = append(current[:i], append([]string{key}, current[i:]...)...)
+		}
+
+		if moved || isColumnChanged(origin, f) {
+			pos := ""
+			if moved {
+				pos = position(i)
+			}
+			modifies = append(modifies, "MODIFY COLUMN "+restoreColumnDef(def)+pos)
+		}
+	}
+	return append(specs, modifies...)
+}
+
+// indexDefinition 生成添加索引的子句
+func indexDefinition(d *indexDef) string {
+	cols := make([]string, len(d.columns))
+	for i, col := range d.columns {
+		if col.subPart > 0 {
+			cols[i] = fmt.Sprintf("`%s`(%d)", col.name, col.subPart)
+		} else {
+			cols[i] = fmt.Sprintf("`%s`", col.name)
+		}
+	}
+
+	if d.isPrimary() {
+		return fmt.Sprintf("ADD PRIMARY KEY (%s)", strings.Join(cols, ","))
+	}
+	keyword := "INDEX"
+	switch {
+	case d.tp == "FULLTEXT" || d.tp == "SPATIAL":
+		keyword = d.tp + " INDEX"
+	case d.unique:
+		keyword = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("ADD %s `%s` (%s)", keyword, d.name, strings.Join(cols, ","))
+}
+
+// dropIndexDefinition 生成删除索引的子句
+func dropIndexDefinition(d *indexDef) string {
+	if d.isPrimary() {
+		return "DROP PRIMARY KEY"
+	}
+	return fmt.Sprintf("DROP INDEX `%s`", d.name)
+}
+
+// diffIndexes 生成索引的变更子句,drop为true时返回删除子句,否则返回添加子句.
+// 索引定义有变化时先删除再添加,函数索引不做对比
+func diffIndexes(t *TableInfo, desired *TableInfo, drop bool) []string {
+	origins := make(map[string]*indexDef)
+	for _, d := range buildIndexDefs(t.Indexes) {
+		origins[strings.ToLower(d.name)] = d
+	}
+	desiredDefs := make(map[string]*indexDef)
+	for _, d := range buildIndexDefs(desired.Indexes) {
+		desiredDefs[strings.ToLower(d.name)] = d
+	}
+
+	changed := func(origin, d *indexDef) bool {
+		return origin.unique != d.unique || origin.tp != d.tp || !origin.isDuplicateOf(d)
+	}
+
+	var specs []string
+	if drop {
+		for _, origin := range buildIndexDefs(t.Indexes) {
+			if origin.functional {
+				continue
+			}
+			d, ok := desiredDefs[strings.ToLower(origin.name)]
+			if !ok || (!d.functional && changed(origin, d)) {
+				specs = append(specs, dropIndexDefinition(origin))
+			}
+		}
+		return specs
+	}
+
+	for _, d := range buildIndexDefs(desired.Indexes) {
+		if d.functional {
+			continue
+		}
+		origin, ok := origins[strings.ToLower(d.name)]
+		if !ok || (!origin.functional && changed(origin, d)) {
+			specs = append(specs, indexDefinition(d))
+		}
+	}
+	return specs
+}
+
+// diffTableOptions 生成表选项的变更子句.
+// 存储引擎,字符集和行格式仅在期望的建表语句中指定,且能获取到现有值时对比
+func diffTableOptions(origin *tableOptions, desired *SnapshotTable, node *ast.CreateTableStmt) []string {
+	if origin == nil {
+		return nil
+	}
+
+	var specs []string
+	if desired.Engine != "" && origin.engine != "" && !strings.EqualFold(origin.engine, desired.Engine) {
+		specs = append(specs, "ENGINE="+desired.Engine)
+	}
+
+	hasCharset := false
+	for _, opt := range node.Options {
+		if opt.Tp == ast.TableOptionCharset || opt.Tp == ast.TableOptionCollate {
+			hasCharset = true
+		}
+	}
+	if hasCharset && desired.Collation != "" && origin.collation != "" &&
+		!strings.EqualFold(origin.collation, desired.Collation) {
+		cs := strings.SplitN(desired.Collation, "_", 2)[0]
+		specs = append(specs, fmt.Sprintf("DEFAULT CHARSET=%s COLLATE=%s", cs, desired.Collation))
+	}
+
+	if desired.RowFormat != "" && desired.RowFormat != "DEFAULT" && desired.RowFormat != "OTHER" &&
+		origin.rowFormat != "" && !strings.EqualFold(origin.rowFormat, desired.RowFormat) {
+		specs = append(specs, "ROW_FORMAT="+desired.RowFormat)
+	}
+
+	if origin.comment != desired.Comment {
+		var builder strings.Builder
+		ctx := format.NewRestoreCtx(format.DefaultRestoreFlags, &builder)
+		ctx.WriteKeyWord("COMMENT")
+		ctx.WritePlain("=")
+		ctx.WriteString(desired.Comment)
+		specs = append(specs, builder.String())
+	}
+	return specs
+}
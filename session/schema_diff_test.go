@@ -0,0 +1,100 @@
+package session_test
+
+import (
+	"strings"
+
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/session"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+func (s *testOfflineSuite) TestSchemaDiff(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		name varchar(20) not null default '' comment 'name',
+		age int comment 'age',
+		old_col int comment 'old',
+		key idx_name(name), key idx_age(age)) engine=innodb comment 't1';
+	create table t3(id int primary key comment 'id',
+		c1 int not null default 0 comment 'c1',
+		c2 int not null default 0 comment 'c2') comment 't3';
+	create table t5(id int primary key comment 'id',
+		created timestamp not null default current_timestamp comment 'created',
+		key idx_created(created)) comment 't5';
+	create table t6(id int primary key comment 'id',
+		uid int not null default 0 comment 'uid',
+		updated datetime(3) not null default current_timestamp(3) comment 'updated',
+		key idx_uid(uid)) comment 't6';`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+	cnf.Inc.EnableNullable = true
+	cnf.Inc.EnableForeignKey = true
+
+	core := session.NewInception()
+	core.LoadOptions(session.SourceOptions{
+		DB:       "test_inc",
+		Offline:  true,
+		Snapshot: snapshot,
+	})
+
+	diffs, result, err := core.SchemaDiff(context.Background(), `
+	create table t1(id bigint not null auto_increment comment 'id',
+		name varchar(20) not null default '' comment 'name',
+		email varchar(100) not null default '' comment 'email',
+		age int(11) comment 'age',
+		primary key(id), unique key uniq_email(email), key idx_name(name, age))
+		engine=innodb comment='users';
+	create table t2(id int primary key comment 'id') comment 't2';
+	create table t3(id int(11) not null comment 'id',
+		c2 int not null default '0' comment 'c2',
+		c1 int not null default 0 comment 'c1',
+		primary key(id)) comment 't3';
+	create table test_inc.t4(id int primary key comment 'id',
+		c1 int not null default 0 comment 'c1') comment 't4';
+	create table t5(id int not null comment 'id',
+		created timestamp not null default now() comment 'created',
+		primary key(id), index idx_created(created)) comment 't5';
+	create table t6(id int primary key comment 'id',
+		uid int not null default 0 comment 'uid',
+		updated datetime(3) not null default current_timestamp(3) comment 'updated',
+		created datetime(3) not null default current_timestamp(3) on update current_timestamp(3) comment 'created',
+		key idx_uid(uid),
+		constraint fk_uid foreign key (uid) references t1(id)) comment 't6';`)
+	c.Assert(err, IsNil)
+	c.Assert(len(diffs), Equals, 6)
+
+	c.Assert(diffs[0].Table, Equals, "t1")
+	c.Assert(diffs[0].SQL, Equals, "ALTER TABLE `test_inc`.`t1` DROP INDEX `idx_name`, DROP INDEX `idx_age`, "+
+		"DROP COLUMN `old_col`, ADD COLUMN `email` VARCHAR(100) NOT NULL DEFAULT '' COMMENT 'email' AFTER `name`, "+
+		"MODIFY COLUMN `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT 'id', "+
+		"ADD UNIQUE INDEX `uniq_email` (`email`), ADD INDEX `idx_name` (`name`,`age`), COMMENT='users';")
+	// 不存在的表直接使用建表语句
+	c.Assert(diffs[1].SQL, Equals, "create table t2(id int primary key comment 'id') comment 't2';")
+	// 仅调整列的位置
+	c.Assert(diffs[2].SQL, Equals,
+		"ALTER TABLE `test_inc`.`t3` MODIFY COLUMN `c2` INT NOT NULL DEFAULT '0' COMMENT 'c2' AFTER `id`;")
+	c.Assert(diffs[3].Schema, Equals, "test_inc")
+	c.Assert(diffs[3].Table, Equals, "t4")
+	// 无差异
+	c.Assert(diffs[4].SQL, Equals, "")
+	c.Assert(diffs[4].Warnings, HasLen, 0)
+	// 保留CURRENT_TIMESTAMP的小数秒精度,外键不做对比
+	c.Assert(diffs[5].SQL, Equals, "ALTER TABLE `test_inc`.`t6` ADD COLUMN `created` DATETIME(3) NOT NULL "+
+		"DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'created';")
+	c.Assert(diffs[5].Warnings, DeepEquals, []string{"外键'fk_uid'未对比,需要手动变更"})
+
+	// 变更语句已审核
+	c.Assert(len(result), Equals, 6)
+	c.Assert(result[0].Sql, Equals, "use `test_inc`")
+	for _, r := range result {
+		c.Assert(r.ErrLevel, Equals, uint8(0), Commentf("%v %v", r.Sql, r.ErrorMessage))
+	}
+	c.Assert(result[1].Sql, Equals, strings.TrimSuffix(diffs[0].SQL, ";"))
+
+	_, result, err = core.SchemaDiff(context.Background(), "alter table t1 add column c1 int;")
+	c.Assert(err, NotNil)
+	c.Assert(len(result), Equals, 0)
+}
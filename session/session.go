@@ -21,6 +21,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -81,6 +82,20 @@ type Session interface {
 	Print(ctx context.Context, sql string) ([]PrintRecord, error)
 	// 打印语法树
 	QueryTree(ctx context.Context, sql string) ([]PrintRecord, error)
+	// RegisterRule 注册自定义审核规则
+	RegisterRule(rules ...Rule)
+	// RegisterObserver 注册事件观察者
+	RegisterObserver(observers ...Observer)
+	// RollbackFromBinlog 解析本地binlog文件生成回滚语句
+	RollbackFromBinlog(ctx context.Context, opt BinlogOptions) ([]BinlogRollbackRecord, error)
+	// Rollback 按opid回滚已执行的操作
+	Rollback(ctx context.Context, opt RollbackOptions) ([]RollbackRecord, error)
+	// RollbackByTicket 回滚一次执行的所有操作
+	RollbackByTicket(ctx context.Context, records []Record, opt RollbackOptions) ([]RollbackRecord, error)
+	// RedundantIndexes 分析已有表的冗余索引
+	RedundantIndexes(ctx context.Context, tables ...string) ([]RedundantIndex, error)
+	// SchemaDiff 对比期望的建表语句和现有表结构,生成变更语句并审核
+	SchemaDiff(ctx context.Context, sql string) ([]TableDiff, []Record, error)
 }
 
 var (
@@ -124,6 +139,8 @@ type session struct {
 
 	db       *gorm.DB
 	backupdb *gorm.DB
+	// 备份存储,backup_storage为mysql时使用backupdb
+	backupSink BackupSink
 
 	// 执行DDL操作的数据库连接. 仅用于事务功能
 	ddlDB *gorm.DB
@@ -150,14 +167,20 @@ type session struct {
 	// 批量写入表$_$Inception_backup_information$_$
 	chBackupRecord chan *chanBackup
 
-	// 批量insert
-	insertBuffer []interface{}
+	// 批量写入的备份记录和回滚语句
+	backupInfoBuffer []*BackupInfo
+	rollbackBuffer   []RollbackStatement
 
 	// 记录表结构以重用
 	// allTables map[uint64]*Table
 
-	// 记录上次的备份表名,如果表名改变时,刷新insert缓存
+	// 记录上次的备份库名,如果库名改变时,刷新备份记录缓存
 	lastBackupTable string
+	// 记录上次的回滚语句表,如果表名改变时,刷新回滚语句缓存
+	lastRollbackTable backupTable
+
+	// 解析本地binlog文件时,接收生成的回滚语句
+	rollbackWriter func(b []byte)
 
 	// 总的操作行数,当备份时用以计算备份进度
 	totalChangeRows int
@@ -195,8 +218,20 @@ type session struct {
 
 	// 自定义审核级别,通过解析config.GetGlobalConfig().IncLevel生成
 	incLevel map[string]uint8
+	// 当前生效的审核规则配置集
+	profile string
+	// 通过inception set设置的会话级审核参数和审核级别,切换配置集后重新生效
+	setInc    map[string]interface{}
+	setLevels map[string]int8
+	// 命名规范正则表达式缓存
+	namePatterns map[string]*regexp.Regexp
 
 	alterRollbackBuffer []string
+	// 分区操作的回滚子句,每个子句单独生成一条ALTER语句
+	partitionRollbackBuffer []string
+
+	// 当前语句WITH子句定义的公用表表达式(CTE),以小写表名为key
+	cteTables map[string]*TableInfo
 
 	// 目标数据库的innodb_large_prefix设置
 	innodbLargePrefix bool
@@ -204,6 +239,16 @@ type session struct {
 	lowerCaseTableNames int
 	// PXC集群节点
 	isClusterNode bool
+
+	// 离线审核时的表结构快照
+	snapshot *snapshotCache
+
+	// 自定义审核规则
+	rules []Rule
+
+	// 事件观察者
+	observers  []Observer
+	observerMu sync.Mutex
 }
 
 func (s *session) AffectedRows() uint64 {
@@ -573,7 +618,8 @@ func logStmt(node ast.StmtNode, vars *variable.SessionVars) {
 	switch stmt := node.(type) {
 	case *ast.CreateUserStmt, *ast.DropUserStmt, *ast.AlterUserStmt, *ast.SetPwdStmt, *ast.GrantStmt,
 		*ast.RevokeStmt, *ast.AlterTableStmt, *ast.CreateDatabaseStmt, *ast.CreateIndexStmt, *ast.CreateTableStmt,
-		*ast.DropDatabaseStmt, *ast.DropIndexStmt, *ast.DropTableStmt, *ast.RenameTableStmt, *ast.TruncateTableStmt:
+		*ast.CreateViewStmt, *ast.DropDatabaseStmt, *ast.DropIndexStmt, *ast.DropTableStmt, *ast.RenameTableStmt, *ast.TruncateTableStmt,
+		*ast.CreateRoutineStmt, *ast.CreateTriggerStmt, *ast.CreateEventStmt, *ast.DropRoutineStmt:
 		user := vars.User
 		schemaVersion := vars.TxnCtx.SchemaVersion
 		if ss, ok := node.(ast.SensitiveStmtNode); ok {
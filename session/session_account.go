@@ -0,0 +1,373 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/format"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/util/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkCreateUser 审核创建用户
+func (s *session) checkCreateUser(node *ast.CreateUserStmt, sql string) {
+
+	log.Debug("checkCreateUser")
+
+	if !s.checkAccountEnabled("CREATE USER") {
+		return
+	}
+
+	requireSsl := false
+	for _, opt := range node.TslOptions {
+		if opt.Type != ast.TslNone {
+			requireSsl = true
+		}
+	}
+
+	var rollback []string
+	for _, spec := range node.Specs {
+		s.checkUserHost(spec.User)
+		s.checkUserPassword(spec.User, spec.AuthOpt)
+		if !requireSsl {
+			s.appendErrorNo(ErrUserRequireSsl, spec.User.Username)
+		}
+
+		if _, exists := s.mysqlShowGrants(spec.User); exists {
+			if !node.IfNotExists {
+				s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrCannotUser],
+					"CREATE USER", spec.User))
+			}
+		} else {
+			rollback = append(rollback, fmt.Sprintf("DROP USER %s;", quoteUser(spec.User)))
+		}
+	}
+
+	s.setAccountRollback(rollback)
+}
+
+// checkAlterUser 审核修改用户
+func (s *session) checkAlterUser(node *ast.AlterUserStmt, sql string) {
+
+	log.Debug("checkAlterUser")
+
+	if !s.checkAccountEnabled("ALTER USER") {
+		return
+	}
+
+	if node.CurrentAuth != nil {
+		s.checkUserPassword(&auth.UserIdentity{CurrentUser: true}, node.CurrentAuth)
+	}
+
+	var rollback []string
+	for _, spec := range node.Specs {
+		s.checkUserPassword(spec.User, spec.AuthOpt)
+		if res := s.mysqlShowCreateUser(spec.User); res != "" {
+			rollback = append(rollback, "ALTER USER"+strings.TrimPrefix(res, "CREATE USER")+";")
+		} else if !node.IfExists && !s.isOffline() {
+			s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrCannotUser],
+				"ALTER USER", spec.User))
+		}
+	}
+
+	s.setAccountRollback(rollback)
+}
+
+// checkSetPwd 审核修改密码
+func (s *session) checkSetPwd(node *ast.SetPwdStmt, sql string) {
+
+	log.Debug("checkSetPwd")
+
+	if !s.checkAccountEnabled("SET PASSWORD") {
+		return
+	}
+
+	user := node.User
+	if user == nil {
+		user = &auth.UserIdentity{CurrentUser: true}
+	}
+	s.checkUserPassword(user, &ast.AuthOption{ByAuthString: true, AuthString: node.Password})
+
+	var rollback []string
+	if node.User != nil {
+		if res := s.mysqlShowCreateUser(node.User); res != "" {
+			rollback = append(rollback, "ALTER USER"+strings.TrimPrefix(res, "CREATE USER")+";")
+		}
+	}
+	s.setAccountRollback(rollback)
+}
+
+// checkDropUser 审核删除用户,回滚语句为删除前的建用户语句和授权语句
+func (s *session) checkDropUser(node *ast.DropUserStmt, sql string) {
+
+	log.Debug("checkDropUser")
+
+	if !s.checkAccountEnabled("DROP USER") {
+		return
+	}
+
+	var rollback []string
+	for _, user := range node.UserList {
+		grants, exists := s.mysqlShowGrants(user)
+		if !exists {
+			if !node.IfExists && !s.isOffline() {
+				s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrCannotUser],
+					"DROP USER", user))
+			}
+			continue
+		}
+		if res := s.mysqlShowCreateUser(user); res != "" {
+			rollback = append(rollback, res+";")
+		}
+		for _, g := range grants {
+			rollback = append(rollback, g+";")
+		}
+	}
+
+	s.setAccountRollback(rollback)
+}
+
+// checkGrant 审核授权.
+// 回滚时先回收本次授予的权限,再重新执行授权前的授权语句
+func (s *session) checkGrant(node *ast.GrantStmt, sql string) {
+
+	log.Debug("checkGrant")
+
+	if !s.checkAccountEnabled("GRANT") {
+		return
+	}
+
+	allOnGlobal := false
+	if node.Level.Level == ast.GrantLevelGlobal {
+		for _, p := range node.Privs {
+			if p.Priv == mysql.AllPriv {
+				allOnGlobal = true
+			}
+		}
+	}
+
+	revoke := s.buildRevokeRollback(node)
+
+	var rollback []string
+	for _, spec := range node.Users {
+		if allOnGlobal {
+			s.appendErrorNo(ErrGrantAllOnGlobal, spec.User.Username)
+		}
+		s.checkUserHost(spec.User)
+		s.checkUserPassword(spec.User, spec.AuthOpt)
+
+		grants, _ := s.mysqlShowGrants(spec.User)
+		if revoke != "" {
+			rollback = append(rollback, fmt.Sprintf("%s %s;", revoke, quoteUser(spec.User)))
+		}
+		for _, g := range grants {
+			rollback = append(rollback, g+";")
+		}
+	}
+
+	s.setAccountRollback(rollback)
+}
+
+// checkRevoke 审核回收权限,回滚语句为回收前的授权语句
+func (s *session) checkRevoke(node *ast.RevokeStmt, sql string) {
+
+	log.Debug("checkRevoke")
+
+	if !s.checkAccountEnabled("REVOKE") {
+		return
+	}
+
+	var rollback []string
+	for _, spec := range node.Users {
+		grants, exists := s.mysqlShowGrants(spec.User)
+		if !exists && !s.isOffline() {
+			s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrNonexistingGrant],
+				spec.User.Username, spec.User.Hostname))
+			continue
+		}
+		for _, g := range grants {
+			rollback = append(rollback, g+";")
+		}
+	}
+
+	s.setAccountRollback(rollback)
+}
+
+// checkAccountEnabled 是否允许账号管理
+func (s *session) checkAccountEnabled(op string) bool {
+	if !s.inc.EnableAccountManagement {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法执行%s.", op))
+		return false
+	}
+
+	// 账号均记录在mysql.user中,同时用以确定备份库
+	s.myRecord.DBName = "mysql"
+	s.myRecord.TableName = "user"
+	return true
+}
+
+// checkUserHost 用户主机不允许使用通配符
+func (s *session) checkUserHost(user *auth.UserIdentity) {
+	if user != nil && !user.CurrentUser && strings.Contains(user.Hostname, "%") {
+		s.appendErrorNo(ErrUserHostWildcard, user)
+	}
+}
+
+// checkUserPassword 审核密码强度,加密后的密码无法审核
+func (s *session) checkUserPassword(user *auth.UserIdentity, opt *ast.AuthOption) {
+	if opt == nil || !opt.ByAuthString {
+		return
+	}
+
+	if !isStrongPassword(opt.AuthString, s.inc.PasswordMinLength) {
+		name := "CURRENT_USER"
+		if !user.CurrentUser {
+			name = user.Username
+		}
+		s.appendErrorNo(ErrPasswordWeak, name, s.inc.PasswordMinLength)
+	}
+}
+
+// isStrongPassword 密码须达到最小长度,且同时包含大写字母,小写字母,数字和特殊字符
+func isStrongPassword(pwd string, minLength uint) bool {
+	if uint(len(pwd)) < minLength {
+		return false
+	}
+
+	var upper, lower, digit, special bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			special = true
+		}
+	}
+	return upper && lower && digit && special
+}
+
+// buildRevokeRollback 生成回收本次授权的语句前缀,不支持的权限类型无法回滚
+func (s *session) buildRevokeRollback(node *ast.GrantStmt) string {
+	var buf bytes.Buffer
+	ctx := format.NewRestoreCtx(format.DefaultRestoreFlags, &buf)
+
+	ctx.WriteKeyWord("REVOKE ")
+	for i, p := range node.Privs {
+		if p.Priv == 0 {
+			log.Warnf("con:%d 不支持的权限类型,无法生成回滚语句: %s",
+				s.sessionVars.ConnectionID, node.Text())
+			return ""
+		}
+		if i > 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := p.Restore(ctx); err != nil {
+			log.Error(err)
+			return ""
+		}
+	}
+	if node.WithGrant {
+		ctx.WriteKeyWord(", GRANT OPTION")
+	}
+	ctx.WriteKeyWord(" ON ")
+	if node.ObjectType == ast.ObjectTypeTable {
+		ctx.WriteKeyWord("TABLE ")
+	}
+	if err := node.Level.Restore(ctx); err != nil {
+		log.Error(err)
+		return ""
+	}
+	ctx.WriteKeyWord(" FROM")
+	return buf.String()
+}
+
+// setAccountRollback 设置回滚语句,仅执行时生成.
+// 多条回滚语句以换行连接,作为一条回滚语句备份,回滚时按原顺序执行
+func (s *session) setAccountRollback(rollback []string) {
+	if s.opt.Execute && len(rollback) > 0 {
+		s.myRecord.DDLRollback = strings.Join(rollback, "\n")
+	}
+
+	s.myRecord.TableInfo = &TableInfo{
+		Schema: s.myRecord.DBName,
+		Name:   s.myRecord.TableName,
+	}
+}
+
+// quoteUser 返回`user`@`host`格式的用户名
+func quoteUser(user *auth.UserIdentity) string {
+	return fmt.Sprintf("`%s`@`%s`",
+		strings.Replace(user.Username, "`", "``", -1),
+		strings.Replace(user.Hostname, "`", "``", -1))
+}
+
+// mysqlShowGrants 获取用户执行前的授权语句,离线审核时不检查
+func (s *session) mysqlShowGrants(user *auth.UserIdentity) ([]string, bool) {
+
+	if s.isOffline() || s.db == nil || user == nil || user.CurrentUser {
+		return nil, false
+	}
+
+	sql := fmt.Sprintf("SHOW GRANTS FOR %s;", quoteUser(user))
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+
+	if err != nil {
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
+			if myErr.Number == mysql.ErrNonexistingGrant {
+				return nil, false
+			}
+			s.appendErrorMessage(myErr.Message)
+		} else {
+			s.appendErrorMessage(err.Error())
+		}
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		return nil, false
+	}
+
+	var grants []string
+	for rows.Next() {
+		var grant string
+		rows.Scan(&grant)
+		grants = append(grants, grant)
+	}
+	return grants, true
+}
+
+// mysqlShowCreateUser 获取用户的创建语句,低版本或用户不存在时返回空
+func (s *session) mysqlShowCreateUser(user *auth.UserIdentity) string {
+
+	if s.isOffline() || s.db == nil || user == nil || user.CurrentUser {
+		return ""
+	}
+
+	sql := fmt.Sprintf("SHOW CREATE USER %s;", quoteUser(user))
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+
+	if err != nil {
+		log.Warnf("con:%d %v", s.sessionVars.ConnectionID, err)
+		return ""
+	}
+
+	var res string
+	for rows.Next() {
+		rows.Scan(&res)
+	}
+	return res
+}
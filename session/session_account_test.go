@@ -0,0 +1,88 @@
+package session_test
+
+import (
+	"github.com/hanchuanchuan/inception-core/config"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestAccountManagement(c *C) {
+	snapshot := "create database test_inc;"
+
+	inc := &config.GetGlobalConfig().Inc
+	defer saveConfig()()
+
+	inc.EnableAccountManagement = false
+	result := s.audit(c, snapshot, `create user 'u1'@'10.0.0.1' identified by 'Abc@12345' require ssl;`)
+	c.Assert(result[0].ErrLevel, Equals, uint8(2))
+	c.Assert(result[0].ErrorMessage, Equals, "命令禁止! 无法执行CREATE USER.")
+
+	inc.EnableAccountManagement = true
+	result = s.audit(c, snapshot, `create user 'u1'@'10.0.0.1' identified by 'Abc@12345' require ssl;
+	create user 'u2'@'%' identified by 'abc';
+	create user 'u3'@'10.0.0.1' identified by password '*3D56A309CD04FA2EEF181462E59011F075C89548' require x509;
+	alter user 'u1'@'10.0.0.1' identified by '12345678';
+	set password for 'u1'@'10.0.0.1' = 'Xyz#98765';
+	grant select, insert on test_inc.* to 'u1'@'10.0.0.1';
+	grant all privileges on *.* to 'u1'@'10.0.0.1' with grant option;
+	grant select on test_inc.* to 'u4'@'10.0.%';
+	revoke insert on test_inc.* from 'u1'@'10.0.0.1';
+	drop user if exists 'u2'@'%';`)
+	c.Assert(len(result), Equals, 10)
+
+	c.Assert(result[0].ErrLevel, Equals, uint8(0), Commentf("%v", result[0].ErrorMessage))
+	c.Assert(result[0].DBName, Equals, "mysql")
+	c.Assert(result[0].TableName, Equals, "user")
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].ErrorMessage, Matches, "(?s).*Wildcard host is not allowed for user 'u2@%'.*")
+	c.Assert(result[1].ErrorMessage, Matches, "(?s).*Password of user 'u2' is too weak, at least 8 characters.*")
+	c.Assert(result[1].ErrorMessage, Matches, "(?s).*User 'u2' must be created with REQUIRE SSL.*")
+
+	c.Assert(result[2].ErrLevel, Equals, uint8(0), Commentf("%v", result[2].ErrorMessage))
+
+	c.Assert(result[3].ErrLevel, Equals, uint8(2))
+	c.Assert(result[3].ErrorMessage, Matches, "Password of user 'u1' is too weak.*")
+
+	c.Assert(result[4].ErrLevel, Equals, uint8(0), Commentf("%v", result[4].ErrorMessage))
+	c.Assert(result[5].ErrLevel, Equals, uint8(0), Commentf("%v", result[5].ErrorMessage))
+
+	c.Assert(result[6].ErrLevel, Equals, uint8(2))
+	c.Assert(result[6].ErrorMessage, Equals, "Grant all privileges on *.* to user 'u1' is not allowed.")
+
+	c.Assert(result[7].ErrLevel, Equals, uint8(1))
+	c.Assert(result[7].ErrorMessage, Equals, "Wildcard host is not allowed for user 'u4@10.0.%'.")
+
+	c.Assert(result[8].ErrLevel, Equals, uint8(0), Commentf("%v", result[8].ErrorMessage))
+	c.Assert(result[9].ErrLevel, Equals, uint8(0), Commentf("%v", result[9].ErrorMessage))
+}
+
+func (s *testOfflineSuite) TestStrongPassword(c *C) {
+	snapshot := "create database test_inc;"
+
+	inc := &config.GetGlobalConfig().Inc
+	defer saveConfig()()
+	inc.EnableAccountManagement = true
+
+	cases := []struct {
+		pwd       string
+		minLength uint
+		strong    bool
+	}{
+		{"Abc@1234", 8, true},
+		{"Abc@123", 8, false},
+		{"abc@1234", 8, false},
+		{"ABC@1234", 8, false},
+		{"Abcd1234", 8, false},
+		{"Abc@defg", 8, false},
+		{"Ab@1", 0, true},
+	}
+	for _, t := range cases {
+		inc.PasswordMinLength = t.minLength
+		result := s.audit(c, snapshot, "alter user 'u1'@'10.0.0.1' identified by '"+t.pwd+"';")
+		if t.strong {
+			c.Assert(result[0].ErrLevel, Equals, uint8(0), Commentf("%s: %s", t.pwd, result[0].ErrorMessage))
+		} else {
+			c.Assert(result[0].ErrorMessage, Matches, "Password of user 'u1' is too weak.*", Commentf("%s", t.pwd))
+		}
+	}
+}
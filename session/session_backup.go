@@ -1,15 +1,13 @@
 package session
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
-	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/hanchuanchuan/inception-core/ast"
 	"github.com/hanchuanchuan/inception-core/mysql"
 	log "github.com/sirupsen/logrus"
@@ -17,10 +15,9 @@ import (
 
 // chanBackup 备份channal数据,用来传递备份的sql等信息
 type chanBackup struct {
-	// values []interface{}
 	// 库名
 	dbname string
-	values []interface{}
+	info   *BackupInfo
 	record *Record
 }
 
@@ -36,10 +33,10 @@ func (s *session) processChanBackup(wg *sync.WaitGroup) {
 		// flush标志. 不能在外面调用flush函数,会导致线程并发操作,写入数据错误
 		// 如数据尚未进入到ch通道,此时调用flush,数据无法正确入库
 
-		if r.values == nil {
+		if r.info == nil {
 			s.flushBackupRecord(r.dbname, r.record)
 		} else {
-			s.writeBackupRecord(r.dbname, r.record, r.values)
+			s.writeBackupRecord(r.dbname, r.record, r.info)
 		}
 	}
 }
@@ -87,46 +84,33 @@ func (s *session) runBackup(ctx context.Context) {
 	}
 }
 
-// 解析的sql写入缓存,并定期入库
-func (s *session) writeBackupRecord(dbname string, record *Record, values []interface{}) {
+// 备份记录写入缓存,并定期入库
+func (s *session) writeBackupRecord(dbname string, record *Record, info *BackupInfo) {
 
-	s.insertBuffer = append(s.insertBuffer, values...)
+	s.backupInfoBuffer = append(s.backupInfoBuffer, info)
 
 	// 每500行insert提交一次
-	if len(s.insertBuffer) >= 500*11 {
+	if len(s.backupInfoBuffer) >= 500 {
 		s.flushBackupRecord(dbname, record)
 	}
 }
 
-// flush用以写入当前insert缓存,并清空缓存.
+// flush用以写入当前备份记录缓存,并清空缓存.
 func (s *session) flushBackupRecord(dbname string, record *Record) {
-	// log.Info("flush ", len(s.insertBuffer))
-
-	if len(s.insertBuffer) > 0 {
-		const backupRecordColumnCount int = 11
-		const rowSQL = "(?,?,?,?,?,?,?,?,?,?,NOW(),?),"
-		tableName := fmt.Sprintf("`%s`.`%s`", dbname, remoteBackupTable)
-
-		sql := "insert into %s values%s"
-		values := strings.TrimRight(
-			strings.Repeat(rowSQL, len(s.insertBuffer)/backupRecordColumnCount), ",")
-
-		err := s.backupdb.Exec(fmt.Sprintf(sql, tableName, values),
-			s.insertBuffer...).Error
+	if len(s.backupInfoBuffer) > 0 {
+		err := s.backupSink.WriteInfo(dbname, s.backupInfoBuffer)
 		if err != nil {
 			log.Error(err)
-			if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
-				s.recordSets.MaxLevel = 2
-				record.StageStatus = StatusBackupFail
-				record.appendErrorMessage(myErr.Message)
-			}
+			s.recordSets.MaxLevel = 2
+			record.StageStatus = StatusBackupFail
+			record.appendErrorMessage(backupErrorMessage(err))
 		}
 
 		// s.BackupTotalRows += len(s.insertBuffer) / backupRecordColumnCount
 		// s.SetMyProcessInfo(record.Sql, time.Now(),
 		//     float64(s.BackupTotalRows)/float64(s.TotalChangeRows))
 
-		s.insertBuffer = nil
+		s.backupInfoBuffer = nil
 	}
 }
 
@@ -135,25 +119,15 @@ func (s *session) mysqlExecuteBackupSqlForDDL(record *Record) {
 		return
 	}
 
-	var buf strings.Builder
-	buf.WriteString("INSERT INTO ")
 	dbname := s.getRemoteBackupDBName(record)
-	buf.WriteString(fmt.Sprintf("`%s`.`%s`", dbname, record.TableInfo.Name))
-	buf.WriteString("(rollback_statement, opid_time) VALUES('")
-	buf.WriteString(HTMLEscapeString(record.DDLRollback))
-	buf.WriteString("','")
-	buf.WriteString(record.OPID)
-	buf.WriteString("')")
-
-	sql := buf.String()
-
-	if err := s.backupdb.Exec(sql).Error; err != nil {
-		log.Errorf("con:%d %v sql:%s", s.sessionVars.ConnectionID, err, sql)
-		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
-			s.appendErrorMessage(myErr.Message)
-		} else {
-			s.appendErrorMessage(err.Error())
-		}
+	statements := []RollbackStatement{{
+		OPID:      record.OPID,
+		Statement: record.DDLRollback,
+	}}
+
+	if err := s.backupSink.WriteRollback(dbname, record.TableInfo.Name, statements); err != nil {
+		log.Errorf("con:%d %v sql:%s", s.sessionVars.ConnectionID, err, record.DDLRollback)
+		s.appendErrorMessage(backupErrorMessage(err))
 		record.StageStatus = StatusBackupFail
 	}
 	record.StageStatus = StatusBackupOK
@@ -166,7 +140,7 @@ func (s *session) mysqlExecuteBackupInfoInsertSql(record *Record, longDataType b
 	record.OPID = makeOPIDByTime(record.ExecTimestamp, record.ThreadId, record.SeqNo)
 
 	typeStr := "UNKNOWN"
-	switch record.Type.(type) {
+	switch node := record.Type.(type) {
 	case *ast.InsertStmt:
 		typeStr = "INSERT"
 	case *ast.DeleteStmt:
@@ -179,8 +153,34 @@ func (s *session) mysqlExecuteBackupInfoInsertSql(record *Record, longDataType b
 		typeStr = "CREATETABLE"
 	case *ast.AlterTableStmt:
 		typeStr = "ALTERTABLE"
+	case *ast.CreateViewStmt:
+		typeStr = "CREATEVIEW"
+	case *ast.CreateRoutineStmt:
+		typeStr = "CREATE" + node.Tp.String()
+	case *ast.CreateTriggerStmt:
+		typeStr = "CREATETRIGGER"
+	case *ast.CreateEventStmt:
+		typeStr = "CREATEEVENT"
+	case *ast.DropRoutineStmt:
+		typeStr = "DROP" + node.Tp.String()
+	case *ast.CreateUserStmt:
+		typeStr = "CREATEUSER"
+	case *ast.AlterUserStmt:
+		typeStr = "ALTERUSER"
+	case *ast.SetPwdStmt:
+		typeStr = "SETPASSWORD"
+	case *ast.DropUserStmt:
+		typeStr = "DROPUSER"
+	case *ast.GrantStmt:
+		typeStr = "GRANT"
+	case *ast.RevokeStmt:
+		typeStr = "REVOKE"
 	case *ast.DropTableStmt:
-		typeStr = "DROPTABLE"
+		if node.IsView {
+			typeStr = "DROPVIEW"
+		} else {
+			typeStr = "DROPTABLE"
+		}
 	case *ast.RenameTableStmt:
 		typeStr = "RENAMETABLE"
 	case *ast.CreateIndexStmt:
@@ -214,18 +214,19 @@ func (s *session) mysqlExecuteBackupInfoInsertSql(record *Record, longDataType b
 		sql_stmt = sql_stmt + "..."
 	}
 
-	values := []interface{}{
-		record.OPID,
-		record.StartFile,
-		strconv.Itoa(record.StartPosition),
-		record.EndFile,
-		strconv.Itoa(record.EndPosition),
-		sql_stmt,
-		s.opt.Host,
-		record.TableInfo.Schema,
-		record.TableInfo.Name,
-		strconv.Itoa(s.opt.Port),
-		typeStr,
+	info := &BackupInfo{
+		OPID:          record.OPID,
+		StartFile:     record.StartFile,
+		StartPosition: record.StartPosition,
+		EndFile:       record.EndFile,
+		EndPosition:   record.EndPosition,
+		Sql:           sql_stmt,
+		Host:          s.opt.Host,
+		DBName:        record.TableInfo.Schema,
+		TableName:     record.TableInfo.Name,
+		Port:          s.opt.Port,
+		Time:          time.Now(),
+		Type:          typeStr,
 	}
 
 	dbName := s.getRemoteBackupDBName(record)
@@ -238,7 +239,6 @@ func (s *session) mysqlExecuteBackupInfoInsertSql(record *Record, longDataType b
 		s.chBackupRecord <- &chanBackup{
 			dbname: s.lastBackupTable,
 			record: record,
-			values: nil,
 		}
 		s.lastBackupTable = dbName
 	}
@@ -246,54 +246,12 @@ func (s *session) mysqlExecuteBackupInfoInsertSql(record *Record, longDataType b
 	s.chBackupRecord <- &chanBackup{
 		dbname: dbName,
 		record: record,
-		values: values,
+		info:   info,
 	}
 
 	return 0
 }
 
-func (s *session) mysqlCreateSqlBackupTable(dbname string) string {
-
-	// if not exists
-	buf := bytes.NewBufferString("CREATE TABLE  ")
-
-	buf.WriteString(fmt.Sprintf("`%s`.`%s`", dbname, remoteBackupTable))
-	buf.WriteString("(")
-
-	buf.WriteString("opid_time varchar(50),")
-	buf.WriteString("start_binlog_file varchar(512),")
-	buf.WriteString("start_binlog_pos int,")
-	buf.WriteString("end_binlog_file varchar(512),")
-	buf.WriteString("end_binlog_pos int,")
-	buf.WriteString("sql_statement mediumtext,")
-	buf.WriteString("host VARCHAR(64),")
-	buf.WriteString("dbname VARCHAR(64),")
-	buf.WriteString("tablename VARCHAR(64),")
-	buf.WriteString("port INT,")
-	buf.WriteString("time TIMESTAMP,")
-	buf.WriteString("type VARCHAR(20),")
-	buf.WriteString("PRIMARY KEY(opid_time)")
-
-	buf.WriteString(")ENGINE INNODB DEFAULT CHARSET UTF8MB4;")
-
-	return buf.String()
-}
-
-func (s *session) mysqlCreateSqlFromTableInfo(dbname string, ti *TableInfo) string {
-
-	buf := bytes.NewBufferString("CREATE TABLE if not exists ")
-	buf.WriteString(fmt.Sprintf("`%s`.`%s`", dbname, ti.Name))
-	buf.WriteString("(")
-
-	buf.WriteString("id bigint auto_increment primary key, ")
-	buf.WriteString("rollback_statement mediumtext, ")
-	buf.WriteString("opid_time varchar(50)")
-
-	buf.WriteString(") ENGINE INNODB DEFAULT CHARSET UTF8MB4;")
-
-	return buf.String()
-}
-
 func (s *session) getRemoteBackupDBName(record *Record) string {
 
 	if record.BackupDBName != "" {
@@ -338,59 +296,33 @@ func (s *session) mysqlCreateBackupTable(record *Record) (longDataType bool) {
 	}
 
 	if _, ok := s.backupDBCacheList[backupDBName]; !ok {
-		sql := fmt.Sprintf("create database if not exists `%s`;", backupDBName)
-		if err := s.backupdb.Exec(sql).Error; err != nil {
+		if err := s.backupSink.CreateDatabase(backupDBName); err != nil {
 			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
-			if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
-				if myErr.Number != 1007 { /*ER_DB_CREATE_EXISTS*/
-					s.appendErrorMessage(myErr.Message)
-					return
-				}
-			} else {
-				s.appendErrorMessage(err.Error())
-				return
-			}
+			s.appendErrorMessage(backupErrorMessage(err))
+			return
 		}
 		s.backupDBCacheList[backupDBName] = true
 	}
 
 	key := fmt.Sprintf("%s.%s", backupDBName, record.TableInfo.Name)
 	if _, ok := s.backupTableCacheList[key]; !ok {
-		createSql := s.mysqlCreateSqlFromTableInfo(backupDBName, record.TableInfo)
-		if err := s.backupdb.Exec(createSql).Error; err != nil {
+		if err := s.backupSink.CreateTable(backupDBName, record.TableInfo.Name); err != nil {
 			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
-			if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
-				if myErr.Number != 1050 { /*ER_TABLE_EXISTS_ERROR*/
-					s.appendErrorMessage(myErr.Message)
-					return
-				}
-			} else {
-				s.appendErrorMessage(err.Error())
-				return
-			}
+			s.appendErrorMessage(backupErrorMessage(err))
+			return
 		}
 		s.backupTableCacheList[key] = true
 	}
 
 	key = fmt.Sprintf("%s.%s", backupDBName, remoteBackupTable)
 	if _, ok := s.backupTableCacheList[key]; !ok {
-		createSql := s.mysqlCreateSqlBackupTable(backupDBName)
-		if err := s.backupdb.Exec(createSql).Error; err != nil {
-			if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
-				if myErr.Number != 1050 { /*ER_TABLE_EXISTS_ERROR*/
-					log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
-					s.appendErrorMessage(myErr.Message)
-					return
-				} else {
-					// 获取sql_statement字段类型,用以兼容类型为text的旧表结构
-					longDataType = s.checkBackupTableSqlStmtColumnType(backupDBName)
-				}
-			} else {
-				s.appendErrorMessage(err.Error())
-				return
-			}
-		} else {
-			longDataType = true
+		var err error
+		// 如果备份记录表已存在,则获取sql_statement字段类型,用以兼容类型为text的旧表结构
+		longDataType, err = s.backupSink.CreateInfoTable(backupDBName)
+		if err != nil {
+			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+			s.appendErrorMessage(backupErrorMessage(err))
+			return
 		}
 		s.backupTableCacheList[key] = longDataType
 	}
@@ -399,34 +331,3 @@ func (s *session) mysqlCreateBackupTable(record *Record) (longDataType bool) {
 
 	return
 }
-
-// checkBackupTableSqlStmtColumnType 检查sql_statement字段类型,用以兼容类型为text的旧表结构
-func (s *session) checkBackupTableSqlStmtColumnType(dbname string) (longDataType bool) {
-
-	// 获取sql_statement字段类型,用以兼容类型为text的旧表结构
-	sql := fmt.Sprintf(`select DATA_TYPE from information_schema.columns
-					where table_schema='%s' and table_name='%s' and column_name='sql_statement';`,
-		dbname, remoteBackupTable)
-
-	var res string
-
-	rows, err2 := s.backupdb.DB().Query(sql)
-	if err2 != nil {
-		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err2)
-		if myErr, ok := err2.(*mysqlDriver.MySQLError); ok {
-			s.appendErrorMessage(myErr.Message)
-		} else {
-			s.appendErrorMessage(err2.Error())
-		}
-	}
-	if rows != nil {
-		defer rows.Close()
-		for rows.Next() {
-			rows.Scan(&res)
-		}
-		return res != "text"
-	}
-
-	return
-
-}
@@ -23,6 +23,7 @@ import (
 	"github.com/hanchuanchuan/inception-core/format"
 	"github.com/hanchuanchuan/inception-core/model"
 	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/parser"
 	"github.com/hanchuanchuan/inception-core/parser/opcode"
 	"github.com/hanchuanchuan/inception-core/sessionctx/variable"
 	"github.com/hanchuanchuan/inception-core/types"
@@ -100,8 +101,6 @@ func (s *session) ExecuteInc(ctx context.Context, sql string) (recordSets []sqle
 }
 
 func (s *session) executeInc(ctx context.Context, sql string) (recordSets []sqlexec.RecordSet, err error) {
-	sqlList := strings.Split(sql, "\n")
-
 	// tidb执行的SQL关闭general日志
 	logging := s.inc.GeneralLog
 
@@ -136,9 +135,6 @@ func (s *session) executeInc(ctx context.Context, sql string) (recordSets []sqle
 
 	charsetInfo, collation := s.sessionVars.GetCharsetInfo()
 
-	lineCount := len(sqlList) - 1
-	// batchSize := 1
-
 	tmp := s.processInfo.Load()
 	if tmp != nil {
 		pi := tmp.(util.ProcessInfo)
@@ -149,277 +145,245 @@ func (s *session) executeInc(ctx context.Context, sql string) (recordSets []sqle
 
 	s.stage = StageCheck
 
-	var buf []string
+	segments, err := parser.Split(sql)
+	if err != nil {
+		log.Errorf("con:%d 解析失败! %s", connID, err)
+		s.appendParseError(strings.TrimSpace(sql), Position{}, err)
+		return s.makeResult()
+	}
 
-	quotaIsDouble := true
-	for i, sql_line := range sqlList {
+	for i := range segments {
+		seg := &segments[i]
 
-		// 100行解析一次
-		// 如果以分号结尾,或者是最后一行,就做解析
-		// strings.HasSuffix(sql_line, ";")
-		// && batchSize >= 100)
+		stmtNodes, err := s.ParseSQL(ctx, seg.Text, charsetInfo, collation)
 
-		if strings.Count(sql_line, "'")%2 == 1 {
-			quotaIsDouble = !quotaIsDouble
+		if err == nil && len(stmtNodes) == 0 {
+			// 未成功解析时，添加异常判断
+			err = errors.New("解析失败! 可能是解析器bug,请联系作者.")
 		}
 
-		if ((strings.HasSuffix(sql_line, ";") || strings.HasSuffix(sql_line, ";\r")) &&
-			quotaIsDouble) || i == lineCount {
-			// batchSize = 1
-			buf = append(buf, sql_line)
-			s1 := strings.Join(buf, "\n")
-
-			s1 = strings.TrimRight(s1, ";")
+		if err != nil {
+			log.Errorf("con:%d 解析失败! %s", connID, err)
+			log.Error(seg.Text)
+			s1 := seg.Text
+			// 移除config配置信息/*user=...*/
+			if !s.haveBegin && strings.Contains(s1, "*/") {
+				s1 = strings.TrimSpace(s1[strings.Index(s1, "*/")+2:])
+			}
+			pos, _ := segmentPosition(seg, 0, s1)
+			s.appendParseError(s1, pos, err)
+			return s.makeResult()
+		}
+
+		offset := 0
+		for _, stmtNode := range stmtNodes {
+			//  是ASCII码160的特殊空格
+			currentSql := strings.Trim(stmtNode.Text(), " ;\t\n\v\f\r ")
+
+			s.myRecord = &Record{
+				Sql:   currentSql,
+				Buf:   new(bytes.Buffer),
+				Type:  stmtNode,
+				Stage: StageCheck,
+			}
+			s.myRecord.Position, offset = segmentPosition(seg, offset, currentSql)
+
+			switch stmtNode.(type) {
+			case *ast.InceptionStartStmt:
+				if s.haveBegin {
+					s.appendErrorNo(ER_HAVE_BEGIN)
+
+					if strings.Contains(currentSql, "*/") {
+						currentSql = currentSql[strings.Index(currentSql, "*/")+2:]
+					}
+					s.myRecord.Sql = currentSql
 
-			stmtNodes, err := s.ParseSQL(ctx, s1, charsetInfo, collation)
+					if s.opt != nil && s.opt.Print {
+						s.printSets.Append(2, currentSql, "", s.getErrorMessage(ER_HAVE_BEGIN))
+					} else if s.opt != nil && s.opt.Split {
+						s.addNewSplitNode()
+						s.splitSets.Append(currentSql, s.getErrorMessage(ER_HAVE_BEGIN))
+					} else {
+						s.recordSets.Append(s.myRecord)
+					}
 
-			if err == nil && len(stmtNodes) == 0 {
-				tmpSQL := strings.TrimSpace(s1)
-				// 未成功解析时，添加异常判断
-				if tmpSQL != "" &&
-					!strings.HasPrefix(tmpSQL, "#") &&
-					!strings.HasPrefix(tmpSQL, "--") &&
-					!strings.HasPrefix(tmpSQL, "/*") {
-					err = errors.New("解析失败! 可能是解析器bug,请联系作者.")
+					log.Errorf("con:%d %v", s.sessionVars.ConnectionID, sql)
+					return s.makeResult()
 				}
-			}
 
-			if err != nil {
-				log.Errorf("con:%d 解析失败! %s", connID, err)
-				log.Error(s1)
-				// 移除config配置信息/*user=...*/
-				if !s.haveBegin && strings.Contains(s1, "*/") {
-					s1 = s1[strings.Index(s1, "*/")+2:]
-				}
-				if s.opt != nil && s.opt.Print {
-					s.printSets.Append(2, strings.TrimSpace(s1), "", err.Error())
-				} else if s.opt != nil && s.opt.Split {
-					s.addNewSplitNode()
-					s.splitSets.Append(strings.TrimSpace(s1), err.Error())
-				} else {
-					s.recordSets.Append(&Record{
-						Sql:          strings.TrimSpace(s1),
-						ErrLevel:     2,
-						ErrorMessage: err.Error(),
-					})
-				}
-				return s.makeResult()
-			}
+				// // 操作前重设上下文
+				// if err := executor.ResetContextOfStmt(s, stmtNode); err != nil {
+				// 	return nil, errors.Trace(err)
+				// }
 
-			for i, stmtNode := range stmtNodes {
-				//  是ASCII码160的特殊空格
-				currentSql := strings.Trim(stmtNode.Text(), " ;\t\n\v\f\r ")
+				s.haveBegin = true
+				s.parseOptions(currentSql)
 
-				s.myRecord = &Record{
-					Sql:   currentSql,
-					Buf:   new(bytes.Buffer),
-					Type:  stmtNode,
-					Stage: StageCheck,
+				if s.db != nil {
+					defer s.db.Close()
+				}
+				if s.ddlDB != nil {
+					defer s.ddlDB.Close()
+				}
+				if s.backupdb != nil {
+					defer s.backupdb.Close()
 				}
 
-				switch stmtNode.(type) {
-				case *ast.InceptionStartStmt:
-					if s.haveBegin {
-						s.appendErrorNo(ER_HAVE_BEGIN)
-
-						if strings.Contains(currentSql, "*/") {
-							currentSql = currentSql[strings.Index(currentSql, "*/")+2:]
-						}
-						s.myRecord.Sql = currentSql
-
-						if s.opt != nil && s.opt.Print {
-							s.printSets.Append(2, currentSql, "", s.getErrorMessage(ER_HAVE_BEGIN))
-						} else if s.opt != nil && s.opt.Split {
-							s.addNewSplitNode()
-							s.splitSets.Append(currentSql, s.getErrorMessage(ER_HAVE_BEGIN))
-						} else {
-							s.recordSets.Append(s.myRecord)
-						}
+				if s.opt.Print {
+					s.printSets = NewPrintSets()
+				} else if s.opt.Split {
+					s.splitSets = NewSplitSets()
+				}
 
-						log.Errorf("con:%d %v", s.sessionVars.ConnectionID, sql)
-						return s.makeResult()
+				if s.myRecord.ErrLevel == 2 {
+					if strings.Contains(currentSql, "*/") {
+						currentSql = currentSql[strings.Index(currentSql, "*/")+2:]
 					}
+					s.myRecord.Sql = currentSql
 
-					// // 操作前重设上下文
-					// if err := executor.ResetContextOfStmt(s, stmtNode); err != nil {
-					// 	return nil, errors.Trace(err)
-					// }
-
-					s.haveBegin = true
-					s.parseOptions(currentSql)
-
-					if s.db != nil {
-						defer s.db.Close()
-					}
-					if s.ddlDB != nil {
-						defer s.ddlDB.Close()
-					}
-					if s.backupdb != nil {
-						defer s.backupdb.Close()
+					if s.opt != nil && s.opt.Print {
+						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else if s.opt != nil && s.opt.Split {
+						s.addNewSplitNode()
+						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else {
+						s.recordSets.Append(s.myRecord)
 					}
+					return s.makeResult()
+				}
 
-					if s.opt.Print {
-						s.printSets = NewPrintSets()
-					} else if s.opt.Split {
-						s.splitSets = NewSplitSets()
-					}
+				// sql指纹设置取并集
+				if s.opt.Fingerprint {
+					s.inc.EnableFingerprint = true
+				}
 
-					if s.myRecord.ErrLevel == 2 {
-						if strings.Contains(currentSql, "*/") {
-							currentSql = currentSql[strings.Index(currentSql, "*/")+2:]
-						}
-						s.myRecord.Sql = currentSql
+				if s.inc.EnableFingerprint {
+					s.sqlFingerprint = make(map[string]*Record, 64)
+				}
 
-						if s.opt != nil && s.opt.Print {
-							s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else if s.opt != nil && s.opt.Split {
-							s.addNewSplitNode()
-							s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else {
-							s.recordSets.Append(s.myRecord)
-						}
-						return s.makeResult()
-					}
+				continue
+			case *ast.InceptionCommitStmt:
 
-					// sql指纹设置取并集
-					if s.opt.Fingerprint {
-						s.inc.EnableFingerprint = true
+				if !s.haveBegin {
+					s.appendErrorMessage("Must start as begin statement.")
+					if s.opt != nil && s.opt.Print {
+						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else if s.opt != nil && s.opt.Split {
+						s.addNewSplitNode()
+						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else {
+						s.recordSets.Append(s.myRecord)
 					}
+					return s.makeResult()
+				}
 
-					if s.inc.EnableFingerprint {
-						s.sqlFingerprint = make(map[string]*Record, 64)
-					}
+				s.haveCommit = true
+				s.executeCommit(ctx)
+				return s.makeResult()
+			default:
+				// // TiDB原生执行器
+				// if !s.haveBegin {
+				// 	istidb, isFlush := s.isRunToTiDB(stmtNode)
+				// 	if istidb {
+				// 		r, err := s.execute(ctx, currentSql)
+				// 		if isFlush {
+				// 			// 权限模块的SQL在执行后自动刷新
+				// 			s.execute(ctx, "FLUSH PRIVILEGES")
+				// 		}
+				// 		logging = false
+				// 		return r, err
+				// 	}
+				// }
 
-					continue
-				case *ast.InceptionCommitStmt:
-
-					if !s.haveBegin {
-						s.appendErrorMessage("Must start as begin statement.")
-						if s.opt != nil && s.opt.Print {
-							s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else if s.opt != nil && s.opt.Split {
-							s.addNewSplitNode()
-							s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else {
-							s.recordSets.Append(s.myRecord)
-						}
-						return s.makeResult()
-					}
+				need := s.needDataSource(stmtNode)
 
-					s.haveCommit = true
-					s.executeCommit(ctx)
+				if !s.haveBegin && need {
+					log.Warnf("%#v", stmtNode)
+					s.appendErrorMessage("Must start as begin statement.")
+					if s.opt != nil && s.opt.Print {
+						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else if s.opt != nil && s.opt.Split {
+						s.addNewSplitNode()
+						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+					} else {
+						s.recordSets.Append(s.myRecord)
+					}
 					return s.makeResult()
-				default:
-					// // TiDB原生执行器
-					// if !s.haveBegin {
-					// 	istidb, isFlush := s.isRunToTiDB(stmtNode)
-					// 	if istidb {
-					// 		r, err := s.execute(ctx, currentSql)
-					// 		if isFlush {
-					// 			// 权限模块的SQL在执行后自动刷新
-					// 			s.execute(ctx, "FLUSH PRIVILEGES")
-					// 		}
-					// 		logging = false
-					// 		return r, err
-					// 	}
-					// }
+				}
 
-					need := s.needDataSource(stmtNode)
+				s.SetMyProcessInfo(currentSql, time.Now(), float64(i)/float64(len(segments)))
 
-					if !s.haveBegin && need {
-						log.Warnf("%#v", stmtNode)
-						s.appendErrorMessage("Must start as begin statement.")
-						if s.opt != nil && s.opt.Print {
-							s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else if s.opt != nil && s.opt.Split {
-							s.addNewSplitNode()
-							s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else {
-							s.recordSets.Append(s.myRecord)
-						}
-						return s.makeResult()
+				// 交互式命令行
+				if _, ok := stmtNode.(*ast.InceptionSetStmt); !need &&
+					(!ok || (ok && !s.haveBegin)) {
+					if s.opt != nil {
+						return nil, errors.New("无效操作!不支持本地操作和远程操作混用!")
 					}
 
-					s.SetMyProcessInfo(currentSql, time.Now(), float64(i)/float64(lineCount+1))
-
-					// 交互式命令行
-					if _, ok := stmtNode.(*ast.InceptionSetStmt); !need &&
-						(!ok || (ok && !s.haveBegin)) {
-						if s.opt != nil {
-							return nil, errors.New("无效操作!不支持本地操作和远程操作混用!")
-						}
-
-						// // 操作前重设上下文
-						// if err := executor.ResetContextOfStmt(s, stmtNode); err != nil {
-						// 	return nil, errors.Trace(err)
-						// }
+					// // 操作前重设上下文
+					// if err := executor.ResetContextOfStmt(s, stmtNode); err != nil {
+					// 	return nil, errors.Trace(err)
+					// }
 
-						return s.processCommand(ctx, stmtNode, currentSql)
+					return s.processCommand(ctx, stmtNode, currentSql)
+				} else {
+					var result []sqlexec.RecordSet
+					var err error
+					if s.opt != nil && s.opt.Print {
+						result, err = s.printCommand(ctx, stmtNode, currentSql)
+					} else if s.opt != nil && s.opt.Split {
+						result, err = s.splitCommand(ctx, stmtNode, currentSql)
 					} else {
-						var result []sqlexec.RecordSet
-						var err error
-						if s.opt != nil && s.opt.Print {
-							result, err = s.printCommand(ctx, stmtNode, currentSql)
-						} else if s.opt != nil && s.opt.Split {
-							result, err = s.splitCommand(ctx, stmtNode, currentSql)
-						} else {
-							result, err = s.processCommand(ctx, stmtNode, currentSql)
-						}
-						if err != nil {
-							return nil, err
-						}
-						if result != nil {
-							return result, nil
-						}
+						result, err = s.processCommand(ctx, stmtNode, currentSql)
 					}
-
-					// 进程Killed
-					if err := checkClose(ctx); err != nil {
-						log.Warn("Killed: ", err)
-						s.appendErrorMessage("Operation has been killed!")
-						if s.opt != nil && s.opt.Print {
-							s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else if s.opt != nil && s.opt.Split {
-							s.addNewSplitNode()
-							s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
-						} else {
-							s.recordSets.Append(s.myRecord)
-						}
-						return s.makeResult()
+					if err != nil {
+						return nil, err
+					}
+					if result != nil {
+						return result, nil
 					}
 				}
 
-				if !s.haveBegin && s.needDataSource(stmtNode) {
-					log.Warnf("%#v", stmtNode)
-					s.appendErrorMessage("Must start as begin statement.")
+				// 进程Killed
+				if err := checkClose(ctx); err != nil {
+					log.Warn("Killed: ", err)
+					s.appendErrorMessage("Operation has been killed!")
 					if s.opt != nil && s.opt.Print {
-						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
+						s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
 					} else if s.opt != nil && s.opt.Split {
 						s.addNewSplitNode()
-						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()))
+						s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
 					} else {
 						s.recordSets.Append(s.myRecord)
 					}
 					return s.makeResult()
 				}
+			}
 
+			if !s.haveBegin && s.needDataSource(stmtNode) {
+				log.Warnf("%#v", stmtNode)
+				s.appendErrorMessage("Must start as begin statement.")
 				if s.opt != nil && s.opt.Print {
-					// s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
+					s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
+				} else if s.opt != nil && s.opt.Split {
+					s.addNewSplitNode()
+					s.splitSets.Append("", strings.TrimSpace(s.myRecord.Buf.String()), s.myRecord.Findings...)
 				} else {
-					// 远程操作时隐藏本地的set命令
-					if _, ok := stmtNode.(*ast.InceptionSetStmt); ok && s.myRecord.ErrLevel == 0 {
-						log.Info(currentSql)
-					} else {
-						s.recordSets.Append(s.myRecord)
-					}
+					s.recordSets.Append(s.myRecord)
 				}
+				return s.makeResult()
 			}
 
-			buf = nil
-
-		} else if i < lineCount {
-			buf = append(buf, sql_line)
-			// batchSize++
+			if s.opt != nil && s.opt.Print {
+				// s.printSets.Append(2, "", "", strings.TrimSpace(s.myRecord.Buf.String()))
+			} else {
+				// 远程操作时隐藏本地的set命令
+				if _, ok := stmtNode.(*ast.InceptionSetStmt); ok && s.myRecord.ErrLevel == 0 {
+					log.Info(currentSql)
+				} else {
+					s.recordSets.Append(s.myRecord)
+				}
+			}
 		}
 	}
 
@@ -434,6 +398,7 @@ func (s *session) executeInc(ctx context.Context, sql string) (recordSets []sqle
 				Sql:          "",
 				ErrLevel:     2,
 				ErrorMessage: "Must end with commit.",
+				Findings:     []Finding{{Level: 2, Message: "Must end with commit."}},
 			})
 		}
 	}
@@ -528,6 +493,8 @@ func (s *session) processCommand(ctx context.Context, stmtNode ast.StmtNode,
 	currentSql string) ([]sqlexec.RecordSet, error) {
 	log.Debug("processCommand")
 
+	s.cteTables = nil
+
 	switch node := stmtNode.(type) {
 	case *ast.InsertStmt:
 		s.checkInsert(node, currentSql)
@@ -584,14 +551,35 @@ func (s *session) processCommand(ctx context.Context, stmtNode ast.StmtNode,
 		s.checkTruncateTable(node, currentSql)
 
 	case *ast.CreateIndexStmt:
-		s.checkCreateIndex(node.Table, node.IndexName,
-			node.IndexColNames, node.IndexOption, nil, node.Unique, ast.ConstraintIndex)
+		s.checkCreateIndexStmt(node)
 
 	case *ast.DropIndexStmt:
 		s.checkDropIndex(node, currentSql)
 
 	case *ast.CreateViewStmt:
-		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建视图'%s'.", node.ViewName.Name))
+		s.checkCreateView(node, currentSql)
+
+	case *ast.CreateRoutineStmt:
+		s.checkCreateRoutine(node, currentSql)
+	case *ast.CreateTriggerStmt:
+		s.checkCreateTrigger(node, currentSql)
+	case *ast.CreateEventStmt:
+		s.checkCreateEvent(node, currentSql)
+	case *ast.DropRoutineStmt:
+		s.checkDropRoutine(node, currentSql)
+
+	case *ast.CreateUserStmt:
+		s.checkCreateUser(node, currentSql)
+	case *ast.AlterUserStmt:
+		s.checkAlterUser(node, currentSql)
+	case *ast.SetPwdStmt:
+		s.checkSetPwd(node, currentSql)
+	case *ast.DropUserStmt:
+		s.checkDropUser(node, currentSql)
+	case *ast.GrantStmt:
+		s.checkGrant(node, currentSql)
+	case *ast.RevokeStmt:
+		s.checkRevoke(node, currentSql)
 
 	case *ast.ShowStmt:
 		if node.IsInception {
@@ -647,6 +635,8 @@ func (s *session) processCommand(ctx context.Context, stmtNode ast.StmtNode,
 		s.appendErrorNo(ER_NOT_SUPPORTED_YET)
 	}
 
+	s.checkCustomRules(stmtNode)
+
 	s.mysqlComputeSqlSha1(s.myRecord)
 
 	return nil, nil
@@ -706,26 +696,24 @@ func (s *session) executeCommit(ctx context.Context) {
 
 	if s.opt.Backup {
 
-		// 保存统计信息
-		if s.inc.EnableSqlStatistic {
+		// 保存统计信息,统计信息保存在备份库中
+		if s.inc.EnableSqlStatistic && s.backupdb != nil {
 			s.sqlStatisticsSave()
 		}
 
 		// 如果连接已断开
-		if err := s.backupdb.DB().Ping(); err != nil {
-			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
-			addr := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=%s&parseTime=True&loc=Local&autocommit=1",
-				s.inc.BackupUser, s.inc.BackupPassword, s.inc.BackupHost, s.inc.BackupPort,
-				s.inc.DefaultCharset)
-			db, err := gorm.Open("mysql", addr)
-			if err != nil {
+		if s.backupdb != nil {
+			if err := s.backupdb.DB().Ping(); err != nil {
 				log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
-				s.appendErrorMessage(err.Error())
-				return
+				sink, err := NewBackupSink(s.inc)
+				if err != nil {
+					log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+					s.appendErrorMessage(err.Error())
+					return
+				}
+				s.backupSink = sink
+				s.backupdb = sink.(*mysqlBackupSink).db
 			}
-			// 禁用日志记录器，不显示任何日志
-			db.LogMode(false)
-			s.backupdb = db
 		}
 
 		log.Debug("开始备份")
@@ -769,6 +757,11 @@ func (s *session) executeCommit(ctx context.Context) {
 		} else if s.opt.ParseHost != "" && s.opt.ParsePort != 0 {
 			s.parserBinlog(ctx)
 		}
+
+		if err := s.backupSink.Flush(); err != nil {
+			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+			s.appendErrorMessage(err.Error())
+		}
 	}
 }
 
@@ -810,6 +803,17 @@ func (s *session) checkSqlIsDDL(record *Record) bool {
 		*ast.DropTableStmt,
 		*ast.RenameTableStmt,
 		*ast.TruncateTableStmt,
+		*ast.CreateViewStmt,
+		*ast.CreateRoutineStmt,
+		*ast.CreateTriggerStmt,
+		*ast.CreateEventStmt,
+		*ast.DropRoutineStmt,
+		*ast.CreateUserStmt,
+		*ast.AlterUserStmt,
+		*ast.SetPwdStmt,
+		*ast.DropUserStmt,
+		*ast.GrantStmt,
+		*ast.RevokeStmt,
 
 		// *ast.CreateDatabaseStmt,
 		// *ast.DropDatabaseStmt,
@@ -848,6 +852,21 @@ func (s *session) executeAllStatement(ctx context.Context) {
 		trans = make([]*Record, 0, s.opt.TranBatch)
 	}
 
+	// DML限流
+	th := s.newThrottler()
+	if th != nil {
+		defer th.close()
+	}
+
+	// 执行事务前检查限流,终止时事务中的语句均标记为执行失败
+	executeTrans := func() bool {
+		if th != nil && !th.wait(ctx, trans...) {
+			return false
+		}
+		s.executeTransaction(trans)
+		return true
+	}
+
 	// 用于事务. 判断是否为DML语句
 	// lastIsDMLTrans := false
 	for i, record := range s.recordSets.All() {
@@ -867,7 +886,9 @@ func (s *session) executeAllStatement(ctx context.Context) {
 				if len(trans) < s.opt.TranBatch {
 					trans = append(trans, record)
 				} else {
-					s.executeTransaction(trans)
+					if !executeTrans() {
+						break
+					}
 					trans = nil
 					trans = append(trans, record)
 
@@ -901,7 +922,9 @@ func (s *session) executeAllStatement(ctx context.Context) {
 				if len(trans) < s.opt.TranBatch {
 					trans = append(trans, record)
 				} else {
-					s.executeTransaction(trans)
+					if !executeTrans() {
+						break
+					}
 
 					trans = nil
 					trans = append(trans, record)
@@ -909,7 +932,9 @@ func (s *session) executeAllStatement(ctx context.Context) {
 
 			default:
 				if len(trans) > 0 {
-					s.executeTransaction(trans)
+					if !executeTrans() {
+						break
+					}
 					trans = nil
 				}
 
@@ -935,6 +960,9 @@ func (s *session) executeAllStatement(ctx context.Context) {
 				}
 			}
 		} else {
+			if th != nil && !th.wait(ctx, record) {
+				break
+			}
 			s.executeRemoteCommand(record, false)
 		}
 
@@ -962,7 +990,7 @@ func (s *session) executeAllStatement(ctx context.Context) {
 	}
 
 	if !s.hasErrorBefore() && s.opt.TranBatch > 1 && len(trans) > 0 {
-		s.executeTransaction(trans)
+		executeTrans()
 	}
 	trans = nil
 }
@@ -977,18 +1005,14 @@ func mysqlSleep(ms int) {
 		ms = 100000
 	}
 
-	for end := time.Now().Add(time.Duration(ms) * time.Millisecond); time.Now().Before(end); {
-	}
-
-	return
-
-	// time.Sleep(time.Duration(ms) * time.Millisecond)
+	time.Sleep(time.Duration(ms) * time.Millisecond)
 }
 
 func (s *session) executeTransaction(records []*Record) int {
 	if records == nil {
 		return 2
 	}
+	defer s.notify(EventExecuted, records...)
 
 	// for _, record := range records {
 	// 	log.Info("sql: ", record.Sql)
@@ -1171,6 +1195,17 @@ func (s *session) executeRemoteCommand(record *Record, isTran bool) int {
 		*ast.DropTableStmt,
 		*ast.RenameTableStmt,
 		*ast.TruncateTableStmt,
+		*ast.CreateViewStmt,
+		*ast.CreateRoutineStmt,
+		*ast.CreateTriggerStmt,
+		*ast.CreateEventStmt,
+		*ast.DropRoutineStmt,
+		*ast.CreateUserStmt,
+		*ast.AlterUserStmt,
+		*ast.SetPwdStmt,
+		*ast.DropUserStmt,
+		*ast.GrantStmt,
+		*ast.RevokeStmt,
 
 		*ast.CreateIndexStmt,
 		*ast.SetStmt,
@@ -1183,6 +1218,7 @@ func (s *session) executeRemoteCommand(record *Record, isTran bool) int {
 		s.appendErrorNo(ER_NOT_SUPPORTED_YET)
 	}
 
+	s.notify(EventExecuted, record)
 	return int(record.ErrLevel)
 }
 
@@ -1231,7 +1267,8 @@ func (s *session) sqlStatisticsIncrement(record *Record) {
 
 			// case ast.AlterTableDropForeignKey:
 
-			case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+			case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn,
+				ast.AlterTableRenameColumn:
 				s.statistics.changecolumn++
 
 			case ast.AlterTableRenameTable:
@@ -1253,7 +1290,10 @@ func (s *session) sqlStatisticsIncrement(record *Record) {
 
 			case ast.AlterTableLock,
 				ast.AlterTableAlgorithm,
-				ast.AlterTableForce:
+				ast.AlterTableForce,
+				ast.AlterTableIndexInvisible,
+				ast.AlterTableDropCheck,
+				ast.AlterTableAlterCheck:
 				s.statistics.alteroption++
 			}
 
@@ -1392,6 +1432,11 @@ func (s *session) executeRemoteStatement(record *Record, isTran bool) {
 
 	start := time.Now()
 
+	if !s.checkDDLBlockers(record) {
+		record.StageStatus = StatusExecFail
+		return
+	}
+
 	if record.UseOsc {
 		if s.ghost.GhostOn {
 			log.Infof("con:%d use gh-ost", s.sessionVars.ConnectionID)
@@ -1411,7 +1456,9 @@ func (s *session) executeRemoteStatement(record *Record, isTran bool) {
 	} else {
 		var res sql.Result
 		var err error
-		if isTran {
+		if s.useDDLLockRetry(record) {
+			res, err = s.execDDLWithLockRetry(record, isTran)
+		} else if isTran {
 			res, err = s.execDDL(sqlStmt, false)
 		} else {
 			res, err = s.exec(sqlStmt, false)
@@ -1655,27 +1702,7 @@ func (s *session) mysqlServerVersion() {
 
 			switch name {
 			case "version":
-				if strings.Contains(strings.ToLower(value), "mariadb") {
-					s.dbType = DBTypeMariaDB
-				} else if strings.Contains(strings.ToLower(value), "tidb") {
-					s.dbType = DBTypeTiDB
-				} else {
-					s.dbType = DBTypeMysql
-				}
-
-				versionStr := strings.Split(value, "-")[0]
-				versionSeg := strings.Split(versionStr, ".")
-				if len(versionSeg) == 3 {
-					versionStr = fmt.Sprintf("%s%02s%02s", versionSeg[0], versionSeg[1], versionSeg[2])
-					version, err := strconv.Atoi(versionStr)
-					if err != nil {
-						s.appendErrorMessage(err.Error())
-					}
-					s.dbVersion = version
-				} else {
-					s.appendErrorMessage(fmt.Sprintf("无法解析版本号:%s", value))
-				}
-				log.Debug("db version: ", s.dbVersion)
+				s.setDBVersion(value)
 			case "innodb_large_prefix":
 				emptyInnodbLargePrefix = false
 				s.innodbLargePrefix = (value == "ON" || value == "1")
@@ -1715,6 +1742,42 @@ func (s *session) mysqlServerVersion() {
 
 }
 
+// setDBVersion 根据版本号解析数据库类型和版本
+func (s *session) setDBVersion(value string) {
+	if strings.Contains(strings.ToLower(value), "mariadb") {
+		s.dbType = DBTypeMariaDB
+	} else if strings.Contains(strings.ToLower(value), "tidb") {
+		s.dbType = DBTypeTiDB
+	} else {
+		s.dbType = DBTypeMysql
+	}
+
+	versionStr := strings.Split(value, "-")[0]
+	versionSeg := strings.Split(versionStr, ".")
+	if len(versionSeg) == 3 {
+		versionStr = fmt.Sprintf("%s%02s%02s", versionSeg[0], versionSeg[1], versionSeg[2])
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			s.appendErrorMessage(err.Error())
+		}
+		s.dbVersion = version
+	} else {
+		s.appendErrorMessage(fmt.Sprintf("无法解析版本号:%s", value))
+	}
+	log.Debug("db version: ", s.dbVersion)
+}
+
+// checkFeatureVersion 校验MySQL 8.0新语法是否被当前数据库版本支持
+// 仅在已知MySQL版本时校验,离线审核及MariaDB/TiDB时跳过
+func (s *session) checkFeatureVersion(feature string, version int) bool {
+	if s.dbType != DBTypeMysql || s.dbVersion == 0 || s.dbVersion >= version {
+		return true
+	}
+	s.appendErrorNo(ErrFeatureRequireVersion, feature,
+		fmt.Sprintf("%d.%d.%d", version/10000, version/100%100, version%100))
+	return false
+}
+
 func (s *session) fetchThreadID() uint32 {
 
 	if s.threadID > 0 {
@@ -1992,6 +2055,8 @@ func (s *session) parseOptions(sql string) {
 
 		// 开启事务功能，设置一次提交多少记录
 		TranBatch: viper.GetInt("trans"),
+
+		Profile: viper.GetString("profile"),
 	}
 
 	if s.opt.Split || s.opt.Check || s.opt.Print {
@@ -2160,7 +2225,28 @@ func (s *session) checkDropTable(node *ast.DropTableStmt, sql string) {
 				if !node.IfExists {
 					s.appendErrorNo(ER_TABLE_NOT_EXISTED_ERROR, fmt.Sprintf("%s.%s", t.Schema, t.Name))
 				}
+			} else if node.IsView != table.IsView {
+				// DROP VIEW只能删除视图,DROP TABLE只能删除表
+				if node.IsView {
+					s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrWrongObject],
+						table.Schema, table.Name, "VIEW"))
+				} else {
+					s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrWrongObject],
+						table.Schema, table.Name, "BASE TABLE"))
+				}
 			} else {
+				if node.IsView {
+					if s.opt.Execute {
+						// 生成回滚语句
+						if res := s.mysqlShowCreateView(table); res != "" {
+							s.myRecord.DDLRollback = res + ";"
+						}
+					}
+					s.myRecord.TableInfo = table
+					s.myRecord.TableInfo.IsDeleted = true
+					continue
+				}
+
 				if s.opt.Execute {
 					// 生成回滚语句
 					s.mysqlShowCreateTable(table)
@@ -2184,6 +2270,179 @@ func (s *session) checkDropTable(node *ast.DropTableStmt, sql string) {
 	}
 }
 
+// checkCreateView 审核创建视图
+func (s *session) checkCreateView(node *ast.CreateViewStmt, sql string) {
+
+	log.Debug("checkCreateView")
+
+	if !s.inc.EnableCreateView {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建视图'%s'.", node.ViewName.Name))
+		return
+	}
+
+	if node.ViewName.Schema.O == "" {
+		node.ViewName.Schema = model.NewCIStr(s.dbName)
+	}
+
+	if !s.checkDBExists(node.ViewName.Schema.O, true) {
+		return
+	}
+
+	s.checkKeyWords(node.ViewName.Name.O)
+	s.checkNamePattern(ErrViewNamePattern, node.ViewName.Name.O, "", "")
+	if s.myRecord.ErrLevel == 2 {
+		return
+	}
+
+	s.myRecord.DBName = node.ViewName.Schema.O
+	s.myRecord.TableName = node.ViewName.Name.O
+
+	origin := s.getTableFromCache(node.ViewName.Schema.O, node.ViewName.Name.O, false)
+	if origin != nil && !node.OrReplace {
+		s.appendErrorNo(ER_TABLE_EXISTS_ERROR, node.ViewName.Name.O)
+		return
+	}
+	// CREATE OR REPLACE VIEW不能替换同名的表
+	if origin != nil && !origin.IsView {
+		s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrWrongObject],
+			origin.Schema, origin.Name, "VIEW"))
+		return
+	}
+
+	if node.Security != model.SecurityInvoker {
+		s.appendErrorNo(ErrViewSecurityInvoker, node.ViewName.Name.O)
+	}
+	if node.Algorithm == model.AlgorithmTemptable {
+		s.appendErrorNo(ErrViewAlgorithmTemptable, node.ViewName.Name.O)
+	}
+
+	hasWhere := false
+	if sel, ok := node.Select.(*ast.SelectStmt); ok {
+		hasWhere = sel.Where != nil
+		for _, f := range sel.Fields.Fields {
+			if f.WildCard != nil {
+				s.appendErrorNo(ErrViewSelectStar, node.ViewName.Name.O)
+				break
+			}
+		}
+	}
+
+	resultSet, _ := node.Select.(ast.ResultSetNode)
+	s.checkSelectItem(resultSet, hasWhere)
+	if s.myRecord.ErrLevel == 2 {
+		return
+	}
+
+	table := s.buildViewInfo(node, resultSet)
+	if table == nil {
+		return
+	}
+
+	if s.opt.Execute {
+		// 生成回滚语句
+		if origin == nil || origin.IsNew {
+			s.myRecord.DDLRollback = fmt.Sprintf("DROP VIEW `%s`.`%s`;", table.Schema, table.Name)
+		} else if res := s.mysqlShowCreateView(origin); res != "" {
+			s.myRecord.DDLRollback = "CREATE OR REPLACE " +
+				strings.TrimPrefix(res, "CREATE ") + ";"
+		}
+	}
+
+	s.cacheNewTable(table)
+	s.myRecord.TableInfo = table
+}
+
+// buildViewInfo 根据视图的列定义或SELECT的列生成视图结构
+func (s *session) buildViewInfo(node *ast.CreateViewStmt, sel ast.ResultSetNode) *TableInfo {
+	cols := s.getViewSelectColumns(sel)
+
+	if len(node.Cols) > 0 {
+		if len(node.Cols) != len(cols) {
+			s.appendErrorNo(ErrViewColumnCount, node.ViewName.Name.O)
+			return nil
+		}
+		cols = cols[:0]
+		for _, c := range node.Cols {
+			cols = append(cols, c.O)
+		}
+	}
+
+	checkDup := map[string]bool{}
+	fields := make([]FieldInfo, len(cols))
+	for i, c := range cols {
+		if _, ok := checkDup[strings.ToLower(c)]; ok {
+			s.appendErrorNo(ER_DUP_FIELDNAME, c)
+		}
+		checkDup[strings.ToLower(c)] = true
+		fields[i].Field = c
+	}
+
+	return &TableInfo{
+		Schema: node.ViewName.Schema.O,
+		Name:   node.ViewName.Name.O,
+		Fields: fields,
+		IsView: true,
+	}
+}
+
+// getViewSelectColumns 获取视图SELECT的列名,表达式列以其文本作为列名
+func (s *session) getViewSelectColumns(node ast.ResultSetNode) []string {
+	switch sel := node.(type) {
+	case *ast.UnionStmt:
+		return s.getViewSelectColumns(sel.SelectList.Selects[0])
+	case *ast.SelectStmt:
+		var columns []string
+		for _, f := range sel.Fields.Fields {
+			if f.WildCard != nil {
+				star := &ast.SelectStmt{
+					From:   sel.From,
+					Fields: &ast.FieldList{Fields: []*ast.SelectField{f}},
+				}
+				columns = append(columns, s.getSubSelectColumns(star)...)
+			} else if f.AsName.L != "" {
+				columns = append(columns, f.AsName.O)
+			} else if e, ok := f.Expr.(*ast.ColumnNameExpr); ok {
+				columns = append(columns, e.Name.Name.O)
+			} else {
+				columns = append(columns, f.Text())
+			}
+		}
+		return columns
+	}
+	return nil
+}
+
+// mysqlShowCreateView 获取视图的创建语句
+func (s *session) mysqlShowCreateView(t *TableInfo) string {
+
+	if t.IsNew {
+		return ""
+	}
+
+	sql := fmt.Sprintf("SHOW CREATE VIEW `%s`.`%s`;", t.Schema, t.Name)
+
+	var name, res, charset, collation string
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
+			s.appendErrorMessage(myErr.Message)
+		} else {
+			s.appendErrorMessage(err.Error())
+		}
+	} else if rows != nil {
+		for rows.Next() {
+			rows.Scan(&name, &res, &charset, &collation)
+		}
+	}
+	return res
+}
+
 // mysqlShowTableStatus 获取表估计的受影响行数
 func (s *session) mysqlShowTableStatus(t *TableInfo) {
 
@@ -2191,13 +2450,23 @@ func (s *session) mysqlShowTableStatus(t *TableInfo) {
 		return
 	}
 
+	if s.isOffline() {
+		if st := s.getSnapshotTable(t.Schema, t.Name); st != nil {
+			s.myRecord.AffectedRows = int(st.TableRows)
+			t.Collation = st.Collation
+			t.RowFormat = st.RowFormat
+		}
+		return
+	}
+
 	// sql := fmt.Sprintf("show table status from `%s` where name = '%s';", dbname, tableName)
-	sql := fmt.Sprintf(`select TABLE_ROWS,TABLE_COLLATION from information_schema.tables
+	sql := fmt.Sprintf(`select TABLE_ROWS,TABLE_COLLATION,IFNULL(ROW_FORMAT,'') from information_schema.tables
 		where table_schema='%s' and table_name='%s';`, t.Schema, t.Name)
 
 	var (
 		res       uint
 		collation string
+		rowFormat string
 	)
 
 	rows, err := s.raw(sql)
@@ -2213,10 +2482,11 @@ func (s *session) mysqlShowTableStatus(t *TableInfo) {
 		}
 	} else if rows != nil {
 		for rows.Next() {
-			rows.Scan(&res, &collation)
+			rows.Scan(&res, &collation, &rowFormat)
 		}
 		s.myRecord.AffectedRows = int(res)
 		t.Collation = collation
+		t.RowFormat = rowFormat
 	}
 }
 
@@ -2227,6 +2497,13 @@ func (s *session) mysqlForeignKeys(t *TableInfo) (keys []string) {
 		return
 	}
 
+	if s.isOffline() {
+		if st := s.getSnapshotTable(t.Schema, t.Name); st != nil {
+			keys = append(keys, st.ForeignKeys...)
+		}
+		return
+	}
+
 	// sql := fmt.Sprintf("show table status from `%s` where name = '%s';", dbname, tableName)
 	sql := fmt.Sprintf(`SELECT CONSTRAINT_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
 		WHERE TABLE_SCHEMA='%s' AND TABLE_NAME='%s' and ORDINAL_POSITION = 1;`, t.Schema, t.Name)
@@ -2261,6 +2538,13 @@ func (s *session) mysqlGetTableSize(t *TableInfo) {
 		return
 	}
 
+	if s.isOffline() {
+		if st := s.getSnapshotTable(t.Schema, t.Name); st != nil {
+			t.TableSize = st.TableSize
+		}
+		return
+	}
+
 	sql := fmt.Sprintf(`select (DATA_LENGTH + INDEX_LENGTH)/1024/1024 as v
 		from information_schema.tables
 		where table_schema='%s' and table_name='%s';`, t.Schema, t.Name)
@@ -2362,6 +2646,7 @@ func (s *session) checkRenameTable(node *ast.RenameTableStmt, sql string) {
 	}
 
 	s.checkKeyWords(node.NewTable.Schema.O)
+	s.checkNamePattern(ErrTableNamePattern, node.NewTable.Name.O, "", "")
 
 	if s.hasError() {
 		return
@@ -2407,6 +2692,7 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 	}
 
 	s.checkKeyWords(node.Table.Name.O)
+	s.checkNamePattern(ErrTableNamePattern, node.Table.Name.O, "", "")
 	// 如果列名有错误的话,则直接跳出
 	if s.myRecord.ErrLevel == 2 {
 		return
@@ -2500,7 +2786,7 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 					hasPrimary = len(ct.Keys) > 0
 					for _, col := range ct.Keys {
 						for _, field := range node.Cols {
-							if field.Name.Name.L == col.Column.Name.L {
+							if col.Column != nil && field.Name.Name.L == col.Column.Name.L {
 								// 设置主键标志位
 								field.Tp.Flag |= mysql.PriKeyFlag
 								break
@@ -2510,7 +2796,7 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 				case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
 					for _, col := range ct.Keys {
 						for _, field := range node.Cols {
-							if field.Name.Name.L == col.Column.Name.L {
+							if col.Column != nil && field.Name.Name.L == col.Column.Name.L {
 								// 设置唯一键标志位
 								field.Tp.Flag |= mysql.UniqueKeyFlag
 								break
@@ -2710,6 +2996,10 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 					s.checkCreateForeignKey(table, ct)
 					continue
 				}
+				if ct.Tp == ast.ConstraintCheck {
+					s.checkItem(ct.Expr, []*TableInfo{table})
+					continue
+				}
 
 				s.checkCreateIndex(nil, ct.Name,
 					ct.Keys, ct.Option, table, false, ct.Tp)
@@ -2719,7 +3009,7 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 					ast.ConstraintIndex, ast.ConstraintUniqKey,
 					ast.ConstraintUniqIndex:
 					if ct.Name == "" {
-						ct.Name = ct.Keys[0].Column.Name.O
+						ct.Name = indexColumnName(ct.Keys[0])
 					}
 					if _, ok := dupIndexes[strings.ToLower(ct.Name)]; ok {
 						s.appendErrorNo(ER_DUP_KEYNAME, ct.Name)
@@ -2739,6 +3029,13 @@ func (s *session) checkCreateTable(node *ast.CreateTableStmt, sql string) {
 		s.checkColumnsMustHaveindex(table)
 	}
 
+	if s.inc.CheckRedundantIndex {
+		s.checkRedundantIndex(table, nil)
+	}
+
+	s.checkTableLimits(table, nil)
+	s.checkCreatePartition(table)
+
 	if !s.hasError() && s.opt.Execute {
 		s.myRecord.DDLRollback = fmt.Sprintf("DROP TABLE `%s`.`%s`;", table.Schema, table.Name)
 	}
@@ -2890,6 +3187,8 @@ func (s *session) buildTableInfo(node *ast.CreateTableStmt) *TableInfo {
 			character = opt.StrValue
 		case ast.TableOptionCollate:
 			collation = opt.StrValue
+		case ast.TableOptionRowFormat:
+			table.RowFormat = rowFormatName(opt.UintValue)
 		}
 	}
 
@@ -2918,6 +3217,10 @@ func (s *session) buildTableInfo(node *ast.CreateTableStmt) *TableInfo {
 	}
 	table.IsNewColumns = true
 
+	if node.Partition != nil {
+		s.buildPartitionInfo(table, node.Partition)
+	}
+
 	return table
 }
 
@@ -2991,15 +3294,35 @@ func (s *session) checkAlterTable(node *ast.AlterTableStmt, sql string) {
 		}
 	}
 
+	// 分区管理操作不能和其他操作同时使用,直接使用原生DDL
+	isPartitionDDL := len(node.Specs) > 0 && isPartitionSpec(node.Specs[0])
+	if isPartitionDDL {
+		s.myRecord.UseOsc = false
+	}
+
 	s.myRecord.TableInfo = table
 
+	// 变更前的索引,用以检查新增索引是否冗余
+	existingIndexes := indexNameSet(table)
+	// 变更前的表结构,用以检查行大小和索引长度
+	origin := table.copy()
+
+	s.checkAlterAlgorithm(origin, node.Specs)
+	// 可以在线执行的DDL不需要使用pt-osc或gh-ost
+	if s.myRecord.UseOsc && s.osc.OscPreferNativeDDL &&
+		s.myRecord.DDLAlgorithm != nil && s.myRecord.DDLAlgorithm.isOnline() {
+		s.myRecord.UseOsc = false
+	}
+
 	if s.opt.Backup {
 		s.myRecord.DDLRollback += fmt.Sprintf("ALTER TABLE `%s`.`%s` ",
 			table.Schema, table.Name)
 	}
 	s.alterRollbackBuffer = nil
+	s.partitionRollbackBuffer = nil
 
-	if s.inc.MaxDDLAffectRows > 0 && s.myRecord.AffectedRows > int(s.inc.MaxDDLAffectRows) {
+	if !isPartitionDDL && s.inc.MaxDDLAffectRows > 0 &&
+		s.myRecord.AffectedRows > int(s.inc.MaxDDLAffectRows) {
 		s.appendErrorNo(ER_CHANGE_TOO_MUCH_ROWS,
 			"Alter", s.myRecord.AffectedRows, s.inc.MaxDDLAffectRows)
 	}
@@ -3012,6 +3335,10 @@ func (s *session) checkAlterTable(node *ast.AlterTableStmt, sql string) {
 				s.appendErrorNo(ER_NOT_SUPPORTED_YET)
 			}
 			s.checkTableOptions(alter.Options, node.Table.Name.String(), false)
+			if len(alter.Options) > 0 && alter.Options[0].Tp == ast.TableOptionCharset &&
+				alter.Options[0].UintValue == ast.TableOptionCharsetWithConvertTo {
+				s.checkAlterTableConvertCharset(table, alter)
+			}
 		case ast.AlterTableAddColumns:
 			s.checkAddColumn(table, alter)
 		case ast.AlterTableDropColumn:
@@ -3055,8 +3382,37 @@ func (s *session) checkAlterTable(node *ast.AlterTableStmt, sql string) {
 				s.checkAlterTableRenameIndex(table, alter)
 			}
 
+		case ast.AlterTableRenameColumn:
+			if s.checkFeatureVersion("RENAME COLUMN", 80000) {
+				s.checkAlterTableRenameColumn(table, alter)
+			}
+
+		case ast.AlterTableIndexInvisible:
+			if s.checkFeatureVersion("Invisible index", 80000) {
+				s.checkAlterTableIndexVisibility(table, alter)
+			}
+
+		case ast.AlterTableDropCheck, ast.AlterTableAlterCheck:
+			// MySQL 8.0.16开始支持CHECK约束
+			s.checkFeatureVersion("CHECK constraint", 80016)
+
+		case ast.AlterTableAlgorithm:
+			if alter.Algorithm == ast.AlgorithmTypeInstant {
+				s.checkFeatureVersion("ALGORITHM=INSTANT", 80012)
+			}
+
+		case ast.AlterTableAddPartitions:
+			s.checkAddPartition(table, alter)
+		case ast.AlterTableDropPartition, ast.AlterTableTruncatePartition:
+			s.checkDropPartition(table, alter)
+		case ast.AlterTableCoalescePartitions:
+			s.checkCoalescePartition(table, alter)
+		case ast.AlterTableReorganizePartition:
+			s.checkReorganizePartition(table, alter)
+		case ast.AlterTableExchangePartition:
+			s.checkExchangePartition(table, alter)
+
 		case ast.AlterTableLock,
-			ast.AlterTableAlgorithm,
 			ast.AlterTableForce:
 			// 不做校验,允许这些参数
 
@@ -3079,6 +3435,31 @@ func (s *session) checkAlterTable(node *ast.AlterTableStmt, sql string) {
 		s.checkColumnsMustHaveindex(tableCopy)
 	}
 
+	if s.inc.CheckRedundantIndex {
+		tableCopy := s.getTableFromCache(node.Table.Schema.O, node.Table.Name.O, false)
+		s.checkRedundantIndex(tableCopy, existingIndexes)
+	}
+
+	s.checkTableLimits(s.getTableFromCache(node.Table.Schema.O, node.Table.Name.O, false), origin)
+
+	// 分区表的主键和唯一索引必须包含分区列
+	hasUniqueKey := false
+	for _, alter := range node.Specs {
+		if alter.Tp == ast.AlterTableAddConstraint && alter.Constraint != nil {
+			switch alter.Constraint.Tp {
+			case ast.ConstraintPrimaryKey, ast.ConstraintUniq,
+				ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+				hasUniqueKey = true
+			}
+		}
+	}
+	if hasUniqueKey {
+		if tableCopy := s.getTableFromCache(node.Table.Schema.O, node.Table.Name.O, false); tableCopy != nil {
+			s.loadPartitions(tableCopy)
+			s.checkPartitionUniqueKeys(tableCopy)
+		}
+	}
+
 	// 生成alter回滚语句,多个时逆向
 	if !s.hasError() && s.opt.Execute && s.opt.Backup {
 		if hasRenameTable {
@@ -3110,12 +3491,26 @@ func (s *session) checkAlterTable(node *ast.AlterTableStmt, sql string) {
 			}
 		}
 
+		prefix := s.myRecord.DDLRollback
 		s.myRecord.DDLRollback += strings.Join(s.alterRollbackBuffer, "")
 		if strings.HasSuffix(s.myRecord.DDLRollback, ",") {
 			s.myRecord.DDLRollback = strings.TrimSuffix(s.myRecord.DDLRollback, ",") + ";"
 		}
+
+		// 分区操作各自生成一条回滚语句,以换行连接
+		if len(s.partitionRollbackBuffer) > 0 {
+			var rollback []string
+			if len(s.alterRollbackBuffer) > 0 {
+				rollback = append(rollback, s.myRecord.DDLRollback)
+			}
+			for i := len(s.partitionRollbackBuffer) - 1; i >= 0; i-- {
+				rollback = append(rollback, prefix+s.partitionRollbackBuffer[i]+";")
+			}
+			s.myRecord.DDLRollback = strings.Join(rollback, "\n")
+		}
 	}
 	s.alterRollbackBuffer = nil
+	s.partitionRollbackBuffer = nil
 }
 
 func (s *session) checkAlterTableAlterColumn(t *TableInfo, c *ast.AlterTableSpec) {
@@ -3134,6 +3529,17 @@ func (s *session) checkAlterTableAlterColumn(t *TableInfo, c *ast.AlterTableSpec
 
 		if !found {
 			s.appendErrorNo(ER_COLUMN_NOT_EXISTED, fmt.Sprintf("%s.%s", t.Name, nc.Name.Name))
+		} else if len(nc.Options) > 0 && (nc.Options[0].Tp == ast.ColumnOptionVisible ||
+			nc.Options[0].Tp == ast.ColumnOptionInvisible) {
+			// "SET" "VISIBLE" | "INVISIBLE"
+			if s.checkFeatureVersion("Invisible column", 80023) && s.opt.Execute {
+				visibility := "VISIBLE"
+				if nc.Options[0].Tp == ast.ColumnOptionVisible {
+					visibility = "INVISIBLE"
+				}
+				s.alterRollbackBuffer = append(s.alterRollbackBuffer,
+					fmt.Sprintf("ALTER COLUMN `%s` SET %s,", foundField.Field, visibility))
+			}
 		} else {
 			if s.opt.Execute {
 				if foundField.Default == nil {
@@ -3169,6 +3575,42 @@ func (s *session) checkAlterTableAlterColumn(t *TableInfo, c *ast.AlterTableSpec
 	}
 }
 
+// checkAlterTableConvertCharset 转换表字符集,在快照上调整表和字符列的排序规则
+func (s *session) checkAlterTableConvertCharset(t *TableInfo, c *ast.AlterTableSpec) {
+	var character, collation string
+	for _, opt := range c.Options {
+		switch opt.Tp {
+		case ast.TableOptionCharset:
+			character = opt.StrValue
+		case ast.TableOptionCollate:
+			collation = opt.StrValue
+		}
+	}
+
+	if collation == "" {
+		var err error
+		collation, err = charset.GetDefaultCollation(character)
+		if err != nil {
+			s.appendErrorMessage(err.Error())
+			return
+		}
+	} else if !charset.ValidCharsetAndCollation(character, collation) {
+		s.appendErrorMessage("字符集和排序规则不匹配!")
+		return
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	t.Collation = collation
+	for i := range t.Fields {
+		switch strings.ToLower(GetDataTypeBase(t.Fields[i].Type)) {
+		case "char", "varchar", "enum", "set",
+			"tinytext", "text", "mediumtext", "longtext":
+			t.Fields[i].Collation = collation
+		}
+	}
+}
+
 func (s *session) checkAlterTableRenameIndex(t *TableInfo, c *ast.AlterTableSpec) {
 
 	indexName := c.FromKey.String()
@@ -3204,6 +3646,12 @@ func (s *session) checkAlterTableRenameIndex(t *TableInfo, c *ast.AlterTableSpec
 		s.appendErrorNo(ER_DUP_KEYNAME, newIndexName)
 	}
 
+	if foundRows[0].NonUnique == 0 {
+		s.checkNamePattern(ErrUniqIndexNamePattern, newIndexName, t.Name, foundRows[0].ColumnName)
+	} else {
+		s.checkNamePattern(ErrIndexNamePattern, newIndexName, t.Name, foundRows[0].ColumnName)
+	}
+
 	if !s.hasError() {
 		// cache new index
 		for _, index := range foundRows {
@@ -3226,9 +3674,92 @@ func (s *session) checkAlterTableRenameIndex(t *TableInfo, c *ast.AlterTableSpec
 	}
 }
 
+// checkAlterTableRenameColumn 校验RENAME COLUMN语法(MySQL 8.0+)
+func (s *session) checkAlterTableRenameColumn(t *TableInfo, c *ast.AlterTableSpec) {
+	oldName := c.OldColumnName.Name.O
+	newName := c.NewColumnName.Name.O
+
+	foundIndexOld := -1
+	newFound := false
+	for i, field := range t.Fields {
+		if field.IsDeleted {
+			continue
+		}
+		if strings.EqualFold(field.Field, oldName) {
+			foundIndexOld = i
+		}
+		if strings.EqualFold(field.Field, newName) {
+			newFound = true
+		}
+	}
+
+	if foundIndexOld == -1 {
+		s.appendErrorNo(ER_COLUMN_NOT_EXISTED, fmt.Sprintf("%s.%s", t.Name, oldName))
+	}
+	if newFound {
+		s.appendErrorNo(ER_COLUMN_EXISTED, fmt.Sprintf("%s.%s", t.Name, newName))
+	}
+
+	s.checkKeyWords(newName)
+	s.checkNamePattern(ErrColumnNamePattern, newName, t.Name, "")
+
+	if s.hasError() {
+		return
+	}
+
+	// 在新的快照上变更表结构
+	t = s.cacheTableSnapshot(t)
+	t.Fields[foundIndexOld].Field = newName
+	for _, index := range t.Indexes {
+		if strings.EqualFold(index.ColumnName, oldName) {
+			index.ColumnName = newName
+		}
+	}
+
+	// 修改列名后标记有新列
+	t.IsNewColumns = true
+
+	if s.opt.Execute {
+		s.alterRollbackBuffer = append(s.alterRollbackBuffer,
+			fmt.Sprintf("RENAME COLUMN `%s` TO `%s`,", newName, oldName))
+	}
+}
+
+// checkAlterTableIndexVisibility 校验修改索引可见性(MySQL 8.0+)
+func (s *session) checkAlterTableIndexVisibility(t *TableInfo, c *ast.AlterTableSpec) {
+	found := false
+	for _, row := range t.Indexes {
+		if strings.EqualFold(row.IndexName, c.Name) && !row.IsDeleted {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		s.appendErrorNo(ER_CANT_DROP_FIELD_OR_KEY, fmt.Sprintf("%s.%s", t.Name, c.Name))
+		return
+	}
+
+	if strings.EqualFold(c.Name, "PRIMARY") && c.Visibility == ast.IndexVisibilityInvisible {
+		s.appendErrorMessage("A primary key index cannot be invisible")
+		return
+	}
+
+	if s.opt.Execute {
+		visibility := ast.IndexVisibilityVisible
+		if c.Visibility == ast.IndexVisibilityVisible {
+			visibility = ast.IndexVisibilityInvisible
+		}
+		s.alterRollbackBuffer = append(s.alterRollbackBuffer,
+			fmt.Sprintf("ALTER INDEX `%s` %s,", c.Name, visibility))
+	}
+}
+
 func (s *session) checkAlterTableRenameTable(t *TableInfo, c *ast.AlterTableSpec) {
 	// log.Info("checkAlterTableRenameTable")
 
+	s.checkNamePattern(ErrTableNamePattern, c.NewTable.Name.O, "", "")
+
 	table := s.getTableFromCache(c.NewTable.Schema.O, c.NewTable.Name.O, false)
 	if table != nil {
 		s.appendErrorNo(ER_TABLE_EXISTS_ERROR, c.NewTable.Name.O)
@@ -3660,6 +4191,7 @@ func (s *session) mysqlCheckField(t *TableInfo, field *ast.ColumnDef) {
 	}
 
 	s.checkKeyWords(field.Name.Name.O)
+	s.checkNamePattern(ErrColumnNamePattern, field.Name.Name.O, t.Name, "")
 
 	// notNullFlag := mysql.HasNotNullFlag(field.Tp.Flag)
 	// autoIncrement := mysql.HasAutoIncrementFlag(field.Tp.Flag)
@@ -3696,6 +4228,8 @@ func (s *session) mysqlCheckField(t *TableInfo, field *ast.ColumnDef) {
 				isPrimary = true
 			case ast.ColumnOptionGenerated:
 				hasGenerated = true
+			case ast.ColumnOptionInvisible:
+				s.checkFeatureVersion("Invisible column", 80023)
 			case ast.ColumnOptionCollate:
 				if s.inc.EnableColumnCharset {
 					s.checkCollation(op.StrValue)
@@ -3902,6 +4436,18 @@ func (s *session) checkIndexAttr(tp ast.ConstraintType, name string,
 
 	s.checkDuplicateColumnName(keys)
 
+	var column string
+	if len(keys) > 0 {
+		column = indexColumnName(keys[0])
+	}
+	switch tp {
+	case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+		s.checkNamePattern(ErrUniqIndexNamePattern, name, table.Name, column)
+	case ast.ConstraintKey, ast.ConstraintIndex,
+		ast.ConstraintFulltext, ast.ConstraintSpatial:
+		s.checkNamePattern(ErrIndexNamePattern, name, table.Name, column)
+	}
+
 	switch tp {
 	case ast.ConstraintForeignKey:
 		s.appendErrorNo(ER_FOREIGN_KEY, table.Name)
@@ -3945,7 +4491,15 @@ func (s *session) checkCreateForeignKey(t *TableInfo, c *ast.Constraint) {
 		return
 	}
 
+	if len(c.Keys) > 0 {
+		s.checkNamePattern(ErrForeignKeyNamePattern, c.Name, t.Name, indexColumnName(c.Keys[0]))
+	}
+
 	for _, col := range c.Keys {
+		if col.Column == nil {
+			s.appendErrorNo(ER_NOT_SUPPORTED_YET)
+			continue
+		}
 		found := false
 		for _, field := range t.Fields {
 			if strings.EqualFold(field.Field, col.Column.Name.O) {
@@ -3961,6 +4515,10 @@ func (s *session) checkCreateForeignKey(t *TableInfo, c *ast.Constraint) {
 	refTable := s.getTableFromCache(c.Refer.Table.Schema.O, c.Refer.Table.Name.O, true)
 	if refTable != nil {
 		for _, col := range c.Refer.IndexColNames {
+			if col.Column == nil {
+				s.appendErrorNo(ER_NOT_SUPPORTED_YET)
+				continue
+			}
 			found := false
 			for _, field := range refTable.Fields {
 				if strings.EqualFold(field.Field, col.Column.Name.O) {
@@ -4286,6 +4844,24 @@ func (s *session) mysqlDropColumnRollback(field FieldInfo) {
 	s.alterRollbackBuffer = append(s.alterRollbackBuffer, buf.String())
 }
 
+func (s *session) checkCreateIndexStmt(node *ast.CreateIndexStmt) {
+	log.Debug("checkCreateIndexStmt")
+
+	t := s.getTableFromCache(node.Table.Schema.O, node.Table.Name.O, true)
+	if t == nil {
+		return
+	}
+
+	existing := indexNameSet(t)
+
+	s.checkCreateIndex(node.Table, node.IndexName,
+		node.IndexColNames, node.IndexOption, t, node.Unique, ast.ConstraintIndex)
+
+	if s.inc.CheckRedundantIndex {
+		s.checkRedundantIndex(t, existing)
+	}
+}
+
 func (s *session) checkDropIndex(node *ast.DropIndexStmt, sql string) {
 	log.Debug("checkDropIndex")
 
@@ -4325,6 +4901,16 @@ func (s *session) checkCreateIndex(table *ast.TableName, IndexName string,
 	isBlobColumn := false
 	isOverflowIndexLength := false
 	for _, col := range IndexColNames {
+		// 函数索引,仅校验表达式引用的列
+		if col.Column == nil {
+			s.checkFeatureVersion("Functional key part", 80013)
+			if tp == ast.ConstraintPrimaryKey {
+				s.appendErrorMessage("The primary key cannot be a functional index")
+			}
+			s.checkItem(col.Expr, []*TableInfo{t})
+			continue
+		}
+
 		found := false
 		var foundField FieldInfo
 
@@ -4454,6 +5040,13 @@ func (s *session) checkCreateIndex(table *ast.TableName, IndexName string,
 		if len(IndexOption.Comment) > INDEX_COMMENT_MAXLEN {
 			s.appendErrorNo(ER_TOO_LONG_INDEX_COMMENT, IndexName, INDEX_COMMENT_MAXLEN)
 		}
+
+		if IndexOption.Visibility == ast.IndexVisibilityInvisible {
+			s.checkFeatureVersion("Invisible index", 80000)
+			if tp == ast.ConstraintPrimaryKey {
+				s.appendErrorMessage("A primary key index cannot be invisible")
+			}
+		}
 	}
 
 	rows := t.Indexes
@@ -4497,8 +5090,9 @@ func (s *session) checkCreateIndex(table *ast.TableName, IndexName string,
 			// NonUnique:  unique  ,
 			IndexName:  IndexName,
 			Seq:        i + 1,
-			ColumnName: col.Column.Name.O,
+			ColumnName: indexColumnName(col),
 			IndexType:  indexType,
+			SubPart:    indexSubPart(col),
 		}
 		if !unique && (tp == ast.ConstraintPrimaryKey || tp == ast.ConstraintUniq ||
 			tp == ast.ConstraintUniqIndex || tp == ast.ConstraintUniqKey) {
@@ -4526,6 +5120,14 @@ func (s *session) checkCreateIndex(table *ast.TableName, IndexName string,
 	}
 }
 
+// indexColumnName 返回索引列名,函数索引(表达式)时返回空
+func indexColumnName(col *ast.IndexColName) string {
+	if col.Column == nil {
+		return ""
+	}
+	return col.Column.Name.O
+}
+
 func (s *session) checkAddConstraint(t *TableInfo, c *ast.AlterTableSpec) {
 	log.Debug("checkAddConstraint")
 
@@ -4543,6 +5145,10 @@ func (s *session) checkAddConstraint(t *TableInfo, c *ast.AlterTableSpec) {
 			c.Constraint.Keys, c.Constraint.Option, t, true, c.Constraint.Tp)
 	case ast.ConstraintForeignKey:
 		s.checkCreateForeignKey(t, c.Constraint)
+	case ast.ConstraintCheck:
+		if s.checkFeatureVersion("CHECK constraint", 80016) {
+			s.checkItem(c.Constraint.Expr, []*TableInfo{t})
+		}
 	default:
 		s.appendErrorNo(ER_NOT_SUPPORTED_YET)
 		log.Info("con:", s.sessionVars.ConnectionID, " 未定义的解析: ", c.Constraint.Tp)
@@ -4568,6 +5174,21 @@ func (s *session) checkDBExists(db string, reportNotExists bool) bool {
 		return !v.IsDeleted
 	}
 
+	if s.isOffline() {
+		if !s.snapshotDBExists(db) {
+			if reportNotExists {
+				s.appendErrorNo(ER_DB_NOT_EXISTED_ERROR, db)
+			}
+			return false
+		}
+		s.dbCacheList[key] = &DBInfo{
+			Name:      db,
+			IsNew:     false,
+			IsDeleted: false,
+		}
+		return true
+	}
+
 	sql := "show databases like '%s';"
 
 	// count:= s.Exec(fmt.Sprintf(sql,db)).AffectedRows
@@ -4724,6 +5345,12 @@ func (s *session) checkInsert(node *ast.InsertStmt, sql string) {
 			}
 		}
 
+		// INSERT ... WITH ... SELECT,CTE在解析select的表前缓存
+		with := resultSetWith(x.Select)
+		if with != nil {
+			s.checkWithClause(with)
+		}
+
 		if sel != nil {
 
 			// 是否有星号列
@@ -4798,7 +5425,14 @@ func (s *session) checkInsert(node *ast.InsertStmt, sql string) {
 					s.checkSelectItem(x.Select, sel.Where != nil)
 				} else {
 					var selectSql string
-					if table.IsNew || table.IsNewColumns || s.dbVersion < 50600 {
+					if with != nil && (table.IsNew || table.IsNewColumns || s.dbVersion < 50600) {
+						// WITH子句中也包含select,需要还原完整的查询
+						var builder strings.Builder
+						if err := x.Select.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder)); err != nil {
+							log.Error(err)
+						}
+						selectSql = builder.String()
+					} else if table.IsNew || table.IsNewColumns || s.dbVersion < 50600 {
 						i := strings.Index(strings.ToLower(sql), "select")
 						selectSql = sql[i:]
 					} else {
@@ -5189,7 +5823,14 @@ func (s *session) executeInceptionSet(node *ast.InceptionSetStmt, sql string) ([
 
 		if v.IsLevel {
 			if s.haveBegin || s.isAPI {
-				return nil, errors.New("暂不支持会话级的自定义审核级别")
+				if err := s.setSessionLevel(v.Name, value); err != nil {
+					return nil, err
+				}
+				if s.setLevels == nil {
+					s.setLevels = make(map[string]int8)
+				}
+				s.setLevels[strings.ToLower(v.Name)] = int8(s.incLevel[strings.ToLower(v.Name)])
+				continue
 			}
 			err := s.setVariableValue(reflect.TypeOf(cnf.IncLevel), reflect.ValueOf(&cnf.IncLevel).Elem(), v.Name, value)
 			if err != nil {
@@ -5245,6 +5886,12 @@ func (s *session) executeInceptionSet(node *ast.InceptionSetStmt, sql string) ([
 			if err != nil {
 				return nil, err
 			}
+			if !v.IsGlobal {
+				if s.setInc == nil {
+					s.setInc = make(map[string]interface{})
+				}
+				s.setInc[strings.ToLower(v.Name)] = value.GetValue()
+			}
 			if prefix == "lang" {
 				s.inc.Lang = strings.Replace(strings.ToLower(s.inc.Lang), "-", "_", 1)
 			}
@@ -5699,59 +6346,84 @@ func (s *session) executeLocalShowOscProcesslist(node *ast.ShowOscStmt) ([]sqlex
 }
 
 func (s *session) executeLocalOscKill(node *ast.ShowOscStmt) ([]sqlexec.RecordSet, error) {
+	return s.oscCommand(KillOscProcess, node.Sqlsha1)
+}
+
+func (s *session) executeLocalOscPause(node *ast.ShowOscStmt) ([]sqlexec.RecordSet, error) {
+	return s.oscCommand(PauseOscProcess, node.Sqlsha1)
+}
+
+func (s *session) executeLocalOscResume(node *ast.ShowOscStmt) ([]sqlexec.RecordSet, error) {
+	return s.oscCommand(ResumeOscProcess, node.Sqlsha1)
+}
+
+// oscCommand 执行osc进程操作,警告信息写入语句上下文
+func (s *session) oscCommand(fn func(string) (string, error), sqlsha1 string) ([]sqlexec.RecordSet, error) {
+	warn, err := fn(sqlsha1)
+	if err != nil {
+		return nil, err
+	}
+	if warn != "" {
+		s.sessionVars.StmtCtx.AppendWarning(errors.New(warn))
+	}
+	return nil, nil
+}
+
+// KillOscProcess 终止osc进程. 无需操作时(如进程已终止)返回提示信息
+func KillOscProcess(sqlsha1 string) (string, error) {
 	pl := config.GetGlobalConfig().ShowOscProcessList()
 
-	if pi, ok := pl[node.Sqlsha1]; ok {
+	if pi, ok := pl[sqlsha1]; ok {
 		if pi.Killed {
-			s.sessionVars.StmtCtx.AppendWarning(errors.New("osc process has been aborted"))
-		} else {
-			pi.Killed = true
+			return "osc process has been aborted", nil
 		}
+		pi.Killed = true
 	} else {
-		return nil, errors.New("osc process not found")
+		return "", ErrOscNotFound
 	}
 
-	return nil, nil
+	return "", nil
 }
 
-func (s *session) executeLocalOscPause(node *ast.ShowOscStmt) ([]sqlexec.RecordSet, error) {
+// PauseOscProcess 暂停osc进程,仅支持gh-ost. 进程已暂停时返回提示信息
+func PauseOscProcess(sqlsha1 string) (string, error) {
 	pl := config.GetGlobalConfig().ShowOscProcessList()
 
-	if pi, ok := pl[node.Sqlsha1]; ok {
+	if pi, ok := pl[sqlsha1]; ok {
 		if !pi.IsGhost {
-			return nil, errors.New("pt-osc process not support pause")
+			return "", errors.New("pt-osc process not support pause")
 		}
 
 		if pi.Pause {
-			s.sessionVars.StmtCtx.AppendWarning(errors.New("osc process has been paused"))
-		} else {
-			pi.Pause = true
+			return "osc process has been paused", nil
 		}
+		pi.Pause = true
 	} else {
-		return nil, errors.New("osc process not found")
+		return "", ErrOscNotFound
 	}
 
-	return nil, nil
+	return "", nil
 }
 
-func (s *session) executeLocalOscResume(node *ast.ShowOscStmt) ([]sqlexec.RecordSet, error) {
+// ResumeOscProcess 恢复暂停的osc进程,仅支持gh-ost. 进程未暂停时返回提示信息
+func ResumeOscProcess(sqlsha1 string) (string, error) {
 	pl := config.GetGlobalConfig().ShowOscProcessList()
 
-	if pi, ok := pl[node.Sqlsha1]; ok {
+	if pi, ok := pl[sqlsha1]; ok {
 		if !pi.IsGhost {
-			return nil, errors.New("pt-osc process not support resume")
+			return "", errors.New("pt-osc process not support resume")
 		}
 
 		if pi.Pause {
 			pi.Pause = false
 		} else {
-			s.sessionVars.StmtCtx.AppendWarning(errors.New("osc process not paused"))
+			return "osc process not paused", nil
 		}
 	} else {
-		return nil, errors.New("osc process not found")
+		return "", ErrOscNotFound
 	}
 
-	return nil, nil
+	return "", nil
 }
 
 func (s *session) executeInceptionShow(sql string) ([]sqlexec.RecordSet, error) {
@@ -5825,6 +6497,7 @@ func (s *session) checkCreateDB(node *ast.CreateDatabaseStmt, sql string) {
 		}
 	} else {
 		s.checkKeyWords(node.Name)
+		s.checkNamePattern(ErrDatabaseNamePattern, node.Name, "", "")
 
 		for _, opt := range node.Options {
 			switch opt.Tp {
@@ -5903,13 +6576,20 @@ func (s *session) checkChangeDB(node *ast.UseStmt, sql string) {
 
 	s.dbName = node.DBName
 
+	// 按库名匹配的配置集随use切换
+	if name := s.matchProfile(node.DBName); name != s.profile {
+		if err := s.loadProfile(name); err != nil {
+			s.appendErrorMessage(err.Error())
+		}
+	}
+
 	// 新建库跳过use 切换
 	if s.checkDBExists(node.DBName, true) {
 		key := node.DBName
 		if s.IgnoreCase() {
 			key = strings.ToLower(key)
 		}
-		if v, ok := s.dbCacheList[key]; ok && !v.IsNew {
+		if v, ok := s.dbCacheList[key]; ok && !v.IsNew && !s.isOffline() {
 			_, err := s.exec(fmt.Sprintf("USE `%s`", node.DBName), true)
 			if err != nil {
 				log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
@@ -6118,6 +6798,11 @@ func (s *session) explainOrAnalyzeSql(sql string) {
 	// 	return
 	// }
 
+	// 离线审核时无法获取执行计划,跳过受影响行数的预估
+	if s.isOffline() {
+		return
+	}
+
 	sqlId, ok := s.checkFingerprint(sql)
 	if ok {
 		return
@@ -6191,6 +6876,10 @@ func (s *session) anlyzeExplain(rows []ExplainInfo) {
 func (s *session) checkUpdate(node *ast.UpdateStmt, sql string) {
 	log.Debug("checkUpdate")
 
+	if node.With != nil {
+		s.checkWithClause(node.With)
+	}
+
 	// 从set列表读取要更新的表
 	var originTable string
 	var firstColumnName string
@@ -6436,6 +7125,22 @@ func (s *session) checkItem(expr ast.ExprNode, tables []*TableInfo) bool {
 	case *ast.AggregateFuncExpr:
 		return s.checkAggregateFuncItem(e, tables)
 
+	case *ast.WindowFuncExpr:
+		s.checkFeatureVersion("Window function", 80000)
+		for _, arg := range e.Args {
+			s.checkItem(arg, tables)
+		}
+		if e.Spec.PartitionBy != nil {
+			for _, item := range e.Spec.PartitionBy.Items {
+				s.checkItem(item.Expr, tables)
+			}
+		}
+		if e.Spec.OrderBy != nil {
+			for _, item := range e.Spec.OrderBy.Items {
+				s.checkItem(item.Expr, tables)
+			}
+		}
+
 	case *ast.PatternInExpr:
 		s.checkItem(e.Expr, tables)
 		for _, expr := range e.List {
@@ -6609,6 +7314,10 @@ func (s *session) checkAggregateFuncItem(f *ast.AggregateFuncExpr, tables []*Tab
 func (s *session) checkDelete(node *ast.DeleteStmt, sql string) {
 	log.Debug("checkDelete")
 
+	if node.With != nil {
+		s.checkWithClause(node.With)
+	}
+
 	// sqlId, ok := s.checkFingerprint(sql)
 	// if ok {
 	// 	return
@@ -6713,6 +7422,9 @@ func (s *session) queryTableFromDB(db string, tableName string, reportNotExists
 	if db == "" {
 		db = s.dbName
 	}
+	if s.isOffline() {
+		return s.queryTableFromSnapshot(db, tableName, reportNotExists)
+	}
 	var rows []FieldInfo
 	sql := fmt.Sprintf("SHOW FULL FIELDS FROM `%s`.`%s`", db, tableName)
 
@@ -6732,10 +7444,40 @@ func (s *session) queryTableFromDB(db string, tableName string, reportNotExists
 	return rows
 }
 
+// queryTableIsView 根据information_schema.TABLES.TABLE_TYPE判断是否为视图
+func (s *session) queryTableIsView(db string, tableName string) bool {
+	if s.isOffline() {
+		if t := s.getSnapshotTable(db, tableName); t != nil {
+			return t.IsView
+		}
+		return false
+	}
+
+	sql := fmt.Sprintf(`select TABLE_TYPE from information_schema.tables
+		where table_schema='%s' and table_name='%s';`, db, tableName)
+
+	var tableType string
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		return false
+	}
+	for rows.Next() {
+		rows.Scan(&tableType)
+	}
+	return tableType == "VIEW"
+}
+
 func (s *session) queryIndexFromDB(db string, tableName string, reportNotExists bool) []*IndexInfo {
 	if db == "" {
 		db = s.dbName
 	}
+	if s.isOffline() {
+		return s.queryIndexFromSnapshot(db, tableName)
+	}
 	var rows []*IndexInfo
 	sql := fmt.Sprintf("SHOW INDEX FROM `%s`.`%s`", db, tableName)
 
@@ -6802,12 +7544,10 @@ func (s *session) appendErrorNo(number ErrorCode, values ...interface{}) {
 		} else if s.stage == StageExec {
 			r.Buf.WriteString("Execute: ")
 		}
-		if len(values) == 0 {
-			r.Buf.WriteString(s.getErrorMessage(number))
-		} else {
-			r.Buf.WriteString(fmt.Sprintf(s.getErrorMessage(number), values...))
-		}
+		f := newFinding(s.inc.Lang, number, level, values...)
+		r.Buf.WriteString(f.Message)
 		r.Buf.WriteString("\n")
+		r.Findings = append(r.Findings, f)
 	}
 }
 
@@ -6827,6 +7567,53 @@ func (s *session) checkKeyWords(name string) {
 	}
 }
 
+// checkNamePattern 按正则表达式校验对象命名规范
+// 规则中的{table}和{column}占位符分别替换为表名和首列列名,规则须匹配完整的名称
+func (s *session) checkNamePattern(number ErrorCode, name, table, column string) {
+	var pattern string
+	switch number {
+	case ErrDatabaseNamePattern:
+		pattern = s.inc.DatabaseNamePattern
+	case ErrTableNamePattern:
+		pattern = s.inc.TableNamePattern
+	case ErrColumnNamePattern:
+		pattern = s.inc.ColumnNamePattern
+	case ErrIndexNamePattern:
+		pattern = s.inc.IndexNamePattern
+	case ErrUniqIndexNamePattern:
+		pattern = s.inc.UniqIndexNamePattern
+	case ErrForeignKeyNamePattern:
+		pattern = s.inc.ForeignKeyNamePattern
+	case ErrViewNamePattern:
+		pattern = s.inc.ViewNamePattern
+	}
+
+	if pattern == "" || name == "" {
+		return
+	}
+
+	expr := strings.NewReplacer("{table}", regexp.QuoteMeta(table),
+		"{column}", regexp.QuoteMeta(column)).Replace(pattern)
+
+	re, ok := s.namePatterns[expr]
+	if !ok {
+		var err error
+		re, err = regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			s.appendErrorMessage(fmt.Sprintf("无效的命名规范'%s': %v", pattern, err))
+			return
+		}
+		if s.namePatterns == nil {
+			s.namePatterns = make(map[string]*regexp.Regexp)
+		}
+		s.namePatterns[expr] = re
+	}
+
+	if !re.MatchString(name) {
+		s.appendErrorNo(number, name, pattern)
+	}
+}
+
 func (s *session) checkInceptionVariables(number ErrorCode) bool {
 	switch number {
 	case ER_WITH_INSERT_FIELD:
@@ -6993,6 +7780,13 @@ func extractTableList(node ast.ResultSetNode, input []*ast.TableSource) []*ast.T
 }
 
 func (s *session) getTableFromCache(db string, tableName string, reportNotExists bool) *TableInfo {
+	if s.cteTables != nil && (db == "" || strings.EqualFold(db, s.dbName)) {
+		if t, ok := s.cteTables[strings.ToLower(tableName)]; ok {
+			t.AsName = ""
+			return t
+		}
+	}
+
 	if db == "" {
 		db = s.dbName
 	}
@@ -7032,6 +7826,7 @@ func (s *session) getTableFromCache(db string, tableName string, reportNotExists
 			if rows := s.queryIndexFromDB(db, tableName, reportNotExists); rows != nil {
 				newT.Indexes = rows
 			}
+			newT.IsView = s.queryTableIsView(db, tableName)
 			s.tableCacheList[key] = newT
 
 			return newT
@@ -7132,16 +7927,31 @@ func (s *session) buildNewColumnToCache(t *TableInfo, field *ast.ColumnDef) *Fie
 			field.Tp.Flag |= mysql.OnUpdateNowFlag
 		case ast.ColumnOptionCollate:
 			c.Collation = op.StrValue
+		case ast.ColumnOptionInvisible:
+			if c.Extra != "" {
+				c.Extra += " "
+			}
+			c.Extra += "INVISIBLE"
 		}
 	}
 
-	if c.Collation == "" && t.Collation != "" {
+	if c.Collation == "" {
 		// 字符串类型才需要排序规则
 		switch strings.ToLower(GetDataTypeBase(c.Type)) {
 		case "char", "binary", "varchar", "varbinary", "enum", "set",
 			"geometry", "point", "linestring", "polygon",
 			"tinytext", "text", "mediumtext", "longtext":
-			c.Collation = t.Collation
+			// 未指定COLLATE时,优先取列级字符集的默认排序规则
+			if field.Tp.Collate != "" {
+				c.Collation = field.Tp.Collate
+			} else if field.Tp.Charset != "" {
+				if co, err := charset.GetDefaultCollation(field.Tp.Charset); err == nil {
+					c.Collation = co
+				}
+			}
+			if c.Collation == "" {
+				c.Collation = t.Collation
+			}
 		}
 	}
 
@@ -7164,6 +7974,10 @@ func (s *session) checkSelectItem(node ast.ResultSetNode, hasWhere bool) []*Tabl
 
 	switch x := node.(type) {
 	case *ast.UnionStmt:
+		if x.With != nil {
+			s.checkWithClause(x.With)
+		}
+
 		stmt := x.SelectList
 		for _, sel := range stmt.Selects[:len(stmt.Selects)-1] {
 			if sel.Limit != nil {
@@ -7252,9 +8066,74 @@ func (s *session) checkSelectItem(node ast.ResultSetNode, hasWhere bool) []*Tabl
 	return nil
 }
 
+// checkWithClause 审核WITH子句,并将CTE缓存为临时表结构,以便后续引用时解析列
+func (s *session) checkWithClause(with *ast.WithClause) {
+	s.checkFeatureVersion("WITH clause", 80000)
+
+	if s.cteTables == nil {
+		s.cteTables = make(map[string]*TableInfo, len(with.CTEs))
+	}
+
+	// 同一WITH子句中的CTE不能重名,子查询中的CTE可以覆盖外层的同名CTE
+	names := make(map[string]bool, len(with.CTEs))
+	for _, cte := range with.CTEs {
+		key := cte.Name.L
+		if names[key] {
+			s.appendErrorNo(ErrNonUniqTable, cte.Name.O)
+			continue
+		}
+		names[key] = true
+
+		// 列名取自列名列表,未指定时取自首个SELECT(递归CTE的初始查询)
+		cols := s.getSubSelectColumns(cte.Query.Query)
+		if len(cte.ColNameList) > 0 {
+			if len(cols) > 0 && len(cols) != len(cte.ColNameList) {
+				s.appendErrorMessage("In definition of view, derived table or common table expression, " +
+					"SELECT list and column names list have different column counts")
+			}
+			cols = cols[:0]
+			for _, col := range cte.ColNameList {
+				cols = append(cols, col.O)
+			}
+		}
+
+		t := &TableInfo{
+			Name:   cte.Name.O,
+			Fields: make([]FieldInfo, len(cols)),
+		}
+		for i, col := range cols {
+			t.Fields[i].Field = col
+		}
+
+		// 递归CTE可在自身定义中引用,需先缓存
+		if with.IsRecursive {
+			s.cteTables[key] = t
+		}
+
+		s.checkSelectItem(cte.Query.Query, false)
+
+		s.cteTables[key] = t
+	}
+}
+
+// resultSetWith 返回查询的WITH子句,没有时返回nil
+func resultSetWith(node ast.ResultSetNode) *ast.WithClause {
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		return x.With
+	case *ast.UnionStmt:
+		return x.With
+	}
+	return nil
+}
+
 func (s *session) checkSubSelectItem(node *ast.SelectStmt) []*TableInfo {
 	log.Debug("checkSubSelectItem")
 
+	if node.With != nil {
+		s.checkWithClause(node.With)
+	}
+
 	var tableList []*ast.TableSource
 	if node.From != nil {
 		// 递归审核子查询
@@ -7446,7 +8325,8 @@ func (s *session) checkFingerprint(sql string) (string, bool) {
 			}
 			msg := record.Buf.String()
 			if msg != "" {
-				s.myRecord.appendErrorMessage(strings.TrimSpace(msg))
+				s.myRecord.writeMessage(strings.TrimSpace(msg))
+				s.myRecord.Findings = append(s.myRecord.Findings, record.Findings...)
 				// 可能是警告,也可能是错误
 				s.myRecord.ErrLevel = record.ErrLevel
 			}
@@ -157,6 +157,19 @@ func (s *testSessionIncExecSuite) testExecuteResult(c *C, sql string, errors ...
 	}
 }
 
+// runExecute 执行并返回结果,用以检查Record的其他字段
+func (s *testSessionIncExecSuite) runExecute(c *C, sql string) []session.Record {
+	s.sessionService.LoadOptions(session.SourceOptions{
+		Host:     s.defaultInc.BackupHost,
+		Port:     int(s.defaultInc.BackupPort),
+		User:     s.defaultInc.BackupUser,
+		Password: s.defaultInc.BackupPassword,
+	})
+	result, err := s.sessionService.RunExecute(context.Background(), s.useDB+sql)
+	c.Assert(err, IsNil)
+	return result
+}
+
 func (s *testSessionIncExecSuite) TestCreateTable(c *C) {
 
 	sql := ""
@@ -861,6 +874,95 @@ func (s *testSessionIncExecSuite) TestAlterTableGhost(c *C) {
 	s.testErrorCode(c, sql)
 }
 
+func (s *testSessionIncExecSuite) TestDDLBlocker(c *C) {
+	saved := config.GetGlobalConfig().Inc
+	defer func() {
+		config.GetGlobalConfig().Inc = saved
+	}()
+
+	config.GetGlobalConfig().Inc.CheckColumnComment = false
+	config.GetGlobalConfig().Inc.CheckTableComment = false
+	config.GetGlobalConfig().Inc.EnableDropTable = true
+	config.GetGlobalConfig().Inc.DDLLongTrxTime = 0
+
+	sql := "drop table if exists t1;create table t1(id int auto_increment primary key,c1 int);"
+	s.mustRunExec(c, sql)
+
+	// performance_schema.metadata_locks在MySQL 8.0默认开启
+	if s.DBVersion < 80000 {
+		return
+	}
+
+	// 在另一个连接中开启事务,持有t1的元数据锁
+	tx := s.db.Begin()
+	c.Assert(tx.Error, IsNil)
+	defer tx.Rollback()
+	c.Assert(tx.Exec("select * from test_inc.t1").Error, IsNil)
+	var threadID int64
+	c.Assert(tx.Raw("select connection_id()").Row().Scan(&threadID), IsNil)
+
+	config.GetGlobalConfig().Inc.DDLBlockerAction = "refuse"
+	result := s.runExecute(c, "alter table t1 add column c2 int;")
+	row := result[len(result)-1]
+	c.Assert(row.ErrLevel, Equals, uint8(2))
+	c.Assert(row.StageStatus, Equals, session.StatusExecFail)
+	c.Assert(strings.Contains(row.ErrorMessage, "DDL on table 't1' is blocked by 1 transaction(s)"),
+		IsTrue, Commentf("%v", row.ErrorMessage))
+	c.Assert(len(row.DDLBlockers), Equals, 1)
+	c.Assert(row.DDLBlockers[0].ThreadID, Equals, threadID)
+	c.Assert(row.DDLBlockers[0].Source, Equals, session.BlockerMetadataLock)
+
+	// 锁等待超时后重试,仍超时则执行失败
+	config.GetGlobalConfig().Inc.DDLBlockerAction = "retry"
+	config.GetGlobalConfig().Inc.DDLLockWaitTimeout = 1
+	config.GetGlobalConfig().Inc.DDLLockRetryCount = 1
+	result = s.runExecute(c, "alter table t1 add column c2 int;")
+	row = result[len(result)-1]
+	c.Assert(row.ErrLevel, Equals, uint8(2))
+	c.Assert(strings.Contains(row.ErrorMessage, "Lock wait timeout exceeded"),
+		IsTrue, Commentf("%v", row.ErrorMessage))
+	c.Assert(len(row.DDLBlockers), Equals, 1)
+	c.Assert(row.DDLBlockers[0].ThreadID, Equals, threadID)
+
+	c.Assert(tx.Rollback().Error, IsNil)
+	result = s.runExecute(c, "alter table t1 add column c2 int;")
+	row = result[len(result)-1]
+	c.Assert(row.StageStatus, Equals, session.StatusExecOK, Commentf("%v", row.ErrorMessage))
+	c.Assert(row.DDLBlockers, IsNil)
+}
+
+func (s *testSessionIncExecSuite) TestThrottle(c *C) {
+	saved := config.GetGlobalConfig().Inc
+	defer func() {
+		config.GetGlobalConfig().Inc = saved
+	}()
+
+	config.GetGlobalConfig().Inc.CheckColumnComment = false
+	config.GetGlobalConfig().Inc.CheckTableComment = false
+	config.GetGlobalConfig().Inc.EnableDropTable = true
+
+	sql := "drop table if exists t1;create table t1(id int auto_increment primary key,c1 int);"
+	s.mustRunExec(c, sql)
+
+	config.GetGlobalConfig().Inc.ThrottleMaxLag = 5
+	config.GetGlobalConfig().Inc.ThrottleCheckInterval = 100
+
+	// 延迟超过阈值的一半时减慢执行
+	config.GetGlobalConfig().Inc.ThrottleLagQuery = "select 3"
+	sql = "insert into t1(c1) values(1);insert into t1(c1) values(2);"
+	s.testErrorCode(c, sql)
+
+	// 延迟超过阈值时暂停执行,超过最长暂停时间后终止执行
+	config.GetGlobalConfig().Inc.ThrottleLagQuery = "select 10"
+	config.GetGlobalConfig().Inc.ThrottleMaxPauseTime = 1
+	result := s.runExecute(c, "insert into t1(c1) values(3);")
+	row := result[len(result)-1]
+	c.Assert(row.ErrLevel, Equals, uint8(2))
+	c.Assert(row.StageStatus, Equals, session.StatusExecFail)
+	c.Assert(row.ErrorMessage, Equals,
+		"Execute: Execution aborted by throttler: replication lag 10.0s > 5.0s.")
+}
+
 // TestDisplayWidth 测试列指定长度参数
 func (s *testSessionIncExecSuite) TestDisplayWidth(c *C) {
 	sql := ""
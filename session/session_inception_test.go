@@ -2609,8 +2609,13 @@ func (s *testSessionIncSuite) TestGetAlterTablePostPart(c *C) {
 		},
 		{
 			"alter table tb_archery default character set utf8 collate utf8_bin;",
-			"CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN",
-			"CONVERT TO CHARACTER SET UTF8 COLLATE UTF8_BIN",
+			"DEFAULT CHARACTER SET = UTF8, DEFAULT COLLATE = UTF8_BIN",
+			"DEFAULT CHARACTER SET = UTF8, DEFAULT COLLATE = UTF8_BIN",
+		},
+		{
+			"alter table tb_archery convert to character set utf8mb4;",
+			"CONVERT TO CHARACTER SET UTF8MB4",
+			"CONVERT TO CHARACTER SET UTF8MB4",
 		},
 		{
 			"alter table tb_archery collate      = utf8_bin;",
@@ -2693,9 +2698,18 @@ func (s *testSessionIncSuite) TestSetSessionVariables(c *C) {
 		session.NewErrf("[variable:1231]Variable 'check_table_comment' can't be set to the value of '123'."),
 		session.NewErr(session.ER_TABLE_MUST_HAVE_COMMENT, "t1"))
 
-	sql = `inception set level er_table_must_have_comment = 2;`
-	s.testManyErrors(c, sql,
-		session.NewErrf("暂不支持会话级的自定义审核级别."))
+	// 会话级审核级别,不影响全局配置
+	sql = `inception set level er_table_must_have_comment = 0;
+	create table t1(id int primary key);`
+	s.testErrorCode(c, sql)
+
+	sql = `inception set level er_table_must_have_comment = 1;
+	create table t1(id int primary key);`
+	result, err := s.sessionService.Audit(context.Background(), s.useDB+sql)
+	c.Assert(err, IsNil)
+	row := result[len(result)-1]
+	c.Assert(row.ErrLevel, Equals, uint8(1))
+	c.Assert(row.ErrorMessage, Equals, session.NewErr(session.ER_TABLE_MUST_HAVE_COMMENT, "t1").Error())
 
 	sql = `inception set global check_table_comment = 1;`
 	s.testManyErrors(c, sql,
@@ -2789,3 +2803,186 @@ func (s *testSessionIncSuite) TestWhereCondition(c *C) {
 	`
 	s.testManyErrors(c, sql)
 }
+
+func (s *testOfflineSuite) TestMySQL80Syntax(c *C) {
+	snapshot := `{
+		"Version": "8.0.23",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t1",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "c1", "Type": "varchar(20)", "Null": "NO"},
+				{"Field": "c2", "Type": "int(11)", "Null": "NO"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"},
+				{"IndexName": "ix_c1", "Seq": 1, "ColumnName": "c1", "IndexType": "BTREE"}
+			]
+		}]
+	}`
+
+	sqls := `use test_inc;
+	with cte(a, b) as (select id, c1 from t1) select a from cte where b = 'a';
+	with recursive cte as (select id from t1 union all select id + 1 from cte where id < 10) select c9 from cte;
+	select id, row_number() over (partition by c1 order by id) as rn from t1 where id > 0;
+	alter table t1 rename column c2 to c3;
+	alter table t1 alter index ix_c1 invisible;
+	alter table t1 add index ix_lower((lower(c1)));
+	alter table t1 add constraint chk_c2 check (c3 > 0);
+	alter table t1 drop check chk_c2, algorithm = instant;
+	create table t2(id int primary key comment 'id',
+		c1 int not null default 0 invisible comment 'c1' check (c1 >= 0),
+		constraint chk_id check (id > 0)) comment 't2';`
+	result := s.audit(c, snapshot, sqls)
+	c.Assert(len(result), Equals, 10)
+	for i, r := range result {
+		if i == 2 {
+			continue
+		}
+		c.Assert(r.ErrLevel, Equals, uint8(0), Commentf("%d: %v", i, r.ErrorMessage))
+	}
+	c.Assert(result[2].ErrLevel, Equals, uint8(2))
+	c.Assert(result[2].ErrorMessage, Equals, "Column 'c9' not existed.")
+
+	// 低版本时提示需要的MySQL版本
+	snapshot = `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		c1 varchar(20) not null default '' comment 'c1',
+		c2 int not null default 0 comment 'c2',
+		key ix_c1(c1)) comment 't1';`
+	result = s.audit(c, snapshot, sqls)
+	c.Assert(len(result), Equals, 10)
+	messages := []string{
+		"WITH clause requires MySQL 8.0.0 or later.",
+		"(?s)WITH clause requires MySQL 8.0.0 or later.*Column 'c9' not existed.",
+		"Window function requires MySQL 8.0.0 or later.",
+		"RENAME COLUMN requires MySQL 8.0.0 or later.",
+		"Invisible index requires MySQL 8.0.0 or later.",
+		"Functional key part requires MySQL 8.0.13 or later.",
+		"(?s).*CHECK constraint requires MySQL 8.0.16 or later.*",
+		"(?s).*ALGORITHM=INSTANT requires MySQL 8.0.12 or later.*",
+		"Invisible column requires MySQL 8.0.23 or later.",
+	}
+	for i, msg := range messages {
+		c.Assert(result[i+1].ErrLevel, Equals, uint8(2))
+		c.Assert(result[i+1].ErrorMessage, Matches, msg)
+	}
+}
+
+func (s *testOfflineSuite) TestWithClause(c *C) {
+	snapshot := `{
+		"Version": "8.0.23",
+		"Databases": ["test_inc"],
+		"Tables": [{
+			"Schema": "test_inc",
+			"Name": "t1",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "c1", "Type": "varchar(20)", "Null": "NO"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			]
+		}, {
+			"Schema": "test_inc",
+			"Name": "t2",
+			"Fields": [
+				{"Field": "id", "Type": "int(11)", "Null": "NO", "Key": "PRI"},
+				{"Field": "c1", "Type": "varchar(20)", "Null": "NO"}
+			],
+			"Indexes": [
+				{"IndexName": "PRIMARY", "Seq": 1, "ColumnName": "id", "IndexType": "BTREE"}
+			]
+		}]
+	}`
+
+	sqls := `use test_inc;
+	insert into t2(id, c1) with cte(a, b) as (select id, c1 from t1) select a, b from cte where a > 0;
+	with cte as (select id from t1 where c1 = 'a') update t2 join cte on t2.id = cte.id set t2.c1 = 'b' where t2.id > 0;
+	with cte as (select id from t1 where c1 = 'a') delete t2 from t2 join cte on t2.id = cte.id where t2.id > 0;
+	select id from (with cte as (select id, c1 from t1) select id from cte where c1 = 'a') dt where id > 0;
+	select id from t1 where id in (with cte as (select id from t2) select id from cte);
+	with cte as (select id from t1) update t2 join cte on t2.id = cte.id set t2.c1 = 'b' where cte.c9 > 0;
+	with cte as (select 1), cte as (select 2) delete from t2 where id in (select 1 from cte);`
+	result := s.audit(c, snapshot, sqls)
+	c.Assert(len(result), Equals, 8)
+	for i, r := range result[:6] {
+		c.Assert(r.ErrLevel, Equals, uint8(0), Commentf("%d: %v", i, r.ErrorMessage))
+	}
+	c.Assert(result[6].ErrLevel, Equals, uint8(2))
+	c.Assert(result[6].ErrorMessage, Equals, "Column 'cte.c9' not existed.")
+	c.Assert(result[7].ErrLevel, Equals, uint8(2))
+	c.Assert(result[7].ErrorMessage, Matches, "(?s).*Not unique table/alias: 'cte'.*")
+}
+
+func (s *testOfflineSuite) TestNamePattern(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table users(id int primary key comment 'id') comment 'users';`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	cnf.Inc.EnableForeignKey = true
+	cnf.Inc.EnableCreateView = true
+	cnf.Inc.DatabaseNamePattern = "^[a-z][a-z0-9_]*$"
+	cnf.Inc.TableNamePattern = "^[a-z][a-z0-9_]*s$"
+	cnf.Inc.ColumnNamePattern = "^[a-z][a-z0-9_]{0,9}$"
+	cnf.Inc.IndexNamePattern = "^idx_{table}_{column}$"
+	cnf.Inc.UniqIndexNamePattern = "^uniq_{table}_{column}$"
+	cnf.Inc.ForeignKeyNamePattern = "^fk_{table}_{column}$"
+	// 规则按完整名称匹配,无需^$
+	cnf.Inc.ViewNamePattern = "v_[a-z_]+"
+
+	result := s.audit(c, snapshot, `use test_inc;
+	create database test_db2;
+	create database testDb3;
+	create table orders(id int primary key comment 'id',
+		user_id int not null default 0 comment 'user_id',
+		order_no varchar(20) not null default '' comment 'order_no',
+		key idx_orders_user_id(user_id),
+		unique key uniq_orders_order_no(order_no),
+		constraint fk_orders_user_id foreign key (user_id) references users(id)) comment 'orders';
+	create table item(id int primary key comment 'id',
+		userId int not null default 0 comment 'userId',
+		order_created_time int not null default 0 comment 'order_created_time',
+		key ix_user(userId),
+		unique key uniq_item(order_created_time),
+		constraint fk_user foreign key (userId) references users(id)) comment 'item';
+	alter table orders rename index idx_orders_user_id to ix_uid;
+	create view v_users as select id from users;
+	create view user_view as select id from users;
+	create view my_v_users as select id from users;`)
+	c.Assert(len(result), Equals, 9)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[2].ErrLevel, Equals, uint8(1))
+	c.Assert(result[2].ErrorMessage, Equals,
+		"Database name 'testDb3' does not match naming pattern '^[a-z][a-z0-9_]*$'.")
+	c.Assert(result[3].ErrLevel, Equals, uint8(0), Commentf("%v", result[3].ErrorMessage))
+
+	for _, msg := range []string{
+		"Table name 'item' does not match naming pattern '^[a-z][a-z0-9_]*s$'.",
+		"Column name 'userId' does not match naming pattern '^[a-z][a-z0-9_]{0,9}$'.",
+		"Column name 'order_created_time' does not match naming pattern '^[a-z][a-z0-9_]{0,9}$'.",
+		"Index name 'ix_user' does not match naming pattern '^idx_{table}_{column}$'.",
+		"Unique index name 'uniq_item' does not match naming pattern '^uniq_{table}_{column}$'.",
+		"Foreign key name 'fk_user' does not match naming pattern '^fk_{table}_{column}$'.",
+	} {
+		c.Assert(strings.Contains(result[4].ErrorMessage, msg), IsTrue,
+			Commentf("%v", result[4].ErrorMessage))
+	}
+
+	c.Assert(result[5].ErrLevel, Equals, uint8(1))
+	c.Assert(result[5].ErrorMessage, Equals,
+		"Index name 'ix_uid' does not match naming pattern '^idx_{table}_{column}$'.")
+
+	c.Assert(result[6].ErrLevel, Equals, uint8(0), Commentf("%v", result[6].ErrorMessage))
+	c.Assert(result[7].ErrLevel, Equals, uint8(1))
+	c.Assert(result[7].ErrorMessage, Equals, "View name 'user_view' does not match naming pattern 'v_[a-z_]+'.")
+	c.Assert(result[8].ErrLevel, Equals, uint8(1))
+	c.Assert(result[8].ErrorMessage, Equals, "View name 'my_v_users' does not match naming pattern 'v_[a-z_]+'.")
+}
@@ -0,0 +1,285 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/model"
+	"github.com/hanchuanchuan/inception-core/mysql"
+	"github.com/hanchuanchuan/inception-core/util/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// routineTypeNames 存储程序类型的中文名称
+var routineTypeNames = map[ast.RoutineType]string{
+	ast.RoutineProcedure: "存储过程",
+	ast.RoutineFunction:  "函数",
+	ast.RoutineTrigger:   "触发器",
+	ast.RoutineEvent:     "事件",
+}
+
+// checkCreateRoutine 审核创建存储过程和函数
+func (s *session) checkCreateRoutine(node *ast.CreateRoutineStmt, sql string) {
+
+	log.Debug("checkCreateRoutine")
+
+	if !s.inc.EnableCreateRoutine {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建%s'%s'.",
+			routineTypeNames[node.Tp], node.Name.Name))
+		return
+	}
+
+	if !s.checkRoutineName(node.Tp, node.Name) {
+		return
+	}
+
+	s.checkRoutineDefiner(node.Tp, node.Name, node.Definer)
+
+	s.buildCreateRoutineRollback(node.Tp, node.Name, node.IfNotExists)
+}
+
+// checkCreateTrigger 审核创建触发器
+func (s *session) checkCreateTrigger(node *ast.CreateTriggerStmt, sql string) {
+
+	log.Debug("checkCreateTrigger")
+
+	if !s.inc.EnableCreateTrigger {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建%s'%s'.",
+			routineTypeNames[ast.RoutineTrigger], node.Name.Name))
+		return
+	}
+
+	if node.Table.Schema.O == "" {
+		node.Table.Schema = model.NewCIStr(s.dbName)
+	}
+
+	// 触发器必须和表在同一个库中
+	if node.Name.Schema.O == "" {
+		node.Name.Schema = node.Table.Schema
+	} else if node.Name.Schema.L != node.Table.Schema.L {
+		s.appendErrorMessage(mysql.MySQLErrName[mysql.ErrTrgInWrongSchema])
+		return
+	}
+
+	if !s.checkRoutineName(ast.RoutineTrigger, node.Name) {
+		return
+	}
+
+	s.checkRoutineDefiner(ast.RoutineTrigger, node.Name, node.Definer)
+
+	table := s.getTableFromCache(node.Table.Schema.O, node.Table.Name.O, true)
+	if table == nil {
+		return
+	}
+
+	if table.IsView {
+		s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrWrongObject],
+			table.Schema, table.Name, "BASE TABLE"))
+		return
+	}
+
+	// pt-osc通过触发器同步增量数据,gh-ost不支持有触发器的表
+	s.mysqlGetTableSize(table)
+	if s.tableUseOsc(table) {
+		s.appendErrorNo(ErrTriggerOnOscTable, table.Name)
+	}
+
+	s.buildCreateRoutineRollback(ast.RoutineTrigger, node.Name, node.IfNotExists)
+
+	// 备份时回滚语句写入触发器所在表对应的备份表
+	s.myRecord.TableInfo = table
+}
+
+// checkCreateEvent 审核创建事件
+func (s *session) checkCreateEvent(node *ast.CreateEventStmt, sql string) {
+
+	log.Debug("checkCreateEvent")
+
+	if !s.inc.EnableCreateRoutine {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建%s'%s'.",
+			routineTypeNames[ast.RoutineEvent], node.Name.Name))
+		return
+	}
+
+	if !s.checkRoutineName(ast.RoutineEvent, node.Name) {
+		return
+	}
+
+	s.checkRoutineDefiner(ast.RoutineEvent, node.Name, node.Definer)
+
+	s.buildCreateRoutineRollback(ast.RoutineEvent, node.Name, node.IfNotExists)
+}
+
+// checkDropRoutine 审核删除存储过程/函数/触发器/事件
+func (s *session) checkDropRoutine(node *ast.DropRoutineStmt, sql string) {
+
+	log.Debug("checkDropRoutine")
+
+	enabled := s.inc.EnableCreateRoutine
+	if node.Tp == ast.RoutineTrigger {
+		enabled = s.inc.EnableCreateTrigger
+	}
+	if !enabled {
+		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法删除%s'%s'.",
+			routineTypeNames[node.Tp], node.Name.Name))
+		return
+	}
+
+	if node.Name.Schema.O == "" {
+		node.Name.Schema = model.NewCIStr(s.dbName)
+	}
+
+	if !s.checkDBExists(node.Name.Schema.O, true) {
+		return
+	}
+
+	s.myRecord.DBName = node.Name.Schema.O
+	s.myRecord.TableName = node.Name.Name.O
+
+	if s.opt.Execute {
+		// 生成回滚语句
+		res, exists := s.mysqlShowCreateRoutine(node.Tp, node.Name)
+		if !exists && !node.IfExists {
+			s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrSpDoesNotExist],
+				node.Tp, node.Name.Name))
+		} else if res != "" {
+			s.myRecord.DDLRollback = res + ";"
+		}
+	}
+
+	s.myRecord.TableInfo = newRoutineTableInfo(node.Name)
+}
+
+// checkRoutineName 审核存储程序名称,未指定库名时使用当前库
+func (s *session) checkRoutineName(tp ast.RoutineType, name *ast.TableName) bool {
+	if name.Schema.O == "" {
+		name.Schema = model.NewCIStr(s.dbName)
+	}
+
+	if !s.checkDBExists(name.Schema.O, true) {
+		return false
+	}
+
+	s.checkKeyWords(name.Name.O)
+
+	var prefix string
+	switch tp {
+	case ast.RoutineProcedure:
+		prefix = s.inc.ProcedurePrefix
+	case ast.RoutineFunction:
+		prefix = s.inc.FunctionPrefix
+	case ast.RoutineTrigger:
+		prefix = s.inc.TriggerPrefix
+	case ast.RoutineEvent:
+		prefix = s.inc.EventPrefix
+	}
+	if prefix != "" && !strings.HasPrefix(name.Name.L, prefix) {
+		s.appendErrorNo(ErrRoutinePrefix, tp, name.Name.O, prefix)
+	}
+
+	s.myRecord.DBName = name.Schema.O
+	s.myRecord.TableName = name.Name.O
+
+	return s.myRecord.ErrLevel != 2
+}
+
+// checkRoutineDefiner 定义者只允许为当前用户,以免权限提升
+func (s *session) checkRoutineDefiner(tp ast.RoutineType, name *ast.TableName, definer *auth.UserIdentity) {
+	if definer == nil || definer.CurrentUser {
+		return
+	}
+	if s.opt != nil && s.opt.User != "" && definer.Username == s.opt.User {
+		return
+	}
+	s.appendErrorNo(ErrRoutineDefiner, tp, name.Name.O)
+}
+
+// buildCreateRoutineRollback 生成创建存储程序的回滚语句
+func (s *session) buildCreateRoutineRollback(tp ast.RoutineType, name *ast.TableName, ifNotExists bool) {
+	if s.myRecord.ErrLevel == 2 {
+		return
+	}
+
+	if _, exists := s.mysqlShowCreateRoutine(tp, name); exists {
+		if !ifNotExists {
+			s.appendErrorMessage(fmt.Sprintf(mysql.MySQLErrName[mysql.ErrSpAlreadyExists],
+				tp, name.Name))
+		}
+		// 已存在时不会创建,也就无需回滚
+		return
+	}
+
+	if s.opt.Execute {
+		s.myRecord.DDLRollback = fmt.Sprintf("DROP %s `%s`.`%s`;", tp, name.Schema, name.Name)
+	}
+
+	s.myRecord.TableInfo = newRoutineTableInfo(name)
+}
+
+// newRoutineTableInfo 存储程序没有表结构,仅用以记录库名和备份表名
+func newRoutineTableInfo(name *ast.TableName) *TableInfo {
+	return &TableInfo{
+		Schema: name.Schema.O,
+		Name:   name.Name.O,
+	}
+}
+
+// mysqlShowCreateRoutine 获取存储程序的创建语句,离线审核时不检查
+func (s *session) mysqlShowCreateRoutine(tp ast.RoutineType, name *ast.TableName) (string, bool) {
+
+	if s.isOffline() || s.db == nil {
+		return "", false
+	}
+
+	sql := fmt.Sprintf("SHOW CREATE %s `%s`.`%s`;", tp, name.Schema, name.Name)
+
+	rows, err := s.raw(sql)
+	if rows != nil {
+		defer rows.Close()
+	}
+
+	if err != nil {
+		if myErr, ok := err.(*mysqlDriver.MySQLError); ok {
+			switch myErr.Number {
+			case mysql.ErrSpDoesNotExist, mysql.ErrTrgDoesNotExist, mysql.ErrEventDoesNotExist:
+				return "", false
+			}
+			s.appendErrorMessage(myErr.Message)
+		} else {
+			s.appendErrorMessage(err.Error())
+		}
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		return "", false
+	}
+
+	// 各类型的返回列不同,按列名取创建语句
+	columns, err := rows.Columns()
+	if err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		s.appendErrorMessage(err.Error())
+		return "", false
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new([]byte)
+	}
+
+	var res string
+	exists := false
+	for rows.Next() {
+		exists = true
+		if err := rows.Scan(values...); err != nil {
+			log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+			continue
+		}
+		for i, col := range columns {
+			if strings.HasPrefix(col, "Create ") || col == "SQL Original Statement" {
+				res = string(*(values[i].(*[]byte)))
+			}
+		}
+	}
+	return res, exists
+}
@@ -0,0 +1,106 @@
+package session_test
+
+import (
+	"github.com/hanchuanchuan/inception-core/config"
+	. "github.com/pingcap/check"
+)
+
+func (s *testOfflineSuite) TestCreateRoutine(c *C) {
+	snapshot := `create database test_inc;
+	use test_inc;
+	create table t1(id int primary key comment 'id',
+		c1 varchar(20) not null default '' comment 'c1') comment 't1';`
+
+	cnf := config.GetGlobalConfig()
+	defer saveConfig()()
+
+	result := s.audit(c, snapshot, `use test_inc;
+	create procedure p1() select 1;
+	create trigger tr1 before insert on t1 for each row set @a = 1;`)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].ErrorMessage, Equals, "命令禁止! 无法创建存储过程'p1'.")
+	c.Assert(result[2].ErrLevel, Equals, uint8(2))
+	c.Assert(result[2].ErrorMessage, Equals, "命令禁止! 无法创建触发器'tr1'.")
+
+	cnf.Inc.EnableCreateRoutine = true
+	cnf.Inc.EnableCreateTrigger = true
+	cnf.Inc.EnableCreateView = true
+	cnf.Inc.ProcedurePrefix = "sp_"
+	result = s.audit(c, snapshot, `use test_inc;
+	create procedure sp_p1(in a int, out b int)
+	begin
+		declare c int default 0;
+		while c < a do
+			set c = c + 1;
+		end while;
+		set b = c;
+	end;
+	create definer = 'admin'@'%' function test_inc.f1(a int) returns int deterministic return a + 1;
+	create procedure p2() select 1;
+	create trigger tr1 before insert on t1 for each row set @a = 1;
+	create trigger test.tr2 before insert on t1 for each row set @a = 1;
+	create sql security invoker view v1 as select id from t1;
+	create trigger tr3 before insert on v1 for each row set @a = 1;
+	create trigger tr4 before insert on t2 for each row set @a = 1;
+	create event e1 on schedule every 1 day do delete from t1 where id < 0;
+	drop procedure if exists sp_p1;`)
+	c.Assert(len(result), Equals, 11)
+
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[1].DBName, Equals, "test_inc")
+	c.Assert(result[1].TableName, Equals, "sp_p1")
+
+	c.Assert(result[2].ErrLevel, Equals, uint8(1))
+	c.Assert(result[2].ErrorMessage, Matches, "(?s).*Definer of FUNCTION 'f1' must be the current user.*")
+
+	c.Assert(result[3].ErrLevel, Equals, uint8(1))
+	c.Assert(result[3].ErrorMessage, Equals, "PROCEDURE name 'p2' must start with 'sp_'.")
+
+	c.Assert(result[4].ErrLevel, Equals, uint8(0), Commentf("%v", result[4].ErrorMessage))
+	c.Assert(result[5].ErrLevel, Equals, uint8(2))
+	c.Assert(result[5].ErrorMessage, Equals, "Trigger in wrong schema.")
+	c.Assert(result[7].ErrLevel, Equals, uint8(2))
+	c.Assert(result[7].ErrorMessage, Equals, "'test_inc.v1' is not BASE TABLE.")
+	c.Assert(result[8].ErrLevel, Equals, uint8(2))
+	c.Assert(result[8].ErrorMessage, Matches, "(?s).*test_inc.t2.*")
+	c.Assert(result[9].ErrLevel, Equals, uint8(0), Commentf("%v", result[9].ErrorMessage))
+	c.Assert(result[10].ErrLevel, Equals, uint8(0), Commentf("%v", result[10].ErrorMessage))
+
+	// 触发器和存储过程中的常用语句
+	result = s.audit(c, snapshot, `use test_inc;
+	create trigger tr5 before update on t1 for each row
+	begin
+		if new.c1 = '' then
+			set new.c1 = old.c1;
+		end if;
+	end;
+	create procedure sp_p3(a int)
+	begin
+		declare n int;
+		select count(*) into n from t1 where id > a;
+		if n = 0 then
+			signal sqlstate '45000' set message_text = 'no rows';
+		end if;
+		call sp_p1(n, @b);
+	end;
+	create procedure sp_p4(a int)
+	begin
+		case a
+			when 1 then select 1;
+			else select 2;
+		end case;
+	end;`)
+	c.Assert(len(result), Equals, 4)
+	c.Assert(result[1].ErrLevel, Equals, uint8(0), Commentf("%v", result[1].ErrorMessage))
+	c.Assert(result[2].ErrLevel, Equals, uint8(0), Commentf("%v", result[2].ErrorMessage))
+	c.Assert(result[3].ErrLevel, Equals, uint8(0), Commentf("%v", result[3].ErrorMessage))
+
+	// 使用pt-osc或gh-ost的表不允许创建触发器
+	cnf.Osc.OscOn = true
+	cnf.Osc.OscMinTableSize = 0
+	result = s.audit(c, snapshot, `use test_inc;
+	create trigger tr1 before insert on t1 for each row set @a = 1;`)
+	c.Assert(result[1].ErrLevel, Equals, uint8(2))
+	c.Assert(result[1].ErrorMessage, Equals,
+		"Trigger is not allowed on table 't1' which may be altered by online schema change.")
+}
@@ -68,3 +68,30 @@ func sendMsg(user string, event string, title string, text string, kwargs map[st
 		return false
 	}
 }
+
+// SocketObserver 通过websocket服务(URL)向指定用户推送审核/执行事件
+type SocketObserver struct {
+	User string
+}
+
+// OnEvent implements Observer.
+func (o *SocketObserver) OnEvent(e *Event) {
+	r := e.Record
+	kwargs := map[string]interface{}{
+		"seq":           r.SeqNo,
+		"level":         r.ErrLevel,
+		"stage":         StageList[r.Stage],
+		"status":        StatusList[r.StageStatus],
+		"affected_rows": r.AffectedRows,
+		"exec_time":     r.ExecTime,
+		"backup_time":   r.BackupCostTime,
+		"opid":          r.OPID,
+		"error":         r.ErrorMessage,
+	}
+	if e.Osc != nil {
+		kwargs["percent"] = e.Osc.Percent
+		kwargs["remain_time"] = e.Osc.RemainTime
+	}
+
+	sendMsg(o.User, e.Type.String(), "执行进度", r.Sql, kwargs)
+}
@@ -111,9 +111,20 @@ func (s *session) splitCommand(ctx context.Context, stmtNode ast.StmtNode,
 		return nil, nil
 
 	case *ast.CreateViewStmt:
-		return nil, nil
+		s.addSplitNode(node.ViewName.Schema.O, node.ViewName.Name.O, false, node, sql)
 
-		s.appendErrorMessage(fmt.Sprintf("命令禁止! 无法创建视图'%s'.", node.ViewName.Name))
+	case *ast.CreateRoutineStmt:
+		s.addSplitNode(node.Name.Schema.O, node.Name.Name.O, false, node, sql)
+	case *ast.CreateTriggerStmt:
+		s.addSplitNode(node.Table.Schema.O, node.Table.Name.O, false, node, sql)
+	case *ast.CreateEventStmt:
+		s.addSplitNode(node.Name.Schema.O, node.Name.Name.O, false, node, sql)
+	case *ast.DropRoutineStmt:
+		s.addSplitNode(node.Name.Schema.O, node.Name.Name.O, false, node, sql)
+
+	case *ast.CreateUserStmt, *ast.AlterUserStmt, *ast.SetPwdStmt,
+		*ast.DropUserStmt, *ast.GrantStmt, *ast.RevokeStmt:
+		s.addSplitNode("mysql", "user", false, node, sql)
 
 	case *ast.ShowStmt:
 		return nil, nil
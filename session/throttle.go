@@ -0,0 +1,340 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/util"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// throttleMetrics 限流检查的指标
+type throttleMetrics struct {
+	// 主从延迟(秒),从库复制停止时为-1
+	lag float64
+	// 主库Threads_running
+	threadsRunning int64
+	// PXC集群在检查间隔内因流控暂停的时间比例
+	flowCtl float64
+}
+
+// throttler DML执行时的限流器.
+// 根据主从延迟,Threads_running和PXC流控状态,暂停,减慢或终止执行
+type throttler struct {
+	s        *session
+	replicas []*gorm.DB
+	// 检查间隔
+	interval time.Duration
+
+	lastCheck time.Time
+	metrics   throttleMetrics
+	// 上次检查时PXC流控累计暂停的时间(纳秒),用以计算检查间隔内的流控比例
+	lastFlowCtlPaused int64
+	// 减慢执行时,每条语句执行前的等待时间
+	slowdown time.Duration
+}
+
+// newThrottler 未配置限流阈值时返回nil
+func (s *session) newThrottler() *throttler {
+	if s.inc.ThrottleMaxLag <= 0 && s.inc.ThrottleMaxThreadsRunning == 0 &&
+		s.inc.ThrottleCriticalThreadsRunning == 0 &&
+		(s.inc.ThrottleMaxFlowCtl <= 0 || !s.isClusterNode) {
+		return nil
+	}
+
+	t := &throttler{
+		s:                 s,
+		interval:          time.Duration(s.inc.ThrottleCheckInterval) * time.Millisecond,
+		lastFlowCtlPaused: -1,
+	}
+	if t.interval <= 0 {
+		t.interval = time.Second
+	}
+	if s.inc.ThrottleMaxLag > 0 {
+		for _, addr := range strings.Split(s.inc.ThrottleReplicas, ",") {
+			addr = strings.TrimSpace(addr)
+			if addr == "" {
+				continue
+			}
+			db, err := gorm.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/?charset=%s&timeout=5s",
+				s.opt.User, s.opt.Password, addr, s.inc.DefaultCharset))
+			if err != nil {
+				log.Errorf("con:%d 连接从库%s失败: %v", s.sessionVars.ConnectionID, addr, err)
+				continue
+			}
+			db.LogMode(false)
+			t.replicas = append(t.replicas, db)
+		}
+	}
+	return t
+}
+
+func (t *throttler) close() {
+	for _, db := range t.replicas {
+		db.Close()
+	}
+	t.replicas = nil
+}
+
+// wait DML语句(或事务)执行前检查是否需要限流.
+// 超过阈值时暂停执行直到恢复,达到阈值的一半时按比例减慢执行.
+// 需要终止执行时将records标记为执行失败并返回false,避免待执行的事务被静默丢弃
+func (t *throttler) wait(ctx context.Context, records ...*Record) bool {
+	dmlCount := 0
+	for _, record := range records {
+		switch record.Type.(type) {
+		case *ast.InsertStmt, *ast.DeleteStmt, *ast.UpdateStmt:
+			dmlCount++
+		}
+	}
+	if dmlCount == 0 {
+		return true
+	}
+
+	s := t.s
+	var pausedSince time.Time
+	for {
+		if time.Since(t.lastCheck) >= t.interval {
+			t.collect()
+		}
+
+		reason, critical := t.exceeded()
+		if reason == "" {
+			break
+		}
+
+		if pausedSince.IsZero() {
+			pausedSince = time.Now()
+			log.Warnf("con:%d 暂停执行: %s", s.sessionVars.ConnectionID, reason)
+		}
+		if critical || s.inc.ThrottleMaxPauseTime > 0 &&
+			time.Since(pausedSince) >= time.Duration(s.inc.ThrottleMaxPauseTime)*time.Second {
+			for _, record := range records {
+				s.myRecord = record
+				s.appendErrorNo(ErrThrottleAbort, reason)
+				record.StageStatus = StatusExecFail
+			}
+			t.setState("")
+			return false
+		}
+		t.setState("paused: " + reason)
+
+		// 暂停期间进程被Kill
+		if err := checkClose(ctx); err != nil {
+			s.killExecute = true
+			log.Warn("Killed: ", err)
+			for _, record := range records {
+				s.myRecord = record
+				s.appendErrorMessage("Operation has been killed!")
+				record.StageStatus = StatusExecFail
+			}
+			t.setState("")
+			return false
+		}
+		time.Sleep(t.interval)
+	}
+
+	if t.slowdown > 0 {
+		t.setState(fmt.Sprintf("slowdown: %v per statement", t.slowdown))
+		time.Sleep(t.slowdown * time.Duration(dmlCount))
+	} else {
+		t.setState("")
+	}
+	return true
+}
+
+// exceeded 返回超过阈值的原因,critical表示需要立即终止执行.
+// 同时根据指标接近阈值的程度计算减慢执行的等待时间
+func (t *throttler) exceeded() (reason string, critical bool) {
+	inc := t.s.inc
+	m := t.metrics
+
+	if inc.ThrottleCriticalThreadsRunning > 0 &&
+		m.threadsRunning > int64(inc.ThrottleCriticalThreadsRunning) {
+		return fmt.Sprintf("threads_running %d > %d",
+			m.threadsRunning, inc.ThrottleCriticalThreadsRunning), true
+	}
+
+	// 各指标占阈值的最大比例
+	ratio := 0.0
+	if inc.ThrottleMaxLag > 0 {
+		if m.lag < 0 {
+			return "replication stopped", false
+		}
+		if m.lag > inc.ThrottleMaxLag {
+			return fmt.Sprintf("replication lag %.1fs > %.1fs", m.lag, inc.ThrottleMaxLag), false
+		}
+		ratio = m.lag / inc.ThrottleMaxLag
+	}
+	if inc.ThrottleMaxThreadsRunning > 0 {
+		if m.threadsRunning > int64(inc.ThrottleMaxThreadsRunning) {
+			return fmt.Sprintf("threads_running %d > %d",
+				m.threadsRunning, inc.ThrottleMaxThreadsRunning), false
+		}
+		if r := float64(m.threadsRunning) / float64(inc.ThrottleMaxThreadsRunning); r > ratio {
+			ratio = r
+		}
+	}
+	if inc.ThrottleMaxFlowCtl > 0 && t.s.isClusterNode {
+		if m.flowCtl > inc.ThrottleMaxFlowCtl {
+			return fmt.Sprintf("flow control paused %.2f > %.2f",
+				m.flowCtl, inc.ThrottleMaxFlowCtl), false
+		}
+		if r := m.flowCtl / inc.ThrottleMaxFlowCtl; r > ratio {
+			ratio = r
+		}
+	}
+
+	// 超过阈值的一半后,等待时间从0线性增加到一个检查间隔
+	t.slowdown = 0
+	if ratio > 0.5 {
+		t.slowdown = time.Duration((ratio - 0.5) * 2 * float64(t.interval))
+	}
+	return "", false
+}
+
+// collect 获取限流指标,获取失败的指标不参与限流
+func (t *throttler) collect() {
+	s := t.s
+	now := time.Now()
+	elapsed := now.Sub(t.lastCheck)
+	t.lastCheck = now
+
+	t.metrics = throttleMetrics{}
+	if s.inc.ThrottleMaxLag > 0 {
+		t.metrics.lag = t.replicationLag()
+	}
+	if s.inc.ThrottleMaxThreadsRunning > 0 || s.inc.ThrottleCriticalThreadsRunning > 0 {
+		if v, ok := t.globalStatus("Threads_running"); ok {
+			t.metrics.threadsRunning = v
+		}
+	}
+	if s.inc.ThrottleMaxFlowCtl > 0 && s.isClusterNode {
+		if v, ok := t.globalStatus("wsrep_flow_control_paused_ns"); ok {
+			if t.lastFlowCtlPaused >= 0 && v >= t.lastFlowCtlPaused && elapsed > 0 {
+				t.metrics.flowCtl = float64(v-t.lastFlowCtlPaused) / float64(elapsed)
+			}
+			t.lastFlowCtlPaused = v
+		}
+	}
+}
+
+// replicationLag 返回各从库的最大延迟.
+// 配置了延迟查询语句时使用该语句,未配置从库时在主库执行
+func (t *throttler) replicationLag() float64 {
+	s := t.s
+	if len(t.replicas) == 0 {
+		if s.inc.ThrottleLagQuery == "" {
+			return 0
+		}
+		return queryLag(s.db, s.inc.ThrottleLagQuery)
+	}
+
+	lag := 0.0
+	for _, db := range t.replicas {
+		var v float64
+		if s.inc.ThrottleLagQuery != "" {
+			v = queryLag(db, s.inc.ThrottleLagQuery)
+		} else {
+			v = slaveLag(db)
+		}
+		if v < 0 {
+			return v
+		}
+		if v > lag {
+			lag = v
+		}
+	}
+	return lag
+}
+
+// queryLag 执行延迟查询语句,结果为延迟秒数
+func queryLag(db *gorm.DB, query string) float64 {
+	var lag sql.NullFloat64
+	if err := db.DB().QueryRow(query).Scan(&lag); err != nil {
+		log.Errorf("查询主从延迟失败: %v", err)
+		return 0
+	}
+	if !lag.Valid {
+		return -1
+	}
+	return lag.Float64
+}
+
+// slaveLag 通过SHOW SLAVE STATUS获取从库延迟,多源复制时取最大值
+func slaveLag(db *gorm.DB) float64 {
+	rows, err := db.DB().Query("SHOW SLAVE STATUS")
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		log.Errorf("查询主从延迟失败: %v", err)
+		return 0
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		log.Errorf("查询主从延迟失败: %v", err)
+		return 0
+	}
+
+	lag := 0.0
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			log.Errorf("查询主从延迟失败: %v", err)
+			return 0
+		}
+		for i, name := range columns {
+			if name != "Seconds_Behind_Master" {
+				continue
+			}
+			// 复制停止时为NULL
+			if values[i] == nil {
+				return -1
+			}
+			if v, err := strconv.ParseFloat(string(values[i]), 64); err == nil && v > lag {
+				lag = v
+			}
+		}
+	}
+	return lag
+}
+
+func (t *throttler) globalStatus(name string) (int64, bool) {
+	s := t.s
+	sql := fmt.Sprintf("SHOW GLOBAL STATUS LIKE '%s';", name)
+	if s.isMiddleware() {
+		sql = s.opt.MiddlewareExtend + sql
+	}
+
+	var variable, value string
+	if err := s.db.DB().QueryRow(sql).Scan(&variable, &value); err != nil {
+		log.Errorf("con:%d %v", s.sessionVars.ConnectionID, err)
+		return 0, false
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	return v, err == nil
+}
+
+// setState 在ProcessInfo中显示限流状态
+func (t *throttler) setState(state string) {
+	tmp := t.s.processInfo.Load()
+	if tmp != nil {
+		pi := tmp.(util.ProcessInfo)
+		if pi.ThrottleState != state {
+			pi.ThrottleState = state
+			t.s.processInfo.Store(pi)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hanchuanchuan/inception-core/ast"
+	"github.com/hanchuanchuan/inception-core/config"
+	"github.com/hanchuanchuan/inception-core/sessionctx/variable"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testThrottleSuite{})
+
+type testThrottleSuite struct{}
+
+func TestThrottle(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *testThrottleSuite) newThrottler(inc config.Inc) *throttler {
+	inc.Lang = "en-US"
+	se := &session{
+		sessionVars: variable.NewSessionVars(),
+		inc:         inc,
+		recordSets:  NewRecordSets(),
+		stage:       StageExec,
+	}
+	return &throttler{
+		s:                 se,
+		interval:          time.Second,
+		lastFlowCtlPaused: -1,
+	}
+}
+
+func (s *testThrottleSuite) TestSlowdown(c *C) {
+	t := s.newThrottler(config.Inc{
+		ThrottleMaxLag:            10,
+		ThrottleMaxThreadsRunning: 100,
+	})
+
+	// 取各指标占阈值的最大比例,超过一半后等待时间从0线性增加到一个检查间隔
+	cases := []struct {
+		lag            float64
+		threadsRunning int64
+		slowdown       time.Duration
+	}{
+		{0, 0, 0},
+		{5, 50, 0},
+		{7.5, 10, 500 * time.Millisecond},
+		{1, 90, 800 * time.Millisecond},
+		{10, 100, time.Second},
+	}
+	for _, test := range cases {
+		t.metrics = throttleMetrics{lag: test.lag, threadsRunning: test.threadsRunning}
+		reason, critical := t.exceeded()
+		c.Assert(reason, Equals, "")
+		c.Assert(critical, IsFalse)
+		c.Assert(t.slowdown, Equals, test.slowdown, Commentf("%#v", test))
+	}
+
+	t.metrics = throttleMetrics{lag: 11}
+	reason, critical := t.exceeded()
+	c.Assert(reason, Equals, "replication lag 11.0s > 10.0s")
+	c.Assert(critical, IsFalse)
+
+	t.metrics = throttleMetrics{threadsRunning: 101}
+	reason, critical = t.exceeded()
+	c.Assert(reason, Equals, "threads_running 101 > 100")
+	c.Assert(critical, IsFalse)
+}
+
+func (s *testThrottleSuite) TestReplicationStopped(c *C) {
+	t := s.newThrottler(config.Inc{ThrottleMaxLag: 10})
+
+	t.metrics = throttleMetrics{lag: -1}
+	reason, critical := t.exceeded()
+	c.Assert(reason, Equals, "replication stopped")
+	c.Assert(critical, IsFalse)
+
+	// 未配置从库和延迟查询语句时不检查延迟
+	t.metrics = throttleMetrics{lag: -1, threadsRunning: 1}
+	t.collect()
+	c.Assert(t.metrics, Equals, throttleMetrics{})
+	c.Assert(time.Since(t.lastCheck) < time.Second, IsTrue)
+
+	// 未启用延迟检查时忽略
+	t = s.newThrottler(config.Inc{ThrottleMaxThreadsRunning: 100})
+	t.metrics = throttleMetrics{lag: -1}
+	reason, _ = t.exceeded()
+	c.Assert(reason, Equals, "")
+}
+
+func (s *testThrottleSuite) TestCriticalAbort(c *C) {
+	t := s.newThrottler(config.Inc{
+		ThrottleMaxThreadsRunning:      100,
+		ThrottleCriticalThreadsRunning: 200,
+	})
+	t.metrics = throttleMetrics{threadsRunning: 201}
+	reason, critical := t.exceeded()
+	c.Assert(reason, Equals, "threads_running 201 > 200")
+	c.Assert(critical, IsTrue)
+
+	// 不包含DML时不限流
+	use := &Record{Type: &ast.UseStmt{}, Buf: new(bytes.Buffer)}
+	c.Assert(t.wait(context.Background(), use), IsTrue)
+	c.Assert(use.StageStatus, Equals, StatusAuditOk)
+
+	// 终止时事务中待执行的语句均标记为执行失败
+	t.lastCheck = time.Now()
+	t.interval = time.Hour
+	trans := []*Record{
+		{Type: &ast.InsertStmt{}, Buf: new(bytes.Buffer)},
+		use,
+		{Type: &ast.UpdateStmt{}, Buf: new(bytes.Buffer)},
+	}
+	c.Assert(t.wait(context.Background(), trans...), IsFalse)
+	for _, record := range trans {
+		c.Assert(record.StageStatus, Equals, StatusExecFail)
+		c.Assert(record.ErrLevel, Equals, uint8(2))
+		c.Assert(strings.Contains(record.Buf.String(),
+			"Execution aborted by throttler: threads_running 201 > 200."), IsTrue, Commentf("%s", record.Buf.String()))
+	}
+	c.Assert(t.s.recordSets.MaxLevel, Equals, uint8(2))
+}
@@ -118,7 +118,8 @@ func isConstraintKeyTp(constraints []*ast.Constraint, colDef *ast.ColumnDef) boo
 	for _, c := range constraints {
 		// If the constraint as follows: primary key(c1, c2)
 		// we only support c1 column can be auto_increment.
-		if colDef.Name.Name.L != c.Keys[0].Column.Name.L {
+		if len(c.Keys) == 0 || c.Keys[0].Column == nil ||
+			colDef.Name.Name.L != c.Keys[0].Column.Name.L {
 			continue
 		}
 		switch c.Tp {
@@ -164,6 +165,9 @@ func (s *session) checkAutoIncrementOp(colDef *ast.ColumnDef, num int) (bool, er
 func (s *session) checkDuplicateColumnName(indexColNames []*ast.IndexColName) {
 	colNames := make(map[string]struct{}, len(indexColNames))
 	for _, indexColName := range indexColNames {
+		if indexColName.Column == nil {
+			continue
+		}
 		name := indexColName.Column.Name
 		if _, ok := colNames[name.L]; ok {
 			s.appendErrorNo(ER_DUP_FIELDNAME, name)
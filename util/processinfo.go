@@ -37,6 +37,8 @@ type ProcessInfo struct {
 	OperState string
 	// 操作进度. 审核/执行/备份时自动计算
 	Percent float64
+	// 执行DML时的限流状态,未限流时为空
+	ThrottleState string `json:",omitempty"`
 }
 
 // SessionManager is an interface for session manage. Show processlist and